@@ -14,7 +14,12 @@ type Chunk struct {
 	SlotNames []string
 	NumSlots  int
 
-	AMODLineNumber int // line number in the amod file of the this chunk declaration
+	// BuiltIn chunk types are provided by a module (e.g. "visual_location") rather than declared
+	// in the amod file. Frameworks already know about them, so they are not written out.
+	BuiltIn bool
+
+	AMODFileName   string // amod file containing this chunk declaration (may be empty)
+	AMODLineNumber int    // line number in the amod file of the this chunk declaration
 }
 
 func IsInternalChunkType(name string) bool {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/asmaloney/gactar/util/container"
 	"github.com/asmaloney/gactar/util/keyvalue"
+	"github.com/asmaloney/gactar/util/numbers"
 	"github.com/asmaloney/gactar/util/runoptions"
 )
 
@@ -35,12 +36,16 @@ type Model struct {
 	// ImplicitChunks are chunks which aren't declared, but need to be created by some frameworks.
 	// e.g. by default vanilla will create them and emit a warning:
 	// 	#|Warning: Creating chunk SHARK with no slots |#
-	// These chunk names come from the initializations & similarities.
+	// These chunk names come from the initializations, similarities & associations.
 	// We keep track of them so we can create them explicitly to avoid the warnings.
 	ImplicitChunks []string
 
 	Initializers []*Initializer
 	Similarities []*Similarity
+	Associations []*Association
+
+	// Screen is the list of items the visual module can see.
+	Screen []*ScreenItem
 
 	Productions []*Production
 
@@ -59,6 +64,27 @@ type Initializer struct {
 	ChunkName *string // optional chunk name
 	Pattern   *Pattern
 
+	BaseLevel *BaseLevel // optional (memory initializers only)
+
+	AMODFileName   string
+	AMODLineNumber int
+}
+
+// BaseLevel holds the settings used to calculate the initial base-level activation of a chunk in
+// memory (like vanilla's "set-base-levels"). The references are spread evenly from the creation
+// time until the start of the run.
+type BaseLevel struct {
+	References   int     // number of times the chunk has been referenced
+	CreationTime float64 // when the chunk was created (seconds - must be <= 0)
+}
+
+// ScreenItem is a text item on the screen at a location (in pixels).
+type ScreenItem struct {
+	Text string
+	X    int
+	Y    int
+
+	AMODFileName   string
 	AMODLineNumber int
 }
 
@@ -67,6 +93,18 @@ type Similarity struct {
 	ChunkTwo string
 	Value    float64
 
+	AMODFileName   string
+	AMODLineNumber int
+}
+
+// Association sets the associative strength (S_ji) from a source chunk (j) to a target chunk (i)
+// in memory. It overrides the strength calculated using the memory module's max_spread_strength.
+type Association struct {
+	Source string
+	Target string
+	Value  float64
+
+	AMODFileName   string
 	AMODLineNumber int
 }
 
@@ -102,10 +140,17 @@ func (model *Model) Initialize() {
 		param.Ptr(0), nil,
 	)
 
+	maxTimeParam := param.NewFloat(
+		"max_time",
+		"the maximum simulated time (seconds) to run the model",
+		nil, nil, // must be greater than 0 - checked in SetParam
+	)
+
 	parameters := param.NewParameters(param.List{
 		loggingParam,
 		traceParam,
 		seedParam,
+		maxTimeParam,
 	})
 
 	model.parameters = parameters
@@ -158,6 +203,13 @@ func (model *Model) AddSimilarity(similar *Similarity) {
 	model.ImplicitChunks = append(model.ImplicitChunks, similar.ChunkOne, similar.ChunkTwo)
 }
 
+// AddAssociation will add an association to the list and keep track of the chunk names.
+func (model *Model) AddAssociation(association *Association) {
+	model.Associations = append(model.Associations, association)
+
+	model.ImplicitChunks = append(model.ImplicitChunks, association.Source, association.Target)
+}
+
 func (model Model) HasImplicitChunks() bool {
 	return len(model.ImplicitChunks) > 0
 }
@@ -238,6 +290,122 @@ func (model Model) ImaginalModule() *modules.Imaginal {
 	return imaginal
 }
 
+// CreateBlending creates the blending module and adds it to the list.
+func (model *Model) CreateBlending() *modules.Blending {
+	blending := modules.NewBlending()
+	model.Modules = append(model.Modules, blending)
+	return blending
+}
+
+// BlendingModule gets the blending module (or returns nil if it does not exist).
+func (model Model) BlendingModule() *modules.Blending {
+	module := model.LookupModule("blending")
+	if module == nil {
+		return nil
+	}
+
+	blending, ok := module.(*modules.Blending)
+	if !ok {
+		return nil
+	}
+
+	return blending
+}
+
+// CreateMotor creates the motor module and adds it to the list.
+func (model *Model) CreateMotor() *modules.Motor {
+	motor := modules.NewMotor()
+	model.Modules = append(model.Modules, motor)
+	return motor
+}
+
+// MotorModule gets the motor module (or returns nil if it does not exist).
+func (model Model) MotorModule() *modules.Motor {
+	module := model.LookupModule("motor")
+	if module == nil {
+		return nil
+	}
+
+	motor, ok := module.(*modules.Motor)
+	if !ok {
+		return nil
+	}
+
+	return motor
+}
+
+// CreateTemporal creates the temporal module and adds it to the list.
+// It also adds the built-in chunk type used by its buffer.
+func (model *Model) CreateTemporal() *modules.Temporal {
+	temporal := modules.NewTemporal()
+	model.Modules = append(model.Modules, temporal)
+
+	model.Chunks = append(model.Chunks,
+		&Chunk{
+			TypeName:  "time",
+			SlotNames: []string{"ticks"},
+			NumSlots:  1,
+			BuiltIn:   true,
+		},
+	)
+
+	return temporal
+}
+
+// TemporalModule gets the temporal module (or returns nil if it does not exist).
+func (model Model) TemporalModule() *modules.Temporal {
+	module := model.LookupModule("temporal")
+	if module == nil {
+		return nil
+	}
+
+	temporal, ok := module.(*modules.Temporal)
+	if !ok {
+		return nil
+	}
+
+	return temporal
+}
+
+// CreateVisual creates the visual module and adds it to the list.
+// It also adds the built-in chunk types used by its buffers.
+func (model *Model) CreateVisual() *modules.Visual {
+	visual := modules.NewVisual()
+	model.Modules = append(model.Modules, visual)
+
+	model.Chunks = append(model.Chunks,
+		&Chunk{
+			TypeName:  "visual_location",
+			SlotNames: []string{"screen_x", "screen_y"},
+			NumSlots:  2,
+			BuiltIn:   true,
+		},
+		&Chunk{
+			TypeName:  "visual_object",
+			SlotNames: []string{"value"},
+			NumSlots:  1,
+			BuiltIn:   true,
+		},
+	)
+
+	return visual
+}
+
+// VisualModule gets the visual module (or returns nil if it does not exist).
+func (model Model) VisualModule() *modules.Visual {
+	module := model.LookupModule("visual")
+	if module == nil {
+		return nil
+	}
+
+	visual, ok := module.(*modules.Visual)
+	if !ok {
+		return nil
+	}
+
+	return visual
+}
+
 // LookupModule looks up the named module in the model and returns it (or nil if it does not exist).
 func (model Model) LookupModule(moduleName string) modules.Interface {
 	for _, module := range model.Modules {
@@ -306,6 +474,19 @@ func (model *Model) SetParam(kv *keyvalue.KeyValue) (err error) {
 		seed := uint32(*value.Number)
 		model.DefaultParams.RandomSeed = &seed
 
+	case "max_time":
+		maxTime := *value.Number
+		if maxTime <= 0 {
+			context := "(must be greater than 0)"
+			return param.ErrInvalidValue{
+				ParameterName: kv.Key,
+				Value:         numbers.Float64Str(maxTime),
+				Context:       &context,
+			}
+		}
+
+		model.DefaultParams.MaxTime = &maxTime
+
 	default:
 		return param.ErrUnrecognizedOption{Option: kv.Key}
 	}
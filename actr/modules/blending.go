@@ -0,0 +1,69 @@
+package modules
+
+import (
+	"github.com/asmaloney/gactar/actr/buffer"
+	"github.com/asmaloney/gactar/actr/param"
+
+	"github.com/asmaloney/gactar/util/keyvalue"
+)
+
+// Blending is a module which provides the ACT-R "blending" buffer. A blend request retrieves a
+// chunk from declarative memory whose unspecified slots are a blend of the values in all the
+// matching chunks, weighted by their probability of retrieval.
+type Blending struct {
+	Module
+
+	// "blend_temperature": the temperature used to calculate the probability of retrieval of each chunk
+	// (if not set, vanilla uses sqrt(2) * memory's instantaneous_noise)
+	// 	ccm: (unsupported)
+	// 	pyactr: (unsupported)
+	// 	vanilla (:tmp): nil
+	Temperature *float64
+}
+
+// NewBlending creates and returns a new Blending module
+func NewBlending() *Blending {
+	temperature := param.NewFloat(
+		"blend_temperature",
+		"temperature used to calculate the probability of retrieval of each chunk",
+		param.Ptr(0.0), nil,
+	)
+
+	parameters := param.NewParameters(param.List{
+		temperature,
+	})
+
+	blendingBuffer := buffer.NewBuffer("blending", buffer.BuiltIn, 0.0, nil)
+
+	return &Blending{
+		Module: Module{
+			Name:                "blending",
+			Version:             BuiltIn,
+			Description:         "provides a blending buffer which retrieves a blend of the chunks in memory",
+			BufferList:          buffer.List{blendingBuffer},
+			ParametersInterface: parameters,
+			MultipleInit:        false,
+		},
+	}
+}
+
+// Buffer returns the "blending" buffer.
+func (b Blending) Buffer() buffer.Interface {
+	return b.BufferList.At(0)
+}
+
+// SetParam is called to set our module's parameter from the parameter in the code ("param")
+func (b *Blending) SetParam(param *keyvalue.KeyValue) (err error) {
+	err = b.ValidateParam(param)
+	if err != nil {
+		return
+	}
+
+	value := param.Value
+
+	if param.Key == "blend_temperature" {
+		b.Temperature = value.Number
+	}
+
+	return
+}
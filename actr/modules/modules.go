@@ -90,11 +90,15 @@ func (m Module) AllowsMultipleInit() bool {
 
 // AllModules returns a slice of all the modules
 func AllModules() (modules []Interface) {
+	modules = append(modules, NewBlending())
 	modules = append(modules, NewExtraBuffers())
 	modules = append(modules, NewGoal())
 	modules = append(modules, NewImaginal())
 	modules = append(modules, NewDeclarativeMemory())
+	modules = append(modules, NewMotor())
 	modules = append(modules, NewProcedural())
+	modules = append(modules, NewTemporal())
+	modules = append(modules, NewVisual())
 
 	return
 }
@@ -0,0 +1,56 @@
+package modules
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr/buffer"
+)
+
+// Motor is a module which provides the ACT-R "manual" buffer used to press keys.
+type Motor struct {
+	Module
+}
+
+var validHands = []string{"left", "right"}
+var validFingers = []string{"index", "middle", "ring", "pinkie", "thumb"}
+
+// NewMotor creates and returns a new Motor module
+func NewMotor() *Motor {
+	manualBuffer := buffer.NewBuffer("manual", buffer.BuiltIn, 0.0, nil)
+
+	return &Motor{
+		Module: Module{
+			Name:         "motor",
+			Version:      BuiltIn,
+			Description:  "provides a manual buffer to press keys on a keyboard",
+			BufferList:   buffer.List{manualBuffer},
+			MultipleInit: false,
+		},
+	}
+}
+
+// Buffer returns the "manual" buffer.
+func (m Motor) Buffer() buffer.Interface {
+	return m.BufferList.At(0)
+}
+
+// IsValidHand checks if 'hand' is a valid hand for a punch request.
+func IsValidHand(hand string) bool {
+	return slices.Contains(validHands, hand)
+}
+
+// IsValidFinger checks if 'finger' is a valid finger for a punch request.
+func IsValidFinger(finger string) bool {
+	return slices.Contains(validFingers, finger)
+}
+
+// ValidHandsStr returns a list of valid hands. Used for error output.
+func ValidHandsStr() string {
+	return strings.Join(validHands, ", ")
+}
+
+// ValidFingersStr returns a list of valid fingers. Used for error output.
+func ValidFingersStr() string {
+	return strings.Join(validFingers, ", ")
+}
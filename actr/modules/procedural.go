@@ -13,6 +13,26 @@ type Procedural struct {
 	// 	pyactr (rule_firing): 0.05
 	// 	vanilla (:dat): 0.05
 	DefaultActionTime *float64
+
+	// "utility_noise": turns on utility noise & sets the noise in the utility calculation
+	// (there are no defaults since setting it activates the capability)
+	// 	ccm (PMNoise submodule 'noise')
+	// 	pyactr (utility_noise)
+	// 	vanilla (:egs)
+	UtilityNoise *float64
+
+	// "utility_learning_rate": turns on utility learning & sets the learning rate (alpha)
+	// (there are no defaults since setting it activates the capability)
+	// 	ccm (PMNew submodule 'alpha')
+	// 	pyactr (utility_learning & utility_alpha)
+	// 	vanilla (:ul & :alpha)
+	UtilityLearningRate *float64
+
+	// "initial_utility": utility of productions which do not specify one
+	// 	ccm: 0.0 (set on each production)
+	// 	pyactr: 0.0 (set on each production)
+	// 	vanilla (:iu): 0.0
+	InitialUtility *float64
 }
 
 // NewProcedural creates and returns a new Procedural module
@@ -23,8 +43,29 @@ func NewProcedural() *Procedural {
 		param.Ptr(0.0), nil,
 	)
 
+	utilityNoise := param.NewFloat(
+		"utility_noise",
+		"turns on utility noise & sets the noise in the utility calculation",
+		param.Ptr(0.0), nil,
+	)
+
+	utilityLearningRate := param.NewFloat(
+		"utility_learning_rate",
+		"turns on utility learning & sets the learning rate (alpha)",
+		param.Ptr(0.0), nil,
+	)
+
+	initialUtility := param.NewFloat(
+		"initial_utility",
+		"utility of productions which do not specify one",
+		nil, nil,
+	)
+
 	parameters := param.NewParameters(param.List{
 		defActionTime,
+		initialUtility,
+		utilityLearningRate,
+		utilityNoise,
 	})
 
 	return &Procedural{
@@ -46,9 +87,24 @@ func (p *Procedural) SetParam(param *keyvalue.KeyValue) (err error) {
 
 	value := param.Value
 
-	if param.Key == "default_action_time" {
+	switch param.Key {
+	case "default_action_time":
 		p.DefaultActionTime = value.Number
+
+	case "utility_noise":
+		p.UtilityNoise = value.Number
+
+	case "utility_learning_rate":
+		p.UtilityLearningRate = value.Number
+
+	case "initial_utility":
+		p.InitialUtility = value.Number
 	}
 
 	return
 }
+
+// IsUsingUtilityLearning returns whether utility learning is active or not.
+func (p Procedural) IsUsingUtilityLearning() bool {
+	return p.UtilityLearningRate != nil
+}
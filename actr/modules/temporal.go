@@ -0,0 +1,101 @@
+package modules
+
+import (
+	"github.com/asmaloney/gactar/actr/buffer"
+	"github.com/asmaloney/gactar/actr/param"
+
+	"github.com/asmaloney/gactar/util/keyvalue"
+)
+
+// Temporal is a module which provides the ACT-R "temporal" buffer used for interval timing.
+// A request starts a timer which puts a "time" chunk in the buffer and increments its "ticks" slot.
+// The length of each tick is the length of the previous one multiplied by "time_mult" plus noise.
+type Temporal struct {
+	Module
+
+	// "time_noise": the noise in the length of each tick (scaled by the tick's length)
+	// 	ccm: (unsupported)
+	// 	pyactr: (unsupported)
+	// 	vanilla (:time-noise): 0.015
+	TimeNoise *float64
+
+	// "time_mult": the multiplier used to calculate the length of the next tick
+	// 	ccm: (unsupported)
+	// 	pyactr: (unsupported)
+	// 	vanilla (:time-mult): 1.1
+	TimeMult *float64
+
+	// "time_start_increment": the length of the first tick (seconds)
+	// 	ccm: (unsupported)
+	// 	pyactr: (unsupported)
+	// 	vanilla (:time-master-start-increment): 0.011
+	TimeStartIncrement *float64
+}
+
+// NewTemporal creates and returns a new Temporal module
+func NewTemporal() *Temporal {
+	timeNoise := param.NewFloat(
+		"time_noise",
+		"noise in the length of each tick (scaled by the tick's length)",
+		param.Ptr(0.0), nil,
+	)
+
+	timeMult := param.NewFloat(
+		"time_mult",
+		"multiplier used to calculate the length of the next tick",
+		param.Ptr(0.0), nil,
+	)
+
+	timeStartIncrement := param.NewFloat(
+		"time_start_increment",
+		"length of the first tick (seconds)",
+		param.Ptr(0.0), nil,
+	)
+
+	parameters := param.NewParameters(param.List{
+		timeNoise,
+		timeMult,
+		timeStartIncrement,
+	})
+
+	temporalBuffer := buffer.NewBuffer("temporal", buffer.BuiltIn, 0.0, nil)
+
+	return &Temporal{
+		Module: Module{
+			Name:                "temporal",
+			Version:             BuiltIn,
+			Description:         "provides a temporal buffer which counts ticks for interval timing",
+			BufferList:          buffer.List{temporalBuffer},
+			ParametersInterface: parameters,
+			MultipleInit:        false,
+		},
+	}
+}
+
+// Buffer returns the "temporal" buffer.
+func (t Temporal) Buffer() buffer.Interface {
+	return t.BufferList.At(0)
+}
+
+// SetParam is called to set our module's parameter from the parameter in the code ("param")
+func (t *Temporal) SetParam(param *keyvalue.KeyValue) (err error) {
+	err = t.ValidateParam(param)
+	if err != nil {
+		return
+	}
+
+	value := param.Value
+
+	switch param.Key {
+	case "time_noise":
+		t.TimeNoise = value.Number
+
+	case "time_mult":
+		t.TimeMult = value.Number
+
+	case "time_start_increment":
+		t.TimeStartIncrement = value.Number
+	}
+
+	return
+}
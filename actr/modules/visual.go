@@ -0,0 +1,89 @@
+package modules
+
+import (
+	"github.com/asmaloney/gactar/actr/buffer"
+	"github.com/asmaloney/gactar/actr/param"
+
+	"github.com/asmaloney/gactar/util/keyvalue"
+)
+
+// Visual is a module which provides the ACT-R "visual_location" and "visual" buffers.
+// It looks at the items on the model's screen.
+type Visual struct {
+	Module
+
+	// "attention_latency": how long it takes to move attention to a location (seconds)
+	// 	ccm: (unsupported)
+	// 	pyactr: (unsupported - the time to encode an object is calculated using EMMA)
+	// 	vanilla (:visual-attention-latency): 0.085
+	AttentionLatency *float64
+}
+
+// t: only match locations which have been attended
+// nil: only match locations which have not been attended
+// new: only match locations which have not been attended and were recently added to the screen
+var validAttendedOptions = []string{"t", "nil", "new"}
+
+// NewVisual creates and returns a new Visual module
+func NewVisual() *Visual {
+	attentionLatency := param.NewFloat(
+		"attention_latency",
+		"time it takes to move attention to a location (seconds)",
+		param.Ptr(0.0), nil,
+	)
+
+	parameters := param.NewParameters(param.List{
+		attentionLatency,
+	})
+
+	rpAttended := param.NewStr(
+		"attended",
+		"query the visual_location buffer about locations which have been attended",
+		validAttendedOptions,
+	)
+
+	rpParameters := param.NewParameters(param.List{
+		rpAttended,
+	})
+
+	locationBuffer := buffer.NewBuffer("visual_location", buffer.BuiltIn, 0.0, rpParameters)
+	visualBuffer := buffer.NewBuffer("visual", buffer.BuiltIn, 0.0, nil)
+
+	// "visual" is first since it is the buffer used to check the module's state
+	return &Visual{
+		Module: Module{
+			Name:                "visual",
+			Version:             BuiltIn,
+			Description:         "finds items on the screen (visual_location) and attends to them (visual)",
+			BufferList:          buffer.List{visualBuffer, locationBuffer},
+			ParametersInterface: parameters,
+			MultipleInit:        false,
+		},
+	}
+}
+
+// VisualBuffer returns the "visual" buffer.
+func (v Visual) VisualBuffer() buffer.Interface {
+	return v.BufferList.At(0)
+}
+
+// LocationBuffer returns the "visual_location" buffer.
+func (v Visual) LocationBuffer() buffer.Interface {
+	return v.BufferList.At(1)
+}
+
+// SetParam is called to set our module's parameter from the parameter in the code ("param")
+func (v *Visual) SetParam(param *keyvalue.KeyValue) (err error) {
+	err = v.ValidateParam(param)
+	if err != nil {
+		return
+	}
+
+	value := param.Value
+
+	if param.Key == "attention_latency" {
+		v.AttentionLatency = value.Number
+	}
+
+	return
+}
@@ -1,6 +1,9 @@
 package actr
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 type Pattern struct {
 	AnyChunk bool
@@ -72,6 +75,70 @@ func (p Pattern) String() (str string) {
 	return
 }
 
+// Compatible checks if some chunk could match both patterns. Variables are treated as matching
+// anything - we don't check whether they are bound to other values.
+func (p Pattern) Compatible(other *Pattern) bool {
+	if p.AnyChunk || other.AnyChunk {
+		return true
+	}
+
+	if p.Chunk.TypeName != other.Chunk.TypeName {
+		return false
+	}
+
+	for i := 0; i < min(len(p.Slots), len(other.Slots)); i++ {
+		if !p.Slots[i].Compatible(other.Slots[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Compatible checks if some value could match both slots. Variables and wildcards match
+// anything, IDs and strings are treated the same, and numbers are compared by value.
+func (p PatternSlot) Compatible(other *PatternSlot) bool {
+	a, b := p.matchValue(), other.matchValue()
+
+	switch {
+	case a == "" || b == "":
+		return true
+
+	case p.Negated && other.Negated:
+		return true
+
+	case p.Negated || other.Negated:
+		return a != b
+	}
+
+	return a == b
+}
+
+// matchValue returns the value of the slot in a form we can compare or an empty string if the
+// slot matches anything.
+func (p PatternSlot) matchValue() string {
+	switch {
+	case p.Nil:
+		return "nil"
+
+	case p.ID != nil:
+		return "sym:" + *p.ID
+
+	case p.Str != nil:
+		return "sym:" + *p.Str
+
+	case p.Num != nil:
+		num, err := strconv.ParseFloat(*p.Num, 64)
+		if err != nil {
+			return "num:" + *p.Num
+		}
+
+		return "num:" + strconv.FormatFloat(num, 'g', -1, 64)
+	}
+
+	return ""
+}
+
 func (p *Pattern) AddSlot(slot *PatternSlot) {
 	p.Slots = append(p.Slots, slot)
 }
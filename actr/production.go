@@ -15,12 +15,16 @@ type Production struct {
 	Name        string
 	Description *string // optional description to output as a comment in the generated code
 
+	Utility *float64 // optional initial utility (overrides the procedural module's "initial_utility")
+	Reward  *float64 // optional reward to trigger when this production fires (used for utility learning)
+
 	VarIndexMap map[string]VarIndex // track the buffer and slot name each variable refers to
 
 	Matches      []*Match
 	DoStatements []*Statement
 
-	AMODLineNumber int // line number in the amod file of the this production
+	AMODFileName   string // amod file containing this production (may be empty)
+	AMODLineNumber int    // line number in the amod file of the this production
 }
 
 // VarIndex is used to track which buffer slot a variable refers to
@@ -35,6 +39,10 @@ type Comparison int
 const (
 	Equal Comparison = iota
 	NotEqual
+	LessThan
+	LessThanOrEqual
+	GreaterThan
+	GreaterThanOrEqual
 )
 
 func (c Comparison) String() string {
@@ -43,11 +51,24 @@ func (c Comparison) String() string {
 		return "=="
 	case NotEqual:
 		return "!="
+	case LessThan:
+		return "<"
+	case LessThanOrEqual:
+		return "<="
+	case GreaterThan:
+		return ">"
+	case GreaterThanOrEqual:
+		return ">="
 	}
 
 	return "unknown"
 }
 
+// IsRelational returns whether this is a numeric comparison (<, <=, >, >=).
+func (c Comparison) IsRelational() bool {
+	return c >= LessThan
+}
+
 type Constraint struct {
 	LHS        *string
 	Comparison Comparison
@@ -90,11 +111,30 @@ type ModuleStateMatch struct {
 }
 
 type Statement struct {
-	Clear  *ClearStatement
-	Print  *PrintStatement
-	Recall *RecallStatement
-	Set    *SetStatement
-	Stop   *StopStatement
+	Attend       *AttendStatement
+	Blend        *BlendStatement
+	Clear        *ClearStatement
+	FindLocation *FindLocationStatement
+	Print        *PrintStatement
+	PressKey     *PressKeyStatement
+	Punch        *PunchStatement
+	Recall       *RecallStatement
+	Reinforce    *ReinforceStatement
+	Remember     *RememberStatement
+	Set          *SetStatement
+	StartTimer   *StartTimerStatement
+	Stop         *StopStatement
+}
+
+// AttendStatement moves visual attention to the location in the visual_location buffer.
+// The object found there is put in the visual buffer. There are no parameters.
+type AttendStatement struct {
+}
+
+// BlendStatement is used to pull a blend of the matching chunks from memory into the blending buffer.
+// Slots in the pattern which are wildcards get the blended values.
+type BlendStatement struct {
+	Pattern *Pattern
 }
 
 // ClearStatement clears a list of buffers.
@@ -109,6 +149,7 @@ type Value struct {
 	ID     *string
 	Str    *string
 	Number *string
+	Expr   *Expression // arithmetic expression (only used when setting a slot)
 }
 
 func (v Value) String() string {
@@ -123,11 +164,135 @@ func (v Value) String() string {
 		return *v.Str
 	case v.Number != nil:
 		return *v.Number
+	case v.Expr != nil:
+		return v.Expr.String()
 	}
 
 	return "unknown"
 }
 
+// PatternSlot returns a slot which matches this value. Variables and expressions are not known
+// until the model runs, so they become wildcards.
+func (v Value) PatternSlot() *PatternSlot {
+	switch {
+	case v.Nil != nil:
+		return &PatternSlot{Nil: true}
+	case v.ID != nil:
+		return &PatternSlot{ID: v.ID}
+	case v.Str != nil:
+		return &PatternSlot{Str: v.Str}
+	case v.Number != nil:
+		return &PatternSlot{Num: v.Number}
+	}
+
+	return &PatternSlot{Wildcard: true}
+}
+
+// Operator is an arithmetic operator used in an Expression.
+type Operator int
+
+const (
+	Add Operator = iota
+	Subtract
+	Multiply
+	Divide
+)
+
+func (o Operator) String() string {
+	switch o {
+	case Add:
+		return "+"
+	case Subtract:
+		return "-"
+	case Multiply:
+		return "*"
+	case Divide:
+		return "/"
+	}
+
+	return "unknown"
+}
+
+// precedence is used to decide where we need parentheses when outputting expressions.
+func (o Operator) precedence() int {
+	if o == Multiply || o == Divide {
+		return 2
+	}
+
+	return 1
+}
+
+// Expression is an arithmetic expression. It is either an operand (a number or a variable)
+// or an operation on two expressions.
+type Expression struct {
+	Operand *Value // if this is set, the other fields are not used
+
+	Operator Operator
+	LHS      *Expression
+	RHS      *Expression
+}
+
+// String outputs the expression using infix notation (e.g. "?count + 1").
+// Variables are output with a "?" prefix.
+func (e Expression) String() string {
+	if e.Operand != nil {
+		if e.Operand.Var != nil {
+			return "?" + *e.Operand.Var
+		}
+
+		return e.Operand.String()
+	}
+
+	lhs := e.LHS.String()
+	if e.LHS.Operand == nil && e.LHS.Operator.precedence() < e.Operator.precedence() {
+		lhs = "(" + lhs + ")"
+	}
+
+	// operators are left-associative, so an operation on the right needs parentheses unless
+	// it has higher precedence
+	rhs := e.RHS.String()
+	if e.RHS.Operand == nil && e.RHS.Operator.precedence() <= e.Operator.precedence() {
+		rhs = "(" + rhs + ")"
+	}
+
+	return fmt.Sprintf("%s %s %s", lhs, e.Operator, rhs)
+}
+
+// Vars returns the names of all the variables used in the expression (without the "?" prefix).
+func (e Expression) Vars() (vars []string) {
+	if e.Operand != nil {
+		if e.Operand.Var != nil {
+			vars = append(vars, *e.Operand.Var)
+		}
+
+		return
+	}
+
+	vars = append(vars, e.LHS.Vars()...)
+	vars = append(vars, e.RHS.Vars()...)
+
+	return
+}
+
+// FindLocationStatement requests a location on the screen from the visual module.
+// The slots of the pattern may use "lowest" or "highest" to find the item with the
+// lowest or highest value of that slot.
+type FindLocationStatement struct {
+	Pattern           *Pattern
+	RequestParameters map[string]string
+}
+
+// PressKeyStatement asks the motor module to press a key.
+type PressKeyStatement struct {
+	Key *Value // string or var
+}
+
+// PunchStatement asks the motor module to strike the key under a finger.
+type PunchStatement struct {
+	Hand   string
+	Finger string
+}
+
 // PrintStatement outputs the string, id, or number to stdout.
 type PrintStatement struct {
 	Values *[]*Value
@@ -145,6 +310,18 @@ type RecallStatement struct {
 	RequestParameters map[string]string
 }
 
+// ReinforceStatement adds a reference to a chunk in memory at the current time.
+// The chunk is either named in the initializers or is the value of a variable.
+type ReinforceStatement struct {
+	Chunk *Value // var or ID
+}
+
+// RememberStatement adds a chunk to memory at the current time. If memory already
+// contains a chunk with the same contents, that chunk gets a new reference instead.
+type RememberStatement struct {
+	Pattern *Pattern
+}
+
 type SetSlot struct {
 	Name      string
 	SlotIndex int // (this slot index in the chunk)
@@ -165,6 +342,12 @@ type SetStatement struct {
 	Pattern *Pattern // (2) pattern if we are setting the whole buffer
 }
 
+// StartTimerStatement asks the temporal module to start a new timer. The timer's "time" chunk
+// is put in the temporal buffer and its ticks are incremented until the buffer is cleared.
+// There are no parameters.
+type StartTimerStatement struct {
+}
+
 // StopStatement outputs a stop command. There are no parameters.
 type StopStatement struct {
 }
@@ -192,6 +375,12 @@ func (p *Production) AddDoStatement(statement *Statement) {
 
 	case statement.Recall != nil:
 		p.Model.AddImplicitChunksFromPattern(statement.Recall.Pattern)
+
+	case statement.Blend != nil:
+		p.Model.AddImplicitChunksFromPattern(statement.Blend.Pattern)
+
+	case statement.Remember != nil:
+		p.Model.AddImplicitChunksFromPattern(statement.Remember.Pattern)
 	}
 
 }
@@ -274,3 +463,44 @@ func (p Production) LookupMatchByVariable(varName string) *BufferPatternMatch {
 
 	return nil
 }
+
+// HasRelationalConstraints returns whether any of the production's matches compare
+// numbers using <, <=, >, or >=.
+func (p Production) HasRelationalConstraints() bool {
+	for _, m := range p.Matches {
+		if m.BufferPattern == nil || m.BufferPattern.Pattern == nil {
+			continue
+		}
+
+		for _, slot := range m.BufferPattern.Pattern.Slots {
+			if slot.Var == nil {
+				continue
+			}
+
+			for _, constraint := range slot.Var.Constraints {
+				if constraint.Comparison.IsRelational() {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// HasExpressions returns whether any of the production's set statements use an arithmetic expression.
+func (p Production) HasExpressions() bool {
+	for _, statement := range p.DoStatements {
+		if statement.Set == nil || statement.Set.Slots == nil {
+			continue
+		}
+
+		for _, slot := range *statement.Set.Slots {
+			if slot.Value.Expr != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -8,9 +8,11 @@ import (
 	"os"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
 
 	"github.com/asmaloney/gactar/actr"
 	"github.com/asmaloney/gactar/actr/buffer"
@@ -52,13 +54,15 @@ func OutputEBNF() {
 }
 
 // GenerateModel generates a model from the text in the buffer.
+// Since there is no file to resolve paths against, the model may not include other files.
 func GenerateModel(buffer string) (model *actr.Model, iLog *issues.Log, err error) {
 	r := strings.NewReader(buffer)
 
-	return modelReader(r)
+	return modelReader("", r)
 }
 
 // GenerateModelFromFile generates a model from the file 'fileName'.
+// Any files it includes are resolved relative to it.
 func GenerateModelFromFile(fileName string) (model *actr.Model, iLog *issues.Log, err error) {
 	file, err := os.Open(fileName)
 	if err != nil {
@@ -66,7 +70,7 @@ func GenerateModelFromFile(fileName string) (model *actr.Model, iLog *issues.Log
 	}
 	defer file.Close()
 
-	return modelReader(file)
+	return modelReader(fileName, file)
 }
 
 // ParseChunk is used to parse goals when given as input from a user.
@@ -110,30 +114,26 @@ func ParseChunk(model *actr.Model, chunk string) (*actr.Pattern, error) {
 	return createChunkPattern(model, log, p)
 }
 
-// modelReader reads the model from a reader and generates the actr.Model
-func modelReader(r io.Reader) (model *actr.Model, iLog *issues.Log, err error) {
+// modelReader reads the model from a reader and generates the actr.Model.
+// "fileName" is used to resolve included files and for the location of issues.
+func modelReader(fileName string, r io.Reader) (model *actr.Model, iLog *issues.Log, err error) {
 	log := newLog()
 	iLog = &log.Log
 
-	amod, err := parseAMOD(r)
+	amod, err := parseAMOD(fileName, r)
 	if err != nil {
-		pErr, ok := err.(participle.Error)
-		if ok {
-			location := issues.Location{
-				SourceFile:  pErr.Position().Filename,
-				Line:        pErr.Position().Line,
-				ColumnStart: pErr.Position().Column,
-				ColumnEnd:   pErr.Position().Column,
-			}
-			log.Error(&location, pErr.Message())
-		} else {
-			log.Error(&issues.Location{}, err.Error())
-		}
+		logParseError(log, err)
 
 		err = ErrParse
 		return
 	}
 
+	includeFiles(amod, fileName, log)
+	if log.HasError() {
+		err = ErrParse
+		return
+	}
+
 	model, err = generateModel(amod, log)
 	if err != nil {
 		return
@@ -143,6 +143,34 @@ func modelReader(r io.Reader) (model *actr.Model, iLog *issues.Log, err error) {
 	return
 }
 
+// logParseError adds the error from parsing to the log
+func logParseError(log *issueLog, err error) {
+	var lexErr LexError
+	if errors.As(err, &lexErr) {
+		location := issues.Location{
+			SourceFile:  lexErr.Filename,
+			Line:        lexErr.Line,
+			ColumnStart: lexErr.Position,
+			ColumnEnd:   lexErr.Position,
+		}
+		log.Error(&location, lexErr.Value)
+		return
+	}
+
+	pErr, ok := err.(participle.Error)
+	if ok {
+		location := issues.Location{
+			SourceFile:  pErr.Position().Filename,
+			Line:        pErr.Position().Line,
+			ColumnStart: pErr.Position().Column,
+			ColumnEnd:   pErr.Position().Column,
+		}
+		log.Error(&location, pErr.Message())
+	} else {
+		log.Error(&issues.Location{}, err.Error())
+	}
+}
+
 // generateModel runs through the parsed structures and creates an actr.Model from them
 func generateModel(amod *amodFile, log *issueLog) (model *actr.Model, err error) {
 	model = &actr.Model{
@@ -224,7 +252,8 @@ func addGACTAR(model *actr.Model, log *issueLog, config *gactarConfig) {
 				// value errors
 			case errors.As(err, &keyvalue.ErrInvalidType{}) ||
 				errors.As(err, &param.ErrInvalidType{}) ||
-				errors.As(err, &param.ErrInvalidValue{}):
+				errors.As(err, &param.ErrInvalidValue{}) ||
+				errors.As(err, &param.ErrValueOutOfRange{}):
 				log.errorTR(value.Tokens, 1, 1, "'%s' %v", field.Key, err)
 				continue
 
@@ -250,6 +279,8 @@ func addModules(model *actr.Model, log *issueLog, config *moduleConfig) {
 		_ = validateFieldList(log, module.Fields)
 
 		switch module.ModuleName {
+		case "blending":
+			addBlending(model, log, module.Fields)
 		case "extra_buffers":
 			addExtraBuffers(model, log, module.Fields)
 		case "goal":
@@ -258,8 +289,14 @@ func addModules(model *actr.Model, log *issueLog, config *moduleConfig) {
 			addImaginal(model, log, module.Fields)
 		case "memory":
 			addMemory(model, log, module.Fields)
+		case "motor":
+			addMotor(model, log, module.Fields)
 		case "procedural":
 			addProcedural(model, log, module.Fields)
+		case "temporal":
+			addTemporal(model, log, module.Fields)
+		case "visual":
+			addVisual(model, log, module.Fields)
 		default:
 			log.errorT(module.Tokens, "unrecognized module in config: '%s'", module.ModuleName)
 		}
@@ -362,6 +399,12 @@ func setModuleParams(module modules.Interface, log *issueLog, fields []*field) {
 	}
 }
 
+func addBlending(model *actr.Model, log *issueLog, fields []*field) {
+	blending := model.CreateBlending()
+
+	setModuleParams(blending, log, fields)
+}
+
 func addExtraBuffers(model *actr.Model, log *issueLog, fields []*field) {
 	eb := model.CreateExtraBuffers()
 
@@ -382,10 +425,28 @@ func addMemory(model *actr.Model, log *issueLog, fields []*field) {
 	setModuleParams(model.Memory, log, fields)
 }
 
+func addMotor(model *actr.Model, log *issueLog, fields []*field) {
+	motor := model.CreateMotor()
+
+	setModuleParams(motor, log, fields)
+}
+
 func addProcedural(model *actr.Model, log *issueLog, fields []*field) {
 	setModuleParams(model.Procedural, log, fields)
 }
 
+func addTemporal(model *actr.Model, log *issueLog, fields []*field) {
+	temporal := model.CreateTemporal()
+
+	setModuleParams(temporal, log, fields)
+}
+
+func addVisual(model *actr.Model, log *issueLog, fields []*field) {
+	visual := model.CreateVisual()
+
+	setModuleParams(visual, log, fields)
+}
+
 func addChunks(model *actr.Model, log *issueLog, config *chunkConfig) {
 	if config == nil {
 		return
@@ -406,6 +467,7 @@ func addChunks(model *actr.Model, log *issueLog, config *chunkConfig) {
 			TypeName:       chunk.TypeName,
 			SlotNames:      chunk.Slots,
 			NumSlots:       len(chunk.Slots),
+			AMODFileName:   chunk.Tokens[0].Pos.Filename,
 			AMODLineNumber: chunk.Tokens[0].Pos.Line,
 		}
 
@@ -426,17 +488,58 @@ func addInitializers(model *actr.Model, log *issueLog, module modules.Interface,
 		return
 	}
 
+	baseLevel, err := createBaseLevel(model, log, module, init)
+	if err != nil {
+		return
+	}
+
 	model.AddInitializer(
 		&actr.Initializer{
 			Module:         module,
 			Buffer:         buffer,
 			ChunkName:      init.ChunkName,
 			Pattern:        actrPattern,
+			BaseLevel:      baseLevel,
+			AMODFileName:   init.Tokens[0].Pos.Filename,
 			AMODLineNumber: init.Tokens[0].Pos.Line,
 		},
 	)
 }
 
+// createBaseLevel converts the base-level settings from an initializer's "with" clause.
+func createBaseLevel(model *actr.Model, log *issueLog, module modules.Interface, init *namedInitializer) (*actr.BaseLevel, error) {
+	if init.With == nil {
+		return nil, nil
+	}
+
+	err := validateInitializerWith(model, log, module, init)
+	if err != nil {
+		return nil, err
+	}
+
+	baseLevel := actr.BaseLevel{
+		References: 1,
+	}
+
+	for _, expr := range *init.With.Expressions {
+		// the numbers were checked (and any errors logged) by validateInitializerWith
+		number, err := strconv.ParseFloat(*expr.Value.Arg.Number, 64)
+		if err != nil {
+			return nil, ErrCompile
+		}
+
+		switch expr.Param {
+		case "references":
+			baseLevel.References = int(number)
+
+		case "creation_time":
+			baseLevel.CreationTime = number
+		}
+	}
+
+	return &baseLevel, nil
+}
+
 func addInit(model *actr.Model, log *issueLog, init *initSection) {
 	if init == nil {
 		return
@@ -466,6 +569,38 @@ func addInit(model *actr.Model, log *issueLog, init *initSection) {
 					}
 				}
 			}
+		} else if initialization.ScreenInitializer != nil {
+			screen := initialization.ScreenInitializer
+			err := validateScreenInitialization(model, log, screen)
+			if err != nil {
+				continue
+			}
+
+			for _, item := range screen.Items {
+				x, _ := strconv.Atoi(item.X)
+				y, _ := strconv.Atoi(item.Y)
+
+				model.Screen = append(model.Screen, &actr.ScreenItem{
+					Text:           item.Text,
+					X:              x,
+					Y:              y,
+					AMODFileName:   item.Tokens[0].Pos.Filename,
+					AMODLineNumber: item.Tokens[0].Pos.Line,
+				})
+			}
+		} else if initialization.AssociationInitializer != nil {
+			associationInitializer := initialization.AssociationInitializer
+			validateAssociationInitialization(model, log, associationInitializer)
+
+			for _, association := range associationInitializer.AssociationList {
+				model.AddAssociation(&actr.Association{
+					Source:         association.Source,
+					Target:         association.Target,
+					Value:          association.Value,
+					AMODFileName:   association.Tokens[0].Pos.Filename,
+					AMODLineNumber: association.Tokens[0].Pos.Line,
+				})
+			}
 		} else if initialization.SimilarityInitializer != nil {
 			partialInitializer := initialization.SimilarityInitializer
 
@@ -474,6 +609,7 @@ func addInit(model *actr.Model, log *issueLog, init *initSection) {
 					ChunkOne:       similar.ChunkOne,
 					ChunkTwo:       similar.ChunkTwo,
 					Value:          similar.Value,
+					AMODFileName:   similar.Tokens[0].Pos.Filename,
 					AMODLineNumber: similar.Tokens[0].Pos.Line,
 				}
 
@@ -483,6 +619,53 @@ func addInit(model *actr.Model, log *issueLog, init *initSection) {
 	}
 }
 
+// addUtility converts the production's utility and reward.
+func addUtility(model *actr.Model, log *issueLog, production *production, prod *actr.Production) {
+	if production.Utility != nil {
+		utility, err := parseNumber(log, production.Tokens, *production.Utility)
+		if err == nil {
+			prod.Utility = &utility
+		}
+	}
+
+	if production.Reward != nil {
+		reward, err := parseNumber(log, production.Tokens, *production.Reward)
+		if err == nil {
+			prod.Reward = &reward
+		}
+
+		if !model.Procedural.IsUsingUtilityLearning() {
+			log.Warning(tokensToLocation(production.Tokens[:1]),
+				"reward in production '%s' has no effect unless the procedural module's 'utility_learning_rate' is set", production.Name)
+		}
+	}
+}
+
+// parseNumber converts a number from the amod source. If it is out of range (e.g. 1e400), it logs
+// an error at the number's location in "tokens".
+func parseNumber(log *issueLog, tokens []lexer.Token, numStr string) (number float64, err error) {
+	number, err = strconv.ParseFloat(numStr, 64)
+	if err == nil {
+		return
+	}
+
+	location := tokens
+	if len(location) > 1 {
+		location = location[:1]
+	}
+
+	for i, token := range tokens {
+		if token.Value == numStr {
+			location = tokens[i : i+1]
+			break
+		}
+	}
+
+	log.errorT(location, "number is out of range")
+
+	return 0, ErrCompile
+}
+
 func addProductions(model *actr.Model, log *issueLog, productions *productionSection) {
 	if productions == nil {
 		return
@@ -494,9 +677,12 @@ func addProductions(model *actr.Model, log *issueLog, productions *productionSec
 			Name:           production.Name,
 			Description:    production.Description,
 			VarIndexMap:    map[string]actr.VarIndex{},
+			AMODFileName:   production.Tokens[0].Pos.Filename,
 			AMODLineNumber: production.Tokens[0].Pos.Line,
 		}
 
+		addUtility(model, log, production, &prod)
+
 		err := validateMatch(production.Match, model, log, &prod)
 		if err != nil {
 			continue
@@ -541,15 +727,9 @@ func addProductions(model *actr.Model, log *issueLog, productions *productionSec
 
 				if match.BufferPattern.When != nil {
 					for _, expr := range *match.BufferPattern.When.Expressions {
-						comparison := actr.Equal
-
-						if expr.Comparison.NotEqual != nil {
-							comparison = actr.NotEqual
-						}
-
 						actrConstraint := actr.Constraint{
 							LHS:        &expr.LHS,
-							Comparison: comparison,
+							Comparison: convertComparison(expr.Comparison),
 							RHS:        convertWhenArg(expr.RHS),
 						}
 
@@ -587,8 +767,8 @@ func addProductions(model *actr.Model, log *issueLog, productions *productionSec
 				module := model.LookupModule(name)
 
 				// The generated code for the frameworks actually uses a buffer name, not the module name.
-				// So store (one) here for convenience. If the module has multiple buffers it should not
-				// matter which one we pick as the requests should be on its module.
+				// So store (one) here for convenience. If the module has multiple buffers we use the first
+				// one, so modules list the buffer which tracks the module's state first (e.g. "visual").
 				buffer := module.Buffers().At(0)
 
 				actrMatch := &actr.ModuleStateMatch{
@@ -741,6 +921,15 @@ func addStatement(model *actr.Model, log *issueLog, statement *statement, produc
 	case statement.Recall != nil:
 		s, err = createRecallStatement(model, log, statement.Recall, production)
 
+	case statement.Blend != nil:
+		s, err = createBlendStatement(model, log, statement.Blend, production)
+
+	case statement.Remember != nil:
+		s, err = createRememberStatement(model, log, statement.Remember, production)
+
+	case statement.Reinforce != nil:
+		s, err = createReinforceStatement(model, log, statement.Reinforce, production)
+
 	case statement.Clear != nil:
 		s, err = createClearStatement(model, log, statement.Clear, production)
 
@@ -750,6 +939,21 @@ func addStatement(model *actr.Model, log *issueLog, statement *statement, produc
 	case statement.Stop != nil:
 		s = createStopStatement()
 
+	case statement.FindLocation != nil:
+		s, err = createFindLocationStatement(model, log, statement.FindLocation, production)
+
+	case statement.Attend != nil:
+		s, err = createAttendStatement(model, log, statement.Attend, production)
+
+	case statement.PressKey != nil:
+		s, err = createPressKeyStatement(model, log, statement.PressKey, production)
+
+	case statement.Punch != nil:
+		s, err = createPunchStatement(model, log, statement.Punch, production)
+
+	case statement.StartTimer != nil:
+		s, err = createStartTimerStatement(model, log, statement.StartTimer, production)
+
 	default:
 		return ErrStatementNotHandled
 	}
@@ -796,8 +1000,13 @@ func createSetStatement(model *actr.Model, log *issueLog, set *setStatement, pro
 		value := &actr.Value{}
 
 		switch {
-		case set.Value.Arg != nil:
-			valueArg := set.Value.Arg
+		case set.Value.Expr != nil:
+			valueArg := set.Value.Expr.arg()
+			if valueArg == nil {
+				value.Expr = convertExpression(set.Value.Expr)
+				break
+			}
+
 			switch {
 			case valueArg.Var != nil:
 				varName := strings.TrimPrefix(*valueArg.Var, "?")
@@ -872,6 +1081,64 @@ func createRecallStatement(model *actr.Model, log *issueLog, recall *recallState
 	return &s, nil
 }
 
+func createBlendStatement(model *actr.Model, log *issueLog, blend *blendStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validateBlendStatement(blend, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := createChunkPattern(model, log, blend.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	s := actr.Statement{
+		Blend: &actr.BlendStatement{
+			Pattern: pattern,
+		},
+	}
+
+	return &s, nil
+}
+
+func createRememberStatement(model *actr.Model, log *issueLog, remember *rememberStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validateRememberStatement(remember, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := createChunkPattern(model, log, remember.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	s := actr.Statement{
+		Remember: &actr.RememberStatement{
+			Pattern: pattern,
+		},
+	}
+
+	return &s, nil
+}
+
+func createReinforceStatement(model *actr.Model, log *issueLog, reinforce *reinforceStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validateReinforceStatement(reinforce, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	s := actr.Statement{
+		Reinforce: &actr.ReinforceStatement{
+			Chunk: &actr.Value{
+				Var: reinforce.Var,
+				ID:  reinforce.ID,
+			},
+		},
+	}
+
+	return &s, nil
+}
+
 func createClearStatement(model *actr.Model, log *issueLog, clear *clearStatement, production *actr.Production) (*actr.Statement, error) {
 	err := validateClearStatement(clear, model, log, production)
 	if err != nil {
@@ -907,6 +1174,85 @@ func createStopStatement() *actr.Statement {
 	return &actr.Statement{Stop: &actr.StopStatement{}}
 }
 
+func createFindLocationStatement(model *actr.Model, log *issueLog, find *findLocationStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validateFindLocationStatement(find, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := createChunkPattern(model, log, find.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	requestParameters := make(map[string]string)
+
+	if find.With != nil {
+		for _, param := range *find.With.Expressions {
+			value := convertWithArg(param.Value)
+			requestParameters[param.Param] = value.String()
+		}
+	}
+
+	s := actr.Statement{
+		FindLocation: &actr.FindLocationStatement{
+			Pattern:           pattern,
+			RequestParameters: requestParameters,
+		},
+	}
+
+	return &s, nil
+}
+
+func createAttendStatement(model *actr.Model, log *issueLog, attend *attendStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validateAttendStatement(attend, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	return &actr.Statement{Attend: &actr.AttendStatement{}}, nil
+}
+
+func createPressKeyStatement(model *actr.Model, log *issueLog, press *pressKeyStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validatePressKeyStatement(press, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	s := actr.Statement{
+		PressKey: &actr.PressKeyStatement{
+			Key: convertArg(press.Key),
+		},
+	}
+
+	return &s, nil
+}
+
+func createPunchStatement(model *actr.Model, log *issueLog, punch *punchStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validatePunchStatement(punch, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	s := actr.Statement{
+		Punch: &actr.PunchStatement{
+			Hand:   punch.Hand,
+			Finger: punch.Finger,
+		},
+	}
+
+	return &s, nil
+}
+
+func createStartTimerStatement(model *actr.Model, log *issueLog, start *startTimerStatement, production *actr.Production) (*actr.Statement, error) {
+	err := validateStartTimerStatement(start, model, log, production)
+	if err != nil {
+		return nil, err
+	}
+
+	return &actr.Statement{StartTimer: &actr.StartTimerStatement{}}, nil
+}
+
 func convertArg(v *arg) (actrValue *actr.Value) {
 	actrValue = &actr.Value{}
 
@@ -924,6 +1270,95 @@ func convertArg(v *arg) (actrValue *actr.Value) {
 	return
 }
 
+func convertComparison(c *comparisonOperator) actr.Comparison {
+	switch {
+	case c.NotEqual != nil:
+		return actr.NotEqual
+	case c.LessThan != nil:
+		return actr.LessThan
+	case c.LessThanOrEqual != nil:
+		return actr.LessThanOrEqual
+	case c.GreaterThan != nil:
+		return actr.GreaterThan
+	case c.GreaterThanOrEqual != nil:
+		return actr.GreaterThanOrEqual
+	}
+
+	return actr.Equal
+}
+
+// convertExpression converts the parsed expression into a tree of actr.Expressions.
+// Operations are left-associative.
+func convertExpression(e *expression) *actr.Expression {
+	expr := convertTerm(e.Left)
+
+	for _, op := range e.Right {
+		operator := actr.Add
+		rhs := convertTerm(op.Term)
+
+		switch {
+		case op.Operator != nil && *op.Operator == "-":
+			operator = actr.Subtract
+
+		case op.Operator == nil:
+			// The term starts with a signed number, so use its sign as the operator.
+			// Validation makes sure we have a number here.
+			leftmost := findLeftmostOperand(rhs)
+
+			if strings.HasPrefix(*leftmost.Number, "-") {
+				operator = actr.Subtract
+			}
+
+			unsigned := strings.TrimLeft(*leftmost.Number, "+-")
+			leftmost.Number = &unsigned
+		}
+
+		expr = &actr.Expression{Operator: operator, LHS: expr, RHS: rhs}
+	}
+
+	return expr
+}
+
+func convertTerm(t *term) *actr.Expression {
+	expr := convertOperand(t.Left)
+
+	for _, op := range t.Right {
+		operator := actr.Multiply
+		if op.Operator == "/" {
+			operator = actr.Divide
+		}
+
+		expr = &actr.Expression{Operator: operator, LHS: expr, RHS: convertOperand(op.Operand)}
+	}
+
+	return expr
+}
+
+func convertOperand(o *operand) *actr.Expression {
+	if o.Expr != nil {
+		return convertExpression(o.Expr)
+	}
+
+	value := convertArg(o.Arg)
+
+	// set statements store variables without the "?"
+	if value.Var != nil {
+		varName := strings.TrimPrefix(*value.Var, "?")
+		value.Var = &varName
+	}
+
+	return &actr.Expression{Operand: value}
+}
+
+// findLeftmostOperand returns the first operand in the expression.
+func findLeftmostOperand(e *actr.Expression) *actr.Value {
+	for e.Operand == nil {
+		e = e.LHS
+	}
+
+	return e.Operand
+}
+
 func convertWhenArg(w *whenArg) *actr.Value {
 	if w.Nil != nil {
 		return &actr.Value{Nil: w.Nil}
@@ -67,6 +67,45 @@ func Example_gactarErrorFieldNotANestedValue() {
 	// ERROR: 'log_level' invalid type (found field; expected string) (line 5, col 21)
 }
 
+func Example_gactarErrorMaxTimeZero() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	gactar { max_time: 0 }
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: 'max_time' invalid value "0" for option "max_time" (must be greater than 0) (line 5, col 20)
+}
+
+func Example_gactarErrorMaxTimeNegative() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	gactar { max_time: -1 }
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: 'max_time' invalid value "-1" for option "max_time" (must be greater than 0) (line 5, col 20)
+}
+
+func Example_gactarErrorRandomSeedOutOfRange() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	gactar { random_seed: -1 }
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: 'random_seed' is out of range (minimum 0) (line 5, col 23)
+}
+
 func Example_gactarSpaceSeparator() {
 	generateToStdout(`
 	~~ model ~~
@@ -326,6 +365,9 @@ func Example_modulesAll() {
 		}
 		procedural {
 			default_action_time: 0.06
+			initial_utility: 1.5
+			utility_learning_rate: 0.2
+			utility_noise: 0.5
 		}
 		goal{
 			goal { spreading_activation: 0.5 }
@@ -443,6 +485,21 @@ func Example_memoryErrorDecayOutOfRange2() {
 	// ERROR: memory "decay" is out of range (0-1) (line 6, col 18)
 }
 
+func Example_proceduralErrorUtilityNoiseNegative() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		procedural { utility_noise: -1 }
+	}
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: procedural "utility_noise" is out of range (minimum 0) (line 6, col 30)
+}
+
 func Example_proceduralErrorFieldUnrecognized() {
 	generateToStdout(`
 	~~ model ~~
@@ -459,6 +516,82 @@ func Example_proceduralErrorFieldUnrecognized() {
 }
 
 // Tests that we can use a keyword from one section as an id in another
+func Example_blendingFields() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		blending { blend_temperature: 0.5 }
+	}
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+}
+
+func Example_blendingErrorFieldRange() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		blending { blend_temperature: -0.5 }
+	}
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: blending "blend_temperature" is out of range (minimum 0) (line 6, col 32)
+}
+
+func Example_temporalFields() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		temporal {
+			time_noise: 0.005
+			time_mult: 1.02
+			time_start_increment: 0.1
+		}
+	}
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+}
+
+func Example_temporalErrorFieldRange() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		temporal { time_mult: -1.1 }
+	}
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: temporal "time_mult" is out of range (minimum 0) (line 6, col 24)
+}
+
+func Example_temporalErrorChunkType() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { temporal {} }
+	chunks { [time: hours minutes] }
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: duplicate chunk type: 'time' (line 6, col 11)
+}
+
 func Example_keywordInDifferentSection() {
 	generateToStdout(`
 	~~ model ~~
@@ -1,5 +1,10 @@
 package amod
 
+import (
+	"fmt"
+	"strings"
+)
+
 func Example_initializer1() {
 	generateToStdout(`
 	~~ model ~~
@@ -260,3 +265,218 @@ func Example_initializerPartialSimilarities() {
 
 	// Output:
 }
+
+func Example_initializerScreen() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	screen {
+		text 'A' at 100 150
+		text 'B' at 200 150
+	}
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { stop }
+	}`)
+
+	// Output:
+}
+
+func Example_initializerErrorScreenWithoutVisual() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	screen {
+		text 'A' at 100 150
+	}
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { stop }
+	}`)
+
+	// Output:
+	// ERROR: screen requires the 'visual' module (line 7, col 1)
+}
+
+func Example_initializerErrorScreenLocation() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	screen {
+		text 'A' at 100.5 -150
+	}
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { stop }
+	}`)
+
+	// Output:
+	// ERROR: screen item "A" location must be a non-negative integer (found '100.5') (line 9, col 2)
+	// ERROR: screen item "A" location must be a non-negative integer (found '-150') (line 9, col 2)
+}
+
+func Example_initializerErrorVisualModule() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	visual { visual_location [visual_location: 100 150] }
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { stop }
+	}`)
+
+	// Output:
+	// ERROR: module 'visual' cannot be initialized (line 8, col 1)
+}
+
+func Example_initializerBaseLevels() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		memory {
+			decay: 0.5
+			max_spread_strength: 1.0
+		}
+	}
+	chunks { [author: person object year] }
+	~~ init ~~
+	memory {
+		fred [author: 'Fred' 'Book' '1972'] with (references 10) and (creation_time -100)
+		[author: 'Jane' 'Book' '1982'] with (references 2)
+		[author: 'Xe' 'Software' '2001']
+	}
+	~~ productions ~~`)
+
+	// Output:
+}
+
+func Example_initializerBaseLevelsWithoutLearning() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [author: person object year] }
+	~~ init ~~
+	memory {
+		[author: 'Fred' 'Book' '1972'] with (references 10)
+	}
+	~~ productions ~~`)
+
+	// Output:
+	// WARN: base-level settings in initializer have no effect unless base-level learning is on (memory's 'decay' and 'max_spread_strength' are set) (line 8, col 33)
+}
+
+func Example_initializerErrorBaseLevelsInvalid() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [author: person object year] }
+	~~ init ~~
+	memory {
+		[author: 'Fred' 'Book' '1972'] with (references 1.5) and (creation_time 10)
+		[author: 'Jane' 'Book' '1982'] with (activation 2)
+		[author: 'Xe' 'Software' '2001'] with (references many)
+	}
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: initializer 'with': 'references' must be a positive integer (found '1.5') (line 8, col 38)
+	// ERROR: initializer 'with': 'creation_time' must be less than or equal to 0 (found '10') (line 8, col 59)
+	// ERROR: initializer 'with': unrecognized parameter 'activation' (should be one of: references, creation_time) (line 9, col 38)
+	// ERROR: initializer 'with': parameter 'references' must be a number (line 10, col 40)
+}
+
+func Example_initializerErrorBaseLevelsOutOfRange() {
+	// the lexer doesn't handle exponents, so use a number with too many digits
+	generateToStdout(fmt.Sprintf(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [author: person object year] }
+	~~ init ~~
+	memory {
+		[author: 'Fred' 'Book' '1972'] with (creation_time -%s)
+	}
+	~~ productions ~~`, "1"+strings.Repeat("0", 400)))
+
+	// Output:
+	// ERROR: number is out of range (line 8, col 53)
+}
+
+func Example_initializerErrorBaseLevelsNotMemory() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	goal [foo: 'blat'] with (references 2)
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: base-level settings ('with') are only allowed when initializing memory (line 7, col 20)
+}
+
+func Example_initializerAssociations() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		memory { max_spread_strength: 1.0 }
+	}
+	chunks { [fact: location person] }
+	~~ init ~~
+	memory {
+		p1 [fact: park hippie]
+		p2 [fact: bank lawyer]
+	}
+	associations {
+		( park p1 1.5 )
+		( bank p2 -0.5 )
+	}
+	~~ productions ~~`)
+
+	// Output:
+}
+
+func Example_initializerAssociationsWithoutSpreading() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [fact: location person] }
+	~~ init ~~
+	memory {
+		p1 [fact: park hippie]
+	}
+	associations {
+		( park p1 1.5 )
+	}
+	~~ productions ~~`)
+
+	// Output:
+	// WARN: associations have no effect unless the memory module's 'max_spread_strength' is set (line 10, col 1)
+}
@@ -131,6 +131,81 @@ func Example_productionSetStatementNil() {
 	// Output:
 }
 
+func Example_productionSetStatementExpression() {
+	// Check setting to arithmetic expressions
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing1 thing2 thing3] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?blat ?ding *] }
+		do {
+			set goal.thing1 to ?blat + 1
+			set goal.thing2 to (?blat - ?ding) * 2 / 3
+			set goal.thing3 to ?ding -1
+		}
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorSetStatementExpressionString() {
+	// Check using a string in an expression
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?blat] }
+		do { set goal.thing to ?blat + 'ding' }
+	}`)
+
+	// Output:
+	// ERROR: cannot use a string in an arithmetic expression in production 'start' (line 10, col 33)
+}
+
+func Example_productionErrorSetStatementExpressionNoOperator() {
+	// Check missing operator in an expression
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?blat] }
+		do { set goal.thing to ?blat 2 }
+	}`)
+
+	// Output:
+	// ERROR: expected operator in arithmetic expression in production 'start' (line 10, col 31)
+}
+
+func Example_productionErrorSetStatementExpressionNonVar() {
+	// Check non-existent var in an expression
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?blat] }
+		do { set goal.thing to ?blat * ?ding }
+	}`)
+
+	// Output:
+	// ERROR: set statement variable '?ding' not found in matches for production 'start' (line 10, col 33)
+}
+
 func Example_productionErrorSetStatementNonBuffer() {
 	// Check setting to non-existent buffer in set statement
 	generateToStdout(`
@@ -643,3 +718,461 @@ func Example_productionErrorPrintStatementInvalidVar() {
 	// Output:
 	// ERROR: print statement variable '?fooVar' not found in matches for production 'start' (line 9, col 13)
 }
+
+func Example_productionFindLocationStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { find_location [visual_location: lowest *] with (attended nil) }
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorFindLocationStatementNoVisual() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { find_location [foo: 'ding'] }
+	}`)
+
+	// Output:
+	// ERROR: find_location statement requires the 'visual' module in production 'start' (line 10, col 7)
+}
+
+func Example_productionErrorFindLocationStatementPattern() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { find_location [foo: 'ding'] }
+	}`)
+
+	// Output:
+	// ERROR: find_location statement requires a 'visual_location' pattern in production 'start' (line 11, col 22)
+}
+
+func Example_productionErrorFindLocationStatementSlots() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { find_location [visual_location: left 'ding'] with (attended maybe) }
+	}`)
+
+	// Output:
+	// ERROR: find_location statement location must be a number, variable, 'lowest', or 'highest' (found 'left') in production 'start' (line 11, col 39)
+	// ERROR: find_location statement location must be a number, variable, 'lowest', or 'highest' in production 'start' (line 11, col 44)
+	// ERROR: find_location 'with': invalid value "maybe" for option "attended" (expected one of: t, nil, new). (line 11, col 57)
+}
+
+func Example_productionAttendStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match {
+			goal [foo: 'blat']
+			visual_location [visual_location: * *]
+		}
+		do { attend }
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorAttendStatementNoLocation() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { visual {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { attend }
+	}`)
+
+	// Output:
+	// ERROR: attend statement requires a match on the 'visual_location' buffer in production 'start' (line 11, col 7)
+}
+
+func Example_productionPressKeyStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { motor {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?key] }
+		do { press_key ?key }
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorPressKeyStatementNoMotor() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { press_key 'a' }
+	}`)
+
+	// Output:
+	// ERROR: press_key statement requires the 'motor' module in production 'start' (line 10, col 7)
+}
+
+func Example_productionErrorPressKeyStatementMultiple() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { motor {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do {
+			press_key 'a'
+			punch left index
+		}
+	}`)
+
+	// Output:
+	// ERROR: only one press_key or punch statement per production is allowed in production 'start' (line 13, col 3)
+}
+
+func Example_productionErrorPunchStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { motor {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { punch middle left }
+	}`)
+
+	// Output:
+	// ERROR: invalid hand 'middle' in production 'start' (should be one of: left, right) (line 11, col 13)
+	// ERROR: invalid finger 'left' in production 'start' (should be one of: index, middle, ring, pinkie, thumb) (line 11, col 20)
+}
+
+func Example_productionStartTimerStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { temporal {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do {
+			set goal.thing to 'wait'
+			start_timer
+		}
+	}
+	wait {
+		match {
+			goal [foo: 'wait']
+			temporal [time: ?ticks] when (?ticks > 10)
+		}
+		do {
+			print ?ticks
+			stop
+		}
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorStartTimerStatementNoTemporal() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { start_timer }
+	}`)
+
+	// Output:
+	// ERROR: start_timer statement requires the 'temporal' module in production 'start' (line 10, col 7)
+}
+
+func Example_productionErrorStartTimerStatementMultiple() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { temporal {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do {
+			start_timer
+			start_timer
+		}
+	}`)
+
+	// Output:
+	// ERROR: only one start_timer statement per production is allowed in production 'start' (line 13, col 3)
+}
+
+func Example_productionBlendStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { blending {} }
+	chunks {
+		[foo: thing]
+		[object: kind size]
+	}
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?kind] }
+		do { blend [object: ?kind *] }
+	}
+	estimated {
+		match { blending [object: * ?size] }
+		do { print ?size }
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorBlendStatementNoBlending() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { blend [foo: *] }
+	}`)
+
+	// Output:
+	// ERROR: blend statement requires the 'blending' module in production 'start' (line 10, col 7)
+}
+
+func Example_productionErrorBlendStatementVarNotFound() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { blending {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { blend [foo: ?ding] }
+	}`)
+
+	// Output:
+	// ERROR: blend statement variable '?ding' not found in matches for production 'start' (line 11, col 19)
+}
+
+func Example_productionErrorBlendStatementAnyChunk() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules { blending {} }
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { blend [any] }
+	}`)
+
+	// Output:
+	// ERROR: blend statement requires a chunk type in production 'start' (line 11, col 13)
+}
+
+func Example_productionRememberStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[foo: thing]
+		[fact: name value]
+	}
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?thing] }
+		do { remember [fact: ?thing 'seen'] }
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorRememberStatementWildcard() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[foo: thing]
+		[fact: name value]
+	}
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: ?thing] }
+		do { remember [fact: * !?thing] }
+	}`)
+
+	// Output:
+	// ERROR: remember statement cannot use wildcards in production 'start' (line 13, col 23)
+	// ERROR: remember statement cannot use negation in production 'start' (line 13, col 25)
+}
+
+func Example_productionErrorRememberStatementVarNotFound() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { remember [foo: ?ding] }
+	}`)
+
+	// Output:
+	// ERROR: remember statement variable '?ding' not found in matches for production 'start' (line 10, col 22)
+}
+
+func Example_productionErrorRememberStatementAnyChunk() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { remember [any] }
+	}`)
+
+	// Output:
+	// ERROR: remember statement requires a chunk type in production 'start' (line 10, col 16)
+}
+
+func Example_productionReinforceStatement() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	memory { blat [foo: 'blat'] }
+	~~ productions ~~
+	start {
+		match { retrieval [foo: ?thing] }
+		do {
+			reinforce ?thing
+			reinforce blat
+		}
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorReinforceStatementVarNotFound() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { reinforce ?ding }
+	}`)
+
+	// Output:
+	// ERROR: reinforce statement variable '?ding' not found in matches for production 'start' (line 10, col 17)
+}
+
+func Example_productionErrorReinforceStatementUnknownChunk() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	goal { blat [foo: 'blat'] }
+	~~ productions ~~
+	start {
+		match { goal [foo: 'blat'] }
+		do { reinforce blat }
+	}`)
+
+	// Output:
+	// ERROR: reinforce statement chunk 'blat' not found in memory's initializers in production 'start' (line 11, col 17)
+}
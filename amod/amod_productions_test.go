@@ -1,5 +1,10 @@
 package amod
 
+import (
+	"fmt"
+	"strings"
+)
+
 func Example_production() {
 	generateToStdout(`
 	~~ model ~~
@@ -19,6 +24,46 @@ func Example_production() {
 	// Output:
 }
 
+func Example_productionUtility() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		procedural { utility_learning_rate: 0.2 }
+	}
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		description: 'start it up'
+		utility: -2.5
+		reward: 10
+		match { goal [foo: *] }
+		do { stop }
+	}`)
+
+	// Output:
+}
+
+func Example_productionRewardWithoutLearning() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		reward: 10
+		match { goal [foo: *] }
+		do { stop }
+	}`)
+
+	// Output:
+	// WARN: reward in production 'start' has no effect unless the procedural module's 'utility_learning_rate' is set (line 8, col 1)
+}
+
 func Example_productionErrorNoDo() {
 	generateToStdout(`
 	~~ model ~~
@@ -188,6 +233,71 @@ func Example_productionErrorWhenClauseInvalidVarRHS() {
 	// ERROR: unknown variable ?ding in where clause (line 10, col 37)
 }
 
+func Example_productionWhenClauseRelational() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing1 thing2] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match {
+			goal [foo: ?blat ?ding] when ( ?blat > 0 ) and ( ?blat <= ?ding )
+		}
+		do {
+			print ?blat
+			stop
+		}
+	}`)
+
+	// Output:
+}
+
+func Example_productionErrorWhenClauseRelationalString() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match {
+			goal [foo: ?blat] when ( ?blat < 'foo' )
+		}
+		do {
+			print ?blat
+			stop
+		}
+	}`)
+
+	// Output:
+	// ERROR: can only compare a variable to a number or a variable using '<' (line 10, col 36)
+}
+
+func Example_productionErrorWhenClauseRelationalNil() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		match {
+			goal [foo: ?blat] when ( ?blat >= nil )
+		}
+		do {
+			print ?blat
+			stop
+		}
+	}`)
+
+	// Output:
+	// ERROR: can only compare a variable to a number or a variable using '>=' (line 10, col 37)
+}
+
 func Example_productionWildcard() {
 	generateToStdout(`
 	~~ model ~~
@@ -484,3 +594,29 @@ func Example_productionErrorMatchModuleStateDuplicate() {
 	// Output:
 	// ERROR: duplicate module state check for 'memory' in production 'start' (line 10, col 3)
 }
+
+func Example_productionUtilityOutOfRange() {
+	// the lexer doesn't handle exponents, so use a number with too many digits
+	huge := "1" + strings.Repeat("0", 400)
+
+	generateToStdout(fmt.Sprintf(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		procedural { utility_learning_rate: 0.2 }
+	}
+	chunks { [foo: thing] }
+	~~ init ~~
+	~~ productions ~~
+	start {
+		utility: %[1]s
+		reward: -%[1]s
+		match { goal [foo: *] }
+		do { stop }
+	}`, huge))
+
+	// Output:
+	// ERROR: number is out of range (line 12, col 11)
+	// ERROR: number is out of range (line 13, col 10)
+}
@@ -0,0 +1,958 @@
+package amod
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+// comment is a comment from the source and where we found it
+type comment struct {
+	text    string
+	line    int
+	written bool
+}
+
+// amodFormatter outputs a parse tree as canonical amod code.
+//
+// Comments are not part of the parse tree, so we pull them out of the tokens and write
+// them out based on the source lines of the things we are formatting:
+//   - comments on their own line are written before the code which follows them
+//   - comments at the end of a line of code are written at the end of that line
+//
+// Single blank lines between items in the source are kept.
+type amodFormatter struct {
+	strings.Builder
+
+	comments []*comment
+
+	indent     int
+	lastLine   int  // last source line we wrote out
+	blockStart bool // true if we are at the start of a block (so no blank line is needed)
+}
+
+// Format parses the amod code in "src" and returns it in a canonical form.
+// Comments are preserved.
+func Format(src string) (formatted []byte, iLog *issues.Log, err error) {
+	log := newLog()
+	iLog = &log.Log
+
+	f := &amodFormatter{
+		comments: collectComments(src),
+	}
+
+	if isIncludeFile(src) {
+		file, err := parseIncludeFile("", strings.NewReader(src))
+		if err != nil {
+			logParseError(log, err)
+			return nil, iLog, ErrParse
+		}
+
+		f.writeIncludeFile(file)
+	} else {
+		amod, err := parseAMOD("", strings.NewReader(src))
+		if err != nil {
+			logParseError(log, err)
+			return nil, iLog, ErrParse
+		}
+
+		f.writeFile(amod)
+	}
+
+	formatted = []byte(f.String())
+	return
+}
+
+// isIncludeFile checks if "src" is a file meant to be included by others. These do not start
+// with a model section.
+func isIncludeFile(src string) bool {
+	l := lex("", src)
+
+	var code []lexer.Token
+	for {
+		token, err := l.Next()
+		if err != nil || token.EOF() {
+			break
+		}
+
+		if isCode(token) && len(code) < 2 {
+			code = append(code, token)
+		}
+	}
+
+	if len(code) == 0 {
+		return false
+	}
+
+	isModelHeader := len(code) == 2 &&
+		lexemeType(code[0].Type) == lexemeSectionDelim &&
+		code[1].Value == "model"
+
+	return !isModelHeader
+}
+
+// collectComments lexes "src" and pulls out all the comments.
+// We can't use the parse tree's tokens since they don't include comments after the last production.
+func collectComments(src string) (comments []*comment) {
+	l := lex("", src)
+
+	for {
+		token, err := l.Next()
+		if err != nil || token.EOF() {
+			break
+		}
+
+		if lexemeType(token.Type) != lexemeComment {
+			continue
+		}
+
+		comments = append(comments, &comment{
+			text: strings.TrimRight(token.Value, " \t"),
+			line: token.Pos.Line,
+		})
+	}
+
+	return
+}
+
+// isCode returns true if the token is not a comment or whitespace
+func isCode(token lexer.Token) bool {
+	if token.EOF() {
+		return false
+	}
+
+	typ := lexemeType(token.Type)
+
+	return typ != lexemeComment && typ != lexemeSpace
+}
+
+// lineRange returns the first and last source lines of the code in "tokens"
+func lineRange(tokens []lexer.Token) (start, end int) {
+	for _, token := range tokens {
+		if !isCode(token) {
+			continue
+		}
+
+		if start == 0 {
+			start = token.Pos.Line
+		}
+
+		end = token.Pos.Line
+	}
+
+	return
+}
+
+// keywordIndex returns the index of the keyword "keyword" in "tokens" or -1 if it is not found
+func keywordIndex(tokens []lexer.Token, keyword string) int {
+	for i, token := range tokens {
+		if lexemeType(token.Type) == lexemeKeyword && token.Value == keyword {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// keywordLine returns the source line of the keyword "keyword" in "tokens" or 0 if it is not found
+func keywordLine(tokens []lexer.Token, keyword string) int {
+	index := keywordIndex(tokens, keyword)
+	if index == -1 {
+		return 0
+	}
+
+	return tokens[index].Pos.Line
+}
+
+// valueToken returns the first token of type "typ" in "tokens"
+func valueToken(tokens []lexer.Token, typ lexemeType) *lexer.Token {
+	for i, token := range tokens {
+		if lexemeType(token.Type) == typ {
+			return &tokens[i]
+		}
+	}
+
+	return nil
+}
+
+// writeComments writes out any comments which appear before the source line "line"
+func (f *amodFormatter) writeComments(line int) {
+	for _, c := range f.comments {
+		if c.written || c.line >= line {
+			continue
+		}
+
+		f.separate(c.line)
+		f.writeIndented(c.text)
+		f.WriteString("\n")
+
+		c.written = true
+		f.lastLine = c.line
+	}
+}
+
+// separate writes a blank line if there was one before "line" in the source
+func (f *amodFormatter) separate(line int) {
+	if !f.blockStart && f.lastLine != 0 && line > f.lastLine+1 {
+		f.blankLine()
+	}
+
+	f.blockStart = false
+}
+
+// blankLine writes a blank line unless we are at the start of the output or already have one
+func (f *amodFormatter) blankLine() {
+	str := f.String()
+	if str == "" || strings.HasSuffix(str, "\n\n") {
+		return
+	}
+
+	f.WriteString("\n")
+}
+
+// topLevel starts a new item at the top level - these are always separated by a blank line
+func (f *amodFormatter) topLevel() {
+	f.blankLine()
+	f.blockStart = true
+}
+
+func (f *amodFormatter) writeIndented(str string) {
+	f.WriteString(strings.Repeat("    ", f.indent))
+	f.WriteString(str)
+}
+
+// writeLine writes one line of code which came from the source lines "start" to "end".
+// Comments before it are written first and comments within it are added to the end of the line.
+func (f *amodFormatter) writeLine(start, end int, format string, a ...any) {
+	f.writeComments(start)
+	f.separate(start)
+	f.writeCode(start, end, fmt.Sprintf(format, a...))
+}
+
+func (f *amodFormatter) writeCode(start, end int, code string) {
+	f.writeIndented(code)
+
+	for _, c := range f.comments {
+		if c.written || c.line < start || c.line > end {
+			continue
+		}
+
+		f.WriteString(" ")
+		f.WriteString(c.text)
+
+		c.written = true
+	}
+
+	f.WriteString("\n")
+
+	if end > f.lastLine {
+		f.lastLine = end
+	}
+}
+
+// openBlock writes the start of a block which begins on source line "line"
+func (f *amodFormatter) openBlock(line int, name string) {
+	f.writeLine(line, line, "%s {", name)
+
+	f.indent++
+	f.blockStart = true
+}
+
+// closeBlock writes the end of a block which ends on source line "line"
+func (f *amodFormatter) closeBlock(line int) {
+	f.writeComments(line)
+
+	f.indent--
+	f.blockStart = false
+
+	f.writeCode(line, line, "}")
+}
+
+func (f *amodFormatter) writeFile(amod *amodFile) {
+	headerLines := sectionHeaderLines(amod.Tokens)
+
+	f.writeSectionHeader(headerLines["model"], "model")
+	f.writeModelSection(amod.Model)
+
+	f.writeSectionHeader(headerLines["config"], "config")
+	f.writeConfigSection(amod.Config)
+
+	f.writeSectionHeader(headerLines["init"], "init")
+	f.writeInitSection(amod.Init)
+
+	f.writeSectionHeader(headerLines["productions"], "productions")
+	f.writeProductionSection(amod.Productions)
+
+	f.finish()
+}
+
+// writeIncludeFile writes a file meant to be included by others. Any of its sections may be missing.
+func (f *amodFormatter) writeIncludeFile(file *includeFile) {
+	headerLines := sectionHeaderLines(file.Tokens)
+
+	f.writeIncludes(file.Includes)
+
+	if line, ok := headerLines["config"]; ok {
+		f.writeSectionHeader(line, "config")
+		f.writeConfigSection(file.Config)
+	}
+
+	if line, ok := headerLines["init"]; ok {
+		f.writeSectionHeader(line, "init")
+		f.writeInitSection(file.Init)
+	}
+
+	if line, ok := headerLines["productions"]; ok {
+		f.writeSectionHeader(line, "productions")
+		f.writeProductionSection(file.Productions)
+	}
+
+	f.finish()
+}
+
+// finish writes any remaining comments and tidies up the end of the output
+func (f *amodFormatter) finish() {
+	// anything left over goes at the end
+	f.topLevel()
+	f.writeComments(math.MaxInt)
+
+	// make sure we end with exactly one newline
+	str := strings.TrimRight(f.String(), "\n") + "\n"
+	f.Reset()
+	f.WriteString(str)
+}
+
+// sectionHeaderLines returns the source line of each section header keyword
+func sectionHeaderLines(tokens []lexer.Token) map[string]int {
+	lines := map[string]int{}
+
+	inHeader := false
+	for _, token := range tokens {
+		switch lexemeType(token.Type) {
+		case lexemeSectionDelim:
+			inHeader = !inHeader
+
+		case lexemeKeyword:
+			if inHeader {
+				lines[token.Value] = token.Pos.Line
+			}
+		}
+	}
+
+	return lines
+}
+
+func (f *amodFormatter) writeSectionHeader(line int, name string) {
+	f.topLevel()
+	f.writeLine(line, line, "~~ %s ~~", name)
+	f.topLevel()
+}
+
+func (f *amodFormatter) writeModelSection(model *modelSection) {
+	tokens := model.Tokens
+
+	index := keywordIndex(tokens, "name")
+	line := tokens[index].Pos.Line
+
+	name := model.Name
+	if nameToken := valueToken(tokens[index:], lexemeString); nameToken != nil && nameToken.Pos.Line == line {
+		name = quoteString(name)
+	}
+
+	f.writeLine(line, line, "name: %s", name)
+
+	if model.Description != "" {
+		f.topLevel()
+
+		line = keywordLine(tokens, "description")
+		f.writeLine(line, line, "description: %s", quoteString(model.Description))
+	}
+
+	if len(model.Authors) > 0 {
+		f.topLevel()
+
+		index = keywordIndex(tokens, "authors")
+		f.openBlock(tokens[index].Pos.Line, "authors")
+
+		end := 0
+		authorIndex := 0
+		for _, token := range tokens[index+1:] {
+			if lexemeType(token.Type) == lexemeString && authorIndex < len(model.Authors) {
+				f.writeLine(token.Pos.Line, token.Pos.Line, "%s", quoteString(model.Authors[authorIndex]))
+				authorIndex++
+			}
+
+			if token.Value == "}" {
+				end = token.Pos.Line
+				break
+			}
+		}
+
+		f.closeBlock(end)
+	}
+
+	if len(model.Examples) > 0 {
+		f.topLevel()
+
+		index = keywordIndex(tokens, "examples")
+		f.openBlock(tokens[index].Pos.Line, "examples")
+
+		end := 0
+		for _, example := range model.Examples {
+			start, exampleEnd := lineRange(example.Tokens)
+			f.writeLine(start, exampleEnd, "%s", formatPattern(example))
+		}
+
+		for _, token := range tokens[index+1:] {
+			if token.Value == "}" {
+				end = token.Pos.Line
+				break
+			}
+		}
+
+		f.closeBlock(end)
+	}
+
+	f.writeIncludes(model.Includes)
+}
+
+func (f *amodFormatter) writeIncludes(includes []*include) {
+	if len(includes) == 0 {
+		return
+	}
+
+	f.topLevel()
+
+	for _, inc := range includes {
+		start, end := lineRange(inc.Tokens)
+		f.writeLine(start, end, "include %s", quoteString(inc.FileName))
+	}
+}
+
+func (f *amodFormatter) writeConfigSection(config *configSection) {
+	if config == nil {
+		return
+	}
+
+	if config.GactarConfig != nil {
+		f.topLevel()
+
+		start, end := lineRange(config.GactarConfig.Tokens)
+		f.openBlock(start, "gactar")
+		for _, field := range config.GactarConfig.GactarFields {
+			f.writeField(field)
+		}
+		f.closeBlock(end)
+	}
+
+	if config.ModuleConfig != nil {
+		f.topLevel()
+
+		start, end := lineRange(config.ModuleConfig.Tokens)
+		f.openBlock(start, "modules")
+		for _, module := range config.ModuleConfig.Modules {
+			f.writeModule(module)
+		}
+		f.closeBlock(end)
+	}
+
+	if config.ChunkConfig != nil {
+		f.topLevel()
+
+		start, end := lineRange(config.ChunkConfig.Tokens)
+		f.openBlock(start, "chunks")
+		for _, decl := range config.ChunkConfig.ChunkDecls {
+			declStart, declEnd := lineRange(decl.Tokens)
+			f.writeLine(declStart, declEnd, "[%s: %s]", decl.TypeName, strings.Join(decl.Slots, " "))
+		}
+		f.closeBlock(end)
+	}
+}
+
+func (f *amodFormatter) writeModule(module *module) {
+	start, end := lineRange(module.Tokens)
+
+	f.writeFields(start, end, module.ModuleName, module.Fields)
+}
+
+// writeField writes a field and any nested fields.
+func (f *amodFormatter) writeField(field *field) {
+	start, end := lineRange(field.Tokens)
+	value := field.Value
+
+	if value.OpenBrace == nil {
+		f.writeLine(start, end, "%s: %s", field.Key, formatFieldValue(&value))
+		return
+	}
+
+	f.writeFields(start, end, field.Key, value.Fields)
+}
+
+// writeFields writes a named block of fields. If there is only one field with a simple value,
+// it is written on one line.
+func (f *amodFormatter) writeFields(start, end int, name string, fields []*field) {
+	switch {
+	case len(fields) == 0:
+		f.writeLine(start, end, "%s {}", name)
+
+	case len(fields) == 1 && fields[0].Value.OpenBrace == nil:
+		field := fields[0]
+		f.writeLine(start, end, "%s { %s: %s }", name, field.Key, formatFieldValue(&field.Value))
+
+	default:
+		f.openBlock(start, name)
+		for _, field := range fields {
+			f.writeField(field)
+		}
+		f.closeBlock(end)
+	}
+}
+
+func (f *amodFormatter) writeInitSection(init *initSection) {
+	if init == nil {
+		return
+	}
+
+	for _, initialization := range init.Initializations {
+		f.topLevel()
+
+		switch {
+		case initialization.ModuleInitializer != nil:
+			f.writeModuleInitializer(initialization.ModuleInitializer)
+
+		case initialization.ScreenInitializer != nil:
+			f.writeScreenInitializer(initialization.ScreenInitializer)
+
+		case initialization.AssociationInitializer != nil:
+			f.writeAssociationInitializer(initialization.AssociationInitializer)
+
+		case initialization.SimilarityInitializer != nil:
+			f.writeSimilarityInitializer(initialization.SimilarityInitializer)
+		}
+	}
+}
+
+func (f *amodFormatter) writeModuleInitializer(init *moduleInitializer) {
+	start, end := lineRange(init.Tokens)
+
+	if len(init.BufferInitPatterns) > 0 {
+		f.openBlock(start, init.ModuleName)
+		for _, bufferInit := range init.BufferInitPatterns {
+			f.writeBufferInitializer(bufferInit)
+		}
+		f.closeBlock(end)
+		return
+	}
+
+	// Keep the braces if they were used in the source
+	if !hasOpenBrace(init.Tokens) {
+		f.writeLine(start, end, "%s %s", init.ModuleName, formatNamedInitializer(init.InitPatterns[0], 0))
+		return
+	}
+
+	f.openBlock(start, init.ModuleName)
+	f.writeNamedInitializers(init.InitPatterns)
+	f.closeBlock(end)
+}
+
+// writeBufferInitializer writes a buffer's initializers. A single initializer is written on one line.
+func (f *amodFormatter) writeBufferInitializer(init *bufferInitializer) {
+	start, end := lineRange(init.Tokens)
+
+	if len(init.InitPatterns) == 1 {
+		f.writeLine(start, end, "%s { %s }", init.BufferName, formatNamedInitializer(init.InitPatterns[0], 0))
+		return
+	}
+
+	f.openBlock(start, init.BufferName)
+	f.writeNamedInitializers(init.InitPatterns)
+	f.closeBlock(end)
+}
+
+// writeNamedInitializers writes a list of initializers with their patterns aligned
+func (f *amodFormatter) writeNamedInitializers(inits []*namedInitializer) {
+	width := 0
+	for _, init := range inits {
+		if init.ChunkName != nil {
+			width = max(width, len(*init.ChunkName))
+		}
+	}
+
+	for _, init := range inits {
+		start, end := lineRange(init.Tokens)
+		f.writeLine(start, end, "%s", formatNamedInitializer(init, width))
+	}
+}
+
+func (f *amodFormatter) writeScreenInitializer(init *screenInitializer) {
+	start, end := lineRange(init.Tokens)
+
+	f.openBlock(start, "screen")
+	for _, item := range init.Items {
+		itemStart, itemEnd := lineRange(item.Tokens)
+		f.writeLine(itemStart, itemEnd, "text %s at %s %s", quoteString(item.Text), item.X, item.Y)
+	}
+	f.closeBlock(end)
+}
+
+func (f *amodFormatter) writeAssociationInitializer(init *associationInitializer) {
+	start, end := lineRange(init.Tokens)
+
+	f.openBlock(start, "associations")
+	for _, association := range init.AssociationList {
+		associationStart, associationEnd := lineRange(association.Tokens)
+		f.writeLine(associationStart, associationEnd, "( %s %s %s )",
+			association.Source, association.Target, valueToken(association.Tokens, lexemeNumber).Value)
+	}
+	f.closeBlock(end)
+}
+
+func (f *amodFormatter) writeSimilarityInitializer(init *similarityInitializer) {
+	start, end := lineRange(init.Tokens)
+
+	f.openBlock(start, "similar")
+	for _, similar := range init.SimilarList {
+		similarStart, similarEnd := lineRange(similar.Tokens)
+		f.writeLine(similarStart, similarEnd, "( %s %s %s )",
+			similar.ChunkOne, similar.ChunkTwo, valueToken(similar.Tokens, lexemeNumber).Value)
+	}
+	f.closeBlock(end)
+}
+
+func (f *amodFormatter) writeProductionSection(productions *productionSection) {
+	if productions == nil {
+		return
+	}
+
+	for _, production := range productions.Productions {
+		f.topLevel()
+		f.writeProduction(production)
+	}
+}
+
+func (f *amodFormatter) writeProduction(production *production) {
+	tokens := production.Tokens
+	start, end := lineRange(tokens)
+
+	f.openBlock(start, production.Name)
+
+	if production.Description != nil {
+		line := keywordLine(tokens, "description")
+		f.writeLine(line, line, "description: %s", quoteString(*production.Description))
+	}
+
+	if production.Utility != nil {
+		line := keywordLine(tokens, "utility")
+		f.writeLine(line, line, "utility: %s", *production.Utility)
+	}
+
+	if production.Reward != nil {
+		line := keywordLine(tokens, "reward")
+		f.writeLine(line, line, "reward: %s", *production.Reward)
+	}
+
+	matchStart, matchEnd := lineRange(production.Match.Tokens)
+	f.openBlock(matchStart, "match")
+	for _, item := range production.Match.Items {
+		itemStart, itemEnd := lineRange(item.Tokens)
+		f.writeLine(itemStart, itemEnd, "%s", formatMatchItem(item))
+	}
+	f.closeBlock(matchEnd)
+
+	doStart, doEnd := lineRange(production.Do.Tokens)
+	f.openBlock(doStart, "do")
+	for _, statement := range *production.Do.Statements {
+		statementStart, statementEnd := lineRange(statement.Tokens)
+		f.writeLine(statementStart, statementEnd, "%s", formatStatement(statement))
+	}
+	f.closeBlock(doEnd)
+
+	f.closeBlock(end)
+}
+
+// hasOpenBrace returns true if the second bit of code in "tokens" is an open brace
+func hasOpenBrace(tokens []lexer.Token) bool {
+	count := 0
+	for _, token := range tokens {
+		if !isCode(token) {
+			continue
+		}
+
+		count++
+		if count == 2 {
+			return token.Value == "{"
+		}
+	}
+
+	return false
+}
+
+func formatFieldValue(value *fieldValue) string {
+	switch {
+	case value.ID != nil:
+		return *value.ID
+
+	case value.Str != nil:
+		return quoteString(*value.Str)
+
+	case value.Number != nil:
+		// use the number as written
+		return valueToken(value.Tokens, lexemeNumber).Value
+	}
+
+	return ""
+}
+
+// formatNamedInitializer formats the initializer, padding the chunk name to "width"
+func formatNamedInitializer(init *namedInitializer, width int) string {
+	if init.ChunkName == nil {
+		return formatPattern(init.Pattern) + formatWithClause(init.With)
+	}
+
+	return fmt.Sprintf("%-*s %s%s", width, *init.ChunkName, formatPattern(init.Pattern), formatWithClause(init.With))
+}
+
+func formatPattern(pattern *pattern) string {
+	if pattern.AnyChunk != nil {
+		return "[any]"
+	}
+
+	slots := make([]string, len(pattern.Chunk.Slots))
+	for i, slot := range pattern.Chunk.Slots {
+		slots[i] = formatPatternSlot(slot)
+	}
+
+	return fmt.Sprintf("[%s: %s]", pattern.Chunk.Name, strings.Join(slots, " "))
+}
+
+func formatPatternSlot(slot *patternSlot) (str string) {
+	switch {
+	case slot.Wildcard != nil:
+		return "*"
+
+	case slot.Nil != nil:
+		str = "nil"
+
+	case slot.ID != nil:
+		str = *slot.ID
+
+	case slot.Str != nil:
+		str = quoteString(*slot.Str)
+
+	case slot.Num != nil:
+		str = *slot.Num
+
+	case slot.Var != nil:
+		str = *slot.Var
+	}
+
+	if slot.Not {
+		str = "!" + str
+	}
+
+	return
+}
+
+func formatArg(arg *arg) string {
+	switch {
+	case arg.Var != nil:
+		return *arg.Var
+
+	case arg.Str != nil:
+		return quoteString(*arg.Str)
+
+	case arg.Number != nil:
+		return *arg.Number
+	}
+
+	return ""
+}
+
+func formatMatchItem(item *matchItem) string {
+	switch {
+	case item.BufferPattern != nil:
+		bufferPattern := item.BufferPattern
+		str := fmt.Sprintf("%s %s", bufferPattern.BufferName, formatPattern(bufferPattern.Pattern))
+
+		if bufferPattern.When != nil {
+			expressions := []string{}
+			for _, expr := range *bufferPattern.When.Expressions {
+				comparison := convertComparison(expr.Comparison)
+
+				rhs := "nil"
+				if expr.RHS.Arg != nil {
+					rhs = formatArg(expr.RHS.Arg)
+				}
+
+				expressions = append(expressions, fmt.Sprintf("(%s %s %s)", expr.LHS, comparison, rhs))
+			}
+
+			str += " when " + strings.Join(expressions, " and ")
+		}
+
+		return str
+
+	case item.BufferState != nil:
+		return fmt.Sprintf("buffer_state %s %s", item.BufferState.BufferName, item.BufferState.State)
+
+	case item.ModuleState != nil:
+		return fmt.Sprintf("module_state %s %s", item.ModuleState.ModuleName, item.ModuleState.State)
+	}
+
+	return ""
+}
+
+func formatStatement(statement *statement) string {
+	switch {
+	case statement.Attend != nil:
+		return "attend"
+
+	case statement.Blend != nil:
+		return "blend " + formatPattern(statement.Blend.Pattern)
+
+	case statement.Clear != nil:
+		return "clear " + strings.Join(statement.Clear.BufferNames, ", ")
+
+	case statement.Print != nil:
+		args := []string{}
+		for _, arg := range statement.Print.Args {
+			if arg.Arg != nil {
+				args = append(args, formatArg(arg.Arg))
+			} else {
+				args = append(args, arg.BufferRef.String())
+			}
+		}
+
+		return strings.TrimSpace("print " + strings.Join(args, ", "))
+
+	case statement.FindLocation != nil:
+		find := statement.FindLocation
+
+		return "find_location " + formatPattern(find.Pattern) + formatWithClause(find.With)
+
+	case statement.PressKey != nil:
+		return "press_key " + formatArg(statement.PressKey.Key)
+
+	case statement.Punch != nil:
+		return fmt.Sprintf("punch %s %s", statement.Punch.Hand, statement.Punch.Finger)
+
+	case statement.Recall != nil:
+		recall := statement.Recall
+
+		return "recall " + formatPattern(recall.Pattern) + formatWithClause(recall.With)
+
+	case statement.Reinforce != nil:
+		if statement.Reinforce.Var != nil {
+			return "reinforce " + *statement.Reinforce.Var
+		}
+
+		return "reinforce " + *statement.Reinforce.ID
+
+	case statement.Remember != nil:
+		return "remember " + formatPattern(statement.Remember.Pattern)
+
+	case statement.Set != nil:
+		set := statement.Set
+
+		value := ""
+		if set.Pattern != nil {
+			value = formatPattern(set.Pattern)
+		} else {
+			value = formatSetArg(set.Value)
+		}
+
+		return fmt.Sprintf("set %s to %s", set.BufferRef.String(), value)
+
+	case statement.StartTimer != nil:
+		return "start_timer"
+
+	case statement.Stop != nil:
+		return "stop"
+	}
+
+	return ""
+}
+
+// formatWithClause returns the "with" clause of a statement (or "" if there isn't one).
+func formatWithClause(with *withClause) string {
+	if with == nil {
+		return ""
+	}
+
+	expressions := []string{}
+	for _, expr := range *with.Expressions {
+		expressions = append(expressions, fmt.Sprintf("(%s %s)", expr.Param, formatWithArg(expr.Value)))
+	}
+
+	return " with " + strings.Join(expressions, " and ")
+}
+
+func formatWithArg(arg *withArg) string {
+	switch {
+	case arg.Arg != nil:
+		return formatArg(arg.Arg)
+
+	case arg.ID != nil:
+		return *arg.ID
+	}
+
+	return "nil"
+}
+
+func formatSetArg(arg *setArg) string {
+	switch {
+	case arg.Expr != nil:
+		return formatExpression(arg.Expr)
+
+	case arg.ID != nil:
+		return *arg.ID
+	}
+
+	return "nil"
+}
+
+// formatExpression outputs an arithmetic expression with single spaces around the operators.
+// A term starting with a signed number and no operator (e.g. "?count -1") gets the sign as
+// its operator.
+func formatExpression(expr *expression) string {
+	str := formatTerm(expr.Left)
+
+	for _, op := range expr.Right {
+		term := formatTerm(op.Term)
+
+		switch {
+		case op.Operator != nil:
+			str += fmt.Sprintf(" %s %s", *op.Operator, term)
+
+		case isSigned(term):
+			str += fmt.Sprintf(" %s %s", term[:1], term[1:])
+
+		default:
+			str += " " + term
+		}
+	}
+
+	return str
+}
+
+func formatTerm(t *term) string {
+	str := formatOperand(t.Left)
+
+	for _, op := range t.Right {
+		str += fmt.Sprintf(" %s %s", op.Operator, formatOperand(op.Operand))
+	}
+
+	return str
+}
+
+func formatOperand(o *operand) string {
+	if o.Expr != nil {
+		return "(" + formatExpression(o.Expr) + ")"
+	}
+
+	return formatArg(o.Arg)
+}
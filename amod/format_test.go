@@ -0,0 +1,159 @@
+package amod
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/asmaloney/gactar/examples"
+)
+
+func formatToStdout(str string) {
+	formatted, log, err := Format(str)
+	if err != nil {
+		fmt.Print(log)
+		return
+	}
+
+	fmt.Print(string(formatted))
+}
+
+func Example_format() {
+	formatToStdout(`
+	// A model
+	~~ model ~~
+	name: Test   // the name
+	description:"A test model"
+	~~ config ~~
+	modules{
+	  memory { latency_factor:0.5
+	     retrieval { spreading_activation: 0.5 } }
+	  extra_buffers { foo {} }
+	}
+	chunks { [count: first second]
+	[countFrom: start end status] }
+	~~ init ~~
+	memory { one [count: 0 1] three [count: 2 3]
+
+
+	  // the last one
+	  four [count: 3 4] }
+	goal [countFrom: 2 5 "starting"]
+	~~ productions ~~
+	start { match { goal [countFrom: ?start ?end 'starting'] when (?start != ?end)
+	module_state memory free }
+	do { recall [count: ?start *] with (recently_retrieved nil)
+	print ?start "and" goal.end set goal.status to 'counting' } }
+	// the end`)
+
+	// Output:
+	// // A model
+	// ~~ model ~~
+	//
+	// name: Test // the name
+	//
+	// description: 'A test model'
+	//
+	// ~~ config ~~
+	//
+	// modules {
+	//     memory {
+	//         latency_factor: 0.5
+	//         retrieval { spreading_activation: 0.5 }
+	//     }
+	//     extra_buffers {
+	//         foo {}
+	//     }
+	// }
+	//
+	// chunks {
+	//     [count: first second]
+	//     [countFrom: start end status]
+	// }
+	//
+	// ~~ init ~~
+	//
+	// memory {
+	//     one   [count: 0 1]
+	//     three [count: 2 3]
+	//
+	//     // the last one
+	//     four  [count: 3 4]
+	// }
+	//
+	// goal [countFrom: 2 5 'starting']
+	//
+	// ~~ productions ~~
+	//
+	// start {
+	//     match {
+	//         goal [countFrom: ?start ?end 'starting'] when (?start != ?end)
+	//         module_state memory free
+	//     }
+	//     do {
+	//         recall [count: ?start *] with (recently_retrieved nil)
+	//         print ?start, 'and', goal.end
+	//         set goal.status to 'counting'
+	//     }
+	// }
+	//
+	// // the end
+}
+
+func Example_formatError() {
+	formatToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [count first second] }
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: unexpected token "first" (expected ":" <ident>+ "]") (line 5, col 17)
+}
+
+// TestFormatRoundTrip checks that formatting our examples is stable and does not change the models.
+func TestFormatRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	files, err := fs.Glob(examples.AMODExamples, "*.amod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		src, err := fs.ReadFile(examples.AMODExamples, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, log, err := Format(string(src))
+		if err != nil {
+			t.Fatalf("%s: %s", file, log)
+		}
+
+		reformatted, log, err := Format(string(formatted))
+		if err != nil {
+			t.Fatalf("%s: could not parse formatted code:\n%s\n%s", file, log, formatted)
+		}
+
+		if string(formatted) != string(reformatted) {
+			t.Errorf("%s: formatting is not stable:\n%s\n---\n%s", file, formatted, reformatted)
+		}
+
+		model, log, err := GenerateModel(string(src))
+		if err != nil {
+			t.Fatalf("%s: %s", file, log)
+		}
+
+		formattedModel, log, err := GenerateModel(string(formatted))
+		if err != nil {
+			t.Fatalf("%s: could not generate model from formatted code:\n%s\n%s", file, log, formatted)
+		}
+
+		if string(GenerateCode(model)) != string(GenerateCode(formattedModel)) {
+			t.Errorf("%s: formatting changed the model", file)
+		}
+	}
+}
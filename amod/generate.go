@@ -0,0 +1,543 @@
+package amod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/buffer"
+	"github.com/asmaloney/gactar/actr/modules"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+// amodWriter accumulates the amod code while we generate it.
+type amodWriter struct {
+	strings.Builder
+}
+
+func (w *amodWriter) writeln(indent int, format string, a ...any) {
+	w.WriteString(strings.Repeat("    ", indent))
+	fmt.Fprintf(w, format, a...)
+	w.WriteString("\n")
+}
+
+// GenerateCode generates amod code from a model.
+// This is the reverse of GenerateModel and is used when converting models from other formats.
+func GenerateCode(model *actr.Model) []byte {
+	w := &amodWriter{}
+
+	writeModelSection(w, model)
+	writeConfigSection(w, model)
+	writeInitSection(w, model)
+	writeProductionsSection(w, model)
+
+	return []byte(w.String())
+}
+
+func writeModelSection(w *amodWriter, model *actr.Model) {
+	w.writeln(0, "~~ model ~~")
+	w.writeln(0, "")
+	w.writeln(0, "name: %s", model.Name)
+
+	if model.Description != "" {
+		w.writeln(0, "")
+		w.writeln(0, "description: %s", quoteString(model.Description))
+	}
+
+	if len(model.Authors) > 0 {
+		w.writeln(0, "")
+		w.writeln(0, "authors {")
+		for _, author := range model.Authors {
+			w.writeln(1, "%s", quoteString(author))
+		}
+		w.writeln(0, "}")
+	}
+
+	if len(model.Examples) > 0 {
+		w.writeln(0, "")
+		w.writeln(0, "examples {")
+		for _, example := range model.Examples {
+			w.writeln(1, "%s", patternString(example))
+		}
+		w.writeln(0, "}")
+	}
+
+	w.writeln(0, "")
+}
+
+func writeConfigSection(w *amodWriter, model *actr.Model) {
+	w.writeln(0, "~~ config ~~")
+	w.writeln(0, "")
+
+	params := model.DefaultParams
+
+	w.writeln(0, "gactar {")
+	if params.LogLevel != nil {
+		w.writeln(1, "log_level: '%s'", *params.LogLevel)
+	}
+	if params.TraceActivations != nil && *params.TraceActivations {
+		w.writeln(1, "trace_activations: true")
+	}
+	if params.RandomSeed != nil {
+		w.writeln(1, "random_seed: %d", *params.RandomSeed)
+	}
+	if params.MaxTime != nil {
+		w.writeln(1, "max_time: %s", numbers.Float64Str(*params.MaxTime))
+	}
+	w.writeln(0, "}")
+	w.writeln(0, "")
+
+	writeModules(w, model)
+
+	w.writeln(0, "chunks {")
+	for _, chunk := range model.Chunks {
+		if chunk.IsInternal() || chunk.BuiltIn {
+			continue
+		}
+
+		w.writeln(1, "[%s: %s]", chunk.TypeName, strings.Join(chunk.SlotNames, " "))
+	}
+	w.writeln(0, "}")
+	w.writeln(0, "")
+}
+
+func writeModules(w *amodWriter, model *actr.Model) {
+	moduleConfig := &amodWriter{}
+
+	memory := model.Memory
+	memoryParams := []string{}
+	memoryParams = appendFloatParam(memoryParams, "latency_factor", memory.LatencyFactor)
+	memoryParams = appendFloatParam(memoryParams, "latency_exponent", memory.LatencyExponent)
+	memoryParams = appendFloatParam(memoryParams, "retrieval_threshold", memory.RetrievalThreshold)
+	if memory.FinstSize != nil {
+		memoryParams = append(memoryParams, fmt.Sprintf("finst_size: %d", *memory.FinstSize))
+	}
+	memoryParams = appendFloatParam(memoryParams, "finst_time", memory.FinstTime)
+	memoryParams = appendFloatParam(memoryParams, "decay", memory.Decay)
+	memoryParams = appendFloatParam(memoryParams, "max_spread_strength", memory.MaxSpreadStrength)
+	memoryParams = appendFloatParam(memoryParams, "instantaneous_noise", memory.InstantaneousNoise)
+	memoryParams = appendFloatParam(memoryParams, "mismatch_penalty", memory.MismatchPenalty)
+	memoryParams = appendBufferParams(memoryParams, model, memory)
+	writeModule(moduleConfig, "memory", memoryParams, false)
+
+	procedural := model.Procedural
+	proceduralParams := []string{}
+	proceduralParams = appendFloatParam(proceduralParams, "default_action_time", procedural.DefaultActionTime)
+	proceduralParams = appendFloatParam(proceduralParams, "initial_utility", procedural.InitialUtility)
+	proceduralParams = appendFloatParam(proceduralParams, "utility_noise", procedural.UtilityNoise)
+	proceduralParams = appendFloatParam(proceduralParams, "utility_learning_rate", procedural.UtilityLearningRate)
+	writeModule(moduleConfig, "procedural", proceduralParams, false)
+
+	writeModule(moduleConfig, "goal", appendBufferParams(nil, model, model.Goal), false)
+
+	imaginal := model.ImaginalModule()
+	if imaginal != nil {
+		imaginalParams := appendFloatParam(nil, "delay", imaginal.Delay)
+		imaginalParams = appendBufferParams(imaginalParams, model, imaginal)
+		writeModule(moduleConfig, "imaginal", imaginalParams, true)
+	}
+
+	blending := model.BlendingModule()
+	if blending != nil {
+		writeModule(moduleConfig, "blending", appendFloatParam(nil, "blend_temperature", blending.Temperature), true)
+	}
+
+	visual := model.VisualModule()
+	if visual != nil {
+		writeModule(moduleConfig, "visual", appendFloatParam(nil, "attention_latency", visual.AttentionLatency), true)
+	}
+
+	if model.MotorModule() != nil {
+		writeModule(moduleConfig, "motor", nil, true)
+	}
+
+	temporal := model.TemporalModule()
+	if temporal != nil {
+		temporalParams := appendFloatParam(nil, "time_noise", temporal.TimeNoise)
+		temporalParams = appendFloatParam(temporalParams, "time_mult", temporal.TimeMult)
+		temporalParams = appendFloatParam(temporalParams, "time_start_increment", temporal.TimeStartIncrement)
+		writeModule(moduleConfig, "temporal", temporalParams, true)
+	}
+
+	extraBuffers := model.LookupModule("extra_buffers")
+	if extraBuffers != nil {
+		bufferParams := []string{}
+		for _, buff := range extraBuffers.Buffers() {
+			bufferParams = append(bufferParams, fmt.Sprintf("%s {%s}", buff.Name(), spreadingActivationParam(model, buff, 0.0)))
+		}
+		writeModule(moduleConfig, "extra_buffers", bufferParams, true)
+	}
+
+	if moduleConfig.Len() == 0 {
+		return
+	}
+
+	w.writeln(0, "modules {")
+	w.WriteString(moduleConfig.String())
+	w.writeln(0, "}")
+	w.writeln(0, "")
+}
+
+// writeModule writes the module's config. If the module has no params, it is only
+// written if "always" is set.
+func writeModule(w *amodWriter, name string, params []string, always bool) {
+	if len(params) == 0 && !always {
+		return
+	}
+
+	if len(params) == 0 {
+		w.writeln(1, "%s {}", name)
+		return
+	}
+
+	// a single param with a simple value goes on one line
+	if len(params) == 1 && !strings.Contains(params[0], "{") {
+		w.writeln(1, "%s { %s }", name, params[0])
+		return
+	}
+
+	w.writeln(1, "%s {", name)
+	for _, param := range params {
+		w.writeln(2, "%s", param)
+	}
+	w.writeln(1, "}")
+}
+
+func appendFloatParam(params []string, name string, value *float64) []string {
+	if value == nil {
+		return params
+	}
+
+	return append(params, fmt.Sprintf("%s: %s", name, numbers.Float64Str(*value)))
+}
+
+// appendBufferParams adds the config for any of the module's buffers which differ from their defaults.
+func appendBufferParams(params []string, model *actr.Model, module modules.Interface) []string {
+	defaultModule := modules.FindModule(module.ModuleName())
+
+	for _, buff := range module.Buffers() {
+		defaultActivation := 0.0
+		if defaultModule != nil {
+			defaultBuffer := defaultModule.Buffers().Lookup(buff.Name())
+			if defaultBuffer != nil {
+				defaultActivation = defaultBuffer.SpreadingActivation()
+			}
+		}
+
+		param := spreadingActivationParam(model, buff, defaultActivation)
+		if param == "" {
+			continue
+		}
+
+		params = append(params, fmt.Sprintf("%s {%s}", buff.Name(), param))
+	}
+
+	return params
+}
+
+// spreadingActivationParam returns the buffer's spreading_activation param if it is in use and
+// is not the default.
+func spreadingActivationParam(model *actr.Model, buff buffer.Interface, defaultActivation float64) string {
+	if !model.Memory.IsUsingSpreadingActivation() || buff.SpreadingActivation() == defaultActivation {
+		return ""
+	}
+
+	return fmt.Sprintf(" spreading_activation: %s ", numbers.Float64Str(buff.SpreadingActivation()))
+}
+
+func writeInitSection(w *amodWriter, model *actr.Model) {
+	w.writeln(0, "~~ init ~~")
+	w.writeln(0, "")
+
+	memoryInits := []*actr.Initializer{}
+	bufferInits := map[string][]*actr.Initializer{}
+	moduleOrder := []string{}
+
+	for _, init := range model.Initializers {
+		moduleName := init.Module.ModuleName()
+
+		if moduleName == "memory" {
+			memoryInits = append(memoryInits, init)
+			continue
+		}
+
+		if _, ok := bufferInits[moduleName]; !ok {
+			moduleOrder = append(moduleOrder, moduleName)
+		}
+
+		bufferInits[moduleName] = append(bufferInits[moduleName], init)
+	}
+
+	if len(memoryInits) > 0 {
+		// align the patterns if the chunks are named
+		width := 0
+		for _, init := range memoryInits {
+			if init.ChunkName != nil {
+				width = max(width, len(*init.ChunkName))
+			}
+		}
+
+		w.writeln(0, "memory {")
+		for _, init := range memoryInits {
+			if init.ChunkName != nil {
+				w.writeln(1, "%-*s %s%s", width, *init.ChunkName, patternString(init.Pattern), baseLevelString(init.BaseLevel))
+			} else {
+				w.writeln(1, "%s%s", patternString(init.Pattern), baseLevelString(init.BaseLevel))
+			}
+		}
+		w.writeln(0, "}")
+		w.writeln(0, "")
+	}
+
+	for _, moduleName := range moduleOrder {
+		inits := bufferInits[moduleName]
+
+		if moduleName != "extra_buffers" {
+			w.writeln(0, "%s %s", moduleName, patternString(inits[0].Pattern))
+			w.writeln(0, "")
+			continue
+		}
+
+		w.writeln(0, "extra_buffers {")
+		for _, init := range inits {
+			w.writeln(1, "%s { %s }", init.Buffer.Name(), patternString(init.Pattern))
+		}
+		w.writeln(0, "}")
+		w.writeln(0, "")
+	}
+
+	if len(model.Screen) > 0 {
+		w.writeln(0, "screen {")
+		for _, item := range model.Screen {
+			w.writeln(1, "text %s at %d %d", quoteString(item.Text), item.X, item.Y)
+		}
+		w.writeln(0, "}")
+		w.writeln(0, "")
+	}
+
+	if len(model.Associations) > 0 {
+		w.writeln(0, "associations {")
+		for _, association := range model.Associations {
+			w.writeln(1, "( %s %s %s )", association.Source, association.Target, numbers.Float64Str(association.Value))
+		}
+		w.writeln(0, "}")
+		w.writeln(0, "")
+	}
+
+	if len(model.Similarities) > 0 {
+		w.writeln(0, "similar {")
+		for _, similar := range model.Similarities {
+			w.writeln(1, "( %s %s %s )", similar.ChunkOne, similar.ChunkTwo, numbers.Float64Str(similar.Value))
+		}
+		w.writeln(0, "}")
+		w.writeln(0, "")
+	}
+}
+
+func writeProductionsSection(w *amodWriter, model *actr.Model) {
+	w.writeln(0, "~~ productions ~~")
+
+	for _, production := range model.Productions {
+		w.writeln(0, "")
+		w.writeln(0, "%s {", production.Name)
+
+		if production.Description != nil {
+			w.writeln(1, "description: %s", quoteString(*production.Description))
+		}
+
+		if production.Utility != nil {
+			w.writeln(1, "utility: %s", numbers.Float64Str(*production.Utility))
+		}
+
+		if production.Reward != nil {
+			w.writeln(1, "reward: %s", numbers.Float64Str(*production.Reward))
+		}
+
+		w.writeln(1, "match {")
+		for _, match := range production.Matches {
+			writeMatch(w, match)
+		}
+		w.writeln(1, "}")
+
+		w.writeln(1, "do {")
+		for _, statement := range production.DoStatements {
+			writeStatement(w, statement)
+		}
+		w.writeln(1, "}")
+
+		w.writeln(0, "}")
+	}
+}
+
+func writeMatch(w *amodWriter, match *actr.Match) {
+	if match.BufferPattern != nil {
+		pattern := match.BufferPattern.Pattern
+		line := fmt.Sprintf("%s %s", match.BufferPattern.Buffer.Name(), patternString(pattern))
+
+		constraints := []string{}
+		for _, slot := range pattern.Slots {
+			if slot.Var == nil {
+				continue
+			}
+
+			for _, constraint := range slot.Var.Constraints {
+				constraints = append(constraints,
+					fmt.Sprintf("(%s %s %s)", *constraint.LHS, constraint.Comparison, valueString(constraint.RHS)))
+			}
+		}
+
+		if len(constraints) > 0 {
+			line += " when " + strings.Join(constraints, " and ")
+		}
+
+		w.writeln(2, "%s", line)
+	}
+
+	if match.BufferState != nil {
+		w.writeln(2, "buffer_state %s %s", match.BufferState.Buffer.Name(), match.BufferState.State)
+	}
+
+	if match.ModuleState != nil {
+		w.writeln(2, "module_state %s %s", match.ModuleState.Module.ModuleName(), match.ModuleState.State)
+	}
+}
+
+func writeStatement(w *amodWriter, statement *actr.Statement) {
+	switch {
+	case statement.Set != nil:
+		set := statement.Set
+
+		if set.Slots != nil {
+			for _, slot := range *set.Slots {
+				value := *slot.Value
+
+				// set statements store variables without the "?"
+				if value.Var != nil {
+					varName := "?" + *value.Var
+					value.Var = &varName
+				}
+
+				w.writeln(2, "set %s.%s to %s", set.Buffer.Name(), slot.Name, valueString(&value))
+			}
+		} else if set.Pattern != nil {
+			w.writeln(2, "set %s to %s", set.Buffer.Name(), patternString(set.Pattern))
+		}
+
+	case statement.Recall != nil:
+		recall := statement.Recall
+
+		w.writeln(2, "recall %s%s", patternString(recall.Pattern), withString(recall.RequestParameters))
+
+	case statement.Blend != nil:
+		w.writeln(2, "blend %s", patternString(statement.Blend.Pattern))
+
+	case statement.Remember != nil:
+		w.writeln(2, "remember %s", patternString(statement.Remember.Pattern))
+
+	case statement.Reinforce != nil:
+		w.writeln(2, "reinforce %s", valueString(statement.Reinforce.Chunk))
+
+	case statement.FindLocation != nil:
+		find := statement.FindLocation
+
+		w.writeln(2, "find_location %s%s", patternString(find.Pattern), withString(find.RequestParameters))
+
+	case statement.Attend != nil:
+		w.writeln(2, "attend")
+
+	case statement.PressKey != nil:
+		w.writeln(2, "press_key %s", valueString(statement.PressKey.Key))
+
+	case statement.Punch != nil:
+		w.writeln(2, "punch %s %s", statement.Punch.Hand, statement.Punch.Finger)
+
+	case statement.StartTimer != nil:
+		w.writeln(2, "start_timer")
+
+	case statement.Clear != nil:
+		w.writeln(2, "clear %s", strings.Join(statement.Clear.BufferNames, ", "))
+
+	case statement.Print != nil:
+		values := []string{}
+		if statement.Print.Values != nil {
+			for _, value := range *statement.Print.Values {
+				values = append(values, valueString(value))
+			}
+		}
+
+		w.writeln(2, "%s", strings.TrimSpace("print "+strings.Join(values, ", ")))
+
+	case statement.Stop != nil:
+		w.writeln(2, "stop")
+	}
+}
+
+// withString returns the "with" clause for a statement's request parameters (or "" if there are none).
+func withString(requestParameters map[string]string) string {
+	if len(requestParameters) == 0 {
+		return ""
+	}
+
+	params := []string{}
+	for key, value := range requestParameters {
+		params = append(params, fmt.Sprintf("(%s %s)", key, value))
+	}
+	sort.Strings(params)
+
+	return " with " + strings.Join(params, " and ")
+}
+
+// baseLevelString returns the "with" clause for a memory initializer's base-level settings
+// (or "" if there are none).
+func baseLevelString(baseLevel *actr.BaseLevel) string {
+	if baseLevel == nil {
+		return ""
+	}
+
+	str := fmt.Sprintf(" with (references %d)", baseLevel.References)
+	if baseLevel.CreationTime != 0.0 {
+		str += fmt.Sprintf(" and (creation_time %s)", numbers.Float64Str(baseLevel.CreationTime))
+	}
+
+	return str
+}
+
+func patternString(pattern *actr.Pattern) string {
+	if pattern.AnyChunk {
+		return "[any]"
+	}
+
+	slots := make([]string, len(pattern.Slots))
+	for i, slot := range pattern.Slots {
+		if slot.Str != nil {
+			slots[i] = quoteString(*slot.Str)
+			if slot.Negated {
+				slots[i] = "!" + slots[i]
+			}
+			continue
+		}
+
+		slots[i] = slot.String()
+	}
+
+	return fmt.Sprintf("[%s: %s]", pattern.Chunk.TypeName, strings.Join(slots, " "))
+}
+
+func valueString(value *actr.Value) string {
+	if value.Str != nil {
+		return quoteString(*value.Str)
+	}
+
+	return value.String()
+}
+
+// quoteString quotes the string using single quotes unless it contains one.
+func quoteString(str string) string {
+	if strings.Contains(str, "'") {
+		return fmt.Sprintf("%q", str)
+	}
+
+	return fmt.Sprintf("'%s'", str)
+}
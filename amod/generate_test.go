@@ -0,0 +1,52 @@
+package amod
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/asmaloney/gactar/examples"
+)
+
+// TestGenerateCodeRoundTrip checks that the code we generate from a model produces the same model.
+func TestGenerateCodeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	files, err := fs.Glob(examples.AMODExamples, "*.amod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		src, err := fs.ReadFile(examples.AMODExamples, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		model, log, err := GenerateModel(string(src))
+		if err != nil {
+			t.Fatalf("%s: %s", file, log)
+		}
+
+		code := GenerateCode(model)
+
+		generated, log, err := GenerateModel(string(code))
+		if err != nil {
+			t.Fatalf("%s: could not parse generated code:\n%s\n%s", file, log, code)
+		}
+
+		regenerated := GenerateCode(generated)
+		if string(code) != string(regenerated) {
+			t.Errorf("%s: generated code differs:\n%s\n---\n%s", file, code, regenerated)
+		}
+
+		// the generated code should already be formatted
+		formatted, log, err := Format(string(code))
+		if err != nil {
+			t.Fatalf("%s: could not format generated code:\n%s\n%s", file, log, code)
+		}
+
+		if string(code) != string(formatted) {
+			t.Errorf("%s: generated code is not formatted:\n%s\n---\n%s", file, code, formatted)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package amod
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// includeFiles resolves the files included in the model section of "amod" and merges their
+// contents into it. Included files are resolved relative to "fileName" and their contents come
+// before the contents of the file which includes them.
+func includeFiles(amod *amodFile, fileName string, log *issueLog) {
+	includes := amod.Model.Includes
+	if len(includes) == 0 {
+		return
+	}
+
+	if fileName == "" {
+		for _, inc := range includes {
+			log.errorT(inc.Tokens, "cannot include '%s' - includes are only supported when reading a model from a file", inc.FileName)
+		}
+		return
+	}
+
+	path, err := filepath.Abs(fileName)
+	if err != nil {
+		path = fileName
+	}
+
+	merged := &includeFile{}
+
+	resolveIncludes(merged, includes, []string{path}, map[string]bool{}, log)
+
+	merged.merge(amod.Config, amod.Init, amod.Productions)
+
+	amod.Config = merged.Config
+	amod.Init = merged.Init
+	amod.Productions = merged.Productions
+}
+
+// resolveIncludes parses each included file and merges it (and anything it includes) into "merged".
+// "includedBy" is the chain of files which led us here and is used to detect cycles. The last one
+// is the file containing "includes". "resolved" is the set of files already merged so a file
+// included by more than one file (e.g. a diamond) is only merged once.
+func resolveIncludes(merged *includeFile, includes []*include, includedBy []string, resolved map[string]bool, log *issueLog) {
+	dir := filepath.Dir(includedBy[len(includedBy)-1])
+
+	for _, inc := range includes {
+		path := inc.FileName
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		if slices.Contains(includedBy, path) {
+			log.errorT(inc.Tokens, "cannot include '%s' - it would include itself", inc.FileName)
+			continue
+		}
+
+		if resolved[path] {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				log.errorT(inc.Tokens, "included file '%s' not found", inc.FileName)
+			} else {
+				log.errorT(inc.Tokens, "cannot include '%s': %s", inc.FileName, err.Error())
+			}
+			continue
+		}
+
+		included, err := parseIncludeFile(path, file)
+		file.Close()
+
+		if err != nil {
+			logParseError(log, err)
+			continue
+		}
+
+		resolved[path] = true
+
+		resolveIncludes(merged, included.Includes, append(slices.Clone(includedBy), path), resolved, log)
+
+		merged.merge(included.Config, included.Init, included.Productions)
+	}
+}
+
+// merge appends the contents of the sections to the sections of the include file.
+func (i *includeFile) merge(config *configSection, init *initSection, productions *productionSection) {
+	if config != nil {
+		if i.Config == nil {
+			i.Config = &configSection{Tokens: config.Tokens}
+		}
+
+		dst := i.Config
+
+		if config.GactarConfig != nil {
+			if dst.GactarConfig == nil {
+				dst.GactarConfig = &gactarConfig{Tokens: config.GactarConfig.Tokens}
+			}
+			dst.GactarConfig.GactarFields = append(dst.GactarConfig.GactarFields, config.GactarConfig.GactarFields...)
+		}
+
+		if config.ModuleConfig != nil {
+			if dst.ModuleConfig == nil {
+				dst.ModuleConfig = &moduleConfig{Tokens: config.ModuleConfig.Tokens}
+			}
+			dst.ModuleConfig.Modules = append(dst.ModuleConfig.Modules, config.ModuleConfig.Modules...)
+		}
+
+		if config.ChunkConfig != nil {
+			if dst.ChunkConfig == nil {
+				dst.ChunkConfig = &chunkConfig{Tokens: config.ChunkConfig.Tokens}
+			}
+			dst.ChunkConfig.ChunkDecls = append(dst.ChunkConfig.ChunkDecls, config.ChunkConfig.ChunkDecls...)
+		}
+	}
+
+	if init != nil {
+		if i.Init == nil {
+			i.Init = &initSection{Tokens: init.Tokens}
+		}
+		i.Init.Initializations = append(i.Init.Initializations, init.Initializations...)
+	}
+
+	if productions != nil {
+		if i.Productions == nil {
+			i.Productions = &productionSection{Tokens: productions.Tokens}
+		}
+		i.Productions.Productions = append(i.Productions.Productions, productions.Productions...)
+	}
+}
@@ -0,0 +1,168 @@
+package amod
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	t.Parallel()
+
+	model, log, err := GenerateModelFromFile(filepath.Join("testdata", "include", "count.amod"))
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	if len(model.Chunks) < 2 {
+		t.Fatalf("expected chunks from included file, got %d", len(model.Chunks))
+	}
+
+	// the memory initializers plus the goal from the including file
+	if len(model.Initializers) != 6 {
+		t.Errorf("expected 6 initializers, got %d", len(model.Initializers))
+	}
+
+	// included productions come before the productions of the including file
+	var names []string
+	for _, production := range model.Productions {
+		names = append(names, production.Name)
+	}
+
+	expected := "begin increment end"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected productions %q, got %q", expected, strings.Join(names, " "))
+	}
+}
+
+func TestIncludeDiamond(t *testing.T) {
+	t.Parallel()
+
+	model, log, err := GenerateModelFromFile(filepath.Join("testdata", "include", "diamond.amod"))
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	var names []string
+	for _, production := range model.Productions {
+		names = append(names, production.Name)
+	}
+
+	expected := "left right"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected productions %q, got %q", expected, strings.Join(names, " "))
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{
+			file:     "missing.amod",
+			expected: "ERROR: included file 'does_not_exist.amod' not found (missing.amod, line 5, col 0)",
+		},
+		{
+			file:     "cycle.amod",
+			expected: "ERROR: cannot include 'cycle.amod' - it would include itself (cycle_include.amod, line 1, col 0)",
+		},
+		{
+			file:     "syntax.amod",
+			expected: `ERROR: unexpected token "first" (expected ":" <ident>+ "]") (syntax_include.amod, line 4, col 11)`,
+		},
+		{
+			file:     "error.amod",
+			expected: "ERROR: could not find chunk named 'countFrom' (error_include.amod, line 5, col 14)",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.file, func(t *testing.T) {
+			t.Parallel()
+
+			_, log, err := GenerateModelFromFile(filepath.Join("testdata", "include", tt.file))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			var out strings.Builder
+			if err := log.Write(&out); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.TrimSpace(out.String()) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, strings.TrimSpace(out.String()))
+			}
+		})
+	}
+}
+
+func Example_includeFromString() {
+	generateToStdout(`
+	~~ model ~~
+	name: Test
+	include 'common.amod'
+	~~ config ~~
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ERROR: cannot include 'common.amod' - includes are only supported when reading a model from a file (line 4, col 1)
+}
+
+func Example_formatInclude() {
+	formatToStdout(`
+	include 'memory.amod'
+	~~ config ~~
+	chunks { [count: first second] }
+	~~ productions ~~
+	start { match { goal [count: ?a ?b] } do { print ?a, ?b } }`)
+
+	// Output:
+	// include 'memory.amod'
+	//
+	// ~~ config ~~
+	//
+	// chunks {
+	//     [count: first second]
+	// }
+	//
+	// ~~ productions ~~
+	//
+	// start {
+	//     match {
+	//         goal [count: ?a ?b]
+	//     }
+	//     do {
+	//         print ?a, ?b
+	//     }
+	// }
+}
+
+func Example_formatModelInclude() {
+	formatToStdout(`
+	~~ model ~~
+	name: Test
+	include   'chunks.amod'  include 'memory.amod'
+	~~ config ~~
+	~~ init ~~
+	~~ productions ~~`)
+
+	// Output:
+	// ~~ model ~~
+	//
+	// name: Test
+	//
+	// include 'chunks.amod'
+	// include 'memory.amod'
+	//
+	// ~~ config ~~
+	//
+	// ~~ init ~~
+	//
+	// ~~ productions ~~
+}
@@ -49,6 +49,7 @@ func tokensToLocation(t []lexer.Token) *issues.Location {
 	}
 
 	return &issues.Location{
+		SourceFile:  firstToken.Pos.Filename,
 		Line:        firstToken.Pos.Line,
 		ColumnStart: firstToken.Pos.Column,
 		ColumnEnd:   lastToken.Pos.Column + lastTokenLen,
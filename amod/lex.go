@@ -25,6 +25,7 @@ var LexerDefinition lexer.Definition = lexer_def{}
 
 // LexError is returned for errors we have while lexing.
 type LexError struct {
+	Filename string // may be empty
 	Line     int
 	Position int
 	Value    string
@@ -51,6 +52,10 @@ const (
 
 	lexemeEquality
 	lexemeInequality
+	lexemeLessThan
+	lexemeLessThanOrEqual
+	lexemeGreaterThan
+	lexemeGreaterThanOrEqual
 
 	lexemeSectionDelim
 
@@ -82,6 +87,14 @@ func (l lexemeType) String() string {
 		return "equality"
 	case lexemeInequality:
 		return "inequality"
+	case lexemeLessThan:
+		return "less than"
+	case lexemeLessThanOrEqual:
+		return "less than or equal"
+	case lexemeGreaterThan:
+		return "greater than"
+	case lexemeGreaterThanOrEqual:
+		return "greater than or equal"
 	case lexemeSectionDelim:
 		return "section delimiter"
 	case lexemePatternVar:
@@ -94,10 +107,11 @@ func (l lexemeType) String() string {
 }
 
 type lexeme struct {
-	typ   lexemeType
-	value string
-	line  int // line number this lexeme is on
-	pos   int // position within the line
+	typ    lexemeType
+	value  string
+	line   int // line number this lexeme is on
+	pos    int // position within the line
+	offset int // offset of the lexeme in the input
 }
 
 // sectionType is used to keep track of what section we are lexing
@@ -141,6 +155,7 @@ var keywordsModel []string = []string{
 	"authors",
 	"description",
 	"examples",
+	"include",
 	"name",
 	"nil",
 }
@@ -155,45 +170,72 @@ var keywordsConfig []string = []string{
 
 // keywordsModel are only keywords for the init section
 var keywordsInit []string = []string{
+	"and",
+	"associations",
 	"nil",
+	"screen",
 	"similar",
+	"with",
 }
 
 // keywordsModel are only keywords for the productions section
 var keywordsProductions []string = []string{
 	"and",
 	"any",
+	"attend",
+	"blend",
 	"buffer_state",
 	"clear",
 	"description",
 	"do",
+	"find_location",
 	"match",
 	"module_state",
 	"nil",
+	"press_key",
 	"print",
+	"punch",
 	"recall",
+	"reinforce",
+	"remember",
+	"reward",
 	"set",
+	"start_timer",
 	"stop",
 	"to",
+	"utility",
 	"when",
 	"with",
 }
 
+// IsKeyword checks if "id" is a keyword in any section of an amod file.
+// This is used when converting from other formats to avoid generating invalid names.
+func IsKeyword(id string) bool {
+	return slices.Contains(keywordsModel, id) ||
+		slices.Contains(keywordsConfig, id) ||
+		slices.Contains(keywordsInit, id) ||
+		slices.Contains(keywordsProductions, id)
+}
+
 // Symbols provides a mapping from participle strings to our lexemes
 func (lexer_def) Symbols() map[string]lexer.TokenType {
 	return map[string]lexer.TokenType{
-		"Comment":      lexer.TokenType(lexemeComment),
-		"Whitespace":   lexer.TokenType(lexemeSpace),
-		"Keyword":      lexer.TokenType(lexemeKeyword),
-		"Ident":        lexer.TokenType(lexemeIdentifier),
-		"Number":       lexer.TokenType(lexemeNumber),
-		"String":       lexer.TokenType(lexemeString),
-		"Char":         lexer.TokenType(lexemeChar),
-		"Equality":     lexer.TokenType(lexemeEquality),
-		"Inequality":   lexer.TokenType(lexemeInequality),
-		"SectionDelim": lexer.TokenType(lexemeSectionDelim),
-		"Var":          lexer.TokenType(lexemePatternVar),
-		"Wildcard":     lexer.TokenType(lexemePatternWildcard),
+		"Comment":            lexer.TokenType(lexemeComment),
+		"Whitespace":         lexer.TokenType(lexemeSpace),
+		"Keyword":            lexer.TokenType(lexemeKeyword),
+		"Ident":              lexer.TokenType(lexemeIdentifier),
+		"Number":             lexer.TokenType(lexemeNumber),
+		"String":             lexer.TokenType(lexemeString),
+		"Char":               lexer.TokenType(lexemeChar),
+		"Equality":           lexer.TokenType(lexemeEquality),
+		"Inequality":         lexer.TokenType(lexemeInequality),
+		"LessThan":           lexer.TokenType(lexemeLessThan),
+		"LessThanOrEqual":    lexer.TokenType(lexemeLessThanOrEqual),
+		"GreaterThan":        lexer.TokenType(lexemeGreaterThan),
+		"GreaterThanOrEqual": lexer.TokenType(lexemeGreaterThanOrEqual),
+		"SectionDelim":       lexer.TokenType(lexemeSectionDelim),
+		"Var":                lexer.TokenType(lexemePatternVar),
+		"Wildcard":           lexer.TokenType(lexemePatternWildcard),
 	}
 }
 
@@ -237,7 +279,7 @@ func (l *lexer_amod) Next() (tok lexer.Token, err error) {
 
 	pos := lexer.Position{
 		Filename: l.name,
-		Offset:   l.pos,
+		Offset:   next.offset,
 		Line:     next.line,
 		Column:   next.pos,
 	}
@@ -254,6 +296,7 @@ func (l *lexer_amod) Next() (tok lexer.Token, err error) {
 
 	if next.typ == lexemeError {
 		err = LexError{
+			Filename: l.name,
 			Line:     next.line,
 			Position: next.pos,
 			Value:    next.value,
@@ -341,10 +384,11 @@ func (l *lexer_amod) acceptRun(valid string) {
 func (l *lexer_amod) emit(t lexemeType) {
 	value := l.input[l.start:l.pos]
 	l.lexemes <- lexeme{
-		typ:   t,
-		value: value,
-		line:  l.line,
-		pos:   l.start - l.lastNewlinePos + 1,
+		typ:    t,
+		value:  value,
+		line:   l.line,
+		pos:    l.start - l.lastNewlinePos + 1,
+		offset: l.start,
 	}
 
 	l.start = l.pos
@@ -357,6 +401,7 @@ func (l *lexer_amod) errorf(format string, args ...interface{}) stateFn {
 		fmt.Sprintf(format, args...),
 		l.line,
 		l.pos - l.lastNewlinePos,
+		l.pos,
 	}
 
 	return nil
@@ -429,6 +474,22 @@ func lexStart(l *lexer_amod) stateFn {
 			l.emit(lexemeChar)
 		}
 
+	case r == '<':
+		if l.nextIs('=') {
+			l.next()
+			l.emit(lexemeLessThanOrEqual)
+		} else {
+			l.emit(lexemeLessThan)
+		}
+
+	case r == '>':
+		if l.nextIs('=') {
+			l.next()
+			l.emit(lexemeGreaterThanOrEqual)
+		} else {
+			l.emit(lexemeGreaterThan)
+		}
+
 	case r == '/':
 		if l.nextIs('/') {
 			l.backup()
@@ -116,3 +116,34 @@ func TestNumberNoDigits(t *testing.T) {
 		t.Errorf("expected to lex %q as char (%d) - got type %d", token.Value, lexemeChar, token.Type)
 	}
 }
+
+func TestComparisonOperators(t *testing.T) {
+	t.Parallel()
+
+	src := `== != < <= > >=`
+
+	l := lex("test", src)
+
+	expectedTypes := []lexemeType{
+		lexemeEquality,
+		lexemeInequality,
+		lexemeLessThan,
+		lexemeLessThanOrEqual,
+		lexemeGreaterThan,
+		lexemeGreaterThanOrEqual,
+	}
+
+	for i, expected := range strings.Split(src, " ") {
+		token, err := l.Next()
+		if err != nil {
+			t.Errorf("[index %d] error getting next token: %s", i, err.Error())
+		}
+
+		if token.Type != lexer.TokenType(expectedTypes[i]) {
+			t.Errorf("[index %d] expected to lex '%s' as %s (%d) - got type %d", i, token.Value, expectedTypes[i], expectedTypes[i], token.Type)
+		}
+		if token.Value != expected {
+			t.Errorf("[index %d] expected token value: %s - got %s", i, expected, token.Value)
+		}
+	}
+}
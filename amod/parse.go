@@ -44,6 +44,31 @@ type modelSection struct {
 	Description string     `parser:"('description':Keyword ':' @String)?"`
 	Authors     []string   `parser:"('authors':Keyword '{' @String* '}')?"`
 	Examples    []*pattern `parser:"('examples':Keyword '{' @@* '}')?"`
+	Includes    []*include `parser:"@@*"`
+
+	Tokens []lexer.Token
+}
+
+type include struct {
+	Include  string `parser:"'include':Keyword"` // not used, but must be visible for parse to work
+	FileName string `parser:"@String"`
+
+	Tokens []lexer.Token
+}
+
+// includeFile is a file included by another using "include". It does not have a model section, but
+// it may include other files and has the same (optional) sections as an amod file.
+type includeFile struct {
+	Includes []*include `parser:"@@*"`
+
+	ConfigHeader string         `parser:"('~~':SectionDelim 'config':Keyword '~~':SectionDelim"`
+	Config       *configSection `parser:"@@?)?"`
+
+	InitHeader string       `parser:"('~~':SectionDelim 'init':Keyword '~~':SectionDelim"`
+	Init       *initSection `parser:"@@?)?"`
+
+	ProductionsHeader string             `parser:"('~~':SectionDelim 'productions':Keyword '~~':SectionDelim"`
+	Productions       *productionSection `parser:"@@?)?"`
 
 	Tokens []lexer.Token
 }
@@ -95,15 +120,108 @@ func (p printArg) hasVar() bool {
 }
 
 type setArg struct {
-	Arg *arg    `parser:"( @@"`
-	Nil *bool   `parser:"| @('nil':Keyword)"`
-	ID  *string `parser:"| @Ident )"`
+	Expr *expression `parser:"( @@"`
+	Nil  *bool       `parser:"| @('nil':Keyword)"`
+	ID   *string     `parser:"| @Ident )"`
 
 	Tokens []lexer.Token
 }
 
 func (s setArg) hasVar() bool {
-	return s.Arg != nil && s.Arg.hasVar()
+	return s.Expr != nil && len(s.Expr.vars()) > 0
+}
+
+// expression is an arithmetic expression used in set statements. It may also be a single
+// argument (e.g. "?count" or "'foo'").
+//
+//	expression: term ( ('+'|'-') term )*
+//	term:       operand ( ('*'|'/') operand )*
+//	operand:    arg | '(' expression ')'
+type expression struct {
+	Left  *term            `parser:"@@"`
+	Right []*termOperation `parser:"@@*"`
+
+	Tokens []lexer.Token
+}
+
+// arg returns the argument if this expression is a single argument, otherwise nil.
+func (e expression) arg() *arg {
+	if len(e.Right) > 0 {
+		return nil
+	}
+
+	return e.Left.arg()
+}
+
+// vars returns all the variable arguments used in the expression.
+func (e expression) vars() (vars []*arg) {
+	vars = e.Left.vars()
+
+	for _, op := range e.Right {
+		vars = append(vars, op.Term.vars()...)
+	}
+
+	return
+}
+
+type termOperation struct {
+	// Operator is nil if the term starts with a signed number (e.g. "?count -1") because
+	// the lexer includes the sign in the number.
+	Operator *string `parser:"@('+'|'-')?"`
+	Term     *term   `parser:"@@"`
+
+	Tokens []lexer.Token
+}
+
+type term struct {
+	Left  *operand            `parser:"@@"`
+	Right []*operandOperation `parser:"@@*"`
+
+	Tokens []lexer.Token
+}
+
+func (t term) arg() *arg {
+	if len(t.Right) > 0 {
+		return nil
+	}
+
+	return t.Left.Arg
+}
+
+func (t term) vars() (vars []*arg) {
+	vars = t.Left.vars()
+
+	for _, op := range t.Right {
+		vars = append(vars, op.Operand.vars()...)
+	}
+
+	return
+}
+
+type operandOperation struct {
+	Operator string   `parser:"@('*'|'/')"`
+	Operand  *operand `parser:"@@"`
+
+	Tokens []lexer.Token
+}
+
+type operand struct {
+	Arg  *arg        `parser:"( @@"`
+	Expr *expression `parser:"| '(' @@ ')' )"`
+
+	Tokens []lexer.Token
+}
+
+func (o operand) vars() []*arg {
+	if o.Expr != nil {
+		return o.Expr.vars()
+	}
+
+	if o.Arg.hasVar() {
+		return []*arg{o.Arg}
+	}
+
+	return nil
 }
 
 type fieldValue struct {
@@ -206,8 +324,9 @@ type configSection struct {
 }
 
 type namedInitializer struct {
-	ChunkName *string  `parser:"(@Ident)?"`
-	Pattern   *pattern `parser:"@@"`
+	ChunkName *string     `parser:"(@Ident)?"`
+	Pattern   *pattern    `parser:"@@"`
+	With      *withClause `parser:"@@?"` // base-level settings (memory only)
 
 	Tokens []lexer.Token
 }
@@ -246,9 +365,47 @@ type similarityInitializer struct {
 	Tokens []lexer.Token
 }
 
+type association struct {
+	OpenParen  string  `parser:"'('"`
+	Source     string  `parser:"@Ident"`
+	Target     string  `parser:"@Ident"`
+	Value      float64 `parser:"@Number"`
+	CloseParen string  `parser:"')'"`
+
+	Tokens []lexer.Token
+}
+
+type associationInitializer struct {
+	Associations    string         `parser:"'associations':Keyword"`
+	OpenBrace       string         `parser:"'{'"`
+	AssociationList []*association `parser:"@@+"`
+	CloseBrace      string         `parser:"'}'"`
+
+	Tokens []lexer.Token
+}
+
+type screenItem struct {
+	Text string `parser:"'text' @String"`
+	X    string `parser:"'at' @Number"`
+	Y    string `parser:"@Number"`
+
+	Tokens []lexer.Token
+}
+
+type screenInitializer struct {
+	Screen     string        `parser:"'screen':Keyword"`
+	OpenBrace  string        `parser:"'{'"`
+	Items      []*screenItem `parser:"@@*"`
+	CloseBrace string        `parser:"'}'"`
+
+	Tokens []lexer.Token
+}
+
 type initialization struct {
-	ModuleInitializer     *moduleInitializer     `parser:"( @@"`
-	SimilarityInitializer *similarityInitializer `parser:"| @@ )"`
+	ModuleInitializer      *moduleInitializer      `parser:"( @@"`
+	AssociationInitializer *associationInitializer `parser:"| @@"`
+	ScreenInitializer      *screenInitializer      `parser:"| @@"`
+	SimilarityInitializer  *similarityInitializer  `parser:"| @@ )"`
 
 	Tokens []lexer.Token
 }
@@ -290,8 +447,12 @@ type pattern struct {
 }
 
 type comparisonOperator struct {
-	Equal    *string `parser:"( @Equality"`
-	NotEqual *string `parser:"| @Inequality )"`
+	Equal              *string `parser:"( @Equality"`
+	NotEqual           *string `parser:"| @Inequality"`
+	LessThan           *string `parser:"| @LessThan"`
+	LessThanOrEqual    *string `parser:"| @LessThanOrEqual"`
+	GreaterThan        *string `parser:"| @GreaterThan"`
+	GreaterThanOrEqual *string `parser:"| @GreaterThanOrEqual )"`
 
 	Tokens []lexer.Token
 }
@@ -351,6 +512,18 @@ type match struct {
 	Tokens []lexer.Token
 }
 
+type attendStatement struct {
+	Attend string `parser:"'attend':Keyword"`
+
+	Tokens []lexer.Token
+}
+
+type blendStatement struct {
+	Pattern *pattern `parser:"'blend':Keyword @@"`
+
+	Tokens []lexer.Token
+}
+
 type clearStatement struct {
 	BufferNames []string `parser:"'clear':Keyword ( @Ident ','? )+"`
 
@@ -386,6 +559,40 @@ type recallStatement struct {
 	Tokens []lexer.Token
 }
 
+type findLocationStatement struct {
+	Pattern *pattern    `parser:"'find_location':Keyword @@"`
+	With    *withClause `parser:"@@?"`
+
+	Tokens []lexer.Token
+}
+
+type pressKeyStatement struct {
+	Key *arg `parser:"'press_key':Keyword @@"`
+
+	Tokens []lexer.Token
+}
+
+type punchStatement struct {
+	Hand   string `parser:"'punch':Keyword @Ident"`
+	Finger string `parser:"@Ident"`
+
+	Tokens []lexer.Token
+}
+
+type reinforceStatement struct {
+	Reinforce string  `parser:"'reinforce':Keyword"` // not used, but must be visible for parse to work
+	Var       *string `parser:"( @Var"`
+	ID        *string `parser:"| @Ident )"`
+
+	Tokens []lexer.Token
+}
+
+type rememberStatement struct {
+	Pattern *pattern `parser:"'remember':Keyword @@"`
+
+	Tokens []lexer.Token
+}
+
 type setStatement struct {
 	Set       string    `parser:"'set':Keyword"` // not used, but must be visible for parse to work
 	BufferRef bufferRef `parser:"@@"`
@@ -397,6 +604,12 @@ type setStatement struct {
 	Tokens []lexer.Token
 }
 
+type startTimerStatement struct {
+	StartTimer string `parser:"'start_timer':Keyword"`
+
+	Tokens []lexer.Token
+}
+
 type stopStatement struct {
 	Stop string `parser:"'stop':Keyword"`
 
@@ -404,11 +617,19 @@ type stopStatement struct {
 }
 
 type statement struct {
-	Clear  *clearStatement  `parser:"  @@"`
-	Print  *printStatement  `parser:"| @@"`
-	Recall *recallStatement `parser:"| @@"`
-	Set    *setStatement    `parser:"| @@"`
-	Stop   *stopStatement   `parser:"| @@"`
+	Attend       *attendStatement       `parser:"  @@"`
+	Blend        *blendStatement        `parser:"| @@"`
+	Clear        *clearStatement        `parser:"| @@"`
+	FindLocation *findLocationStatement `parser:"| @@"`
+	Print        *printStatement        `parser:"| @@"`
+	PressKey     *pressKeyStatement     `parser:"| @@"`
+	Punch        *punchStatement        `parser:"| @@"`
+	Recall       *recallStatement       `parser:"| @@"`
+	Reinforce    *reinforceStatement    `parser:"| @@"`
+	Remember     *rememberStatement     `parser:"| @@"`
+	Set          *setStatement          `parser:"| @@"`
+	StartTimer   *startTimerStatement   `parser:"| @@"`
+	Stop         *stopStatement         `parser:"| @@"`
 
 	Tokens []lexer.Token
 }
@@ -423,6 +644,8 @@ type do struct {
 type production struct {
 	Name        string  `parser:"@Ident '{'"`
 	Description *string `parser:"('description':Keyword ':' @String)?"`
+	Utility     *string `parser:"('utility':Keyword ':' @Number)?"`
+	Reward      *string `parser:"('reward':Keyword ':' @Number)?"`
 	Match       *match  `parser:"@@"`
 	Do          *do     `parser:"@@"`
 	End         string  `parser:"'}'"` // not used, but must be visible for parse to work
@@ -442,8 +665,25 @@ var amodParser = participle.MustBuild[amodFile](
 	participle.Unquote(),
 )
 
-func parseAMOD(r io.Reader) (amod *amodFile, err error) {
-	amod, err = amodParser.Parse("", r)
+var includeParser = participle.MustBuild[includeFile](
+	participle.Lexer(LexerDefinition),
+	participle.Elide("Comment", "Whitespace"),
+	participle.Unquote(),
+)
+
+// parseAMOD parses an amod file. "fileName" is used for the location of issues and may be empty.
+func parseAMOD(fileName string, r io.Reader) (amod *amodFile, err error) {
+	amod, err = amodParser.Parse(fileName, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// parseIncludeFile parses a file which was included using "include".
+func parseIncludeFile(fileName string, r io.Reader) (file *includeFile, err error) {
+	file, err = includeParser.Parse(fileName, r)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,466 @@
+package amod
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+// SymbolKind is the kind of item a Symbol refers to.
+type SymbolKind int
+
+const (
+	SymbolChunkType SymbolKind = iota // chunk type name in a pattern or chunk declaration
+	SymbolSlot                        // slot name in a chunk declaration
+	SymbolBuffer                      // buffer name
+	SymbolModule                      // module name in the config or init sections
+	SymbolVar                         // variable
+	SymbolInclude                     // file name of an include
+)
+
+// Symbol is a named item found in the source of an amod file. Locations use the same line & column
+// numbering as the issues we log.
+type Symbol struct {
+	Kind SymbolKind
+	Name string
+
+	// IsDeclaration is set for chunk types & slots in chunk declarations and for modules & buffers
+	// in the modules config.
+	IsDeclaration bool
+
+	Production string // name of the production the symbol is in (if any)
+	Buffer     string // buffer of the match item a pattern variable is in (if any)
+	ChunkType  string // chunk type of the pattern the symbol is in (if any)
+	SlotIndex  int    // position of the symbol in its pattern (-1 if it is not in one)
+
+	issues.Location
+}
+
+// PatternPosition describes where a position in the source falls within a pattern such as
+// [count: ?x ?y].
+type PatternPosition struct {
+	ChunkType string // empty if the position is in the chunk type name
+	SlotIndex int    // slot the position is in (-1 if the position is in the chunk type name)
+}
+
+// Symbols returns the chunk types, slots, buffers, modules, variables, and includes in the amod
+// source "src". It only uses the lexer so that it works on source which does not parse yet.
+func Symbols(src string) []Symbol {
+	return scanSymbols(src).symbols
+}
+
+// PatternAt returns where the position (line, column) falls in a pattern in "src". The second return
+// value is false if the position is not in a pattern.
+func PatternAt(src string, line, column int) (pos PatternPosition, ok bool) {
+	for _, p := range scanSymbols(src).patterns {
+		if !p.contains(line, column) {
+			continue
+		}
+
+		if !p.hasColon || p.colon.after(line, column) {
+			return PatternPosition{SlotIndex: -1}, true
+		}
+
+		index := 0
+		for _, slot := range p.slots {
+			if !slot.before(line, column) {
+				break
+			}
+			index++
+		}
+
+		// if we are touching the end of a slot, we are still in it
+		if index > 0 && p.slots[index-1].touches(line, column) {
+			index--
+		}
+
+		return PatternPosition{ChunkType: p.chunkType, SlotIndex: index}, true
+	}
+
+	return
+}
+
+// CheckSource parses and validates the amod source "src" as if it was read from "fileName" (which
+// may be empty) and returns the generated model and a log of any issues. The model is nil if
+// there were errors.
+//
+// Files which are meant to be included by others only have their syntax checked.
+func CheckSource(fileName, src string) (model *actr.Model, iLog *issues.Log) {
+	if isIncludeFile(src) {
+		log := newLog()
+
+		_, err := parseIncludeFile(fileName, strings.NewReader(src))
+		if err != nil {
+			logParseError(log, err)
+		}
+
+		return nil, &log.Log
+	}
+
+	model, iLog, _ = modelReader(fileName, strings.NewReader(src))
+	return
+}
+
+// span is the range of a token (or tokens) on a line
+type span struct {
+	line  int
+	start int
+	end   int // exclusive
+}
+
+func tokenSpan(token lexer.Token) span {
+	return span{
+		line:  token.Pos.Line,
+		start: token.Pos.Column,
+		end:   token.Pos.Column + len(token.Value),
+	}
+}
+
+// before checks if the span ends at or before the position
+func (s span) before(line, column int) bool {
+	return s.line < line || (s.line == line && s.end <= column)
+}
+
+// after checks if the span starts after the position
+func (s span) after(line, column int) bool {
+	return s.line > line || (s.line == line && s.start >= column)
+}
+
+// touches checks if the position is in the span or directly after it
+func (s span) touches(line, column int) bool {
+	return s.line == line && column >= s.start && column <= s.end
+}
+
+// patternRange tracks the location of a pattern & its parts
+type patternRange struct {
+	chunkType string
+
+	open     span
+	close    span // may be unset if the pattern is not closed
+	colon    span
+	hasColon bool
+	slots    []span
+}
+
+func (p patternRange) contains(line, column int) bool {
+	if !p.open.before(line, column) {
+		return false
+	}
+
+	if p.close.line == 0 {
+		return true
+	}
+
+	return p.close.after(line, column)
+}
+
+// symbolScanner walks through the code tokens of amod source and keeps track of enough context to
+// find the symbols.
+type symbolScanner struct {
+	tokens []lexer.Token
+	index  int
+
+	section sectionType
+	blocks  []string // names of the blocks ("{ ... }") we are in
+
+	production string // name of the production we are in
+	inClear    bool   // are we in the list of buffers of a clear statement?
+
+	symbols  []Symbol
+	patterns []patternRange
+}
+
+func scanSymbols(src string) *symbolScanner {
+	s := &symbolScanner{}
+
+	l := lex("", src)
+	for {
+		token, err := l.Next()
+		if err != nil || token.EOF() {
+			break
+		}
+
+		if isCode(token) {
+			s.tokens = append(s.tokens, token)
+		}
+	}
+
+	for s.index < len(s.tokens) {
+		s.scanToken()
+	}
+
+	return s
+}
+
+// peek returns the token "offset" tokens from the current one, or an empty token if there isn't one
+func (s *symbolScanner) peek(offset int) lexer.Token {
+	index := s.index + offset
+	if index < 0 || index >= len(s.tokens) {
+		return lexer.Token{}
+	}
+
+	return s.tokens[index]
+}
+
+// inBlock checks if the innermost blocks we are in match "names"
+func (s *symbolScanner) inBlock(names ...string) bool {
+	if len(s.blocks) != len(names) {
+		return false
+	}
+
+	for i, name := range names {
+		if name != "" && s.blocks[i] != name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// add adds a symbol for "token" and returns it so the caller may fill in more details
+func (s *symbolScanner) add(kind SymbolKind, token lexer.Token, isDeclaration bool) *Symbol {
+	span := tokenSpan(token)
+
+	s.symbols = append(s.symbols, Symbol{
+		Kind:          kind,
+		Name:          token.Value,
+		IsDeclaration: isDeclaration,
+		Production:    s.production,
+		SlotIndex:     -1,
+		Location: issues.Location{
+			Line:        span.line,
+			ColumnStart: span.start,
+			ColumnEnd:   span.end,
+		},
+	})
+
+	return &s.symbols[len(s.symbols)-1]
+}
+
+func (s *symbolScanner) scanToken() {
+	token := s.peek(0)
+	typ := lexemeType(token.Type)
+
+	if typ == lexemeSectionDelim {
+		s.scanSectionHeader()
+		return
+	}
+
+	if typ == lexemeKeyword || token.Value == "}" {
+		s.inClear = false
+	}
+
+	switch {
+	case token.Value == "{":
+		s.openBlock()
+
+	case token.Value == "}":
+		if len(s.blocks) > 0 {
+			s.blocks = s.blocks[:len(s.blocks)-1]
+		}
+
+		if len(s.blocks) == 0 {
+			s.production = ""
+		}
+
+	case token.Value == "[":
+		s.scanPattern()
+		return
+
+	case typ == lexemePatternVar:
+		s.add(SymbolVar, token, false)
+
+	case typ == lexemeKeyword && token.Value == "include":
+		next := s.peek(1)
+		if lexemeType(next.Type) == lexemeString {
+			symbol := s.add(SymbolInclude, next, false)
+			symbol.Name = unquote(next.Value)
+			s.index++
+		}
+
+	case typ == lexemeKeyword && token.Value == "clear":
+		s.inClear = true
+
+	case typ == lexemeIdentifier:
+		s.scanIdentifier()
+	}
+
+	s.index++
+}
+
+func (s *symbolScanner) scanSectionHeader() {
+	name := s.peek(1)
+	if lexemeType(s.peek(2).Type) != lexemeSectionDelim {
+		s.index++
+		return
+	}
+
+	switch name.Value {
+	case "model":
+		s.section = sectionModel
+	case "config":
+		s.section = sectionConfig
+	case "init":
+		s.section = sectionInit
+	case "productions":
+		s.section = sectionProductions
+	}
+
+	s.blocks = nil
+	s.production = ""
+	s.inClear = false
+
+	s.index += 3
+}
+
+func (s *symbolScanner) openBlock() {
+	name := ""
+
+	prev := s.peek(-1)
+	switch lexemeType(prev.Type) {
+	case lexemeIdentifier, lexemeKeyword:
+		name = prev.Value
+	}
+
+	if s.section == sectionProductions && len(s.blocks) == 0 {
+		s.production = name
+	}
+
+	s.blocks = append(s.blocks, name)
+}
+
+func (s *symbolScanner) scanIdentifier() {
+	token := s.peek(0)
+	prev := s.peek(-1)
+	next := s.peek(1)
+
+	switch s.section {
+	case sectionConfig:
+		if next.Value != "{" {
+			return
+		}
+
+		switch {
+		case s.inBlock("modules") && token.Value != "extra_buffers":
+			s.add(SymbolModule, token, true)
+
+		// buffers are either extra buffers or configured in their module (e.g. memory { retrieval {...} })
+		case s.inBlock("modules", ""):
+			s.add(SymbolBuffer, token, true)
+		}
+
+	case sectionInit:
+		// only the first identifier of an initializer (e.g. memory { ... } or goal [ ... ]) and
+		// not the name of a chunk (e.g. goal name [ ... ])
+		if len(s.blocks) != 0 || lexemeType(prev.Type) == lexemeIdentifier {
+			return
+		}
+
+		if next.Value == "{" || next.Value == "[" || lexemeType(next.Type) == lexemeIdentifier {
+			s.add(SymbolModule, token, false)
+		}
+
+	case sectionProductions:
+		switch {
+		case s.inBlock("", "match") && next.Value == "[":
+			s.add(SymbolBuffer, token, false)
+
+		case prev.Value == "buffer_state":
+			s.add(SymbolBuffer, token, false)
+
+		case s.inBlock("", "do") && (prev.Value == "set" || s.inClear):
+			s.add(SymbolBuffer, token, false)
+		}
+	}
+}
+
+// scanPattern scans a pattern like [count: ?x ?y]. It stops at the closing bracket or at a token
+// which cannot be in a pattern so that unfinished patterns work.
+func (s *symbolScanner) scanPattern() {
+	p := patternRange{
+		open: tokenSpan(s.peek(0)),
+	}
+
+	isDeclaration := s.section == sectionConfig && s.inBlock("chunks")
+
+	buffer := ""
+	if s.section == sectionProductions && s.inBlock("", "match") {
+		if prev := s.peek(-1); lexemeType(prev.Type) == lexemeIdentifier {
+			buffer = prev.Value
+		}
+	}
+
+	s.index++
+
+	typeToken := s.peek(0)
+	switch lexemeType(typeToken.Type) {
+	case lexemeIdentifier, lexemeKeyword:
+		p.chunkType = typeToken.Value
+		s.add(SymbolChunkType, typeToken, isDeclaration)
+		s.index++
+	}
+
+	if s.peek(0).Value == ":" {
+		p.colon = tokenSpan(s.peek(0))
+		p.hasColon = true
+		s.index++
+
+		s.scanPatternSlots(&p, buffer, isDeclaration)
+	}
+
+	if token := s.peek(0); token.Value == "]" {
+		p.close = tokenSpan(token)
+		s.index++
+	}
+
+	s.patterns = append(s.patterns, p)
+}
+
+func (s *symbolScanner) scanPatternSlots(p *patternRange, buffer string, isDeclaration bool) {
+	for s.index < len(s.tokens) {
+		token := s.peek(0)
+		typ := lexemeType(token.Type)
+
+		var symbol *Symbol
+
+		switch {
+		case token.Value == "!":
+			s.index++
+			continue
+
+		case typ == lexemePatternVar:
+			symbol = s.add(SymbolVar, token, false)
+
+		case typ == lexemeIdentifier && isDeclaration:
+			symbol = s.add(SymbolSlot, token, true)
+
+		case typ == lexemeIdentifier, typ == lexemeKeyword && token.Value == "nil",
+			typ == lexemeString, typ == lexemeNumber, typ == lexemePatternWildcard:
+			// values in the pattern
+
+		default:
+			return
+		}
+
+		if symbol != nil {
+			symbol.Buffer = buffer
+			symbol.ChunkType = p.chunkType
+			symbol.SlotIndex = len(p.slots)
+		}
+
+		p.slots = append(p.slots, tokenSpan(token))
+		s.index++
+	}
+}
+
+// unquote removes the quotes from a string token
+func unquote(str string) string {
+	if len(str) >= 2 {
+		return str[1 : len(str)-1]
+	}
+
+	return str
+}
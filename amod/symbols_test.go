@@ -0,0 +1,86 @@
+package amod
+
+import (
+	"testing"
+)
+
+func TestSymbols(t *testing.T) {
+	t.Parallel()
+
+	src := `~~ model ~~
+name: Test
+~~ config ~~
+chunks { [count: first second] }
+~~ init ~~
+goal [count: 0 1]
+~~ productions ~~
+start {
+    match { goal [count: ?first *] }
+    do { clear goal }
+}`
+
+	type expected struct {
+		kind          SymbolKind
+		name          string
+		isDeclaration bool
+		production    string
+		slotIndex     int
+	}
+
+	tests := []expected{
+		{SymbolChunkType, "count", true, "", -1},
+		{SymbolSlot, "first", true, "", 0},
+		{SymbolSlot, "second", true, "", 1},
+		{SymbolModule, "goal", false, "", -1},
+		{SymbolChunkType, "count", false, "", -1},
+		{SymbolBuffer, "goal", false, "start", -1},
+		{SymbolChunkType, "count", false, "start", -1},
+		{SymbolVar, "?first", false, "start", 0},
+		{SymbolBuffer, "goal", false, "start", -1},
+	}
+
+	symbols := Symbols(src)
+	if len(symbols) != len(tests) {
+		t.Fatalf("expected %d symbols, got %d: %+v", len(tests), len(symbols), symbols)
+	}
+
+	for i, test := range tests {
+		symbol := symbols[i]
+		got := expected{symbol.Kind, symbol.Name, symbol.IsDeclaration, symbol.Production, symbol.SlotIndex}
+		if got != test {
+			t.Errorf("symbol %d: expected %+v, got %+v", i, test, got)
+		}
+	}
+}
+
+func TestPatternAt(t *testing.T) {
+	t.Parallel()
+
+	src := "[count: ?a ?b]"
+
+	tests := []struct {
+		column   int
+		expected PatternPosition
+		ok       bool
+	}{
+		{0, PatternPosition{}, false},
+		{3, PatternPosition{SlotIndex: -1}, true},
+		{8, PatternPosition{ChunkType: "count", SlotIndex: 0}, true},
+		{10, PatternPosition{ChunkType: "count", SlotIndex: 0}, true},
+		{11, PatternPosition{ChunkType: "count", SlotIndex: 1}, true},
+		{14, PatternPosition{}, false},
+	}
+
+	for _, test := range tests {
+		pos, ok := PatternAt(src, 1, test.column)
+		if ok != test.ok || pos != test.expected {
+			t.Errorf("column %d: expected %+v (%v), got %+v (%v)", test.column, test.expected, test.ok, pos, ok)
+		}
+	}
+
+	// unfinished patterns are still patterns
+	pos, ok := PatternAt("[count: ?a ", 1, 11)
+	if !ok || pos.SlotIndex != 1 {
+		t.Errorf("expected slot 1 of unfinished pattern, got %+v (%v)", pos, ok)
+	}
+}
@@ -1,6 +1,7 @@
 package amod
 
 import (
+	"math"
 	"slices"
 	"strconv"
 
@@ -71,6 +72,58 @@ func validateBufferInitPatterns(model *actr.Model, log *issueLog, initializers [
 	return
 }
 
+// validateInitializerWith checks the base-level settings in an initializer's "with" clause.
+func validateInitializerWith(model *actr.Model, log *issueLog, module modules.Interface, init *namedInitializer) (err error) {
+	if module != model.Memory {
+		log.errorT(init.With.Tokens, "base-level settings ('with') are only allowed when initializing memory")
+		return ErrCompile
+	}
+
+	for _, expr := range *init.With.Expressions {
+		key := expr.Param
+
+		if key != "references" && key != "creation_time" {
+			log.errorT(expr.Tokens, "initializer 'with': unrecognized parameter '%s' (should be one of: references, creation_time)", key)
+			err = ErrCompile
+			continue
+		}
+
+		if expr.Value.Arg == nil || expr.Value.Arg.Number == nil {
+			log.errorT(expr.Tokens, "initializer 'with': parameter '%s' must be a number", key)
+			err = ErrCompile
+			continue
+		}
+
+		numStr := *expr.Value.Arg.Number
+		number, parseErr := parseNumber(log, expr.Tokens, numStr)
+		if parseErr != nil {
+			err = parseErr
+			continue
+		}
+
+		switch key {
+		case "references":
+			if number < 1 || number != math.Trunc(number) {
+				log.errorT(expr.Tokens, "initializer 'with': 'references' must be a positive integer (found '%s')", numStr)
+				err = ErrCompile
+			}
+
+		case "creation_time":
+			if number > 0 {
+				log.errorT(expr.Tokens, "initializer 'with': 'creation_time' must be less than or equal to 0 (found '%s')", numStr)
+				err = ErrCompile
+			}
+		}
+	}
+
+	if err == nil && !model.Memory.IsUsingBaseLevelLearning() {
+		log.Warning(tokensToLocation(init.With.Tokens[:1]),
+			"base-level settings in initializer have no effect unless base-level learning is on (memory's 'decay' and 'max_spread_strength' are set)")
+	}
+
+	return
+}
+
 func validateModuleInitialization(model *actr.Model, log *issueLog, init *moduleInitializer) (err error) {
 	moduleName := init.ModuleName
 	module := model.LookupModule(moduleName)
@@ -80,6 +133,11 @@ func validateModuleInitialization(model *actr.Model, log *issueLog, init *module
 		return ErrCompile
 	}
 
+	if moduleName == "blending" || moduleName == "visual" || moduleName == "motor" || moduleName == "temporal" {
+		log.errorTR(init.Tokens, 0, 1, "module '%s' cannot be initialized", moduleName)
+		return ErrCompile
+	}
+
 	numBuffers := module.Buffers().Count()
 	if numBuffers == 0 {
 		log.errorTR(init.Tokens, 0, 1, "module '%s' does not have any buffers", moduleName)
@@ -121,6 +179,35 @@ func validateModuleInitialization(model *actr.Model, log *issueLog, init *module
 	return
 }
 
+// validateScreenInitialization checks that we have a visual module to look at the screen and
+// that the item locations are valid.
+func validateScreenInitialization(model *actr.Model, log *issueLog, screen *screenInitializer) (err error) {
+	if model.VisualModule() == nil {
+		log.errorTR(screen.Tokens, 0, 1, "screen requires the 'visual' module")
+		return ErrCompile
+	}
+
+	for _, item := range screen.Items {
+		for _, coord := range []string{item.X, item.Y} {
+			value, convErr := strconv.Atoi(coord)
+			if convErr != nil || value < 0 {
+				log.errorT(item.Tokens, "screen item %q location must be a non-negative integer (found '%s')", item.Text, coord)
+				err = ErrCompile
+			}
+		}
+	}
+
+	return
+}
+
+// validateAssociationInitialization warns if associations are set without spreading activation.
+func validateAssociationInitialization(model *actr.Model, log *issueLog, associations *associationInitializer) {
+	if !model.Memory.IsUsingSpreadingActivation() {
+		log.Warning(tokensToLocation(associations.Tokens[:1]),
+			"associations have no effect unless the memory module's 'max_spread_strength' is set")
+	}
+}
+
 // validateInterModuleInitDependencies checks for inconsistent options set between modules
 func validateInterModuleInitDependencies(model *actr.Model, log *issueLog, config *moduleConfig) (err error) {
 	// when not using spreading activation, check for spreading_activation option set on any buffer
@@ -199,6 +286,14 @@ func validateBufferPatternMatch(item *matchBufferPatternItem, model *actr.Model,
 			if expr.RHS.hasVar() && expr.LHS == *expr.RHS.Arg.Var {
 				log.errorT(expr.RHS.Arg.Tokens, "cannot compare a variable to itself '%s'", expr.LHS)
 			}
+
+			// Check that we are only comparing numbers with <, <=, >, >=
+			comparison := convertComparison(expr.Comparison)
+			if comparison.IsRelational() {
+				if expr.RHS.Arg == nil || expr.RHS.Arg.Str != nil {
+					log.errorT(expr.RHS.Tokens, "can only compare a variable to a number or a variable using '%s'", comparison)
+				}
+			}
 		}
 	}
 
@@ -300,29 +395,128 @@ func validateMatch(match *match, model *actr.Model, log *issueLog, production *a
 	return
 }
 
-// validateDo checks for multiple recall statements.
+// validateDo checks for multiple requests to the same module.
 func validateDo(log *issueLog, production *production) {
 	type ref struct {
-		token lexer.Token // keep track of the "recall" token from last case
+		token lexer.Token // keep track of the statement token from last case
 		count int         // ref count
 	}
 
-	recallRef := ref{
-		count: 0,
+	// keyed by the statement name(s) used in the error
+	refs := map[string]*ref{}
+	order := []string{}
+
+	addRef := func(name string, token lexer.Token) {
+		r, ok := refs[name]
+		if !ok {
+			r = &ref{}
+			refs[name] = r
+			order = append(order, name)
+		}
+
+		r.token = token
+		r.count++
 	}
 
 	for _, statement := range *production.Do.Statements {
-		if statement.Recall != nil {
-			recallRef.token = statement.Tokens[0]
-			recallRef.count++
+		switch {
+		case statement.Recall != nil:
+			addRef("recall", statement.Tokens[0])
+
+		case statement.Blend != nil:
+			addRef("blend", statement.Tokens[0])
+
+		case statement.FindLocation != nil:
+			addRef("find_location", statement.Tokens[0])
+
+		case statement.Attend != nil:
+			addRef("attend", statement.Tokens[0])
+
+		case statement.PressKey != nil || statement.Punch != nil:
+			addRef("press_key or punch", statement.Tokens[0])
+
+		case statement.StartTimer != nil:
+			addRef("start_timer", statement.Tokens[0])
 		}
 	}
 
-	if recallRef.count > 1 {
-		log.errorT([]lexer.Token{recallRef.token}, "only one recall statement per production is allowed in production '%s'", production.Name)
+	for _, name := range order {
+		r := refs[name]
+		if r.count > 1 {
+			log.errorT([]lexer.Token{r.token}, "only one %s statement per production is allowed in production '%s'", name, production.Name)
+		}
 	}
 }
 
+// validateExpression checks that the variables in a set statement's expression exist and that
+// arithmetic is only done on numbers and variables.
+func validateExpression(expr *expression, log *issueLog, production *actr.Production) (err error) {
+	for _, v := range expr.vars() {
+		match := production.LookupMatchByVariable(*v.Var)
+		if match == nil {
+			log.errorT(v.Tokens, "set statement variable '%s' not found in matches for production '%s'", *v.Var, production.Name)
+			err = ErrCompile
+		}
+	}
+
+	// a single argument is not arithmetic, so we are done
+	if expr.arg() != nil {
+		return
+	}
+
+	checkOperand := func(o *operand) {
+		if o.Arg != nil && o.Arg.Str != nil {
+			log.errorT(o.Tokens, "cannot use a string in an arithmetic expression in production '%s'", production.Name)
+			err = ErrCompile
+		}
+	}
+
+	var checkExpression func(e *expression)
+
+	checkTerm := func(t *term) {
+		for _, o := range append([]*operand{t.Left}, operandsOf(t.Right)...) {
+			checkOperand(o)
+
+			if o.Expr != nil {
+				checkExpression(o.Expr)
+			}
+		}
+	}
+
+	checkExpression = func(e *expression) {
+		checkTerm(e.Left)
+
+		for _, op := range e.Right {
+			// If there's no operator, the term must start with a signed number (e.g. "?count -1")
+			if op.Operator == nil {
+				first := op.Term.Left
+				if first.Arg == nil || first.Arg.Number == nil || !isSigned(*first.Arg.Number) {
+					log.errorT(op.Term.Tokens, "expected operator in arithmetic expression in production '%s'", production.Name)
+					err = ErrCompile
+				}
+			}
+
+			checkTerm(op.Term)
+		}
+	}
+
+	checkExpression(expr)
+
+	return
+}
+
+func isSigned(number string) bool {
+	return number[0] == '+' || number[0] == '-'
+}
+
+func operandsOf(ops []*operandOperation) (operands []*operand) {
+	for _, op := range ops {
+		operands = append(operands, op.Operand)
+	}
+
+	return
+}
+
 // validateSetStatement checks a "set" statement to verify the buffer name & field indexing is correct.
 // The production's matches have been constructed, so that's what we check against.
 func validateSetStatement(set *setStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
@@ -340,13 +534,10 @@ func validateSetStatement(set *setStatement, model *actr.Model, log *issueLog, p
 			return
 		}
 
-		// If we have a var, check if it exists
-		if set.Value.hasVar() {
-			varItem := *set.Value.Arg.Var
-			match := production.LookupMatchByVariable(varItem)
-			if match == nil {
-				log.errorT(set.Value.Tokens, "set statement variable '%s' not found in matches for production '%s'", varItem, production.Name)
-				err = ErrCompile
+		if set.Value.Expr != nil {
+			exprErr := validateExpression(set.Value.Expr, log, production)
+			if exprErr != nil {
+				err = exprErr
 			}
 		}
 	} else {
@@ -432,6 +623,226 @@ func validateRecallStatement(recall *recallStatement, model *actr.Model, log *is
 	return
 }
 
+// validateBlendStatement checks that we have a blending module and that the pattern's variables are bound.
+func validateBlendStatement(blend *blendStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if model.BlendingModule() == nil {
+		log.errorTR(blend.Tokens, 0, 1, "blend statement requires the 'blending' module in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	if blend.Pattern.AnyChunk != nil {
+		log.errorT(blend.Pattern.Tokens, "blend statement requires a chunk type in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	pattern_err := validatePattern(model, log, blend.Pattern)
+	if pattern_err != nil {
+		return ErrCompile
+	}
+
+	vars := varsFromPattern(blend.Pattern)
+
+	for _, v := range vars {
+		match := production.LookupMatchByVariable(v.text)
+		if match == nil {
+			log.errorT(blend.Pattern.Chunk.Slots[v.index].Tokens, "blend statement variable '%s' not found in matches for production '%s'", v.text, production.Name)
+			err = ErrCompile
+		}
+	}
+
+	return
+}
+
+// validateRememberStatement checks that the pattern fully specifies a chunk and that its variables are bound.
+func validateRememberStatement(remember *rememberStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if remember.Pattern.AnyChunk != nil {
+		log.errorT(remember.Pattern.Tokens, "remember statement requires a chunk type in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	pattern_err := validatePattern(model, log, remember.Pattern)
+	if pattern_err != nil {
+		return ErrCompile
+	}
+
+	for _, slot := range remember.Pattern.Chunk.Slots {
+		switch {
+		case slot.Wildcard != nil:
+			log.errorT(slot.Tokens, "remember statement cannot use wildcards in production '%s'", production.Name)
+			err = ErrCompile
+
+		case slot.Not:
+			log.errorT(slot.Tokens, "remember statement cannot use negation in production '%s'", production.Name)
+			err = ErrCompile
+		}
+	}
+
+	vars := varsFromPattern(remember.Pattern)
+
+	for _, v := range vars {
+		match := production.LookupMatchByVariable(v.text)
+		if match == nil {
+			log.errorT(remember.Pattern.Chunk.Slots[v.index].Tokens, "remember statement variable '%s' not found in matches for production '%s'", v.text, production.Name)
+			err = ErrCompile
+		}
+	}
+
+	return
+}
+
+// validateReinforceStatement checks that the chunk is either a bound variable or the name of a chunk
+// in memory's initializers.
+func validateReinforceStatement(reinforce *reinforceStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if reinforce.Var != nil {
+		match := production.LookupMatchByVariable(*reinforce.Var)
+		if match == nil {
+			log.errorTR(reinforce.Tokens, 1, 1, "reinforce statement variable '%s' not found in matches for production '%s'", *reinforce.Var, production.Name)
+			return ErrCompile
+		}
+
+		return
+	}
+
+	for _, init := range model.Initializers {
+		if init.Module == model.Memory && init.ChunkName != nil && *init.ChunkName == *reinforce.ID {
+			return
+		}
+	}
+
+	log.errorTR(reinforce.Tokens, 1, 1, "reinforce statement chunk '%s' not found in memory's initializers in production '%s'", *reinforce.ID, production.Name)
+	return ErrCompile
+}
+
+// validateFindLocationStatement checks a "find_location" statement's pattern and request parameters.
+func validateFindLocationStatement(find *findLocationStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	visual := model.VisualModule()
+	if visual == nil {
+		log.errorTR(find.Tokens, 0, 1, "find_location statement requires the 'visual' module in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	pattern_err := validatePattern(model, log, find.Pattern)
+	if pattern_err != nil {
+		return ErrCompile
+	}
+
+	if find.Pattern.AnyChunk != nil || find.Pattern.Chunk.Name != "visual_location" {
+		log.errorTR(find.Pattern.Tokens, 1, 2, "find_location statement requires a 'visual_location' pattern in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	for _, slot := range find.Pattern.Chunk.Slots {
+		switch {
+		case slot.ID != nil:
+			if *slot.ID != "lowest" && *slot.ID != "highest" {
+				log.errorT(slot.Tokens, "find_location statement location must be a number, variable, 'lowest', or 'highest' (found '%s') in production '%s'", *slot.ID, production.Name)
+				err = ErrCompile
+			}
+
+		case slot.Str != nil || slot.Nil != nil:
+			log.errorT(slot.Tokens, "find_location statement location must be a number, variable, 'lowest', or 'highest' in production '%s'", production.Name)
+			err = ErrCompile
+
+		case slot.Var != nil:
+			match := production.LookupMatchByVariable(*slot.Var)
+			if match == nil {
+				log.errorT(slot.Tokens, "find_location statement variable '%s' not found in matches for production '%s'", *slot.Var, production.Name)
+				err = ErrCompile
+			}
+		}
+	}
+
+	if find.With != nil {
+		buffer := visual.LocationBuffer()
+
+		for _, param := range *find.With.Expressions {
+			key := param.Param
+
+			if param.Value.hasVar() {
+				log.errorT(param.Tokens, "find_location 'with': parameter '%s'. Unexpected variable", key)
+				err = ErrCompile
+				continue
+			}
+
+			kv := withArgToKeyValue(key, param.Value)
+			paramErr := buffer.RequestParameters().ValidateParam(kv)
+			if paramErr != nil {
+				log.errorT(param.Tokens,
+					"find_location 'with': %s.",
+					paramErr.Error(),
+				)
+				err = ErrCompile
+			}
+		}
+	}
+
+	return
+}
+
+// validateAttendStatement checks that an "attend" statement has a location to attend to.
+func validateAttendStatement(attend *attendStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if model.VisualModule() == nil {
+		log.errorT(attend.Tokens, "attend statement requires the 'visual' module in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	match := production.LookupMatchByBuffer("visual_location")
+	if match == nil {
+		log.errorT(attend.Tokens, "attend statement requires a match on the 'visual_location' buffer in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	return
+}
+
+// validatePressKeyStatement checks a "press_key" statement's key.
+func validatePressKeyStatement(press *pressKeyStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if model.MotorModule() == nil {
+		log.errorTR(press.Tokens, 0, 1, "press_key statement requires the 'motor' module in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	if press.Key.hasVar() {
+		match := production.LookupMatchByVariable(*press.Key.Var)
+		if match == nil {
+			log.errorT(press.Key.Tokens, "press_key statement variable '%s' not found in matches for production '%s'", *press.Key.Var, production.Name)
+			err = ErrCompile
+		}
+	}
+
+	return
+}
+
+// validatePunchStatement checks a "punch" statement's hand and finger.
+func validatePunchStatement(punch *punchStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if model.MotorModule() == nil {
+		log.errorTR(punch.Tokens, 0, 1, "punch statement requires the 'motor' module in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	if !modules.IsValidHand(punch.Hand) {
+		log.errorTR(punch.Tokens, 1, 1, "invalid hand '%s' in production '%s' (should be one of: %v)", punch.Hand, production.Name, modules.ValidHandsStr())
+		err = ErrCompile
+	}
+
+	if !modules.IsValidFinger(punch.Finger) {
+		log.errorTR(punch.Tokens, 2, 2, "invalid finger '%s' in production '%s' (should be one of: %v)", punch.Finger, production.Name, modules.ValidFingersStr())
+		err = ErrCompile
+	}
+
+	return
+}
+
+// validateStartTimerStatement checks that we have a temporal module to start the timer.
+func validateStartTimerStatement(start *startTimerStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
+	if model.TemporalModule() == nil {
+		log.errorT(start.Tokens, "start_timer statement requires the 'temporal' module in production '%s'", production.Name)
+		return ErrCompile
+	}
+
+	return
+}
+
 // validateClearStatement checks a "clear" statement to verify the buffer names.
 func validateClearStatement(clear *clearStatement, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
 	bufferNames := clear.BufferNames
@@ -583,9 +994,10 @@ func validateVariableUsage(log *issueLog, match *match, do *do) {
 				arg := statement.Set.Value
 				if arg != nil {
 					if arg.hasVar() {
-						varItem := *arg.Arg.Var
-						if r, ok := varRefCount[varItem]; ok {
-							r.count++
+						for _, v := range arg.Expr.vars() {
+							if r, ok := varRefCount[*v.Var]; ok {
+								r.count++
+							}
 						}
 					}
 				} else { // pattern
@@ -595,6 +1007,29 @@ func validateVariableUsage(log *issueLog, match *match, do *do) {
 			case statement.Recall != nil:
 				addPatternRefs(statement.Recall.Pattern, false)
 
+			case statement.Blend != nil:
+				addPatternRefs(statement.Blend.Pattern, false)
+
+			case statement.Remember != nil:
+				addPatternRefs(statement.Remember.Pattern, false)
+
+			case statement.Reinforce != nil:
+				if statement.Reinforce.Var != nil {
+					if r, ok := varRefCount[*statement.Reinforce.Var]; ok {
+						r.count++
+					}
+				}
+
+			case statement.FindLocation != nil:
+				addPatternRefs(statement.FindLocation.Pattern, false)
+
+			case statement.PressKey != nil:
+				if statement.PressKey.Key.hasVar() {
+					if r, ok := varRefCount[*statement.PressKey.Key.Var]; ok {
+						r.count++
+					}
+				}
+
 			case statement.Print != nil:
 				for _, arg := range statement.Print.Args {
 					if arg.hasVar() {
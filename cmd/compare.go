@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/modes/compare"
+)
+
+var (
+	flagCompareGoal   string
+	flagCompareSeed   uint32
+	flagCompareIgnore []string
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [amod file]",
+	Short: "Run a model on all the active frameworks and report where they diverge",
+	Long: `Run a model on all the active frameworks and report where they diverge.
+
+The production firing order, retrieved chunks, printed values, and final buffer contents are
+compared with those of the reference framework ("native" if it is active). Exits with a non-zero
+status if there is any divergence which was not ignored using --ignore.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		options := compare.Options{
+			Goal: flagCompareGoal,
+		}
+
+		for _, name := range flagCompareIgnore {
+			category, err := compare.ParseCategory(name)
+			if err != nil {
+				return err
+			}
+
+			options.Ignore = append(options.Ignore, category)
+		}
+
+		if cmd.Flags().Changed("seed") {
+			options.RandomSeed = &flagCompareSeed
+		}
+
+		settings, err := setupForRun(cmd)
+		if err != nil {
+			return err
+		}
+
+		report, err := compare.Run(settings, args[0], options)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(report)
+
+		if report.HasUnexpectedDivergence() {
+			return compare.ErrUnexpectedDivergence
+		}
+
+		return
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVarP(&flagCompareGoal, "goal", "g", "", "initial goal (e.g. '[countFrom: 2 5 starting]')")
+	compareCmd.Flags().Uint32VarP(&flagCompareSeed, "seed", "s", 0, "set the random number seed")
+	compareCmd.Flags().StringSliceVar(&flagCompareIgnore, "ignore", []string{},
+		fmt.Sprintf("categories where divergence is expected - valid options: %s", strings.Join(compare.CategoryNames(), ", ")))
+}
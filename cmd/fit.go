@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/modes/fit"
+)
+
+var (
+	flagFitData      string
+	flagFitMeasure   string
+	flagFitFree      []string
+	flagFitObjective string
+	flagFitMethod    string
+	flagFitRuns      int
+	flagFitSeed      uint32
+	flagFitMaxEvals  int
+	flagFitGridSteps int
+)
+
+var fitCmd = &cobra.Command{
+	Use:   "fit [amod file]",
+	Short: "Fit a model's parameters to observed data",
+	Long: `Fit a model's parameters to observed data.
+
+The data is a CSV file with a header. It must have a column named after the measure and may have a
+"goal" column with the initial goal to use for each observation. Each --free parameter is of the
+form "module.param[min,max]" (or "module.buffer.param[min,max]").
+
+The model is run --runs times for each observation and the mean of the measure is compared with the
+observed value. The free parameters are searched (using Nelder–Mead or a grid) to minimize the RMSE
+or maximize the correlation. Fitting requires exactly one active framework. pyactr does not report
+printed values, so it cannot be used with the "print" measure.`,
+	Example: `  gactar fit model.amod -f native --data human.csv --measure time --free memory.latency_factor[0.1,2]`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		options := fit.Options{
+			DataFile:  flagFitData,
+			Measure:   flagFitMeasure,
+			Objective: flagFitObjective,
+			Method:    flagFitMethod,
+			Runs:      flagFitRuns,
+			Seed:      flagFitSeed,
+			MaxEvals:  flagFitMaxEvals,
+			GridSteps: flagFitGridSteps,
+		}
+
+		for _, spec := range flagFitFree {
+			param, err := fit.ParseFreeParam(spec)
+			if err != nil {
+				return err
+			}
+
+			options.Params = append(options.Params, param)
+		}
+
+		settings, err := setupForRun(cmd)
+		if err != nil {
+			return err
+		}
+
+		report, err := fit.Run(settings, args[0], options)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(report)
+
+		return
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fitCmd)
+
+	fitCmd.Flags().StringVar(&flagFitData, "data", "", "CSV file containing the observed data")
+	fitCmd.Flags().StringVar(&flagFitMeasure, "measure", fit.Measures[0], fmt.Sprintf("measure to fit - valid options: %s", strings.Join(fit.Measures, ", ")))
+	fitCmd.Flags().StringArrayVar(&flagFitFree, "free", []string{}, "parameter to fit with its bounds, e.g. memory.latency_factor[0.1,2] (may be repeated)")
+	fitCmd.Flags().StringVar(&flagFitObjective, "objective", fit.Objectives[0], fmt.Sprintf("what to optimize - valid options: %s", strings.Join(fit.Objectives, ", ")))
+	fitCmd.Flags().StringVar(&flagFitMethod, "method", fit.Methods[0], fmt.Sprintf("search method - valid options: %s", strings.Join(fit.Methods, ", ")))
+	fitCmd.Flags().IntVar(&flagFitRuns, "runs", 10, "number of runs to average for each observation")
+	fitCmd.Flags().Uint32VarP(&flagFitSeed, "seed", "s", 1, "random number seed for the first run")
+	fitCmd.Flags().IntVar(&flagFitMaxEvals, "max-evals", 100, "(nelder-mead) maximum number of evaluations")
+	fitCmd.Flags().IntVar(&flagFitGridSteps, "grid-steps", 5, "(grid) number of values to try for each parameter")
+
+	_ = fitCmd.MarkFlagRequired("data")
+	_ = fitCmd.MarkFlagRequired("free")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/amod"
+
+	"github.com/asmaloney/gactar/util/chalk"
+)
+
+var (
+	flagFmtWrite bool
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [files...]",
+	Short: "Format amod files",
+	Long: `Format amod files using a canonical layout.
+
+The formatted code is written to stdout unless -w is used, in which case the files are
+rewritten in place. Comments are preserved.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		for _, fileName := range args {
+			err = formatFile(fileName)
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+
+	fmtCmd.Flags().BoolVarP(&flagFmtWrite, "write", "w", false, "write the result to the file instead of stdout")
+}
+
+func formatFile(fileName string) (err error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return
+	}
+
+	src, err := os.ReadFile(fileName)
+	if err != nil {
+		return
+	}
+
+	formatted, log, err := amod.Format(string(src))
+	if err != nil {
+		chalk.PrintErrStr(fileName)
+		fmt.Print(log)
+		return
+	}
+
+	if !flagFmtWrite {
+		_, err = os.Stdout.Write(formatted)
+		return
+	}
+
+	if bytes.Equal(src, formatted) {
+		return
+	}
+
+	err = os.WriteFile(fileName, formatted, info.Mode().Perm())
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("%s %s\n", chalk.Success("Formatted"), fileName)
+	return
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/graph"
+
+	"github.com/asmaloney/gactar/util/chalk"
+)
+
+var (
+	flagGraphFormat string
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [file]",
+	Short: "Output the production dependency graph of an amod file",
+	Long: `Output the production dependency graph of an amod file.
+
+Each production is a node. An edge from A to B means A's set and recall statements can
+produce buffer contents which satisfy B's match patterns. Edges are labelled with the buffers.
+
+The graph is written to stdout as Graphviz DOT, a Mermaid flowchart, or JSON.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		fileName := args[0]
+
+		model, log, err := amod.GenerateModelFromFile(fileName)
+		if err != nil {
+			chalk.PrintErrStr(fileName)
+			if log != nil {
+				fmt.Print(log)
+			}
+			return
+		}
+
+		return graph.New(model).Write(os.Stdout, flagGraphFormat)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVar(&flagGraphFormat, "format", "dot", "output format - valid options: dot, mermaid, json")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework/vanilla_actr"
+
+	"github.com/asmaloney/gactar/util/chalk"
+	"github.com/asmaloney/gactar/util/filesystem"
+)
+
+var (
+	ErrImportFailed = errors.New("import failed")
+)
+
+// The formats we know how to import
+var importFormats = []string{"vanilla"}
+
+type errUnknownImportFormat struct {
+	Format string
+}
+
+func (e errUnknownImportFormat) Error() string {
+	return fmt.Sprintf("unknown import format %q - valid options: %s", e.Format, strings.Join(importFormats, ", "))
+}
+
+type errOutputFileExists struct {
+	FileName string
+}
+
+func (e errOutputFileExists) Error() string {
+	return fmt.Sprintf("output file %q already exists (use --force to overwrite it)", e.FileName)
+}
+
+var (
+	flagImportFrom   string
+	flagImportOutput string
+	flagImportForce  bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Convert a model from another format into an amod file",
+	Long: `Convert a model from another format into an amod file.
+
+Only the parts of the model which amod can represent are converted. Anything which
+cannot be converted is reported with its line number.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if flagImportFrom != "vanilla" {
+			return errUnknownImportFormat{Format: flagImportFrom}
+		}
+
+		inputFile := args[0]
+
+		outputFile := flagImportOutput
+		if outputFile == "" {
+			outputFile = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ".amod"
+		}
+
+		if !flagImportForce && filesystem.FileExists(outputFile) {
+			return errOutputFileExists{FileName: outputFile}
+		}
+
+		model, log, err := vanilla_actr.ImportModel(inputFile)
+		if log != nil {
+			fmt.Print(log)
+		}
+		if err != nil {
+			return
+		}
+
+		code := amod.GenerateCode(model)
+
+		// Make sure what we generated is valid
+		_, amodLog, err := amod.GenerateModel(string(code))
+		if err != nil {
+			fmt.Print(amodLog)
+			return ErrImportFailed
+		}
+
+		err = os.WriteFile(outputFile, code, 0644)
+		if err != nil {
+			return
+		}
+
+		fmt.Printf("%s %s\n", chalk.Success("Wrote"), outputFile)
+
+		if log.HasError() {
+			chalk.PrintWarningStr("Some parts of the model could not be converted - see above for details.")
+		}
+
+		return
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&flagImportFrom, "from", "", fmt.Sprintf("format of the file to import - valid options: %s", strings.Join(importFormats, ", ")))
+	importCmd.Flags().StringVarP(&flagImportOutput, "output", "o", "", "amod file to write (defaults to the input file with an .amod extension)")
+	importCmd.Flags().BoolVar(&flagImportForce, "force", false, "overwrite the output file if it exists")
+
+	_ = importCmd.MarkFlagRequired("from")
+}
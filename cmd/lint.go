@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/lint"
+
+	"github.com/asmaloney/gactar/util/chalk"
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+var (
+	ErrLintIssues = errors.New("lint found issues")
+)
+
+var (
+	flagLintEnable    []string
+	flagLintDisable   []string
+	flagLintListRules bool
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [files...]",
+	Short: "Check amod files for likely mistakes",
+	Long: `Check amod files for things which are valid but are almost certainly mistakes.
+
+Each issue is reported with the ID of the rule which found it. Rules may be disabled
+using --disable. Opt-in rules are only run if they are enabled using --enable. Use
+--list-rules to see all the rules.`,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if flagLintListRules {
+			for _, rule := range lint.Rules {
+				description := rule.Description
+				if rule.OptIn {
+					description += " (opt-in)"
+				}
+
+				fmt.Printf("%-26s %s\n", rule.ID, description)
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			return ErrNoInputFiles
+		}
+
+		foundIssues := false
+
+		for _, fileName := range args {
+			found, err := lintFile(fileName)
+			if err != nil {
+				return err
+			}
+
+			foundIssues = foundIssues || found
+		}
+
+		if foundIssues {
+			return ErrLintIssues
+		}
+
+		return
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringSliceVar(&flagLintEnable, "enable", nil, "comma-separated list of opt-in rule IDs to enable")
+	lintCmd.Flags().StringSliceVar(&flagLintDisable, "disable", nil, "comma-separated list of rule IDs to disable")
+	lintCmd.Flags().BoolVar(&flagLintListRules, "list-rules", false, "list the rules and exit")
+}
+
+// lintFile lints one file and prints any issues. It returns whether it found any issues.
+func lintFile(fileName string) (found bool, err error) {
+	model, log, err := amod.GenerateModelFromFile(fileName)
+	if err != nil {
+		chalk.PrintErrStr(fileName)
+		if log != nil {
+			fmt.Print(log)
+		}
+		return true, nil
+	}
+
+	lintLog := issues.New()
+
+	err = lint.Lint(model, lintLog, lint.Options{Enabled: flagLintEnable, Disabled: flagLintDisable})
+	if err != nil {
+		return
+	}
+
+	if !lintLog.HasIssues() {
+		fmt.Printf("%s %s\n", chalk.Success("OK"), fileName)
+		return false, nil
+	}
+
+	fmt.Println(fileName)
+	fmt.Print(lintLog)
+
+	return true, nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/modes/lsp"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a Language Server Protocol server for amod files",
+	Long: `Run a Language Server Protocol server for amod files using stdin and stdout.
+
+This is meant to be started by an editor. It provides diagnostics as you type, go to definition for
+chunk types, buffers, variables, and included files, hover info for chunk types, variables,
+buffers, and modules, completion of chunk types and slots in patterns, and highlighting of
+variables within a production.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lsp.New(os.Stdin, os.Stdout).Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
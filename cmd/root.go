@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/jwalton/gchalk"
 	"github.com/spf13/cobra"
@@ -30,7 +31,8 @@ var (
 	ErrNoInputFiles = errors.New("no input files specified on command line")
 	ErrSilent       = errors.New("SilentErr")
 
-	errNoFrameworks = errors.New("no frameworks specified on command line")
+	errNoFrameworks   = errors.New("no frameworks specified on command line")
+	errInvalidTimeout = errors.New("timeout must be greater than zero")
 
 	validDebugOptions = []string{"lex", "parse", "exec"}
 
@@ -39,6 +41,7 @@ var (
 	flagFrameworks = []string{"all"}
 	flagDebug      = []string{}
 	flagNoColour   = false
+	flagTimeout    time.Duration
 
 	// special option just for outputting version
 	flagVersion = false
@@ -47,6 +50,7 @@ var (
 	defaultModeRunAfterGeneration bool
 	defaultModeLogLevel           string
 	defaultModeTraceActivations   bool
+	defaultModeTraceJSON          string
 	defaultModeRandomSeed         uint32
 )
 
@@ -106,11 +110,22 @@ var rootCmd = &cobra.Command{
 			RunAfterGeneration: defaultModeRunAfterGeneration,
 		}
 
+		options.Timeout = settings.Timeout
+
 		// validate & override options
 		if cmd.Flags().Changed("trace") {
 			options.TraceActivations = &defaultModeTraceActivations
 		}
 
+		if cmd.Flags().Changed("trace-json") {
+			traceEvents := true
+			options.TraceEvents = &traceEvents
+
+			if defaultModeTraceJSON != "-" {
+				options.TraceFile = defaultModeTraceJSON
+			}
+		}
+
 		if cmd.Flags().Changed("logging") {
 			if !runoptions.ValidLogLevel(defaultModeLogLevel) {
 				return runoptions.ErrInvalidLogLevel{Level: defaultModeLogLevel}
@@ -167,12 +182,13 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&flagEnv, "env", flagEnv, "directory where ACT-R, pyactr, and other necessary files are installed")
-	rootCmd.PersistentFlags().StringVar(&flagTemp, "temp", flagTemp, "directory for generated files (it will be created if it does not exist - defaults to <env>/gactar-temp)")
+	rootCmd.PersistentFlags().StringVar(&flagTemp, "temp", flagTemp, "directory for generated files (it will be created if it does not exist - defaults to <env>/gactar-temp, or the system temp dir if there is no env)")
 	rootCmd.PersistentFlags().StringSliceVarP(&flagFrameworks, "framework", "f", flagFrameworks,
 		fmt.Sprintf("add framework - valid frameworks: %s", strings.Join(framework.ValidFrameworks, ", ")))
 	rootCmd.PersistentFlags().StringSliceVarP(&flagDebug, "debug", "d", flagDebug,
 		fmt.Sprintf("turn on debugging - valid options: %s", strings.Join(validDebugOptions, ", ")))
 	rootCmd.PersistentFlags().BoolVar(&flagNoColour, "no-colour", false, "do not use colour output on command line")
+	rootCmd.PersistentFlags().DurationVar(&flagTimeout, "timeout", 0, "cancel any run which takes longer than this (e.g. 30s, 5m)")
 
 	// Local flags - only run when this action is called directly.
 	rootCmd.Flags().BoolVarP(&flagVersion, "version", "v", false, "output the version and quit")
@@ -180,6 +196,8 @@ func init() {
 	rootCmd.Flags().BoolVarP(&defaultModeRunAfterGeneration, "run", "r", false, "run the models after generating the code")
 	rootCmd.Flags().StringVarP(&defaultModeLogLevel, "logging", "l", defaultModeLogLevel, fmt.Sprintf("logging level - valid options: %s", strings.Join(runoptions.ACTRLoggingLevels, ", ")))
 	rootCmd.Flags().BoolVarP(&defaultModeTraceActivations, "trace", "t", false, "output trace activations")
+	rootCmd.Flags().StringVar(&defaultModeTraceJSON, "trace-json", "", "output the run's trace events as JSON to stdout, or to a file using --trace-json=file (requires --run)")
+	rootCmd.Flags().Lookup("trace-json").NoOptDefVal = "-"
 	rootCmd.Flags().Uint32VarP(&defaultModeRandomSeed, "seed", "s", 0, "set the random number seed")
 
 	rootCmd.MarkFlagsMutuallyExclusive("run", "version")
@@ -200,12 +218,26 @@ func setupForRun(cmd *cobra.Command) (settings *cli.Settings, err error) {
 		Version: fmt.Sprintf("gactar %s %s", "version", version.BuildVersion),
 	}
 
-	envPath, err := setupVirtualEnvironment(cmd.Flags())
-	if err != nil {
-		return
+	if cmd.Flags().Changed("timeout") {
+		if flagTimeout <= 0 {
+			err = errInvalidTimeout
+			return
+		}
+
+		settings.Timeout = &flagTimeout
 	}
 
-	settings.EnvPath = envPath
+	// The native framework runs in-process, so if it is the only one requested we
+	// do not need a virtual environment.
+	if requiresVirtualEnvironment(cmd.Flags()) {
+		envPath, envErr := setupVirtualEnvironment(cmd.Flags())
+		if envErr != nil {
+			err = envErr
+			return
+		}
+
+		settings.EnvPath = envPath
+	}
 
 	// Create our temp dir. If it wasn't set, use <env>/gactar-temp.
 	// createTempDirFromFlag() will expand our "temp" to an absolute path.
@@ -262,7 +294,12 @@ func createTempDirFromFlag(flags *pflag.FlagSet) (path string, err error) {
 	}
 
 	if path == "" {
-		defaultTemp := fmt.Sprintf("%s%c%s", os.Getenv("VIRTUAL_ENV"), filepath.Separator, "gactar-temp")
+		basePath := os.Getenv("VIRTUAL_ENV")
+		if basePath == "" {
+			basePath = os.TempDir()
+		}
+
+		defaultTemp := fmt.Sprintf("%s%c%s", basePath, filepath.Separator, "gactar-temp")
 
 		err = flags.Set("temp", defaultTemp)
 		if err != nil {
@@ -301,6 +338,23 @@ func getVirtualEnvironmentPath(flags *pflag.FlagSet) (envPath string, err error)
 	return
 }
 
+// requiresVirtualEnvironment checks if any of the frameworks requested on the command line
+// need the virtual environment. Only the native framework does not.
+func requiresVirtualEnvironment(flags *pflag.FlagSet) bool {
+	list, err := flags.GetStringSlice("framework")
+	if err != nil || len(list) == 0 {
+		return true
+	}
+
+	for _, name := range list {
+		if name != "native" {
+			return true
+		}
+	}
+
+	return false
+}
+
 // setupVirtualEnvironment will check that the environment path exists and set our PATH with it.
 func setupVirtualEnvironment(flags *pflag.FlagSet) (path string, err error) {
 	envPath, err := getVirtualEnvironmentPath(flags)
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/asmaloney/gactar/modes/sweep"
+
+	"github.com/asmaloney/gactar/util/chalk"
+)
+
+// The formats we can write sweep results in
+var sweepFormats = []string{"csv", "json"}
+
+type errUnknownSweepFormat struct {
+	Format string
+}
+
+func (e errUnknownSweepFormat) Error() string {
+	return fmt.Sprintf("unknown output format %q - valid options: %s", e.Format, strings.Join(sweepFormats, ", "))
+}
+
+var (
+	flagSweepParams []string
+	flagSweepRuns   int
+	flagSweepSeed   uint32
+	flagSweepGoal   string
+	flagSweepFormat string
+	flagSweepOutput string
+)
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep [amod file]",
+	Short: "Run a model over combinations of parameter values and output the results",
+	Long: `Run a model over combinations of parameter values and output the results.
+
+Each --param is of the form "module.param=values" (or "module.buffer.param=values") where the
+values are either a range "start:end:step" (end is included) or a list "value,value,...". The
+model is run on all the active frameworks for every combination of values. Each combination is run
+--runs times, with the random seed starting at --seed and incremented for each run.
+
+The parameter values, seed, framework, simulation time, number of productions fired, and printed
+values of each run are output as CSV or JSON. pyactr does not report printed values, so they are
+always empty for it.`,
+	Example: `  gactar sweep model.amod -f native --param memory.decay=0.3:0.7:0.1 --param procedural.default_action_time=0.05,0.1 --runs 50`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		format := flagSweepFormat
+		if format == "" {
+			format = "csv"
+			if strings.EqualFold(filepath.Ext(flagSweepOutput), ".json") {
+				format = "json"
+			}
+		}
+
+		if format != "csv" && format != "json" {
+			return errUnknownSweepFormat{Format: format}
+		}
+
+		options := sweep.Options{
+			Runs: flagSweepRuns,
+			Seed: flagSweepSeed,
+			Goal: flagSweepGoal,
+		}
+
+		for _, spec := range flagSweepParams {
+			param, err := sweep.ParseParam(spec)
+			if err != nil {
+				return err
+			}
+
+			options.Params = append(options.Params, param)
+		}
+
+		settings, err := setupForRun(cmd)
+		if err != nil {
+			return err
+		}
+
+		results, err := sweep.Run(settings, args[0], options)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if flagSweepOutput != "" {
+			file, err := os.Create(flagSweepOutput)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			w = file
+		}
+
+		if format == "json" {
+			err = results.WriteJSON(w)
+		} else {
+			err = results.WriteCSV(w)
+		}
+
+		if err != nil {
+			return
+		}
+
+		if flagSweepOutput != "" {
+			fmt.Printf("%s %d runs to %s\n", chalk.Success("Wrote"), len(results.Runs), flagSweepOutput)
+		}
+
+		return
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+
+	sweepCmd.Flags().StringArrayVar(&flagSweepParams, "param", []string{}, "parameter to vary, e.g. memory.decay=0.3:0.7:0.1 (may be repeated)")
+	sweepCmd.Flags().IntVar(&flagSweepRuns, "runs", 1, "number of runs for each combination of values")
+	sweepCmd.Flags().Uint32VarP(&flagSweepSeed, "seed", "s", 1, "random number seed for the first run")
+	sweepCmd.Flags().StringVarP(&flagSweepGoal, "goal", "g", "", "initial goal (e.g. '[countFrom: 2 5 starting]')")
+	sweepCmd.Flags().StringVar(&flagSweepFormat, "format", "", fmt.Sprintf("output format - valid options: %s (defaults to the output file's extension or csv)", strings.Join(sweepFormats, ", ")))
+	sweepCmd.Flags().StringVarP(&flagSweepOutput, "output", "o", "", "file to write the results to (defaults to stdout)")
+
+	_ = sweepCmd.MarkFlagRequired("param")
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"runtime"
+
 	"github.com/spf13/cobra"
 
 	"github.com/asmaloney/gactar/examples"
@@ -8,7 +10,9 @@ import (
 )
 
 var (
-	flagPort = 8181
+	flagPort      = 8181
+	flagMaxJobs   = runtime.NumCPU()
+	flagMaxQueued = 100
 )
 
 var webCmd = &cobra.Command{
@@ -20,7 +24,12 @@ var webCmd = &cobra.Command{
 			return err
 		}
 
-		w, err := web.Initialize(settings, flagPort, &examples.AMODExamples)
+		queue := web.QueueOptions{
+			MaxJobs:   flagMaxJobs,
+			MaxQueued: flagMaxQueued,
+		}
+
+		w, err := web.Initialize(settings, flagPort, queue, &examples.AMODExamples)
 		if err != nil {
 			return err
 		}
@@ -38,4 +47,6 @@ func init() {
 	rootCmd.AddCommand(webCmd)
 
 	webCmd.Flags().IntVarP(&flagPort, "port", "p", flagPort, "port to run the web server on")
+	webCmd.Flags().IntVar(&flagMaxJobs, "max-jobs", flagMaxJobs, "maximum number of framework processes to run at once")
+	webCmd.Flags().IntVar(&flagMaxQueued, "max-queued", flagMaxQueued, "maximum number of runs waiting to start (more are rejected)")
 }
@@ -3,6 +3,7 @@
 package ccm_pyactr
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"strings"
@@ -25,11 +26,16 @@ var ccmPrintPython string
 //go:embed gactar_ccm_activate_trace.py
 var gactarActivateTraceFile string
 
+//go:embed gactar_ccm_trace.py
+var gactarTraceFile string
+
 const (
 	ccmPrintFileName              = "ccm_print.py"
 	ccmPrintImportName            = "ccm_print"
 	gactarActivateTraceFileName   = "gactar_ccm_activate_trace.py"
 	gactarActivateTraceImportName = "gactar_ccm_activate_trace"
+	gactarTraceFileName           = "gactar_ccm_trace.py"
+	gactarTraceImportName         = "gactar_ccm_trace"
 )
 
 var Info framework.Info = framework.Info{
@@ -49,6 +55,9 @@ type CCMPyACTR struct {
 
 	model     *actr.Model
 	className string
+
+	// traceEvents is set while generating code if we are outputting trace events
+	traceEvents bool
 }
 
 // New creates a new CCMPyACTR instance and sets the temp path.
@@ -68,6 +77,10 @@ func (CCMPyACTR) Info() *framework.Info {
 	return &Info
 }
 
+func (CCMPyACTR) NewInstance(tempPath string) framework.Framework {
+	return &CCMPyACTR{tmpPath: tempPath}
+}
+
 func (CCMPyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 	log = issues.New()
 
@@ -75,9 +88,80 @@ func (CCMPyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 		log.Warning(nil, "ccm does not support memory module's latency_exponent")
 	}
 
+	if model.Procedural.InitialUtility != nil {
+		log.Warning(nil, "ccm does not support procedural module's initial_utility")
+	}
+
+	if model.VisualModule() != nil {
+		log.Error(nil, "ccm does not support the visual module")
+	}
+
+	if model.MotorModule() != nil {
+		log.Error(nil, "ccm does not support the motor module")
+	}
+
+	if model.TemporalModule() != nil {
+		log.Error(nil, "ccm does not support the temporal module")
+	}
+
+	if model.BlendingModule() != nil {
+		log.Error(nil, "ccm does not support the blending module")
+	}
+
+	if len(model.Associations) > 0 {
+		log.Warning(nil, "ccm does not support setting associative strengths using 'associations' (ignored)")
+	}
+
+	for _, init := range model.Initializers {
+		if init.BaseLevel != nil {
+			location := issues.Location{
+				Line:        init.AMODLineNumber,
+				ColumnStart: 0,
+				ColumnEnd:   0,
+			}
+
+			log.Warning(&location, "ccm does not support base-level settings on memory initializers (ignored)")
+		}
+	}
+
 	for _, production := range model.Productions {
+		if production.Utility != nil {
+			location := issues.Location{
+				Line:        production.AMODLineNumber,
+				ColumnStart: 0,
+				ColumnEnd:   0,
+			}
+
+			log.Warning(&location, "ccm does not support setting a production's utility (in %q)", production.Name)
+		}
+
+		if production.HasRelationalConstraints() {
+			location := issues.Location{
+				Line:        production.AMODLineNumber,
+				ColumnStart: 0,
+				ColumnEnd:   0,
+			}
+
+			log.Error(&location, "ccm does not support comparing numbers using <, <=, >, or >= (in %q)", production.Name)
+		}
+
 		if production.DoStatements != nil {
 			for _, statement := range production.DoStatements {
+				if statement.Remember != nil || statement.Reinforce != nil {
+					location := issues.Location{
+						Line:        production.AMODLineNumber,
+						ColumnStart: 0,
+						ColumnEnd:   0,
+					}
+
+					name := "remember"
+					if statement.Reinforce != nil {
+						name = "reinforce"
+					}
+
+					log.Error(&location, "ccm does not support the %s statement (in %q)", name, production.Name)
+				}
+
 				if (statement.Recall != nil) && (len(statement.Recall.RequestParameters) > 0) {
 					keys := maps.Keys(statement.Recall.RequestParameters)
 					location := issues.Location{
@@ -116,7 +200,8 @@ func (c CCMPyACTR) Model() (model *actr.Model) {
 
 // Run generates the python code from the amod file, writes it to disk, creates a "run" file
 // to actually run the model, and returns the output (stdout and stderr combined).
-func (c *CCMPyACTR) Run(options *runoptions.Options) (result *framework.RunResult, err error) {
+// If we are tracing events, the trace is removed from the output and returned in the result.
+func (c *CCMPyACTR) Run(ctx context.Context, options *runoptions.Options, sink framework.OutputSink) (result *framework.RunResult, err error) {
 	runFile, err := c.WriteModel(c.tmpPath, options)
 	if err != nil {
 		return
@@ -127,13 +212,19 @@ func (c *CCMPyACTR) Run(options *runoptions.Options) (result *framework.RunResul
 		GeneratedCode: c.GetContents(),
 	}
 
-	output, err := executil.ExecCommand(Info.ExecutableName, runFile)
+	writer := framework.NewLineWriter(sink, options.IsTracingEvents())
+	output, err := executil.ExecCommandWithOutput(ctx, writer, Info.ExecutableName, runFile)
+	writer.Flush()
 	if err != nil {
 		return
 	}
 
 	result.Output = []byte(output)
 
+	if options.IsTracingEvents() {
+		result.Trace, result.Output = framework.ParseTrace(result.Output)
+	}
+
 	return
 }
 
@@ -155,6 +246,14 @@ func (c *CCMPyACTR) WriteModel(path string, options *runoptions.Options) (output
 		}
 	}
 
+	// If we are tracing events, then write out our support file
+	if options.IsTracingEvents() {
+		err = framework.WriteSupportFile(path, gactarTraceFileName, gactarTraceFile)
+		if err != nil {
+			return
+		}
+	}
+
 	outputFileName = fmt.Sprintf("%s.py", c.className)
 	if path != "" {
 		outputFileName = fmt.Sprintf("%s/%s", path, outputFileName)
@@ -187,6 +286,8 @@ func (c *CCMPyACTR) GenerateCode(options *runoptions.Options) (code []byte, err
 
 	goal := patterns["goal"]
 
+	c.traceEvents = options.IsTracingEvents()
+
 	err = c.InitWriterHelper()
 	if err != nil {
 		return
@@ -271,6 +372,18 @@ func (c *CCMPyACTR) GenerateCode(options *runoptions.Options) (code []byte, err
 		c.Writeln("")
 	}
 
+	// Turn on PMNoise if we have set "utility_noise"
+	if procedural.UtilityNoise != nil {
+		c.Writeln("    pm_noise = PMNoise(noise=%s)", numbers.Float64Str(*procedural.UtilityNoise))
+		c.Writeln("")
+	}
+
+	// Turn on PMNew if we have set "utility_learning_rate"
+	if procedural.IsUsingUtilityLearning() {
+		c.Writeln("    pm_new = PMNew(alpha=%s)", numbers.Float64Str(*procedural.UtilityLearningRate))
+		c.Writeln("")
+	}
+
 	if c.model.HasPrintStatement() {
 		c.Writeln("    # create a printer helper and register chunks with their slots for lookup")
 		c.Writeln("    printer = CCMPrint()")
@@ -376,6 +489,16 @@ func (c CCMPyACTR) writeImports(runOptions *runoptions.Options) {
 		additionalImports = append(additionalImports, "Partial")
 	}
 
+	procedural := c.model.Procedural
+
+	if procedural.UtilityNoise != nil {
+		additionalImports = append(additionalImports, "PMNoise")
+	}
+
+	if procedural.IsUsingUtilityLearning() {
+		additionalImports = append(additionalImports, "PMNew")
+	}
+
 	if len(additionalImports) > 0 {
 		c.Write("from python_actr import %s\n", strings.Join(additionalImports, ", "))
 	}
@@ -393,6 +516,11 @@ func (c CCMPyACTR) writeImports(runOptions *runoptions.Options) {
 		c.Writeln("")
 		c.Writeln(fmt.Sprintf("from %s import ActivateTrace", gactarActivateTraceImportName))
 	}
+
+	if runOptions.IsTracingEvents() {
+		c.Writeln("")
+		c.Writeln(fmt.Sprintf("from %s import trace as gactar_trace", gactarTraceImportName))
+	}
 }
 
 // If spreading activation is on, write its parameters
@@ -480,12 +608,20 @@ func (c CCMPyACTR) writeProductions() {
 
 		c.Writeln("):")
 
+		if c.traceEvents {
+			c.Writeln("        gactar_trace(self, 'production-fired', production='%s')", production.Name)
+		}
+
 		if production.DoStatements != nil {
 			for _, statement := range production.DoStatements {
 				c.outputStatement(statement)
 			}
 		}
 
+		if production.Reward != nil {
+			c.Writeln("        self.reward(%s)", numbers.Float64Str(*production.Reward))
+		}
+
 		c.Write("\n")
 	}
 }
@@ -499,7 +635,11 @@ func (c CCMPyACTR) writeMain(runOptions *runoptions.Options) {
 		c.Writeln("    log_everything(model)")
 	}
 
-	c.Writeln("    model.run()")
+	if runOptions.MaxTime != nil {
+		c.Writeln("    model.run(limit=%s)", numbers.Float64Str(*runOptions.MaxTime))
+	} else {
+		c.Writeln("    model.run()")
+	}
 }
 
 func (c CCMPyACTR) outputPattern(pattern *actr.Pattern) {
@@ -573,6 +713,11 @@ func patternSlotString(slot *actr.PatternSlot) string {
 	if slot.Var != nil {
 		if len(slot.Var.Constraints) > 0 {
 			for _, constraint := range slot.Var.Constraints {
+				// Numeric comparisons are not supported (see ValidateModel)
+				if constraint.Comparison.IsRelational() {
+					continue
+				}
+
 				if constraint.Comparison == actr.NotEqual {
 					str += "!"
 				}
@@ -592,6 +737,11 @@ func (c CCMPyACTR) outputStatement(s *actr.Statement) {
 			slotAssignments := []string{}
 			for _, slot := range *s.Set.Slots {
 				value := convertValue(slot.Value)
+				if slot.Value.Expr != nil {
+					// ccm stores everything as strings, so convert the result back to one
+					value = fmt.Sprintf("'%%.15g' %% %s", pythonExpression(slot.Value.Expr))
+				}
+
 				slotAssignments = append(slotAssignments, fmt.Sprintf("_%d=%s", slot.SlotIndex, value))
 			}
 			c.Writeln("        %s.modify(%s)", s.Set.Buffer.Name(), strings.Join(slotAssignments, ", "))
@@ -601,11 +751,19 @@ func (c CCMPyACTR) outputStatement(s *actr.Statement) {
 			c.Writeln(")")
 		}
 
+		if c.traceEvents {
+			c.Writeln("        gactar_trace(self, 'buffer-set', buffer='%[1]s', chunk=%[1]s.chunk)", s.Set.Buffer.Name())
+		}
+
 	case s.Recall != nil:
 		c.Write("        %s.request(", s.Recall.MemoryModuleName)
 		c.outputPattern(s.Recall.Pattern)
 		c.Writeln(")")
 
+		if c.traceEvents {
+			c.Writeln("        gactar_trace(self, 'retrieval-started', buffer='retrieval')")
+		}
+
 	case s.Clear != nil:
 		for _, name := range s.Clear.BufferNames {
 			c.Writeln("        %s.clear()", name)
@@ -621,12 +779,23 @@ func (c CCMPyACTR) outputStatement(s *actr.Statement) {
 			} else {
 				c.Writeln("        printer.print_chunk_slot(%s, %q, %q)", ids[0], ids[0], ids[1])
 			}
+
+			if c.traceEvents {
+				c.Writeln("        gactar_trace(self, 'print', buffer='%s')", ids[0])
+			}
 		} else {
 			values := pythonValuesToStrings(s.Print.Values, true)
 			c.Writeln("        print(%s, sep='')", strings.Join(values, ", "))
+
+			if c.traceEvents {
+				c.Writeln("        gactar_trace(self, 'print', text=''.join(map(str, [%s])))", strings.Join(values, ", "))
+			}
 		}
 
 	case s.Stop != nil:
+		if c.traceEvents {
+			c.Writeln("        gactar_trace(self, 'stop')")
+		}
 		c.Writeln("        self.stop()")
 	}
 }
@@ -652,6 +821,20 @@ func convertValue(s *actr.Value) string {
 	return ""
 }
 
+// pythonExpression converts an arithmetic expression to python. Variables are converted to
+// floats since ccm binds them as strings.
+func pythonExpression(expr *actr.Expression) string {
+	if expr.Operand != nil {
+		if expr.Operand.Var != nil {
+			return fmt.Sprintf("float(%s)", *expr.Operand.Var)
+		}
+
+		return expr.Operand.String()
+	}
+
+	return fmt.Sprintf("(%s %s %s)", pythonExpression(expr.LHS), expr.Operator, pythonExpression(expr.RHS))
+}
+
 func pythonValuesToStrings(values *[]*actr.Value, quoteStrings bool) []string {
 	str := make([]string, len(*values))
 	for i, v := range *values {
@@ -0,0 +1,30 @@
+"""
+gactar_ccm_trace adds gactar's framework-neutral event trace to python_actr.
+
+It outputs lines of the form:
+
+    gactar-trace|<time>|<type>|<production>|<buffer>|<chunk>|<text>
+
+which gactar removes from the output and parses into its trace.
+
+python_actr does not have hooks we can use, so the generated productions call
+trace() themselves. This means we cannot report the results of retrievals.
+
+To use it, call it from a production:
+
+    def foo(goal="..."):
+        trace(self, "production-fired", production="foo")
+"""
+
+from python_actr import Model
+
+
+def trace(model: Model, event_type: str, production="", buffer="", chunk="", text=""):
+    """
+    Outputs one trace event at the model's current time.
+    """
+
+    if chunk is None:
+        chunk = ""
+
+    print(f"gactar-trace|{model.now():.3f}|{event_type}|{production}|{buffer}|{chunk}|{text}")
@@ -9,6 +9,9 @@ import (
 
 var (
 	ErrModelMissingName = errors.New("model missing name")
+	ErrNoModel          = errors.New("no model loaded")
+	ErrRunCancelled     = errors.New("run cancelled")
+	ErrRunTimedOut      = errors.New("run timed out")
 )
 
 type ErrBufferNotFound struct {
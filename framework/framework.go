@@ -2,6 +2,7 @@
 package framework
 
 import (
+	"context"
 	"time"
 
 	"github.com/asmaloney/gactar/actr"
@@ -14,7 +15,7 @@ import (
 var (
 	// ValidFrameworks lists the valid options for choosing frameworks on the command line and in the
 	// interactive case. Make sure "all" is the first entry as we use [1:] to get the rest.
-	ValidFrameworks = []string{"all", "ccm", "pyactr", "vanilla"}
+	ValidFrameworks = []string{"all", "ccm", "native", "pyactr", "vanilla"}
 
 	// GactarVersion stores the current build version. It is a var so we can replace it in testing.
 	GactarVersion = version.BuildVersion
@@ -42,16 +43,27 @@ type RunResult struct {
 	FileName      string // full path to the intermediate file
 	GeneratedCode []byte // code which was run
 	Output        []byte // resulting output (stdout + stderr)
+
+	Trace *Trace // events which occurred during the run (only if requested using the TraceEvents option)
+
+	Warnings []string // problems with the run which did not cause an error (e.g. it was stopped early)
 }
 
 type Framework interface {
 	Info() *Info
 
+	// NewInstance returns a new instance of the framework (without a model) which writes its files
+	// to "tempPath". It does not repeat the setup checks which were done when this one was created.
+	// Each instance may only run one model at a time, so this is used to run models concurrently.
+	NewInstance(tempPath string) Framework
+
 	ValidateModel(model *actr.Model) (log *issues.Log)
 	SetModel(model *actr.Model) (err error)
 	Model() (model *actr.Model)
 
-	Run(options *runoptions.Options) (result *RunResult, err error)
+	// Run runs the model. If "sink" is not nil, output is sent to it as it is produced.
+	// If "ctx" is cancelled or times out, the run is stopped and the context's error is returned.
+	Run(ctx context.Context, options *runoptions.Options, sink OutputSink) (result *RunResult, err error)
 	WriteModel(path string, options *runoptions.Options) (outputFileName string, err error)
 	GenerateCode(options *runoptions.Options) (code []byte, err error)
 }
@@ -0,0 +1,159 @@
+package native
+
+import (
+	"math"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/modules"
+)
+
+// defaultBlendTemperature is used if the model sets neither the blending module's
+// "blend_temperature" nor memory's "instantaneous_noise".
+const defaultBlendTemperature = 1.0
+
+// pendingBlend is a blend request which completes at a specific time.
+type pendingBlend struct {
+	result blendResult
+	time   float64
+}
+
+// blendResult is the result of a blend request.
+type blendResult struct {
+	chunk   *chunk // nil on failure
+	latency float64
+}
+
+// blendTemperature returns the temperature used to calculate the probability of retrieval.
+// Like vanilla, if it is not set we use sqrt(2) * instantaneous_noise.
+func blendTemperature(blending *modules.Blending, memory *modules.DeclarativeMemory) float64 {
+	if blending != nil && blending.Temperature != nil {
+		return *blending.Temperature
+	}
+
+	if memory.InstantaneousNoise != nil && *memory.InstantaneousNoise != 0.0 {
+		return math.Sqrt2 * *memory.InstantaneousNoise
+	}
+
+	return defaultBlendTemperature
+}
+
+// blend creates a chunk from all the chunks in memory which match the pattern and are above the
+// retrieval threshold. Each chunk's probability of retrieval is:
+//
+//	P_i = exp( A_i / t ) / Σ exp( A_j / t )
+//
+// Slots which are wildcards (or negated, or unbound variables) in the pattern get blended values.
+// If all the values of a slot are numbers, the blended value is their mean weighted by P_i.
+// Otherwise it is the value with the highest total probability.
+//
+// The latency is calculated using the log of the sum of the exponentiated activations.
+func (dm *declarativeMemory) blend(pattern *actr.Pattern, b bindings, temperature float64, sources []spreadingSource, now float64, trace func(format string, a ...any)) (result blendResult) {
+	threshold := dm.retrievalThreshold()
+
+	matched := []activatedChunk{}
+	for _, m := range dm.match(pattern, b, nil, sources, now, trace) {
+		if m.activation >= threshold {
+			matched = append(matched, m)
+		}
+	}
+
+	if len(matched) == 0 {
+		result.latency = dm.latency(threshold)
+		return
+	}
+
+	// Subtract the largest activation before exponentiating to avoid overflow.
+	maxActivation := matched[0].activation
+	for _, m := range matched[1:] {
+		maxActivation = math.Max(maxActivation, m.activation)
+	}
+
+	// A temperature of 0 gives all the weight to the chunks with the highest activation.
+	if temperature <= 0.0 {
+		temperature = math.SmallestNonzeroFloat64
+	}
+
+	weights := make([]float64, len(matched))
+	sum := 0.0
+	for i, m := range matched {
+		weights[i] = math.Exp((m.activation - maxActivation) / temperature)
+		sum += weights[i]
+	}
+
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	sumExp := 0.0
+	for _, m := range matched {
+		sumExp += math.Exp(m.activation - maxActivation)
+	}
+
+	c := chunkFromPattern(pattern, b)
+
+	for i, slot := range pattern.Slots {
+		if i >= len(c.slots) {
+			break
+		}
+
+		if _, ok := patternSlotValue(slot, b); ok && !slot.Negated {
+			continue
+		}
+
+		c.slots[i] = blendSlot(matched, weights, i)
+	}
+
+	result.chunk = c
+	result.latency = dm.latency(maxActivation + math.Log(sumExp))
+	return
+}
+
+// blendSlot calculates the blended value of the slot at "index".
+func blendSlot(matched []activatedChunk, weights []float64, index int) value {
+	allNumbers := true
+	for _, m := range matched {
+		if m.memChunk.slots[index].kind != kindNumber {
+			allNumbers = false
+			break
+		}
+	}
+
+	if allNumbers {
+		blended := 0.0
+		for i, m := range matched {
+			blended += weights[i] * m.memChunk.slots[index].num
+		}
+
+		return floatValue(blended)
+	}
+
+	values := []value{}
+	totals := []float64{}
+
+	for i, m := range matched {
+		v := m.memChunk.slots[index]
+
+		found := false
+		for j := range values {
+			if values[j].equal(v) {
+				totals[j] += weights[i]
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			values = append(values, v)
+			totals = append(totals, weights[i])
+		}
+	}
+
+	best := 0
+	for j := range totals {
+		if totals[j] > totals[best] {
+			best = j
+		}
+	}
+
+	return values[best]
+}
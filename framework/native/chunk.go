@@ -0,0 +1,168 @@
+package native
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+type valueKind int
+
+const (
+	kindNil valueKind = iota
+	kindID
+	kindStr
+	kindNumber
+)
+
+// value is the run-time contents of a slot or a variable binding.
+type value struct {
+	kind valueKind
+	text string  // the ID, string, or the number as written
+	num  float64 // only used if kind is kindNumber
+}
+
+func nilValue() value {
+	return value{kind: kindNil}
+}
+
+func idValue(id string) value {
+	return value{kind: kindID, text: id}
+}
+
+func strValue(str string) value {
+	return value{kind: kindStr, text: str}
+}
+
+func numberValue(num string) value {
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		// The parser has already validated numbers, so treat anything else as an ID.
+		return idValue(num)
+	}
+
+	return value{kind: kindNumber, text: num, num: f}
+}
+
+func floatValue(f float64) value {
+	return value{kind: kindNumber, text: numbers.Float64Str(f), num: f}
+}
+
+func (v value) isNil() bool {
+	return v.kind == kindNil
+}
+
+// isChunkRef returns whether this value may refer to another chunk (used for spreading activation).
+func (v value) isChunkRef() bool {
+	return v.kind == kindID
+}
+
+// equal compares two values. Numbers are compared numerically so "1" and "1.0" are the same.
+func (v value) equal(other value) bool {
+	if v.kind != other.kind {
+		return false
+	}
+
+	switch v.kind {
+	case kindNil:
+		return true
+
+	case kindNumber:
+		return v.num == other.num
+	}
+
+	return v.text == other.text
+}
+
+// String returns the value as it should be printed by the print statement.
+func (v value) String() string {
+	if v.kind == kindNil {
+		return "nil"
+	}
+
+	return v.text
+}
+
+// slotString returns the value as it should appear in a chunk.
+func (v value) slotString() string {
+	if v.kind == kindStr {
+		return fmt.Sprintf("'%s'", v.text)
+	}
+
+	return v.String()
+}
+
+// chunk is the run-time version of a chunk which is stored in buffers and in memory.
+type chunk struct {
+	name      string // optional
+	chunkType *actr.Chunk
+	slots     []value
+}
+
+// newChunk creates a chunk of the type with all slots set to nil.
+func newChunk(chunkType *actr.Chunk) *chunk {
+	slots := make([]value, chunkType.NumSlots)
+	for i := range slots {
+		slots[i] = nilValue()
+	}
+
+	return &chunk{
+		chunkType: chunkType,
+		slots:     slots,
+	}
+}
+
+// copy creates a copy of the chunk so it may be modified independently of the original.
+func (c chunk) copy() *chunk {
+	slots := make([]value, len(c.slots))
+	copy(slots, c.slots)
+
+	return &chunk{
+		name:      c.name,
+		chunkType: c.chunkType,
+		slots:     slots,
+	}
+}
+
+// sameContents checks if two chunks have the same type and slot values.
+func (c chunk) sameContents(other *chunk) bool {
+	if c.chunkType != other.chunkType {
+		return false
+	}
+
+	for i, v := range c.slots {
+		if !v.equal(other.slots[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// slotValue returns the value of the named slot.
+func (c chunk) slotValue(slotName string) (v value, ok bool) {
+	for i, name := range c.chunkType.SlotNames {
+		if name == slotName {
+			return c.slots[i], true
+		}
+	}
+
+	return nilValue(), false
+}
+
+func (c chunk) String() string {
+	slots := make([]string, len(c.slots))
+	for i, v := range c.slots {
+		slots[i] = v.slotString()
+	}
+
+	if len(slots) == 0 {
+		return fmt.Sprintf("[%s]", c.chunkType.TypeName)
+	}
+
+	return fmt.Sprintf("[%s: %s]", c.chunkType.TypeName, strings.Join(slots, " "))
+}
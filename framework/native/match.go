@@ -0,0 +1,279 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// bindings maps variable names (e.g. "?obj") to their values.
+type bindings map[string]value
+
+// patternSlotValue returns the value of a pattern slot. If the slot is a wildcard or an
+// unbound variable, ok will be false.
+func patternSlotValue(slot *actr.PatternSlot, b bindings) (v value, ok bool) {
+	switch {
+	case slot.Wildcard:
+		return nilValue(), false
+
+	case slot.Nil:
+		return nilValue(), true
+
+	case slot.ID != nil:
+		return idValue(*slot.ID), true
+
+	case slot.Str != nil:
+		return strValue(*slot.Str), true
+
+	case slot.Num != nil:
+		return numberValue(*slot.Num), true
+
+	case slot.Var != nil:
+		v, ok = b[*slot.Var.Name]
+		return
+	}
+
+	return nilValue(), false
+}
+
+// actrValue converts an actr.Value (used in statements and constraints) into a value.
+func actrValue(v *actr.Value, b bindings) value {
+	switch {
+	case v.Nil != nil:
+		return nilValue()
+
+	case v.Var != nil:
+		name := *v.Var
+		if name[0] != '?' {
+			name = "?" + name
+		}
+
+		return b[name]
+
+	case v.ID != nil:
+		return idValue(*v.ID)
+
+	case v.Str != nil:
+		return strValue(*v.Str)
+
+	case v.Number != nil:
+		return numberValue(*v.Number)
+	}
+
+	return nilValue()
+}
+
+// matchType checks if the chunk is of the type the pattern requires.
+func matchType(pattern *actr.Pattern, c *chunk) bool {
+	if pattern.AnyChunk {
+		return true
+	}
+
+	return c.chunkType.TypeName == pattern.Chunk.TypeName
+}
+
+// matchSlots matches the pattern's slots against the chunk. Any unbound variables are bound.
+// If "negatedVars" is false, negated variables are skipped so they may be checked once all
+// variables have been bound.
+func matchSlots(pattern *actr.Pattern, c *chunk, b bindings, negatedVars bool) bool {
+	for i, slot := range pattern.Slots {
+		if i >= len(c.slots) {
+			return false
+		}
+
+		isNegatedVar := slot.Negated && (slot.Var != nil)
+		if isNegatedVar != negatedVars {
+			continue
+		}
+
+		if !matchSlot(slot, c.slots[i], b) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchSlot(slot *actr.PatternSlot, v value, b bindings) bool {
+	result := false
+
+	switch {
+	case slot.Wildcard:
+		// "!*" means the slot must be empty
+		return !slot.Negated || v.isNil()
+
+	case slot.Var != nil:
+		name := *slot.Var.Name
+
+		bound, ok := b[name]
+		if !ok {
+			if slot.Negated {
+				return true
+			}
+
+			b[name] = v
+			return true
+		}
+
+		result = bound.equal(v)
+
+	default:
+		expected, _ := patternSlotValue(slot, b)
+		result = expected.equal(v)
+	}
+
+	if slot.Negated {
+		return !result
+	}
+
+	return result
+}
+
+// checkConstraints checks all the "when" constraints of the variables in the pattern.
+func checkConstraints(pattern *actr.Pattern, b bindings) bool {
+	for _, slot := range pattern.Slots {
+		if slot.Var == nil {
+			continue
+		}
+
+		for _, constraint := range slot.Var.Constraints {
+			lhs := b[*constraint.LHS]
+			rhs := actrValue(constraint.RHS, b)
+
+			switch constraint.Comparison {
+			case actr.Equal:
+				if !lhs.equal(rhs) {
+					return false
+				}
+
+			case actr.NotEqual:
+				if lhs.equal(rhs) {
+					return false
+				}
+
+			default:
+				if !compareNumbers(lhs, constraint.Comparison, rhs) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// compareNumbers checks a numeric comparison (<, <=, >, >=). If either value is not a number
+// the comparison fails.
+func compareNumbers(lhs value, comparison actr.Comparison, rhs value) bool {
+	if lhs.kind != kindNumber || rhs.kind != kindNumber {
+		return false
+	}
+
+	switch comparison {
+	case actr.LessThan:
+		return lhs.num < rhs.num
+	case actr.LessThanOrEqual:
+		return lhs.num <= rhs.num
+	case actr.GreaterThan:
+		return lhs.num > rhs.num
+	case actr.GreaterThanOrEqual:
+		return lhs.num >= rhs.num
+	}
+
+	return false
+}
+
+// evaluate calculates the value of an arithmetic expression using the bindings.
+func evaluate(expr *actr.Expression, b bindings) (result value, err error) {
+	if expr.Operand != nil {
+		result = actrValue(expr.Operand, b)
+		if result.kind != kindNumber {
+			return nilValue(), fmt.Errorf("%q is not a number", result)
+		}
+
+		return
+	}
+
+	lhs, err := evaluate(expr.LHS, b)
+	if err != nil {
+		return
+	}
+
+	rhs, err := evaluate(expr.RHS, b)
+	if err != nil {
+		return
+	}
+
+	switch expr.Operator {
+	case actr.Add:
+		return floatValue(lhs.num + rhs.num), nil
+	case actr.Subtract:
+		return floatValue(lhs.num - rhs.num), nil
+	case actr.Multiply:
+		return floatValue(lhs.num * rhs.num), nil
+	case actr.Divide:
+		if rhs.num == 0 {
+			return nilValue(), errors.New("division by zero")
+		}
+
+		return floatValue(lhs.num / rhs.num), nil
+	}
+
+	return nilValue(), fmt.Errorf("unknown operator %q", expr.Operator)
+}
+
+// chunkFromPattern creates a new chunk from a pattern using the bindings to fill in variables.
+// Wildcards and unbound variables result in empty slots.
+func chunkFromPattern(pattern *actr.Pattern, b bindings) *chunk {
+	c := newChunk(pattern.Chunk)
+
+	for i, slot := range pattern.Slots {
+		if i >= len(c.slots) {
+			break
+		}
+
+		v, ok := patternSlotValue(slot, b)
+		if ok {
+			c.slots[i] = v
+		}
+	}
+
+	return c
+}
+
+// patternString returns the pattern as a string with any bound variables replaced by their values.
+func patternString(pattern *actr.Pattern, b bindings) string {
+	resolved := *pattern
+	resolved.Slots = make([]*actr.PatternSlot, len(pattern.Slots))
+
+	for i, slot := range pattern.Slots {
+		resolved.Slots[i] = slot
+
+		if slot.Var == nil {
+			continue
+		}
+
+		v, ok := b[*slot.Var.Name]
+		if !ok {
+			continue
+		}
+
+		newSlot := &actr.PatternSlot{Negated: slot.Negated}
+
+		switch v.kind {
+		case kindNil:
+			newSlot.Nil = true
+		case kindID:
+			newSlot.ID = &v.text
+		case kindStr:
+			newSlot.Str = &v.text
+		case kindNumber:
+			newSlot.Num = &v.text
+		}
+
+		resolved.Slots[i] = newSlot
+	}
+
+	return resolved.String()
+}
@@ -0,0 +1,469 @@
+package native
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/modules"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+// Defaults for the memory parameters. These are the same as vanilla ACT-R.
+const (
+	defaultLatencyFactor      = 1.0
+	defaultLatencyExponent    = 1.0
+	defaultRetrievalThreshold = 0.0
+	defaultFinstSize          = 4
+	defaultFinstTime          = 3.0
+
+	// similarity of two different chunks if not otherwise specified
+	defaultMaxDifference = -1.0
+)
+
+// memoryChunk is a chunk stored in declarative memory along with the times it was referenced.
+type memoryChunk struct {
+	*chunk
+
+	references []float64
+}
+
+type finst struct {
+	memChunk *memoryChunk
+	time     float64
+}
+
+// declarativeMemory implements the memory module.
+type declarativeMemory struct {
+	module *modules.DeclarativeMemory
+
+	chunks []*memoryChunk
+	finsts []finst
+
+	similarities map[[2]string]float64
+	associations map[[2]string]float64 // keyed by [source, target]
+
+	random *rand.Rand
+}
+
+// retrievalResult is the result of a retrieval request.
+type retrievalResult struct {
+	memChunk   *memoryChunk // nil on failure
+	activation float64
+	latency    float64
+}
+
+func newDeclarativeMemory(model *actr.Model, random *rand.Rand) *declarativeMemory {
+	dm := &declarativeMemory{
+		module:       model.Memory,
+		similarities: map[[2]string]float64{},
+		associations: map[[2]string]float64{},
+		random:       random,
+	}
+
+	for _, association := range model.Associations {
+		dm.associations[[2]string{association.Source, association.Target}] = association.Value
+	}
+
+	for _, similar := range model.Similarities {
+		dm.similarities[[2]string{similar.ChunkOne, similar.ChunkTwo}] = similar.Value
+		dm.similarities[[2]string{similar.ChunkTwo, similar.ChunkOne}] = similar.Value
+	}
+
+	return dm
+}
+
+// add merges the chunk into memory at time "now". If a chunk with the same contents
+// exists, it gets a new reference. Otherwise the chunk is added.
+func (dm *declarativeMemory) add(c *chunk, now float64) {
+	dm.addReferences(c, []float64{now})
+}
+
+// addWithBaseLevel adds the chunk to memory with "references" references spread evenly from
+// "creationTime" until "now" (like vanilla's set-base-levels).
+func (dm *declarativeMemory) addWithBaseLevel(c *chunk, baseLevel *actr.BaseLevel, now float64) {
+	references := make([]float64, baseLevel.References)
+
+	interval := (now - baseLevel.CreationTime) / float64(baseLevel.References)
+	for i := range references {
+		references[i] = baseLevel.CreationTime + float64(i)*interval
+	}
+
+	dm.addReferences(c, references)
+}
+
+// addReferences merges the chunk into memory with the list of reference times.
+func (dm *declarativeMemory) addReferences(c *chunk, references []float64) {
+	for _, memChunk := range dm.chunks {
+		if memChunk.sameContents(c) {
+			memChunk.references = append(memChunk.references, references...)
+			return
+		}
+	}
+
+	dm.chunks = append(dm.chunks, &memoryChunk{
+		chunk:      c.copy(),
+		references: references,
+	})
+}
+
+// reinforce adds a reference at time "now" to the chunk named "name".
+// It returns false if there is no such chunk.
+func (dm *declarativeMemory) reinforce(name string, now float64) bool {
+	for _, memChunk := range dm.chunks {
+		if memChunk.name == name {
+			memChunk.references = append(memChunk.references, now)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (dm declarativeMemory) latencyFactor() float64 {
+	if dm.module.LatencyFactor != nil {
+		return *dm.module.LatencyFactor
+	}
+
+	return defaultLatencyFactor
+}
+
+func (dm declarativeMemory) latencyExponent() float64 {
+	if dm.module.LatencyExponent != nil {
+		return *dm.module.LatencyExponent
+	}
+
+	return defaultLatencyExponent
+}
+
+func (dm declarativeMemory) retrievalThreshold() float64 {
+	if dm.module.RetrievalThreshold != nil {
+		return *dm.module.RetrievalThreshold
+	}
+
+	return defaultRetrievalThreshold
+}
+
+func (dm declarativeMemory) finstSize() int {
+	if dm.module.FinstSize != nil {
+		return *dm.module.FinstSize
+	}
+
+	return defaultFinstSize
+}
+
+func (dm declarativeMemory) finstTime() float64 {
+	if dm.module.FinstTime != nil {
+		return *dm.module.FinstTime
+	}
+
+	return defaultFinstTime
+}
+
+// latency calculates the retrieval time given an activation.
+//
+//	RT = F * exp( - f * A )
+func (dm declarativeMemory) latency(activation float64) float64 {
+	return dm.latencyFactor() * math.Exp(-dm.latencyExponent()*activation)
+}
+
+// hasFinst checks if the chunk has an active finst at time "now".
+func (dm declarativeMemory) hasFinst(memChunk *memoryChunk, now float64) bool {
+	for _, f := range dm.finsts {
+		if f.memChunk == memChunk && (now-f.time) <= dm.finstTime() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addFinst marks the chunk as recently retrieved, removing the oldest finst if necessary.
+func (dm *declarativeMemory) addFinst(memChunk *memoryChunk, now float64) {
+	list := []finst{}
+	for _, f := range dm.finsts {
+		if f.memChunk != memChunk && (now-f.time) <= dm.finstTime() {
+			list = append(list, f)
+		}
+	}
+
+	list = append(list, finst{memChunk: memChunk, time: now})
+
+	size := dm.finstSize()
+	if len(list) > size {
+		list = list[len(list)-size:]
+	}
+
+	dm.finsts = list
+}
+
+// activatedChunk is a chunk in memory which matches a request along with its activation.
+type activatedChunk struct {
+	memChunk   *memoryChunk
+	activation float64
+}
+
+// retrieve finds the chunk with the highest activation which matches the pattern.
+// "sources" are the values in buffers used for spreading activation.
+func (dm *declarativeMemory) retrieve(pattern *actr.Pattern, b bindings, requestParams map[string]string, sources []spreadingSource, now float64, trace func(format string, a ...any)) (result retrievalResult) {
+	for _, matched := range dm.match(pattern, b, requestParams, sources, now, trace) {
+		if result.memChunk == nil || matched.activation > result.activation {
+			result.memChunk = matched.memChunk
+			result.activation = matched.activation
+		}
+	}
+
+	threshold := dm.retrievalThreshold()
+
+	if result.memChunk == nil || result.activation < threshold {
+		result.memChunk = nil
+		result.latency = dm.latency(threshold)
+		return
+	}
+
+	result.latency = dm.latency(result.activation)
+	return
+}
+
+// match finds all the chunks in memory which match the pattern and calculates their activations.
+func (dm *declarativeMemory) match(pattern *actr.Pattern, b bindings, requestParams map[string]string, sources []spreadingSource, now float64, trace func(format string, a ...any)) (matched []activatedChunk) {
+	recentlyRetrieved, hasRecentlyRetrieved := requestParams["recently_retrieved"]
+	if hasRecentlyRetrieved && recentlyRetrieved == "reset" {
+		dm.finsts = nil
+		hasRecentlyRetrieved = false
+	}
+
+	usingPartialMatching := dm.module.MismatchPenalty != nil
+
+	for _, memChunk := range dm.chunks {
+		if !matchType(pattern, memChunk.chunk) {
+			continue
+		}
+
+		if hasRecentlyRetrieved {
+			hasFinst := dm.hasFinst(memChunk, now)
+			if (recentlyRetrieved == "t") != hasFinst {
+				continue
+			}
+		}
+
+		matchBindings := copyBindings(b)
+
+		penalty := 0.0
+		if usingPartialMatching {
+			var ok bool
+			penalty, ok = dm.partialMatch(pattern, memChunk.chunk, matchBindings)
+			if !ok {
+				continue
+			}
+		} else if !matchSlots(pattern, memChunk.chunk, matchBindings, false) ||
+			!matchSlots(pattern, memChunk.chunk, matchBindings, true) {
+			continue
+		}
+
+		if !checkConstraints(pattern, matchBindings) {
+			continue
+		}
+
+		activation := dm.baseLevel(memChunk, now) + dm.spreading(memChunk, sources) + penalty + dm.noise()
+
+		if trace != nil {
+			trace("chunk %s has activation %s", memChunk.chunk, numbers.Float64Str(round(activation)))
+		}
+
+		matched = append(matched, activatedChunk{memChunk: memChunk, activation: activation})
+	}
+
+	return
+}
+
+// baseLevel calculates the base-level activation of a chunk:
+//
+//	B = ln( Σ t_j ^ -d )
+//
+// If base-level learning is not on, this is 0.
+func (dm declarativeMemory) baseLevel(memChunk *memoryChunk, now float64) float64 {
+	if !dm.module.IsUsingBaseLevelLearning() {
+		return 0.0
+	}
+
+	decay := *dm.module.Decay
+
+	sum := 0.0
+	for _, ref := range memChunk.references {
+		age := now - ref
+		if age <= 0 {
+			continue
+		}
+
+		sum += math.Pow(age, -decay)
+	}
+
+	if sum == 0.0 {
+		return 0.0
+	}
+
+	return math.Log(sum)
+}
+
+// spreadingSource is a value in a buffer which spreads activation.
+type spreadingSource struct {
+	value  value
+	weight float64
+}
+
+// spreading calculates the spreading activation of a chunk:
+//
+//	S = Σ W_j * S_ji where S_ji = S - ln(fan_j)
+//
+// If the model sets an association between the source and the chunk, it is used for S_ji instead.
+func (dm declarativeMemory) spreading(memChunk *memoryChunk, sources []spreadingSource) float64 {
+	if !dm.module.IsUsingSpreadingActivation() {
+		return 0.0
+	}
+
+	maxStrength := *dm.module.MaxSpreadStrength
+
+	total := 0.0
+	for _, source := range sources {
+		if source.value.kind == kindID && memChunk.name != "" {
+			if strength, ok := dm.associations[[2]string{source.value.text, memChunk.name}]; ok {
+				total += source.weight * strength
+				continue
+			}
+		}
+
+		if !containsValue(memChunk.chunk, source.value) {
+			continue
+		}
+
+		strength := maxStrength - math.Log(float64(dm.fan(source.value)))
+		total += source.weight * strength
+	}
+
+	return total
+}
+
+// fan returns the number of chunks in memory which have the value in a slot (plus one for itself).
+func (dm declarativeMemory) fan(v value) int {
+	count := 1
+
+	for _, memChunk := range dm.chunks {
+		if containsValue(memChunk.chunk, v) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func containsValue(c *chunk, v value) bool {
+	if c.name != "" && v.kind == kindID && c.name == v.text {
+		return true
+	}
+
+	for _, slot := range c.slots {
+		if slot.equal(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// partialMatch matches the pattern against the chunk allowing mismatches of specified values.
+// It returns the penalty to apply to the activation.
+func (dm declarativeMemory) partialMatch(pattern *actr.Pattern, c *chunk, b bindings) (penalty float64, ok bool) {
+	mismatchPenalty := *dm.module.MismatchPenalty
+
+	// First bind our variables & check anything which cannot partially match.
+	for i, slot := range pattern.Slots {
+		if i >= len(c.slots) {
+			return 0, false
+		}
+
+		v := c.slots[i]
+
+		_, bound := patternSlotValue(slot, b)
+		if slot.Var != nil && !bound && !slot.Negated {
+			b[*slot.Var.Name] = v
+		}
+	}
+
+	for i, slot := range pattern.Slots {
+		v := c.slots[i]
+
+		if slot.Wildcard || slot.Negated || slot.Nil {
+			if !matchSlot(slot, v, b) {
+				return 0, false
+			}
+			continue
+		}
+
+		expected, specified := patternSlotValue(slot, b)
+		if !specified {
+			continue
+		}
+
+		penalty += mismatchPenalty * dm.similarity(expected, v)
+	}
+
+	return penalty, true
+}
+
+// similarity returns the similarity between two values (0 is identical).
+func (dm declarativeMemory) similarity(a, b value) float64 {
+	if a.equal(b) {
+		return 0.0
+	}
+
+	sim, ok := dm.similarities[[2]string{a.text, b.text}]
+	if ok {
+		return sim
+	}
+
+	return defaultMaxDifference
+}
+
+// noise generates the instantaneous noise using a logistic distribution.
+func (dm declarativeMemory) noise() float64 {
+	if dm.module.InstantaneousNoise == nil {
+		return 0.0
+	}
+
+	s := *dm.module.InstantaneousNoise
+	if s == 0.0 {
+		return 0.0
+	}
+
+	p := dm.random.Float64()
+	for p == 0.0 {
+		p = dm.random.Float64()
+	}
+
+	return s * math.Log((1.0-p)/p)
+}
+
+func copyBindings(b bindings) bindings {
+	newBindings := make(bindings, len(b))
+	for k, v := range b {
+		newBindings[k] = v
+	}
+
+	return newBindings
+}
+
+// round is used to make output of activations and times consistent.
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
+
+func (r retrievalResult) String() string {
+	if r.memChunk == nil {
+		return "retrieval failure"
+	}
+
+	return fmt.Sprintf("retrieved %s", r.memChunk.chunk)
+}
@@ -0,0 +1,414 @@
+// Package native provides a framework which runs the internal actr data structures directly
+// using an ACT-R simulator written in Go. It does not require Python or Lisp, so it may be used
+// on any machine and acts as a reference implementation for the other frameworks.
+package native
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/filesystem"
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+var Info framework.Info = framework.Info{
+	Name:          "native",
+	Language:      "text",
+	FileExtension: "txt",
+	// ExecutableName: none - runs in-process
+}
+
+type Native struct {
+	framework.Framework
+	framework.WriterHelper
+
+	tmpPath string
+
+	model     *actr.Model
+	modelName string
+}
+
+// New creates a new Native instance and sets the temp path.
+// Since it runs in-process, there is nothing to check.
+func New(tempPath string) (n *Native, err error) {
+	n = &Native{tmpPath: tempPath}
+	return
+}
+
+func (Native) Info() *framework.Info {
+	return &Info
+}
+
+func (Native) NewInstance(tempPath string) framework.Framework {
+	return &Native{tmpPath: tempPath}
+}
+
+func (Native) ValidateModel(model *actr.Model) (log *issues.Log) {
+	log = issues.New()
+
+	for _, production := range model.Productions {
+		for _, statement := range production.DoStatements {
+			if statement.Recall == nil {
+				continue
+			}
+
+			for _, param := range maps.Keys(statement.Recall.RequestParameters) {
+				if param != "recently_retrieved" {
+					location := issues.Location{
+						Line:        production.AMODLineNumber,
+						ColumnStart: 0,
+						ColumnEnd:   0,
+					}
+					log.Warning(&location, "native only supports the 'recently_retrieved' request parameter (in %q)", production.Name)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func (n *Native) SetModel(model *actr.Model) (err error) {
+	if model.Name == "" {
+		err = framework.ErrModelMissingName
+		return
+	}
+
+	n.model = model
+	n.modelName = fmt.Sprintf("native_%s", n.model.Name)
+
+	return
+}
+
+func (n Native) Model() (model *actr.Model) {
+	return n.model
+}
+
+// Run writes out a summary of the model and then runs the model using our simulator.
+func (n *Native) Run(ctx context.Context, options *runoptions.Options, sink framework.OutputSink) (result *framework.RunResult, err error) {
+	summaryFile, err := n.WriteModel(n.tmpPath, options)
+	if err != nil {
+		return
+	}
+
+	result = &framework.RunResult{
+		FileName:      summaryFile,
+		GeneratedCode: n.GetContents(),
+	}
+
+	result.Output, result.Trace, result.Warnings, err = n.runModel(ctx, options, sink)
+
+	return
+}
+
+// runModel runs the model in our simulator and returns its output, trace (if requested), and any
+// warnings. If "ctx" is cancelled, the run stops and the context's error is returned with the
+// output so far.
+func (n Native) runModel(ctx context.Context, options *runoptions.Options, sink framework.OutputSink) (output []byte, trace *framework.Trace, warnings []string, err error) {
+	patterns, err := framework.ParseInitialBuffers(n.model, options.InitialBuffers)
+	if err != nil {
+		return
+	}
+
+	sim := newSimulator(n.model, options, patterns, sink)
+
+	output, trace, err = sim.run(ctx)
+	warnings = sim.warnings
+	return
+}
+
+// WriteModel writes a summary of the model to a file.
+func (n *Native) WriteModel(path string, options *runoptions.Options) (outputFileName string, err error) {
+	outputFileName = fmt.Sprintf("%s.txt", n.modelName)
+	if path != "" {
+		outputFileName = fmt.Sprintf("%s/%s", path, outputFileName)
+	}
+
+	err = filesystem.RemoveFile(outputFileName)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = n.GenerateCode(options)
+	if err != nil {
+		return
+	}
+
+	err = n.WriteFile(outputFileName)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GenerateCode creates a text summary of the model as the simulator will run it.
+// Since this framework runs the model directly, there is no code to generate.
+func (n *Native) GenerateCode(options *runoptions.Options) (code []byte, err error) {
+	patterns, err := framework.ParseInitialBuffers(n.model, options.InitialBuffers)
+	if err != nil {
+		return
+	}
+
+	err = n.InitWriterHelper()
+	if err != nil {
+		return
+	}
+
+	n.writeHeader()
+
+	n.writeParameters(options)
+
+	n.writeChunkTypes()
+
+	n.writeInitializers(patterns)
+
+	n.writeScreen()
+
+	n.writeSimilarities()
+
+	n.writeAssociations()
+
+	n.writeProductions()
+
+	code = n.GetContents()
+	return
+}
+
+func (n Native) writeHeader() {
+	n.Writeln("# Generated by gactar %s", framework.GactarVersion)
+	n.Writeln("#           on %s", framework.TimeNow().Format("2006-01-02 @ 15:04:05"))
+	n.Writeln("#   https://github.com/asmaloney/gactar")
+	n.Writeln("")
+	n.Writeln("# *** NOTE: The native framework runs the model directly. This is a summary of what it runs.")
+	n.Writeln("")
+
+	n.Writeln("model: %s", n.model.Name)
+
+	if n.model.Description != "" {
+		n.Writeln("description: %s", n.model.Description)
+	}
+
+	if len(n.model.Authors) > 0 {
+		n.Writeln("authors:")
+
+		for _, author := range n.model.Authors {
+			n.Writeln("\t%s", author)
+		}
+	}
+
+	n.Writeln("")
+}
+
+func (n Native) writeParameters(options *runoptions.Options) {
+	n.Writeln("parameters:")
+
+	memory := newDeclarativeMemory(n.model, nil)
+
+	tabbedItems := framework.KeyValueList{}
+	tabbedItems.Add("latency_factor", numbers.Float64Str(memory.latencyFactor()))
+	tabbedItems.Add("latency_exponent", numbers.Float64Str(memory.latencyExponent()))
+	tabbedItems.Add("retrieval_threshold", numbers.Float64Str(memory.retrievalThreshold()))
+	tabbedItems.Add("finst_size", fmt.Sprintf("%d", memory.finstSize()))
+	tabbedItems.Add("finst_time", numbers.Float64Str(memory.finstTime()))
+
+	module := n.model.Memory
+	if module.IsUsingBaseLevelLearning() {
+		tabbedItems.Add("decay", numbers.Float64Str(*module.Decay))
+	}
+
+	if module.MaxSpreadStrength != nil {
+		tabbedItems.Add("max_spread_strength", numbers.Float64Str(*module.MaxSpreadStrength))
+	}
+
+	if module.InstantaneousNoise != nil {
+		tabbedItems.Add("instantaneous_noise", numbers.Float64Str(*module.InstantaneousNoise))
+	}
+
+	if module.MismatchPenalty != nil {
+		tabbedItems.Add("mismatch_penalty", numbers.Float64Str(*module.MismatchPenalty))
+	}
+
+	actionTime := defaultActionTime
+	if n.model.Procedural.DefaultActionTime != nil {
+		actionTime = *n.model.Procedural.DefaultActionTime
+	}
+	tabbedItems.Add("default_action_time", numbers.Float64Str(actionTime))
+
+	procedural := n.model.Procedural
+	if procedural.InitialUtility != nil {
+		tabbedItems.Add("initial_utility", numbers.Float64Str(*procedural.InitialUtility))
+	}
+
+	if procedural.UtilityNoise != nil {
+		tabbedItems.Add("utility_noise", numbers.Float64Str(*procedural.UtilityNoise))
+	}
+
+	if procedural.IsUsingUtilityLearning() {
+		tabbedItems.Add("utility_learning_rate", numbers.Float64Str(*procedural.UtilityLearningRate))
+	}
+
+	if n.model.BlendingModule() != nil {
+		tabbedItems.Add("blend_temperature", numbers.Float64Str(blendTemperature(n.model.BlendingModule(), n.model.Memory)))
+	}
+
+	if n.model.VisualModule() != nil {
+		tabbedItems.Add("attention_latency", numbers.Float64Str(newVisual(n.model).attentionLatency()))
+	}
+
+	if n.model.TemporalModule() != nil {
+		temporal := newTemporal(n.model, nil)
+		tabbedItems.Add("time_noise", numbers.Float64Str(temporal.timeNoise()))
+		tabbedItems.Add("time_mult", numbers.Float64Str(temporal.timeMult()))
+		tabbedItems.Add("time_start_increment", numbers.Float64Str(temporal.timeStartIncrement()))
+	}
+
+	if options.LogLevel != nil {
+		tabbedItems.Add("log_level", string(*options.LogLevel))
+	}
+
+	if options.TraceActivations != nil && *options.TraceActivations {
+		tabbedItems.Add("trace_activations", "true")
+	}
+
+	if options.RandomSeed != nil {
+		tabbedItems.Add("random_seed", fmt.Sprintf("%d", *options.RandomSeed))
+	}
+
+	if options.MaxTime != nil {
+		tabbedItems.Add("max_time", numbers.Float64Str(*options.MaxTime))
+	}
+
+	n.TabWrite(1, tabbedItems)
+	n.Writeln("")
+}
+
+func (n Native) writeChunkTypes() {
+	n.Writeln("chunks:")
+
+	for _, chunk := range n.model.Chunks {
+		if chunk.IsInternal() || chunk.BuiltIn {
+			continue
+		}
+
+		n.Writeln("\t[%s: %s]", chunk.TypeName, strings.Join(chunk.SlotNames, " "))
+	}
+
+	n.Writeln("")
+}
+
+func (n Native) writeInitializers(patterns framework.ParsedInitialBuffers) {
+	n.Writeln("memory:")
+
+	for _, init := range n.model.Initializers {
+		if init.Module != n.model.Memory {
+			continue
+		}
+
+		baseLevel := ""
+		if init.BaseLevel != nil {
+			baseLevel = fmt.Sprintf(" (references: %d, creation time: %s)", init.BaseLevel.References, numbers.Float64Str(init.BaseLevel.CreationTime))
+		}
+
+		if init.ChunkName != nil {
+			n.Writeln("\t%s %s%s", *init.ChunkName, init.Pattern, baseLevel)
+		} else {
+			n.Writeln("\t%s%s", init.Pattern, baseLevel)
+		}
+	}
+
+	n.Writeln("")
+
+	n.Writeln("buffers:")
+
+	for _, init := range n.model.Initializers {
+		if init.Module == n.model.Memory {
+			continue
+		}
+
+		bufferName := init.Buffer.Name()
+		if _, ok := patterns[bufferName]; ok {
+			continue
+		}
+
+		n.Writeln("\t%s %s", bufferName, init.Pattern)
+	}
+
+	bufferNames := maps.Keys(patterns)
+	slices.Sort(bufferNames)
+
+	for _, bufferName := range bufferNames {
+		n.Writeln("\t%s %s (set by user)", bufferName, patterns[bufferName])
+	}
+
+	n.Writeln("")
+}
+
+func (n Native) writeScreen() {
+	if len(n.model.Screen) == 0 {
+		return
+	}
+
+	n.Writeln("screen:")
+
+	for _, item := range n.model.Screen {
+		n.Writeln("\t'%s' at %d %d", item.Text, item.X, item.Y)
+	}
+
+	n.Writeln("")
+}
+
+func (n Native) writeSimilarities() {
+	if len(n.model.Similarities) == 0 {
+		return
+	}
+
+	n.Writeln("similarities:")
+
+	for _, similar := range n.model.Similarities {
+		n.Writeln("\t(%s %s %s)", similar.ChunkOne, similar.ChunkTwo, numbers.Float64Str(similar.Value))
+	}
+
+	n.Writeln("")
+}
+
+func (n Native) writeAssociations() {
+	if len(n.model.Associations) == 0 {
+		return
+	}
+
+	n.Writeln("associations:")
+
+	for _, association := range n.model.Associations {
+		n.Writeln("\t(%s %s %s)", association.Source, association.Target, numbers.Float64Str(association.Value))
+	}
+
+	n.Writeln("")
+}
+
+func (n Native) writeProductions() {
+	n.Writeln("productions:")
+
+	for _, production := range n.model.Productions {
+		n.Write("\t%s (amod line %d)", production.Name, production.AMODLineNumber)
+
+		if production.Utility != nil {
+			n.Write(" utility: %s", numbers.Float64Str(*production.Utility))
+		}
+
+		if production.Reward != nil {
+			n.Write(" reward: %s", numbers.Float64Str(*production.Reward))
+		}
+
+		n.Writeln("")
+	}
+}
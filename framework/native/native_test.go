@@ -0,0 +1,853 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/diff"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+func init() {
+	framework.GactarVersion = "test"
+	framework.TimeNow = func() time.Time {
+		return time.Time{}
+	}
+}
+
+func TestCodeGeneration(t *testing.T) {
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// determine input files
+	match, err := filepath.Glob("../testdata/*.amod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range match {
+		name := filepath.Base(input)
+		t.Run(name, func(t *testing.T) {
+			output := input[:len(input)-len(".amod")] + ".txt.golden"
+			output = filepath.Join("testdata", output)
+
+			code, err := framework.GenerateCodeFromFile(fw, input, runoptions.InitialBuffers{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			compareGolden(t, code, output)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// determine input files
+	match, err := filepath.Glob("../testdata/*.amod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range match {
+		name := filepath.Base(input)
+		t.Run(name, func(t *testing.T) {
+			output := input[:len(input)-len(".amod")] + ".run.golden"
+			output = filepath.Join("testdata", output)
+
+			amodCode, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			model, log, err := amod.GenerateModel(string(amodCode))
+			if err != nil {
+				t.Fatal(log)
+			}
+
+			err = fw.SetModel(model)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			seed := uint32(1)
+			options := model.DefaultParams
+			options.RandomSeed = &seed
+
+			result, err := fw.Run(context.Background(), &options, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compareGolden(t, result.Output, output)
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// determine input files
+	match, err := filepath.Glob("../testdata/*.amod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range match {
+		name := filepath.Base(input)
+		t.Run(name, func(t *testing.T) {
+			output := input[:len(input)-len(".amod")] + ".trace.golden"
+			output = filepath.Join("testdata", output)
+
+			amodCode, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			model, log, err := amod.GenerateModel(string(amodCode))
+			if err != nil {
+				t.Fatal(log)
+			}
+
+			err = fw.SetModel(model)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			seed := uint32(1)
+			traceEvents := true
+			options := model.DefaultParams
+			options.RandomSeed = &seed
+			options.TraceEvents = &traceEvents
+
+			result, err := fw.Run(context.Background(), &options, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Trace == nil {
+				t.Fatal("expected a trace")
+			}
+
+			trace, err := json.MarshalIndent(result.Trace, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compareGolden(t, trace, output)
+		})
+	}
+}
+
+func compareGolden(t *testing.T, result []byte, output string) { //nolint to avoid Helper info since it doesn't apply
+	expected, err := os.ReadFile(output)
+	if err != nil {
+		file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		_, err = file.WriteString(string(result))
+		if err != nil {
+			return
+		}
+
+		t.Skip("golden file did not exist, so I created it")
+		return
+	}
+
+	if !bytes.Equal(result, expected) {
+		diffs := diff.Diff(string(expected), string(result))
+		t.Errorf("output does not match %s file:\n%s", output, diffs)
+	}
+}
+
+// runAndCollectPrints runs the model with tracing turned on and returns the text of each print
+// statement along with the result of the run.
+func runAndCollectPrints(t *testing.T, src string) (printed []string, result *framework.RunResult) {
+	t.Helper()
+
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, log, err := amod.GenerateModel(src)
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	err = fw.SetModel(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed := uint32(1)
+	traceEvents := true
+	options := model.DefaultParams
+	options.RandomSeed = &seed
+	options.TraceEvents = &traceEvents
+
+	result, err = fw.Run(context.Background(), &options, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range result.Trace.Events {
+		if event.Type == framework.TracePrint {
+			printed = append(printed, event.Text)
+		}
+	}
+
+	return
+}
+
+// eventTimes returns the times of the trace events of this type.
+func eventTimes(result *framework.RunResult, eventType framework.TraceEventType) (times []float64) {
+	for _, event := range result.Trace.Events {
+		if event.Type == eventType {
+			times = append(times, event.Time)
+		}
+	}
+
+	return
+}
+
+func TestArithmetic(t *testing.T) {
+	_, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: arithmetic
+	~~ config ~~
+	chunks { [foo: a b c] }
+	~~ init ~~
+	goal [foo: 1 2 3]
+	~~ productions ~~
+	too_big {
+		match { goal [foo: ?a * *] when (?a > 1) }
+		do { stop }
+	}
+	calculate {
+		match { goal [foo: ?a ?b ?c] when (?a >= 1) and (?b < ?c) }
+		do {
+			set goal.a to (?a + ?b) * 2 / ?c
+			set goal.b to ?b -1
+			set goal.c to ?a - (?b - ?c) - 2 * -1
+			stop
+		}
+	}`)
+
+	var fired []string
+	var chunk string
+	for _, event := range result.Trace.Events {
+		switch event.Type {
+		case framework.TraceProductionFired:
+			fired = append(fired, event.Production)
+		case framework.TraceBufferSet:
+			chunk = event.Chunk
+		}
+	}
+
+	if len(fired) != 1 || fired[0] != "calculate" {
+		t.Errorf("expected only 'calculate' to fire - got %v", fired)
+	}
+
+	expected := "[foo: 2 1 4]"
+	if chunk != expected {
+		t.Errorf("expected goal to be %s - got %s", expected, chunk)
+	}
+}
+
+func TestArithmeticError(t *testing.T) {
+	_, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: arithmetic_error
+	~~ config ~~
+	chunks { [foo: a b c] }
+	~~ init ~~
+	goal [foo: 1 0 3]
+	~~ productions ~~
+	divide {
+		match { goal [foo: * ?b ?c] }
+		do {
+			set goal.a to ?c / ?b
+			set goal.c to 4
+			stop
+		}
+	}`)
+
+	var chunk string
+	for _, event := range result.Trace.Events {
+		if event.Type == framework.TraceBufferSet {
+			chunk = event.Chunk
+		}
+	}
+
+	expected := "[foo: 1 0 3]"
+	if chunk != expected {
+		t.Errorf("expected goal to be unchanged (%s) - got %s", expected, chunk)
+	}
+}
+
+func TestLongRun(t *testing.T) {
+	// more cycles than maxStalledCycles, but time advances so we run until max_time
+	printed, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: long_run
+	~~ config ~~
+	gactar { max_time: 1000 }
+	chunks { [count: value] }
+	~~ init ~~
+	goal [count: 0]
+	~~ productions ~~
+	increment {
+		match { goal [count: ?v] when (?v < 15000) }
+		do { set goal.value to ?v + 1 }
+	}
+	done {
+		match { goal [count: ?v] when (?v >= 15000) }
+		do {
+			print ?v
+			stop
+		}
+	}`)
+
+	if len(printed) != 1 || printed[0] != "15000" {
+		t.Errorf("expected model to count to 15000 - got %v", printed)
+	}
+
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected no warnings - got %v", result.Warnings)
+	}
+}
+
+func TestStalledRun(t *testing.T) {
+	_, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: stalled
+	~~ config ~~
+	modules {
+		procedural { default_action_time: 0 }
+	}
+	chunks { [task: state] }
+	~~ init ~~
+	goal [task: loop]
+	~~ productions ~~
+	loop {
+		match { goal [task: loop] }
+		do { set goal.state to loop }
+	}`)
+
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "time did not advance") {
+		t.Errorf("expected warning about time not advancing - got %v", result.Warnings)
+	}
+}
+
+func TestVisual(t *testing.T) {
+	_, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: visual
+	~~ config ~~
+	modules {
+		visual {}
+		motor {}
+	}
+	chunks { [task: state] }
+	~~ init ~~
+	goal [task: find]
+	screen {
+		text 'B' at 200 150
+		text 'A' at 100 150
+	}
+	~~ productions ~~
+	find_letter {
+		match {
+			goal [task: find]
+			buffer_state visual_location empty
+		}
+		do {
+			set goal.state to look
+			find_location [visual_location: lowest *] with (attended nil)
+		}
+	}
+	attend_letter {
+		match {
+			goal [task: look]
+			visual_location [visual_location: * *]
+			module_state visual free
+		}
+		do {
+			set goal.state to encode
+			attend
+		}
+	}
+	type_letter {
+		match {
+			goal [task: encode]
+			visual [visual_object: ?letter]
+		}
+		do {
+			set goal.state to find
+			press_key ?letter
+			clear visual
+		}
+	}`)
+
+	var keys []string
+	for _, line := range strings.Split(string(result.Output), "\n") {
+		_, key, found := strings.Cut(line, "press key: ")
+		if found {
+			keys = append(keys, key)
+		}
+	}
+
+	expected := []string{"A", "B"}
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("expected keys %v to be pressed - got %v", expected, keys)
+	}
+}
+
+func TestVisualInvalidLocation(t *testing.T) {
+	_, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: visual_invalid
+	~~ config ~~
+	modules {
+		visual {}
+	}
+	chunks { [task: state] }
+	~~ init ~~
+	goal [task: start]
+	~~ productions ~~
+	start {
+		match { goal [task: start] }
+		do {
+			set goal.state to look
+			set visual_location to [visual_location: 'left' 'top']
+		}
+	}
+	look {
+		match {
+			goal [task: look]
+			visual_location [visual_location: * *]
+			module_state visual free
+		}
+		do {
+			set goal.state to done
+			attend
+		}
+	}`)
+
+	if !strings.Contains(string(result.Output), "cannot attend") {
+		t.Errorf("expected attend error in output - got:\n%s", result.Output)
+	}
+
+	for _, event := range result.Trace.Events {
+		if event.Type == framework.TraceBufferSet && event.Buffer == "visual" {
+			t.Errorf("expected visual buffer to be empty - got %s", event.Chunk)
+		}
+	}
+}
+
+func TestTemporal(t *testing.T) {
+	printed, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: temporal
+	~~ config ~~
+	modules {
+		temporal { time_noise: 0.0 }
+	}
+	chunks { [task: state] }
+	~~ init ~~
+	goal [task: start]
+	~~ productions ~~
+	start {
+		match { goal [task: start] }
+		do {
+			set goal.state to wait
+			start_timer
+		}
+	}
+	done {
+		match {
+			goal [task: wait]
+			temporal [time: ?ticks] when (?ticks >= 5)
+		}
+		do {
+			print ?ticks
+			stop
+		}
+	}`)
+
+	if len(printed) != 1 || printed[0] != "5" {
+		t.Errorf("expected only '5' to be printed - got %v", printed)
+	}
+
+	// Without noise, the fifth tick is at 0.05 + 0.011 * (1 + 1.1 + 1.1^2 + 1.1^3 + 1.1^4) = 0.117
+	// and the production fires one action time later. Trace times are rounded to milliseconds.
+	expected := 0.167
+	if stopTimes := eventTimes(result, framework.TraceStop); len(stopTimes) != 1 || math.Abs(stopTimes[0]-expected) > 0.0005 {
+		t.Errorf("expected model to stop at %v - got %v", expected, stopTimes)
+	}
+}
+
+func TestBlend(t *testing.T) {
+	printed, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: blend
+	~~ config ~~
+	modules { blending {} }
+	chunks {
+		[object: kind size]
+		[task: state]
+	}
+	~~ init ~~
+	memory {
+		[object: medium 10]
+		[object: medium 12]
+		[object: medium 20]
+		[object: large 50]
+	}
+	goal [task: start]
+	~~ productions ~~
+	start {
+		match { goal [task: start] }
+		do {
+			set goal.state to estimate
+			blend [object: medium *]
+		}
+	}
+	estimated {
+		match {
+			goal [task: estimate]
+			blending [object: medium ?size]
+		}
+		do {
+			print ?size
+			stop
+		}
+	}`)
+
+	// Without noise or base-level learning all the medium objects have the same activation (0),
+	// so the blended size is their mean.
+	if len(printed) != 1 || printed[0] != "14" {
+		t.Errorf("expected only '14' to be printed - got %v", printed)
+	}
+
+	// The blend takes exp( -ln(3) ) = 0.333 and the productions take 0.05 each.
+	expected := 0.433
+	if stopTimes := eventTimes(result, framework.TraceStop); len(stopTimes) != 1 || math.Abs(stopTimes[0]-expected) > 0.0005 {
+		t.Errorf("expected model to stop at %v - got %v", expected, stopTimes)
+	}
+}
+
+func TestRememberAndReinforce(t *testing.T) {
+	printed, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: remember
+	~~ config ~~
+	modules {
+		memory {
+			decay: 0.5
+			max_spread_strength: 1.0
+		}
+	}
+	chunks {
+		[fact: name value]
+		[task: state]
+	}
+	~~ init ~~
+	memory {
+		a [fact: a 1]
+		b [fact: b 2] with (references 10) and (creation_time -100)
+	}
+	goal [task: start]
+	~~ productions ~~
+	start {
+		match { goal [task: start] }
+		do {
+			reinforce a
+			remember [fact: c 3]
+			set goal.state to retrieve
+		}
+	}
+	retrieve {
+		match { goal [task: retrieve] }
+		do {
+			recall [fact: * *]
+			set goal.state to retrieve_b
+		}
+	}
+	retrieve_b {
+		match {
+			goal [task: retrieve_b]
+			retrieval [fact: ?name *]
+		}
+		do {
+			print ?name
+			recall [fact: b *]
+			set goal.state to retrieve_c
+		}
+	}
+	retrieve_c {
+		match {
+			goal [task: retrieve_c]
+			retrieval [fact: b ?value]
+		}
+		do {
+			print ?value
+			recall [fact: c *]
+			set goal.state to done
+		}
+	}
+	done {
+		match {
+			goal [task: done]
+			retrieval [fact: c ?value]
+		}
+		do {
+			print ?value
+			stop
+		}
+	}`)
+
+	// Without the reinforcement, "c" (which was remembered more recently) would have a
+	// higher activation than "a".
+	if len(printed) != 3 || printed[0] != "a" || printed[1] != "2" || printed[2] != "3" {
+		t.Fatalf("expected 'a', '2', and '3' to be printed - got %v", printed)
+	}
+
+	// "b" is retrieved at 0.281 using its ten references from -100 to -10:
+	// 	B = ln( Σ t_j ^ -0.5 ) = 0.457 so the retrieval takes exp( -0.457 ) = 0.633
+	expected := 0.964
+	if printTime := eventTimes(result, framework.TracePrint)[1]; math.Abs(printTime-expected) > 0.0005 {
+		t.Errorf("expected '2' to be printed at %v - got %v", expected, printTime)
+	}
+}
+
+func TestAssociations(t *testing.T) {
+	printed, result := runAndCollectPrints(t, `
+	~~ model ~~
+	name: associations
+	~~ config ~~
+	modules {
+		memory { max_spread_strength: 1.0 }
+		goal { goal { spreading_activation: 1.0 } }
+	}
+	chunks {
+		[fact: location person]
+		[probe: item state]
+	}
+	~~ init ~~
+	memory {
+		p1 [fact: park doctor]
+		p2 [fact: bank lawyer]
+	}
+	goal [probe: park start]
+	associations {
+		( park p2 2.0 )
+	}
+	~~ productions ~~
+	start {
+		match { goal [probe: * start] }
+		do {
+			recall [fact: * *]
+			set goal.state to wait
+		}
+	}
+	retrieved {
+		match {
+			goal [probe: * wait]
+			retrieval [fact: * ?person]
+		}
+		do {
+			print ?person
+			stop
+		}
+	}`)
+
+	// Without the association, "p1" would get the spreading activation from "park".
+	if len(printed) != 1 || printed[0] != "lawyer" {
+		t.Fatalf("expected only 'lawyer' to be printed - got %v", printed)
+	}
+
+	// The goal has two sources (park & start) so "p2" gets 0.5 * 2.0 = 1.0 and
+	// the retrieval takes exp( -1.0 ) = 0.368.
+	expected := 0.468
+	if printTime := eventTimes(result, framework.TracePrint)[0]; math.Abs(printTime-expected) > 0.0005 {
+		t.Errorf("expected 'lawyer' to be printed at %v - got %v", expected, printTime)
+	}
+}
+
+// testSink collects what is streamed to it
+type testSink struct {
+	lines  []string
+	events []framework.TraceEvent
+}
+
+func (s *testSink) OutputLine(line string) {
+	s.lines = append(s.lines, line)
+}
+
+func (s *testSink) TraceEvent(event framework.TraceEvent) {
+	s.events = append(s.events, event)
+}
+
+func TestRunStreamsOutput(t *testing.T) {
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, log, err := amod.GenerateModel(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [foo: a] }
+	~~ init ~~
+	goal [foo: 1]
+	~~ productions ~~
+	start {
+		match { goal [foo: ?a] }
+		do {
+			print ?a
+			stop
+		}
+	}`)
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	err = fw.SetModel(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed := uint32(1)
+	traceEvents := true
+	options := model.DefaultParams
+	options.RandomSeed = &seed
+	options.TraceEvents = &traceEvents
+
+	sink := &testSink{}
+
+	result, err := fw.Run(context.Background(), &options, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamed := strings.Join(sink.lines, "\n") + "\n"
+	if streamed != string(result.Output) {
+		t.Errorf("expected streamed output to match result:\n%s\ngot:\n%s", result.Output, streamed)
+	}
+
+	if len(sink.events) != len(result.Trace.Events) {
+		t.Errorf("expected %d streamed events - got %d", len(result.Trace.Events), len(sink.events))
+	}
+}
+
+// loopModel has a production which fires forever
+const loopModel = `
+	~~ model ~~
+	name: loop
+	~~ config ~~
+	gactar { max_time: %s }
+	chunks { [count: value] }
+	~~ init ~~
+	goal [count: 0]
+	~~ productions ~~
+	loop {
+		match { goal [count: ?value] }
+		do { set goal.value to ?value }
+	}`
+
+func TestMaxTime(t *testing.T) {
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, log, err := amod.GenerateModel(fmt.Sprintf(loopModel, "0.52"))
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	err = fw.SetModel(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceEvents := true
+	options := model.DefaultParams
+	options.TraceEvents = &traceEvents
+
+	result, err := fw.Run(context.Background(), &options, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// default_action_time is 0.05, so we should fire 10 times before 0.52 seconds
+	fired := 0
+	for _, event := range result.Trace.Events {
+		if event.Type == framework.TraceProductionFired {
+			fired++
+		}
+	}
+
+	if fired != 10 {
+		t.Errorf("expected 'loop' to fire 10 times - got %d", fired)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	fw, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, log, err := amod.GenerateModel(fmt.Sprintf(loopModel, "1000"))
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	options := model.DefaultParams
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = framework.RunModel(ctx, model, &options, fw, nil)
+	if !errors.Is(err, framework.ErrRunCancelled) {
+		t.Errorf("expected ErrRunCancelled - got %v", err)
+	}
+
+	timeout := time.Nanosecond
+	options.Timeout = &timeout
+
+	_, err = framework.RunModel(context.Background(), model, &options, fw, nil)
+	if !errors.Is(err, framework.ErrRunTimedOut) {
+		t.Errorf("expected ErrRunTimedOut - got %v", err)
+	}
+}
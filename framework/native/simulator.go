@@ -0,0 +1,891 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+const (
+	// maxRunTime is the simulated time (seconds) after which we stop the run if the model does
+	// not set max_time. This matches the "(run 10.0)" used for vanilla.
+	maxRunTime = 10.0
+
+	// maxStalledCycles protects us from models which never advance time (e.g. default_action_time
+	// of 0). It is the number of cycles in a row we allow without simulated time advancing.
+	maxStalledCycles = 10000
+
+	defaultActionTime = 0.05
+)
+
+// traceLevel is used to decide what to output for each of the ACT-R logging levels.
+type traceLevel int
+
+const (
+	traceMin traceLevel = iota
+	traceInfo
+	traceDetail
+)
+
+// pendingRetrieval is a retrieval request which completes at a specific time.
+type pendingRetrieval struct {
+	result retrievalResult
+	time   float64
+}
+
+// simulator runs an actr.Model.
+type simulator struct {
+	model   *actr.Model
+	options *runoptions.Options
+
+	traceLevel       traceLevel
+	traceActivations bool
+
+	maxTime float64 // simulated time (seconds) after which we stop the run
+
+	random     *rand.Rand
+	memory     *declarativeMemory
+	procedural *procedural
+
+	// buffer contents by buffer name (nil if the buffer is empty)
+	buffers map[string]*chunk
+
+	retrieval   *pendingRetrieval
+	memoryError bool // set if the last retrieval failed
+
+	blending      *pendingBlend
+	blendingError bool // set if the last blend failed
+
+	visual   *visual
+	temporal *temporal
+
+	time    float64
+	stopped bool
+
+	output   bytes.Buffer
+	events   *framework.Trace // nil if we are not collecting events
+	warnings []string         // problems which stopped the run early (reported in the run's log)
+
+	sink framework.OutputSink // nil if we are not streaming output
+}
+
+func newSimulator(model *actr.Model, options *runoptions.Options, initialBuffers framework.ParsedInitialBuffers, sink framework.OutputSink) *simulator {
+	seed := time.Now().UnixNano()
+	if options.RandomSeed != nil {
+		seed = int64(*options.RandomSeed)
+	}
+
+	random := rand.New(rand.NewSource(seed)) //nolint:gosec // we don't need crypto-level random numbers
+
+	s := &simulator{
+		model:      model,
+		options:    options,
+		random:     random,
+		memory:     newDeclarativeMemory(model, random),
+		procedural: newProcedural(model, random),
+		buffers:    map[string]*chunk{},
+		visual:     newVisual(model),
+		temporal:   newTemporal(model, random),
+		sink:       sink,
+		maxTime:    maxRunTime,
+	}
+
+	if options.MaxTime != nil {
+		s.maxTime = *options.MaxTime
+	}
+
+	if options.LogLevel != nil {
+		switch *options.LogLevel {
+		case "min":
+			s.traceLevel = traceMin
+		case "info":
+			s.traceLevel = traceInfo
+		case "detail":
+			s.traceLevel = traceDetail
+		}
+	}
+
+	if options.TraceActivations != nil {
+		s.traceActivations = *options.TraceActivations
+	}
+
+	if options.IsTracingEvents() {
+		s.events = &framework.Trace{Events: []framework.TraceEvent{}}
+	}
+
+	for _, bufferName := range model.BufferNames() {
+		s.buffers[bufferName] = nil
+	}
+
+	s.initialize(initialBuffers)
+
+	return s
+}
+
+// initialize sets up memory and the buffers from the model's initializers.
+// Buffers set by the user override the model's initializers.
+func (s *simulator) initialize(initialBuffers framework.ParsedInitialBuffers) {
+	for _, init := range s.model.Initializers {
+		c := chunkFromPattern(init.Pattern, bindings{})
+		if init.ChunkName != nil {
+			c.name = *init.ChunkName
+		}
+
+		if init.Module == s.model.Memory {
+			if init.BaseLevel != nil {
+				s.memory.addWithBaseLevel(c, init.BaseLevel, 0.0)
+			} else {
+				s.memory.add(c, 0.0)
+			}
+			continue
+		}
+
+		bufferName := init.Buffer.Name()
+		if _, ok := initialBuffers[bufferName]; ok {
+			continue
+		}
+
+		s.setBuffer(bufferName, c)
+	}
+
+	for bufferName, pattern := range initialBuffers {
+		s.setBuffer(bufferName, chunkFromPattern(pattern, bindings{}))
+	}
+}
+
+// run runs the model until it stops, runs out of things to do, or reaches the time limit.
+// If "ctx" is cancelled, it stops and returns the context's error.
+func (s *simulator) run(ctx context.Context) ([]byte, *framework.Trace, error) {
+	reason := ""
+
+	lastTime := s.time
+	stalled := 0
+
+	for {
+		if ctx.Err() != nil {
+			s.trace(traceMin, "------", "stopped: run cancelled")
+			return s.output.Bytes(), s.events, ctx.Err()
+		}
+
+		if s.time > lastTime {
+			lastTime = s.time
+			stalled = 0
+		} else {
+			stalled++
+		}
+
+		if stalled > maxStalledCycles {
+			reason = fmt.Sprintf("time did not advance for %d cycles", maxStalledCycles)
+			s.warnings = append(s.warnings, fmt.Sprintf("run stopped at %s: %s", numbers.Float64Str(s.time), reason))
+			break
+		}
+
+		production, b := s.selectProduction()
+		if production != nil {
+			fireTime := s.time + s.actionTime()
+			if fireTime > s.maxTime {
+				reason = "time limit reached"
+				break
+			}
+
+			// Anything which completes while the production is firing happens first
+			for {
+				eventTime, ok := s.nextEventTime()
+				if !ok || eventTime >= fireTime {
+					break
+				}
+
+				s.time = eventTime
+				s.completeNextEvent()
+			}
+
+			s.procedural.selected(production, s.time)
+
+			s.time = fireTime
+			s.fire(production, b)
+
+			if s.stopped {
+				reason = "stop requested"
+				break
+			}
+
+			continue
+		}
+
+		eventTime, ok := s.nextEventTime()
+		if !ok {
+			reason = "no productions match and no events left to run"
+			break
+		}
+
+		if eventTime > s.maxTime {
+			reason = "time limit reached"
+			break
+		}
+
+		s.time = eventTime
+		s.completeNextEvent()
+	}
+
+	s.trace(traceMin, "------", "stopped: %s", reason)
+
+	return s.output.Bytes(), s.events, nil
+}
+
+// moduleEvent is a kind of pending module event
+type moduleEvent int
+
+const (
+	noEvent moduleEvent = iota
+	retrievalEvent
+	blendEvent
+	attentionEvent
+	tickEvent
+)
+
+// nextEvent returns the earliest pending module event (retrieval, blend, attention, or timer tick)
+// and its time. If there are none, it returns noEvent.
+func (s simulator) nextEvent() (event moduleEvent, eventTime float64) {
+	if s.retrieval != nil {
+		event, eventTime = retrievalEvent, s.retrieval.time
+	}
+
+	if s.blending != nil && (event == noEvent || s.blending.time < eventTime) {
+		event, eventTime = blendEvent, s.blending.time
+	}
+
+	if s.visual.attention != nil && (event == noEvent || s.visual.attention.time < eventTime) {
+		event, eventTime = attentionEvent, s.visual.attention.time
+	}
+
+	if s.temporal.running && (event == noEvent || s.temporal.nextTick < eventTime) {
+		event, eventTime = tickEvent, s.temporal.nextTick
+	}
+
+	return
+}
+
+// nextEventTime returns the time of the earliest pending module event.
+// If there are none, ok will be false.
+func (s simulator) nextEventTime() (eventTime float64, ok bool) {
+	event, eventTime := s.nextEvent()
+
+	return eventTime, event != noEvent
+}
+
+// completeNextEvent completes the earliest pending module event.
+func (s *simulator) completeNextEvent() {
+	event, _ := s.nextEvent()
+
+	switch event {
+	case retrievalEvent:
+		s.completeRetrieval()
+
+	case blendEvent:
+		s.completeBlend()
+
+	case attentionEvent:
+		s.completeAttention()
+
+	case tickEvent:
+		s.completeTick()
+	}
+}
+
+func (s simulator) actionTime() float64 {
+	if s.model.Procedural.DefaultActionTime != nil {
+		return *s.model.Procedural.DefaultActionTime
+	}
+
+	return defaultActionTime
+}
+
+// selectProduction performs conflict resolution. If the model uses utilities, the matching
+// production with the highest utility (plus noise) is selected. Otherwise (or if there is a
+// tie) the first production (in the order they are declared) which matches is selected.
+func (s simulator) selectProduction() (selected *actr.Production, selectedBindings bindings) {
+	usingUtilities := s.procedural.isUsingUtilities(s.model)
+	bestUtility := 0.0
+
+	for _, production := range s.model.Productions {
+		b, ok := s.matchProduction(production)
+		if !ok {
+			continue
+		}
+
+		if !usingUtilities {
+			return production, b
+		}
+
+		utility := s.procedural.utility(production) + s.procedural.noise()
+
+		s.trace(traceDetail, "procedural", "production %s matches with utility %s", production.Name, numbers.Float64Str(round(utility)))
+
+		if selected == nil || utility > bestUtility {
+			selected = production
+			selectedBindings = b
+			bestUtility = utility
+		}
+	}
+
+	return
+}
+
+// matchProduction checks if the production's match section matches the current state.
+func (s simulator) matchProduction(production *actr.Production) (bindings, bool) {
+	b := bindings{}
+
+	type patternMatch struct {
+		pattern *actr.Pattern
+		chunk   *chunk
+	}
+
+	patterns := []patternMatch{}
+
+	for _, match := range production.Matches {
+		switch {
+		case match.BufferPattern != nil:
+			c := s.buffers[match.BufferPattern.Buffer.Name()]
+			if c == nil {
+				return nil, false
+			}
+
+			pattern := match.BufferPattern.Pattern
+			if !matchType(pattern, c) {
+				return nil, false
+			}
+
+			patterns = append(patterns, patternMatch{pattern, c})
+
+		default:
+			if match.BufferState != nil && !s.matchBufferState(match.BufferState) {
+				return nil, false
+			}
+
+			if match.ModuleState != nil && !s.matchModuleState(match.ModuleState) {
+				return nil, false
+			}
+		}
+	}
+
+	// Bind all the variables first, then check negated variables and constraints.
+	for _, pm := range patterns {
+		if !matchSlots(pm.pattern, pm.chunk, b, false) {
+			return nil, false
+		}
+	}
+
+	for _, pm := range patterns {
+		if !matchSlots(pm.pattern, pm.chunk, b, true) {
+			return nil, false
+		}
+
+		if !checkConstraints(pm.pattern, b) {
+			return nil, false
+		}
+	}
+
+	return b, true
+}
+
+func (s simulator) matchBufferState(match *actr.BufferStateMatch) bool {
+	full := s.buffers[match.Buffer.Name()] != nil
+
+	switch match.State {
+	case "empty":
+		return !full
+	case "full":
+		return full
+	}
+
+	return false
+}
+
+func (s simulator) matchModuleState(match *actr.ModuleStateMatch) bool {
+	state := "free"
+
+	switch match.Module.ModuleName() {
+	case s.model.Memory.ModuleName():
+		switch {
+		case s.retrieval != nil:
+			state = "busy"
+		case s.memoryError:
+			state = "error"
+		}
+
+	case "blending":
+		switch {
+		case s.blending != nil:
+			state = "busy"
+		case s.blendingError:
+			state = "error"
+		}
+
+	case "visual":
+		switch {
+		case s.visual.attention != nil:
+			state = "busy"
+		case s.visual.err:
+			state = "error"
+		}
+	}
+
+	return match.State == state
+}
+
+// fire executes the production's do statements and then clears any buffers which
+// were matched but not modified (strict harvesting).
+func (s *simulator) fire(production *actr.Production, b bindings) {
+	s.trace(traceMin, "procedural", "production fired: %s", production.Name)
+	s.addEvent(framework.TraceEvent{Type: framework.TraceProductionFired, Production: production.Name})
+
+	modified := map[string]bool{}
+
+	for _, statement := range production.DoStatements {
+		switch {
+		case statement.Set != nil:
+			modified[statement.Set.Buffer.Name()] = true
+			s.set(statement.Set, b)
+
+		case statement.Recall != nil:
+			modified[s.model.Memory.BufferName()] = true
+			s.recall(statement.Recall, b)
+
+		case statement.Blend != nil:
+			modified["blending"] = true
+			s.blend(statement.Blend, b)
+
+		case statement.Remember != nil:
+			s.remember(statement.Remember, b)
+
+		case statement.Reinforce != nil:
+			s.reinforce(statement.Reinforce, b)
+
+		case statement.Clear != nil:
+			for _, bufferName := range statement.Clear.BufferNames {
+				modified[bufferName] = true
+				s.clearBuffer(bufferName)
+			}
+
+		case statement.Print != nil:
+			s.print(statement.Print, b)
+
+		case statement.FindLocation != nil:
+			modified["visual_location"] = true
+			s.findLocation(statement.FindLocation, b)
+
+		case statement.Attend != nil:
+			modified["visual"] = true
+			s.attend()
+
+		case statement.PressKey != nil:
+			s.trace(traceInfo, "motor", "press key: %s", actrValue(statement.PressKey.Key, b))
+
+		case statement.Punch != nil:
+			s.trace(traceInfo, "motor", "punch: %s %s", statement.Punch.Hand, statement.Punch.Finger)
+
+		case statement.StartTimer != nil:
+			modified["temporal"] = true
+			s.startTimer()
+
+		case statement.Stop != nil:
+			s.stopped = true
+			s.addEvent(framework.TraceEvent{Type: framework.TraceStop})
+		}
+	}
+
+	if production.Reward != nil {
+		s.trace(traceInfo, "utility", "reward %s triggered by %s", numbers.Float64Str(*production.Reward), production.Name)
+		s.procedural.reward(*production.Reward, s.time, func(format string, a ...any) {
+			s.trace(traceInfo, "utility", format, a...)
+		})
+	}
+
+	// We don't harvest the imaginal buffer to match what we do in vanilla (":do-not-harvest imaginal").
+	// Vanilla's temporal module also adds its buffer to :do-not-harvest so the timer keeps running.
+	imaginal := s.model.ImaginalModule()
+	temporal := s.model.TemporalModule()
+
+	for _, match := range production.Matches {
+		if match.BufferPattern == nil {
+			continue
+		}
+
+		bufferName := match.BufferPattern.Buffer.Name()
+		if modified[bufferName] ||
+			(imaginal != nil && imaginal.Buffers().Has(bufferName)) ||
+			(temporal != nil && temporal.Buffers().Has(bufferName)) {
+			continue
+		}
+
+		s.clearBuffer(bufferName)
+	}
+}
+
+func (s *simulator) set(statement *actr.SetStatement, b bindings) {
+	bufferName := statement.Buffer.Name()
+
+	if statement.Pattern != nil {
+		s.setBuffer(bufferName, chunkFromPattern(statement.Pattern, b))
+		return
+	}
+
+	c := s.buffers[bufferName]
+	if c == nil {
+		s.trace(traceMin, bufferName, "cannot set slots: buffer is empty")
+		return
+	}
+
+	c = c.copy()
+	for _, slot := range *statement.Slots {
+		index := slot.SlotIndex - 1 // SlotIndex is 1-based
+		if index < 0 || index >= len(c.slots) {
+			continue
+		}
+
+		if slot.Value.Expr == nil {
+			c.slots[index] = actrValue(slot.Value, b)
+			continue
+		}
+
+		v, err := evaluate(slot.Value.Expr, b)
+		if err != nil {
+			s.trace(traceMin, bufferName, "cannot set %s to %s: %v", slot.Name, slot.Value.Expr, err)
+			return
+		}
+
+		c.slots[index] = v
+	}
+
+	s.buffers[bufferName] = c
+	s.trace(traceDetail, bufferName, "modified: %s", c)
+	s.addEvent(framework.TraceEvent{Type: framework.TraceBufferSet, Buffer: bufferName, Chunk: c.String()})
+}
+
+func (s *simulator) setBuffer(bufferName string, c *chunk) {
+	s.buffers[bufferName] = c
+	s.trace(traceDetail, bufferName, "set buffer chunk: %s", c)
+	s.addEvent(framework.TraceEvent{Type: framework.TraceBufferSet, Buffer: bufferName, Chunk: c.String()})
+}
+
+// clearBuffer empties the buffer and merges its chunk into memory.
+func (s *simulator) clearBuffer(bufferName string) {
+	c := s.buffers[bufferName]
+	if c == nil {
+		return
+	}
+
+	s.buffers[bufferName] = nil
+	s.memory.add(c, s.time)
+
+	s.trace(traceDetail, bufferName, "cleared")
+}
+
+// remember adds a chunk to memory (or adds a reference to an existing chunk with the same contents).
+func (s *simulator) remember(statement *actr.RememberStatement, b bindings) {
+	c := chunkFromPattern(statement.Pattern, b)
+	s.memory.add(c, s.time)
+
+	s.trace(traceDetail, "memory", "remembered chunk: %s", c)
+}
+
+// reinforce adds a reference to a named chunk in memory.
+func (s *simulator) reinforce(statement *actr.ReinforceStatement, b bindings) {
+	name := actrValue(statement.Chunk, b).String()
+
+	if !s.memory.reinforce(name, s.time) {
+		s.trace(traceInfo, "memory", "cannot reinforce '%s': chunk not found in memory", name)
+		return
+	}
+
+	s.trace(traceDetail, "memory", "reinforced chunk: %s", name)
+}
+
+// recall starts a retrieval request. The result is calculated now, but it does not appear
+// in the buffer until the retrieval time has elapsed.
+func (s *simulator) recall(statement *actr.RecallStatement, b bindings) {
+	bufferName := s.model.Memory.BufferName()
+
+	s.clearBuffer(bufferName)
+	s.memoryError = false
+
+	request := patternString(statement.Pattern, b)
+	s.trace(traceInfo, "memory", "retrieval request: %s", request)
+	s.addEvent(framework.TraceEvent{Type: framework.TraceRetrievalStarted, Buffer: bufferName, Chunk: request})
+
+	var activationTrace func(format string, a ...any)
+	if s.traceActivations {
+		activationTrace = func(format string, a ...any) {
+			s.trace(traceMin, "memory", format, a...)
+		}
+	}
+
+	result := s.memory.retrieve(statement.Pattern, b, statement.RequestParameters, s.spreadingSources(), s.time, activationTrace)
+
+	s.retrieval = &pendingRetrieval{
+		result: result,
+		time:   s.time + result.latency,
+	}
+}
+
+// completeRetrieval puts the result of the pending retrieval into the buffer.
+func (s *simulator) completeRetrieval() {
+	result := s.retrieval.result
+	s.retrieval = nil
+
+	if result.memChunk == nil {
+		s.memoryError = true
+		s.trace(traceInfo, "memory", "retrieval failure")
+		s.addEvent(framework.TraceEvent{Type: framework.TraceRetrievalFailed, Buffer: s.model.Memory.BufferName()})
+		return
+	}
+
+	s.memory.addFinst(result.memChunk, s.time)
+
+	c := result.memChunk.chunk.copy()
+	s.buffers[s.model.Memory.BufferName()] = c
+
+	s.trace(traceInfo, "memory", "retrieved chunk: %s", c)
+	s.addEvent(framework.TraceEvent{Type: framework.TraceRetrievalSucceeded, Buffer: s.model.Memory.BufferName(), Chunk: c.String()})
+}
+
+// blend starts a blend request. Like recall, the result is calculated now, but it does not appear
+// in the buffer until the retrieval time has elapsed.
+func (s *simulator) blend(statement *actr.BlendStatement, b bindings) {
+	s.clearBuffer("blending")
+	s.blendingError = false
+
+	s.trace(traceInfo, "blending", "blend request: %s", patternString(statement.Pattern, b))
+
+	var activationTrace func(format string, a ...any)
+	if s.traceActivations {
+		activationTrace = func(format string, a ...any) {
+			s.trace(traceMin, "blending", format, a...)
+		}
+	}
+
+	temperature := blendTemperature(s.model.BlendingModule(), s.model.Memory)
+	result := s.memory.blend(statement.Pattern, b, temperature, s.spreadingSources(), s.time, activationTrace)
+
+	s.blending = &pendingBlend{
+		result: result,
+		time:   s.time + result.latency,
+	}
+}
+
+// completeBlend puts the result of the pending blend into the buffer.
+func (s *simulator) completeBlend() {
+	result := s.blending.result
+	s.blending = nil
+
+	if result.chunk == nil {
+		s.blendingError = true
+		s.trace(traceInfo, "blending", "blend failure")
+		return
+	}
+
+	s.trace(traceInfo, "blending", "blended chunk: %s", result.chunk)
+	s.setBuffer("blending", result.chunk)
+}
+
+// findLocation looks for an item on the screen and puts its location in the visual_location buffer.
+// Unlike retrievals, this happens immediately.
+func (s *simulator) findLocation(statement *actr.FindLocationStatement, b bindings) {
+	s.clearBuffer("visual_location")
+	s.visual.err = false
+
+	request := patternString(statement.Pattern, b)
+	s.trace(traceInfo, "visual", "find location: %s", request)
+
+	item := s.visual.find(statement.Pattern, b, statement.RequestParameters)
+	if item == nil {
+		s.visual.err = true
+		s.trace(traceInfo, "visual", "find location failure")
+		return
+	}
+
+	s.setBuffer("visual_location", locationChunk(s.model, item))
+}
+
+// attend moves attention to the location in the visual_location buffer. The object at that
+// location appears in the visual buffer once the attention latency has elapsed.
+func (s *simulator) attend() {
+	s.clearBuffer("visual")
+	s.visual.err = false
+
+	location := s.buffers["visual_location"]
+	if location == nil {
+		return
+	}
+
+	s.trace(traceInfo, "visual", "move attention: %s", location)
+
+	if len(location.slots) < 2 || location.slots[0].kind != kindNumber || location.slots[1].kind != kindNumber {
+		s.visual.err = true
+		s.trace(traceMin, "visual", "cannot attend: %s is not a screen location", location)
+		return
+	}
+
+	s.visual.attention = &pendingAttention{
+		item: s.visual.itemAt(location.slots[0].num, location.slots[1].num),
+		time: s.time + s.visual.attentionLatency(),
+	}
+}
+
+// completeAttention puts the attended object into the visual buffer.
+func (s *simulator) completeAttention() {
+	item := s.visual.attention.item
+	s.visual.attention = nil
+
+	if item == nil {
+		s.visual.err = true
+		s.trace(traceInfo, "visual", "nothing to attend at location")
+		return
+	}
+
+	item.attended = true
+
+	s.setBuffer("visual", objectChunk(s.model, item))
+}
+
+// startTimer starts a new timer and puts its time chunk in the temporal buffer.
+func (s *simulator) startTimer() {
+	s.clearBuffer("temporal")
+
+	s.temporal.start(s.time)
+
+	s.trace(traceInfo, "temporal", "start timer")
+	s.setBuffer("temporal", s.temporal.timeChunk())
+}
+
+// completeTick increments the ticks of the time chunk in the temporal buffer.
+// If the buffer has been cleared, the timer stops.
+func (s *simulator) completeTick() {
+	if s.buffers["temporal"] == nil {
+		s.temporal.stop()
+		s.trace(traceDetail, "temporal", "timer stopped")
+		return
+	}
+
+	s.temporal.tick()
+
+	c := s.temporal.timeChunk()
+	s.buffers["temporal"] = c
+
+	s.trace(traceDetail, "temporal", "tick: %d", s.temporal.ticks)
+	s.addEvent(framework.TraceEvent{Type: framework.TraceBufferSet, Buffer: "temporal", Chunk: c.String()})
+}
+
+// spreadingSources collects the values of slots in the buffers which spread activation.
+func (s simulator) spreadingSources() (sources []spreadingSource) {
+	if !s.model.Memory.IsUsingSpreadingActivation() {
+		return
+	}
+
+	for _, buff := range s.model.Buffers() {
+		weight := buff.SpreadingActivation()
+		if weight == 0.0 {
+			continue
+		}
+
+		c := s.buffers[buff.Name()]
+		if c == nil {
+			continue
+		}
+
+		values := []value{}
+		for _, v := range c.slots {
+			if v.isChunkRef() {
+				values = append(values, v)
+			}
+		}
+
+		for _, v := range values {
+			sources = append(sources, spreadingSource{
+				value:  v,
+				weight: weight / float64(len(values)),
+			})
+		}
+	}
+
+	return
+}
+
+func (s *simulator) print(statement *actr.PrintStatement, b bindings) {
+	if statement.Values == nil {
+		s.writeLine("")
+		return
+	}
+
+	var str strings.Builder
+
+	if statement.IsBufferOutput() {
+		id := *(*statement.Values)[0].ID
+		str.WriteString(fmt.Sprintf("%s: %s", id, s.bufferRefString(id)))
+	} else {
+		for _, v := range *statement.Values {
+			str.WriteString(actrValue(v, b).String())
+		}
+	}
+
+	s.writeLine(str.String())
+	s.addEvent(framework.TraceEvent{Type: framework.TracePrint, Text: str.String()})
+}
+
+// bufferRefString returns the contents of a buffer ("goal") or a buffer's slot ("goal.count").
+func (s simulator) bufferRefString(id string) string {
+	bufferName, slotName, hasSlot := strings.Cut(id, ".")
+
+	c := s.buffers[bufferName]
+	if c == nil {
+		return "nil"
+	}
+
+	if !hasSlot {
+		return c.String()
+	}
+
+	v, _ := c.slotValue(slotName)
+	return v.String()
+}
+
+// addEvent adds an event at the current time to our trace if we are collecting them.
+func (s *simulator) addEvent(event framework.TraceEvent) {
+	if s.events == nil {
+		return
+	}
+
+	event.Time = round(s.time)
+	s.events.Add(event)
+
+	if s.sink != nil {
+		s.sink.TraceEvent(event)
+	}
+}
+
+// trace outputs a line of the trace if the level is at or below our logging level.
+func (s *simulator) trace(level traceLevel, source, format string, a ...any) {
+	if level > s.traceLevel {
+		return
+	}
+
+	s.writeLine(fmt.Sprintf("%10.3f   %-12s %s", s.time, source, fmt.Sprintf(format, a...)))
+}
+
+// writeLine adds a line to our output and sends it to the sink if we are streaming.
+func (s *simulator) writeLine(line string) {
+	s.output.WriteString(line + "\n")
+
+	if s.sink != nil {
+		s.sink.OutputLine(line)
+	}
+}
@@ -0,0 +1,104 @@
+package native
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// These are used if the model does not set the temporal module's parameters. They match vanilla.
+const (
+	defaultTimeNoise          = 0.015
+	defaultTimeMult           = 1.1
+	defaultTimeStartIncrement = 0.011
+)
+
+// temporal tracks the state of the temporal module's timer.
+type temporal struct {
+	model  *actr.Model
+	random *rand.Rand
+
+	running      bool
+	ticks        int
+	tickDuration float64 // length (seconds) of the current tick
+	nextTick     float64 // time of the next tick
+}
+
+func newTemporal(model *actr.Model, random *rand.Rand) *temporal {
+	return &temporal{
+		model:  model,
+		random: random,
+	}
+}
+
+func (t temporal) timeNoise() float64 {
+	module := t.model.TemporalModule()
+	if module != nil && module.TimeNoise != nil {
+		return *module.TimeNoise
+	}
+
+	return defaultTimeNoise
+}
+
+func (t temporal) timeMult() float64 {
+	module := t.model.TemporalModule()
+	if module != nil && module.TimeMult != nil {
+		return *module.TimeMult
+	}
+
+	return defaultTimeMult
+}
+
+func (t temporal) timeStartIncrement() float64 {
+	module := t.model.TemporalModule()
+	if module != nil && module.TimeStartIncrement != nil {
+		return *module.TimeStartIncrement
+	}
+
+	return defaultTimeStartIncrement
+}
+
+// start (re)starts the timer at zero ticks.
+func (t *temporal) start(now float64) {
+	t.running = true
+	t.ticks = 0
+	t.tickDuration = t.addNoise(t.timeStartIncrement())
+	t.nextTick = now + t.tickDuration
+}
+
+// tick increments the number of ticks and schedules the next one.
+// Each tick is longer than the last by a factor of time_mult (plus noise).
+func (t *temporal) tick() {
+	t.ticks++
+	t.tickDuration = t.addNoise(t.timeMult() * t.tickDuration)
+	t.nextTick += t.tickDuration
+}
+
+func (t *temporal) stop() {
+	t.running = false
+}
+
+// addNoise adds noise to the tick duration using a logistic distribution scaled by the duration.
+func (t temporal) addNoise(duration float64) float64 {
+	s := t.timeNoise() * duration
+	if s == 0.0 {
+		return duration
+	}
+
+	p := t.random.Float64()
+	for p == 0.0 {
+		p = t.random.Float64()
+	}
+
+	return duration + s*math.Log((1.0-p)/p)
+}
+
+// timeChunk creates a time chunk with the current number of ticks.
+func (t temporal) timeChunk() *chunk {
+	c := newChunk(t.model.LookupChunk("time"))
+	c.slots[0] = numberValue(strconv.Itoa(t.ticks))
+
+	return c
+}
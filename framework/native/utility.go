@@ -0,0 +1,134 @@
+package native
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/modules"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+// Defaults for the procedural parameters. These are the same as vanilla ACT-R.
+const (
+	defaultInitialUtility = 0.0
+)
+
+// firedProduction records when a production was selected so we can apply rewards to it.
+type firedProduction struct {
+	production *actr.Production
+	time       float64
+}
+
+// procedural tracks the utilities of the productions for conflict resolution and utility learning.
+type procedural struct {
+	module *modules.Procedural
+
+	// learned utilities (only set once a production has received a reward)
+	utilities map[*actr.Production]float64
+
+	// productions selected since the last reward
+	fired []firedProduction
+
+	random *rand.Rand
+}
+
+func newProcedural(model *actr.Model, random *rand.Rand) *procedural {
+	return &procedural{
+		module:    model.Procedural,
+		utilities: map[*actr.Production]float64{},
+		random:    random,
+	}
+}
+
+// isUsingUtilities checks if anything in the model affects utilities. If not, conflict
+// resolution simply selects the first production which matches.
+func (p procedural) isUsingUtilities(model *actr.Model) bool {
+	if p.module.UtilityNoise != nil || p.module.InitialUtility != nil || p.module.IsUsingUtilityLearning() {
+		return true
+	}
+
+	for _, production := range model.Productions {
+		if production.Utility != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p procedural) initialUtility() float64 {
+	if p.module.InitialUtility != nil {
+		return *p.module.InitialUtility
+	}
+
+	return defaultInitialUtility
+}
+
+// utility returns the current utility of the production (without noise).
+func (p procedural) utility(production *actr.Production) float64 {
+	if utility, ok := p.utilities[production]; ok {
+		return utility
+	}
+
+	if production.Utility != nil {
+		return *production.Utility
+	}
+
+	return p.initialUtility()
+}
+
+// noise generates the utility noise using a logistic distribution.
+func (p procedural) noise() float64 {
+	if p.module.UtilityNoise == nil {
+		return 0.0
+	}
+
+	s := *p.module.UtilityNoise
+	if s == 0.0 {
+		return 0.0
+	}
+
+	r := p.random.Float64()
+	for r == 0.0 {
+		r = p.random.Float64()
+	}
+
+	return s * math.Log((1.0-r)/r)
+}
+
+// selected records that the production was selected at time "now".
+func (p *procedural) selected(production *actr.Production, now float64) {
+	if !p.module.IsUsingUtilityLearning() {
+		return
+	}
+
+	p.fired = append(p.fired, firedProduction{production: production, time: now})
+}
+
+// reward applies a reward at time "now" to all productions selected since the last reward:
+//
+//	U_i(n) = U_i(n-1) + α[R_i(n) - U_i(n-1)] where R_i(n) = reward - (now - t_i)
+func (p *procedural) reward(reward, now float64, trace func(format string, a ...any)) {
+	if !p.module.IsUsingUtilityLearning() {
+		return
+	}
+
+	alpha := *p.module.UtilityLearningRate
+
+	for _, f := range p.fired {
+		effectiveReward := reward - (now - f.time)
+
+		previous := p.utility(f.production)
+		utility := previous + alpha*(effectiveReward-previous)
+
+		p.utilities[f.production] = utility
+
+		if trace != nil {
+			trace("utility of %s updated from %s to %s", f.production.Name, numbers.Float64Str(round(previous)), numbers.Float64Str(round(utility)))
+		}
+	}
+
+	p.fired = nil
+}
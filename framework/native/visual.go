@@ -0,0 +1,176 @@
+package native
+
+import (
+	"strconv"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// defaultAttentionLatency is the time (seconds) it takes to move attention to a location if the
+// model does not set the visual module's "attention_latency". This matches vanilla.
+const defaultAttentionLatency = 0.085
+
+// screenItem is the run-time version of an item on the screen.
+type screenItem struct {
+	*actr.ScreenItem
+
+	attended bool
+}
+
+// pendingAttention is an attention shift which completes at a specific time.
+type pendingAttention struct {
+	item *screenItem // nil if there is nothing at the location
+	time float64
+}
+
+// visual tracks the state of the visual module.
+type visual struct {
+	model *actr.Model
+
+	screen []*screenItem
+
+	attention *pendingAttention
+	err       bool // set if the last request failed
+}
+
+func newVisual(model *actr.Model) *visual {
+	v := &visual{model: model}
+
+	for _, item := range model.Screen {
+		v.screen = append(v.screen, &screenItem{ScreenItem: item})
+	}
+
+	return v
+}
+
+func (v visual) attentionLatency() float64 {
+	module := v.model.VisualModule()
+	if module != nil && module.AttentionLatency != nil {
+		return *module.AttentionLatency
+	}
+
+	return defaultAttentionLatency
+}
+
+// find returns the screen item which matches the pattern & request parameters, or nil if there is none.
+// Slots may use "lowest" or "highest" to pick the item with the lowest or highest value. These are
+// applied in slot order after all the other slots have been matched.
+func (v visual) find(pattern *actr.Pattern, b bindings, requestParams map[string]string) *screenItem {
+	candidates := []*screenItem{}
+
+	for _, item := range v.screen {
+		if !matchAttended(item, requestParams["attended"]) {
+			continue
+		}
+
+		if !matchLocation(pattern, item, b) {
+			continue
+		}
+
+		candidates = append(candidates, item)
+	}
+
+	for i, slot := range pattern.Slots {
+		if slot.ID == nil || len(candidates) == 0 {
+			continue
+		}
+
+		best := []*screenItem{}
+		bestCoord := 0
+
+		for _, item := range candidates {
+			coord := itemCoord(item, i)
+
+			better := len(best) == 0 ||
+				(*slot.ID == "lowest" && coord < bestCoord) ||
+				(*slot.ID == "highest" && coord > bestCoord)
+
+			switch {
+			case better:
+				best = []*screenItem{item}
+				bestCoord = coord
+
+			case coord == bestCoord:
+				best = append(best, item)
+			}
+		}
+
+		candidates = best
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[0]
+}
+
+// itemAt returns the item at the location or nil if there is none.
+func (v visual) itemAt(x, y float64) *screenItem {
+	for _, item := range v.screen {
+		if float64(item.X) == x && float64(item.Y) == y {
+			return item
+		}
+	}
+
+	return nil
+}
+
+func matchAttended(item *screenItem, attended string) bool {
+	switch attended {
+	case "t":
+		return item.attended
+
+	case "nil", "new":
+		return !item.attended
+	}
+
+	return true
+}
+
+// matchLocation matches the numbers and variables in a visual_location pattern against the item.
+func matchLocation(pattern *actr.Pattern, item *screenItem, b bindings) bool {
+	for i, slot := range pattern.Slots {
+		if slot.ID != nil {
+			continue // lowest or highest
+		}
+
+		want, ok := patternSlotValue(slot, b)
+		if !ok {
+			continue
+		}
+
+		coord := numberValue(strconv.Itoa(itemCoord(item, i)))
+		if want.equal(coord) == slot.Negated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// itemCoord returns the item's x (index 0) or y (index 1) coordinate.
+func itemCoord(item *screenItem, index int) int {
+	if index == 0 {
+		return item.X
+	}
+
+	return item.Y
+}
+
+// locationChunk creates a visual_location chunk for the item.
+func locationChunk(model *actr.Model, item *screenItem) *chunk {
+	c := newChunk(model.LookupChunk("visual_location"))
+	c.slots[0] = numberValue(strconv.Itoa(item.X))
+	c.slots[1] = numberValue(strconv.Itoa(item.Y))
+
+	return c
+}
+
+// objectChunk creates a visual_object chunk for the item.
+func objectChunk(model *actr.Model, item *screenItem) *chunk {
+	c := newChunk(model.LookupChunk("visual_object"))
+	c.slots[0] = strValue(item.Text)
+
+	return c
+}
@@ -0,0 +1,101 @@
+package framework
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// OutputSink receives the output of a run as it is produced so it may be shown before the run
+// finishes. Sinks may be called from multiple goroutines when running on multiple frameworks.
+type OutputSink interface {
+	OutputLine(line string)      // one line of output (stdout + stderr) without the newline
+	TraceEvent(event TraceEvent) // one trace event (only if the TraceEvents option is set)
+}
+
+// RunStream receives the output of runs on multiple frameworks as it is produced.
+type RunStream interface {
+	// Sink returns the sink to use for the named framework's output.
+	Sink(frameworkName string) OutputSink
+
+	// Finished is called when the named framework has finished validating and running.
+	Finished(frameworkName string, run FrameworkRun)
+}
+
+// LineWriter is an io.Writer which splits the output of a framework's executable into lines
+// and sends them to a sink. If we are tracing events, trace lines are sent as events instead.
+type LineWriter struct {
+	sink        OutputSink
+	traceEvents bool
+
+	mutex   sync.Mutex
+	partial []byte
+}
+
+// NewLineWriter creates a writer which sends lines to the sink. If "sink" is nil, the output
+// is discarded.
+func NewLineWriter(sink OutputSink, traceEvents bool) *LineWriter {
+	return &LineWriter{
+		sink:        sink,
+		traceEvents: traceEvents,
+	}
+}
+
+// Write sends each complete line to the sink and keeps any partial line until it is finished.
+func (w *LineWriter) Write(p []byte) (n int, err error) {
+	if w.sink == nil {
+		return len(p), nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.partial = append(w.partial, p...)
+
+	for {
+		index := bytes.IndexByte(w.partial, '\n')
+		if index == -1 {
+			break
+		}
+
+		w.sendLine(strings.TrimSuffix(string(w.partial[:index]), "\r"))
+		w.partial = w.partial[index+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush sends any partial line to the sink. Call this when the executable has finished.
+func (w *LineWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.partial) > 0 {
+		w.sendLine(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *LineWriter) sendLine(line string) {
+	if !w.traceEvents {
+		w.sink.OutputLine(line)
+		return
+	}
+
+	// Some frameworks prefix output (e.g. with whitespace), so look for the marker anywhere.
+	index := strings.Index(line, TraceMarker)
+	if index == -1 {
+		w.sink.OutputLine(line)
+		return
+	}
+
+	event, ok := parseTraceLine(line[index:])
+	if ok {
+		w.sink.TraceEvent(event)
+	}
+
+	prefix := strings.TrimSpace(line[:index])
+	if prefix != "" {
+		w.sink.OutputLine(prefix)
+	}
+}
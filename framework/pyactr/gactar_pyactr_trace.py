@@ -0,0 +1,68 @@
+"""
+gactar_pyactr_trace adds gactar's framework-neutral event trace to pyactr.
+
+It outputs lines of the form:
+
+    gactar-trace|<time>|<type>|<production>|<buffer>|<chunk>|<text>
+
+which gactar removes from the output and parses into its trace.
+
+It works by wrapping the simulation's step() method and translating pyactr's events.
+
+To use it, add it to the simulation before running:
+
+    import gactar_pyactr_trace
+
+    sim = model.simulation()
+    gactar_pyactr_trace.add_trace(sim)
+    sim.run()
+"""
+
+import pyactr as actr
+
+RULE_FIRED = "RULE FIRED: "
+RETRIEVED = "RETRIEVED: "
+
+
+def add_trace(sim: actr.simulation.Simulation):
+    """
+    Wraps the simulation's step() so we can output the events.
+    """
+
+    original_step = sim.step
+
+    def step():
+        original_step()
+        trace_event(sim.current_event)
+
+    sim.step = step
+
+
+def trace(time, event_type: str, production="", buffer="", chunk="", text=""):
+    """
+    Outputs one trace event.
+    """
+
+    print(f"gactar-trace|{float(time):.3f}|{event_type}|{production}|{buffer}|{chunk}|{text}")
+
+
+def trace_event(event):
+    """
+    Translates a pyactr event into a gactar trace event.
+    """
+
+    if event is None:
+        return
+
+    action = str(event.action)
+
+    if action.startswith(RULE_FIRED):
+        trace(event.time, "production-fired", production=action[len(RULE_FIRED) :])
+    elif action.startswith("START RETRIEVAL"):
+        trace(event.time, "retrieval-started", buffer="retrieval")
+    elif action.startswith(RETRIEVED):
+        chunk = action[len(RETRIEVED) :]
+        if chunk == "None":
+            trace(event.time, "retrieval-failed", buffer="retrieval")
+        else:
+            trace(event.time, "retrieval-succeeded", buffer="retrieval", chunk=chunk)
@@ -3,6 +3,7 @@
 package pyactr
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"strings"
@@ -22,13 +23,23 @@ import (
 //go:embed pyactr_print.py
 var pyactrPrintPython string
 
+//go:embed gactar_pyactr_trace.py
+var gactarTracePython string
+
 const (
 	pyactrPrintFileName = "pyactr_print.py"
+	gactarTraceFileName = "gactar_pyactr_trace.py"
 
 	// When a pattern's AnyChunk is true, we use ANY_CHUNK_TYPE for the chunk type
 	ANY_CHUNK_TYPE = "any_chunk"
 )
 
+// builtInChunkTypes maps the chunk types provided by our modules to pyactr's names for them.
+var builtInChunkTypes = map[string]string{
+	"visual_location": "_visuallocation",
+	"visual_object":   "_visual",
+}
+
 var Info framework.Info = framework.Info{
 	Name:           "pyactr",
 	Language:       "python",
@@ -65,6 +76,10 @@ func (PyACTR) Info() *framework.Info {
 	return &Info
 }
 
+func (PyACTR) NewInstance(tempPath string) framework.Framework {
+	return &PyACTR{tmpPath: tempPath}
+}
+
 func (PyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 	log = issues.New()
 
@@ -72,12 +87,75 @@ func (PyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 		log.Warning(nil, "pyactr does not support memory module's finst_time")
 	}
 
+	if visual := model.VisualModule(); visual != nil && visual.AttentionLatency != nil {
+		log.Warning(nil, "pyactr does not support visual module's attention_latency")
+	}
+
+	if model.TemporalModule() != nil {
+		log.Error(nil, "pyactr does not support the temporal module")
+	}
+
+	if model.BlendingModule() != nil {
+		log.Error(nil, "pyactr does not support the blending module")
+	}
+
+	if len(model.Associations) > 0 {
+		log.Warning(nil, "pyactr does not support setting associative strengths using 'associations' (ignored)")
+	}
+
+	for _, init := range model.Initializers {
+		if init.BaseLevel != nil {
+			location := issues.Location{
+				Line:        init.AMODLineNumber,
+				ColumnStart: 0,
+				ColumnEnd:   0,
+			}
+
+			log.Warning(&location, "pyactr does not support base-level settings on memory initializers (ignored)")
+		}
+	}
+
 	for _, production := range model.Productions {
 		numPrintStatements := 0
 		warnedPrintStatements := false
 
+		if production.HasRelationalConstraints() {
+			location := issues.Location{
+				Line:        production.AMODLineNumber,
+				ColumnStart: 0,
+				ColumnEnd:   0,
+			}
+
+			log.Error(&location, "pyactr does not support comparing numbers using <, <=, >, or >= (in %q)", production.Name)
+		}
+
+		if production.HasExpressions() {
+			location := issues.Location{
+				Line:        production.AMODLineNumber,
+				ColumnStart: 0,
+				ColumnEnd:   0,
+			}
+
+			log.Error(&location, "pyactr does not support arithmetic expressions (in %q)", production.Name)
+		}
+
 		if production.DoStatements != nil {
 			for _, statement := range production.DoStatements {
+				if statement.Remember != nil || statement.Reinforce != nil {
+					location := issues.Location{
+						Line:        production.AMODLineNumber,
+						ColumnStart: 0,
+						ColumnEnd:   0,
+					}
+
+					name := "remember"
+					if statement.Reinforce != nil {
+						name = "reinforce"
+					}
+
+					log.Error(&location, "pyactr does not support the %s statement (in %q)", name, production.Name)
+				}
+
 				if !warnedPrintStatements && statement.Print != nil {
 					numPrintStatements++
 					if numPrintStatements > 1 {
@@ -91,6 +169,28 @@ func (PyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 					}
 				}
 
+				if statement.Punch != nil {
+					location := issues.Location{
+						Line:        production.AMODLineNumber,
+						ColumnStart: 0,
+						ColumnEnd:   0,
+					}
+
+					log.Error(&location, "pyactr does not support the punch statement (in %q)", production.Name)
+				}
+
+				if statement.FindLocation != nil {
+					if _, ok := statement.FindLocation.RequestParameters["attended"]; ok {
+						location := issues.Location{
+							Line:        production.AMODLineNumber,
+							ColumnStart: 0,
+							ColumnEnd:   0,
+						}
+
+						log.Warning(&location, "pyactr does not support the 'attended' request parameter - ignoring it (in %q)", production.Name)
+					}
+				}
+
 				if statement.Recall != nil {
 					for _, param := range maps.Keys(statement.Recall.RequestParameters) {
 						location := issues.Location{
@@ -133,7 +233,7 @@ func (p PyACTR) Model() (model *actr.Model) {
 	return p.model
 }
 
-func (p *PyACTR) Run(options *runoptions.Options) (result *framework.RunResult, err error) {
+func (p *PyACTR) Run(ctx context.Context, options *runoptions.Options, sink framework.OutputSink) (result *framework.RunResult, err error) {
 	runFile, err := p.WriteModel(p.tmpPath, options)
 	if err != nil {
 		return
@@ -145,14 +245,19 @@ func (p *PyACTR) Run(options *runoptions.Options) (result *framework.RunResult,
 	}
 
 	// run it!
-	output, err := executil.ExecCommand(Info.ExecutableName, runFile)
+	writer := framework.NewLineWriter(sink, options.IsTracingEvents())
+	output, err := executil.ExecCommandWithOutput(ctx, writer, Info.ExecutableName, runFile)
+	writer.Flush()
 	if err != nil {
-		err = &executil.ErrExecuteCommand{Output: output}
 		return
 	}
 
 	result.Output = []byte(output)
 
+	if options.IsTracingEvents() {
+		result.Trace, result.Output = framework.ParseTrace(result.Output)
+	}
+
 	return
 }
 
@@ -166,6 +271,14 @@ func (p *PyACTR) WriteModel(path string, options *runoptions.Options) (outputFil
 		}
 	}
 
+	// If we are tracing events, then write out our support file
+	if options.IsTracingEvents() {
+		err = framework.WriteSupportFile(path, gactarTraceFileName, gactarTracePython)
+		if err != nil {
+			return
+		}
+	}
+
 	outputFileName = fmt.Sprintf("%s.py", p.className)
 	if path != "" {
 		outputFileName = fmt.Sprintf("%s/%s", path, outputFileName)
@@ -214,9 +327,24 @@ func (p *PyACTR) GenerateCode(options *runoptions.Options) (code []byte, err err
 		p.Writeln("numpy.random.seed(%d)\n", *options.RandomSeed)
 	}
 
+	usesEnvironment := p.model.VisualModule() != nil || p.model.MotorModule() != nil
+	if usesEnvironment {
+		p.Writeln("environment = actr.Environment(focus_position=(0, 0))")
+		p.Writeln("")
+	}
+
 	memory := p.model.Memory
 	p.Writeln("%s = actr.ACTRModel(", p.className)
 
+	if usesEnvironment {
+		p.Writeln("    environment=environment,")
+	}
+
+	if p.model.VisualModule() != nil {
+		p.Writeln("    # locations are only found using find_location")
+		p.Writeln("    automatic_visual_search=False,")
+	}
+
 	// enable subsymbolic computations
 	p.Writeln("    subsymbolic=True,")
 
@@ -254,6 +382,14 @@ func (p *PyACTR) GenerateCode(options *runoptions.Options) (code []byte, err err
 		p.Writeln("    rule_firing=%s,", numbers.Float64Str(*procedural.DefaultActionTime))
 	}
 
+	if procedural.UtilityNoise != nil {
+		p.Writeln("    utility_noise=%s,", numbers.Float64Str(*procedural.UtilityNoise))
+	}
+
+	if procedural.IsUsingUtilityLearning() {
+		p.Writeln("    utility_learning=True, utility_alpha=%s,", numbers.Float64Str(*procedural.UtilityLearningRate))
+	}
+
 	if *options.TraceActivations {
 		p.Writeln("    activation_trace=True,")
 	}
@@ -277,7 +413,7 @@ func (p *PyACTR) GenerateCode(options *runoptions.Options) (code []byte, err err
 
 	// chunks
 	for _, chunk := range p.model.Chunks {
-		if chunk.IsInternal() {
+		if chunk.IsInternal() || chunk.BuiltIn {
 			continue
 		}
 
@@ -309,6 +445,10 @@ func (p *PyACTR) GenerateCode(options *runoptions.Options) (code []byte, err err
 		p.Writeln(")")
 	}
 
+	if p.model.VisualModule() != nil {
+		p.Writeln("%[1]s.visualBuffer('visual', 'visual_location', %[1]s.decmem)", p.className)
+	}
+
 	p.writeExtraBufferInit()
 
 	p.Writeln("")
@@ -379,6 +519,11 @@ func (p PyACTR) writeImports(runOptions *runoptions.Options) {
 		// Import gactar's print handling
 		p.Writeln("import pyactr_print")
 	}
+
+	if runOptions.IsTracingEvents() {
+		// Import gactar's event tracing
+		p.Writeln("import gactar_pyactr_trace")
+	}
 }
 
 // If we have any extra buffers, define them in code
@@ -488,10 +633,29 @@ func (p PyACTR) writeProductions() {
 			}
 		}
 
-		p.Write("''')\n\n")
+		p.Write("'''%s)\n\n", p.productionParams(production))
 	}
 }
 
+// productionParams returns the utility & reward parameters for a production (if any).
+// pyactr does not have an initial utility parameter, so we set it on each production instead.
+func (p PyACTR) productionParams(production *actr.Production) (params string) {
+	utility := production.Utility
+	if utility == nil {
+		utility = p.model.Procedural.InitialUtility
+	}
+
+	if utility != nil {
+		params += fmt.Sprintf(", utility=%s", numbers.Float64Str(*utility))
+	}
+
+	if production.Reward != nil {
+		params += fmt.Sprintf(", reward=%s", numbers.Float64Str(*production.Reward))
+	}
+
+	return
+}
+
 func (p PyACTR) writeMain(runOptions *runoptions.Options) {
 	p.Writeln("# Main")
 	p.Writeln("if __name__ == '__main__':")
@@ -502,8 +666,33 @@ func (p PyACTR) writeMain(runOptions *runoptions.Options) {
 		options = append(options, "trace=False")
 	}
 
+	if p.model.VisualModule() != nil || p.model.MotorModule() != nil {
+		options = append(options, "environment_process=environment.environment_process")
+	}
+
+	if len(p.model.Screen) > 0 {
+		options = append(options, "stimuli=stimuli")
+
+		items := []string{}
+		for i, item := range p.model.Screen {
+			items = append(items, fmt.Sprintf("%d: {'text': %q, 'position': (%d, %d)}", i+1, item.Text, item.X, item.Y))
+		}
+
+		p.Writeln("    # the screen")
+		p.Writeln("    stimuli = [{%s}]", strings.Join(items, ", "))
+	}
+
 	p.Writeln("    sim = %s.simulation( %s )", p.className, strings.Join(options, ", "))
-	p.Writeln("    sim.run()")
+
+	if runOptions.IsTracingEvents() {
+		p.Writeln("    gactar_pyactr_trace.add_trace(sim)")
+	}
+
+	if runOptions.MaxTime != nil {
+		p.Writeln("    sim.run(max_time=%s)", numbers.Float64Str(*runOptions.MaxTime))
+	} else {
+		p.Writeln("    sim.run()")
+	}
 
 	if *runOptions.LogLevel != "min" {
 		p.Writeln("    if goal.test_buffer('full'):")
@@ -513,9 +702,18 @@ func (p PyACTR) writeMain(runOptions *runoptions.Options) {
 	}
 }
 
+// chunkTypeName returns pyactr's name for the chunk type.
+func chunkTypeName(chunk *actr.Chunk) string {
+	if name, ok := builtInChunkTypes[chunk.TypeName]; ok && chunk.BuiltIn {
+		return name
+	}
+
+	return chunk.TypeName
+}
+
 func (p PyACTR) outputPattern(pattern *actr.Pattern, tabs int) {
 	tabbedItems := framework.KeyValueList{}
-	tabbedItems.Add("isa", pattern.Chunk.TypeName)
+	tabbedItems.Add("isa", chunkTypeName(pattern.Chunk))
 
 	for i, slot := range pattern.Slots {
 		slotName := pattern.Chunk.SlotNames[i]
@@ -603,6 +801,11 @@ func addPatternSlot(tabbedItems *framework.KeyValueList, slotName string, slot *
 	if slot.Var != nil {
 		if len(slot.Var.Constraints) > 0 {
 			for _, constraint := range slot.Var.Constraints {
+				// Numeric comparisons are not supported (see ValidateModel)
+				if constraint.Comparison.IsRelational() {
+					continue
+				}
+
 				// default to equality
 				value := ""
 
@@ -652,7 +855,7 @@ func (p PyACTR) outputStatement(production *actr.Production, s *actr.Statement)
 
 		if s.Set.Slots != nil {
 			tabbedItems := framework.KeyValueList{}
-			tabbedItems.Add("isa", s.Set.Chunk.TypeName)
+			tabbedItems.Add("isa", chunkTypeName(s.Set.Chunk))
 
 			for _, slot := range *s.Set.Slots {
 				slotName := slot.Name
@@ -687,6 +890,39 @@ func (p PyACTR) outputStatement(production *actr.Production, s *actr.Statement)
 		p.Writeln("     +retrieval>")
 		p.outputPattern(s.Recall.Pattern, 2)
 
+	case s.FindLocation != nil:
+		// The "attended" request parameter is not supported (see ValidateModel)
+		p.Writeln("     +visual_location>")
+		p.outputPattern(s.FindLocation.Pattern, 2)
+
+	case s.Attend != nil:
+		tabbedItems := framework.KeyValueList{}
+		tabbedItems.Add("isa", "_visual")
+		tabbedItems.Add("cmd", "move_attention")
+		tabbedItems.Add("screen_pos", "=visual_location")
+
+		p.Writeln("     +visual>")
+		p.TabWrite(2, tabbedItems)
+
+	case s.PressKey != nil:
+		key := s.PressKey.Key
+
+		tabbedItems := framework.KeyValueList{}
+		tabbedItems.Add("isa", "_manual")
+		tabbedItems.Add("cmd", "press_key")
+
+		switch {
+		case key.Var != nil:
+			tabbedItems.Add("key", "="+strings.TrimPrefix(*key.Var, "?"))
+		case key.Str != nil:
+			tabbedItems.Add("key", fmt.Sprintf("%q", *key.Str))
+		default:
+			tabbedItems.Add("key", key.String())
+		}
+
+		p.Writeln("     +manual>")
+		p.TabWrite(2, tabbedItems)
+
 	case s.Print != nil:
 		// Using "print" here will use our automatically-included PrintBuffer class.
 		// The "buffer" and "text" statements handle their own formatting and lookup
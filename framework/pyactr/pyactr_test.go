@@ -2,6 +2,7 @@ package pyactr
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -34,10 +35,10 @@ func TestCodeGeneration(t *testing.T) {
 	}
 
 	fw, err := New(ctx.TempPath)
-
 	if fw == nil {
+		// We don't need pyactr installed to generate code
 		fmt.Println(err.Error())
-		t.Skip("pyactr framework not active")
+		fw = &PyACTR{tmpPath: t.TempDir()}
 	}
 
 	// determine input files
@@ -60,8 +61,14 @@ func TestCodeGeneration(t *testing.T) {
 func runCodeGenerationTest(t *testing.T, fw framework.Framework, input, output string) { //nolint to avoid Helper info since it doesn't apply
 	code, err := framework.GenerateCodeFromFile(fw, input, runoptions.InitialBuffers{})
 	if err != nil {
-		t.Error(err)
-		return
+		// If the framework does not support the model, the golden file holds the issues
+		var validationErr *framework.ErrModelValidationFailed
+		if !errors.As(err, &validationErr) {
+			t.Error(err)
+			return
+		}
+
+		code = []byte(validationErr.Log.String())
 	}
 
 	expected, err := os.ReadFile(output)
@@ -0,0 +1,119 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/asmaloney/gactar/actr"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+// FrameworkRun is the result of validating and running a model on one framework.
+type FrameworkRun struct {
+	Log    *issues.Log // issues from validation and any error from running
+	Result *RunResult  // nil if the model was not run
+}
+
+// FrameworkRunMap maps the framework name to the result of running a model on it.
+type FrameworkRunMap map[string]FrameworkRun
+
+// RunModelOnFrameworks validates and runs the model on each of the frameworks in parallel.
+func RunModelOnFrameworks(ctx context.Context, model *actr.Model, options *runoptions.Options, frameworks List) (runMap FrameworkRunMap) {
+	return StreamModelOnFrameworks(ctx, model, options, frameworks, nil)
+}
+
+// StreamModelOnFrameworks validates and runs the model on each of the frameworks in parallel. If
+// "stream" is not nil, each framework's output is sent to it as it is produced and it is told
+// when each framework finishes.
+func StreamModelOnFrameworks(ctx context.Context, model *actr.Model, options *runoptions.Options, frameworks List, stream RunStream) (runMap FrameworkRunMap) {
+	runMap = make(FrameworkRunMap, len(frameworks))
+
+	var wg sync.WaitGroup
+	var mutex = &sync.Mutex{}
+
+	for name, f := range frameworks {
+		wg.Add(1)
+
+		go func(wg *sync.WaitGroup, name string, f Framework) {
+			defer wg.Done()
+
+			var sink OutputSink
+			if stream != nil {
+				sink = stream.Sink(name)
+			}
+
+			run := FrameworkRun{}
+
+			run.Log = f.ValidateModel(model)
+			if !run.Log.HasError() {
+				result, err := RunModel(ctx, model, options, f, sink)
+				if err != nil {
+					run.Log.Error(nil, err.Error())
+				}
+
+				if result != nil {
+					for _, warning := range result.Warnings {
+						run.Log.Warning(nil, warning)
+					}
+				}
+
+				run.Result = result
+			}
+
+			if stream != nil {
+				stream.Finished(name, run)
+			}
+
+			mutex.Lock()
+			runMap[name] = run
+			mutex.Unlock()
+		}(&wg, name, f)
+	}
+	wg.Wait()
+
+	return
+}
+
+// RunModel sets the model on the framework and runs it. If "sink" is not nil, output is sent to
+// it as it is produced. If "ctx" is cancelled or the Timeout option is exceeded, it returns
+// ErrRunCancelled or ErrRunTimedOut.
+func RunModel(ctx context.Context, model *actr.Model, options *runoptions.Options, f Framework, sink OutputSink) (result *RunResult, err error) {
+	if model == nil {
+		err = ErrNoModel
+		return
+	}
+
+	err = f.SetModel(model)
+	if err != nil {
+		return
+	}
+
+	if options.Timeout != nil && *options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *options.Timeout)
+		defer cancel()
+	}
+
+	result, err = f.Run(ctx, options, sink)
+	if err != nil {
+		err = runError(err)
+		return
+	}
+
+	return
+}
+
+// runError replaces context errors with our own so they make sense to the user.
+func runError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrRunTimedOut
+	case errors.Is(err, context.Canceled):
+		return ErrRunCancelled
+	}
+
+	return err
+}
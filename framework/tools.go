@@ -20,12 +20,7 @@ import (
 // Reads a file, generates a model, validates it, and generates code from it for a given framework.
 // This is useful for testing.
 func GenerateCodeFromFile(fw Framework, inputFile string, initialBuffers runoptions.InitialBuffers) (code []byte, err error) {
-	amodCode, err := os.ReadFile(inputFile)
-	if err != nil {
-		return
-	}
-
-	model, log, err := amod.GenerateModel(string(amodCode))
+	model, log, err := amod.GenerateModelFromFile(inputFile)
 	if err != nil {
 		fmt.Print(log)
 		return
@@ -96,6 +91,11 @@ func ParseInitialBuffers(model *actr.Model, initialBuffers runoptions.InitialBuf
 			return
 		}
 
+		// empty contents mean we use the model's initializer
+		if pattern == nil {
+			continue
+		}
+
 		parsed[bufferName] = pattern
 	}
 
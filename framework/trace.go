@@ -0,0 +1,131 @@
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// TraceEventType is the kind of event in a Trace.
+type TraceEventType string
+
+const (
+	TraceProductionFired    TraceEventType = "production-fired"
+	TraceBufferSet          TraceEventType = "buffer-set"
+	TraceRetrievalStarted   TraceEventType = "retrieval-started"
+	TraceRetrievalSucceeded TraceEventType = "retrieval-succeeded"
+	TraceRetrievalFailed    TraceEventType = "retrieval-failed"
+	TracePrint              TraceEventType = "print"
+	TraceStop               TraceEventType = "stop"
+)
+
+// TraceMarker starts each line of output which the generated code uses to report a trace event.
+// The line is of the form:
+//
+//	gactar-trace|<time>|<type>|<production>|<buffer>|<chunk>|<text>
+//
+// Empty fields are allowed. Text is last so it may contain the separator.
+const TraceMarker = "gactar-trace|"
+
+// numTraceFields is the number of fields (including the marker) in a trace line.
+const numTraceFields = 7
+
+// TraceEvent is one event which occurred while running a model.
+type TraceEvent struct {
+	Time float64        `json:"time"` // simulation time in seconds
+	Type TraceEventType `json:"type"`
+
+	Production string `json:"production,omitempty"` // production-fired
+	Buffer     string `json:"buffer,omitempty"`     // buffer-set, retrieval-*
+	Chunk      string `json:"chunk,omitempty"`      // buffer-set, retrieval-* (format depends on the framework)
+	Text       string `json:"text,omitempty"`       // print
+}
+
+// Trace is a framework-neutral list of events which occurred while running a model.
+// Not all frameworks are able to report all types of events - see "doc/Framework Comparison.md".
+type Trace struct {
+	Events []TraceEvent `json:"events"`
+}
+
+// Add appends an event to the trace.
+func (t *Trace) Add(event TraceEvent) {
+	t.Events = append(t.Events, event)
+}
+
+// EventsOfType returns all the events in the trace of the given type.
+func (t Trace) EventsOfType(eventType TraceEventType) (events []TraceEvent) {
+	for _, event := range t.Events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+
+	return
+}
+
+// ParseTrace extracts the trace lines from the output of a run. It returns the trace and
+// the output with the trace lines removed.
+func ParseTrace(output []byte) (trace *Trace, remaining []byte) {
+	trace = &Trace{Events: []TraceEvent{}}
+
+	var cleaned bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Some frameworks prefix output (e.g. with whitespace), so look for the marker anywhere.
+		index := strings.Index(line, TraceMarker)
+		if index == -1 {
+			cleaned.WriteString(line)
+			cleaned.WriteString("\n")
+			continue
+		}
+
+		event, ok := parseTraceLine(line[index:])
+		if ok {
+			trace.Add(event)
+		}
+
+		prefix := strings.TrimSpace(line[:index])
+		if prefix != "" {
+			cleaned.WriteString(prefix)
+			cleaned.WriteString("\n")
+		}
+	}
+
+	remaining = cleaned.Bytes()
+
+	// Keep the original if it did not end with a newline.
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		remaining = bytes.TrimSuffix(remaining, []byte("\n"))
+	}
+
+	return
+}
+
+func parseTraceLine(line string) (event TraceEvent, ok bool) {
+	fields := strings.SplitN(line, "|", numTraceFields)
+	if len(fields) != numTraceFields {
+		return
+	}
+
+	time, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+	if err != nil {
+		return
+	}
+
+	event = TraceEvent{
+		Time:       time,
+		Type:       TraceEventType(strings.TrimSpace(fields[2])),
+		Production: strings.TrimSpace(fields[3]),
+		Buffer:     strings.TrimSpace(fields[4]),
+		Chunk:      strings.TrimSpace(fields[5]),
+		Text:       fields[6],
+	}
+
+	return event, true
+}
@@ -0,0 +1,1704 @@
+package vanilla_actr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/buffer"
+	"github.com/asmaloney/gactar/actr/modules"
+	"github.com/asmaloney/gactar/amod"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/keyvalue"
+	"github.com/asmaloney/gactar/util/lisp"
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+var (
+	ErrImportNoModel = errors.New("no define-model found")
+
+	// e.g. =goal>, ?retrieval>, +imaginal>, -goal>
+	bufferActionRegex = regexp.MustCompile(`^([=?+\-@*])(.+)>$`)
+
+	// e.g. !output!, !stop!
+	commandRegex = regexp.MustCompile(`^!(.+)!$`)
+
+	// format directives we can convert in !output!
+	formatDirectiveRegex = regexp.MustCompile(`~[aAsS%]`)
+)
+
+// The sgp parameters which we can safely ignore because they don't have an amod equivalent,
+// but don't affect the model.
+var ignoredParams = []string{
+	":do-not-harvest",
+	":esc",
+	":model-warnings",
+	":show-focus",
+	":style-warnings",
+	":trace-filter",
+	":v",
+}
+
+// importer converts a vanilla ACT-R model in Lisp to an actr.Model.
+type importer struct {
+	fileName string
+	log      *issues.Log
+	model    *actr.Model
+
+	// lowercase Lisp symbol -> amod identifier so names are consistent regardless of case
+	names map[string]string
+
+	// chunks from add-dm in declaration order (by lowercase name)
+	chunks     map[string]*importedChunk
+	chunkOrder []string
+
+	productions map[string]*actr.Production
+
+	utilityLearning bool
+	utilityAlpha    *float64
+}
+
+type importedChunk struct {
+	name    string
+	pattern *actr.Pattern
+	node    *lisp.Node
+}
+
+// slotTest is one slot test (or modification) from a production buffer spec.
+//
+//	e.g. "- value =cat" is modifier "-", slot "value", value "=cat"
+type slotTest struct {
+	modifier string
+	slot     string
+	value    *lisp.Node
+}
+
+// bufferSpec is one buffer test or action in a production.
+//
+//	e.g. "=goal> isa count state start"
+type bufferSpec struct {
+	action     string // one of "=", "?", "+", "-", "@", "*" for buffers, or the command name (e.g. "output")
+	bufferName string
+	isa        *lisp.Node
+	tests      []slotTest
+	params     []slotTest // request parameters (e.g. ":recently-retrieved nil")
+	args       []*lisp.Node
+	node       *lisp.Node
+}
+
+// ImportModel reads a vanilla ACT-R model from a Lisp file and converts it to an actr.Model.
+// Anything which cannot be translated is reported in the log.
+func ImportModel(fileName string) (model *actr.Model, log *issues.Log, err error) {
+	src, err := os.ReadFile(fileName)
+	if err != nil {
+		return
+	}
+
+	return ImportModelFromString(string(src), fileName)
+}
+
+// ImportModelFromString converts a vanilla ACT-R model in Lisp to an actr.Model.
+// The fileName is only used in the log.
+func ImportModelFromString(src, fileName string) (model *actr.Model, log *issues.Log, err error) {
+	log = issues.New()
+
+	nodes, err := lisp.Read(src)
+	if err != nil {
+		var syntaxErr lisp.ErrSyntax
+		if errors.As(err, &syntaxErr) {
+			log.Error(&issues.Location{SourceFile: fileName, Line: syntaxErr.Line, ColumnStart: syntaxErr.Column, ColumnEnd: syntaxErr.Column},
+				syntaxErr.Message)
+		}
+		return
+	}
+
+	i := &importer{
+		fileName:    fileName,
+		log:         log,
+		model:       &actr.Model{},
+		names:       map[string]string{},
+		chunks:      map[string]*importedChunk{},
+		productions: map[string]*actr.Production{},
+	}
+
+	i.model.Initialize()
+
+	foundModel := false
+
+	for _, node := range nodes {
+		switch node.Head() {
+		case "clear-all", "require-compiled":
+			// nothing to do
+
+		case "define-module":
+			i.addModule(node)
+
+		case "define-model":
+			if foundModel {
+				i.warning(node, "only one model can be imported - ignoring %q", node.Children[1].Value)
+				continue
+			}
+
+			foundModel = true
+			i.addModel(node)
+
+		default:
+			i.warning(node, "ignoring unsupported form %s", formName(node))
+		}
+	}
+
+	if !foundModel {
+		err = ErrImportNoModel
+		log.Error(nil, err.Error())
+		return
+	}
+
+	i.finalizeUtilityLearning()
+	i.model.FinalizeImplicitChunks()
+
+	model = i.model
+	return
+}
+
+func (i importer) location(node *lisp.Node) *issues.Location {
+	if node == nil {
+		return nil
+	}
+
+	return &issues.Location{
+		SourceFile:  i.fileName,
+		Line:        node.Line,
+		ColumnStart: node.Column,
+		ColumnEnd:   node.Column,
+	}
+}
+
+func (i importer) warning(node *lisp.Node, format string, a ...any) {
+	i.log.Warning(i.location(node), format, a...)
+}
+
+func (i importer) error(node *lisp.Node, format string, a ...any) {
+	i.log.Error(i.location(node), format, a...)
+}
+
+// formName returns the name of a form for output - e.g. "(run ...)".
+func formName(node *lisp.Node) string {
+	if node.Head() == "" {
+		return node.String()
+	}
+
+	return fmt.Sprintf("(%s ...)", node.Head())
+}
+
+// ident converts a Lisp symbol into a valid amod identifier. Lisp is case-insensitive, so we use
+// the first spelling we see of any symbol.
+func (i *importer) ident(symbol string) string {
+	key := strings.ToLower(symbol)
+
+	if name, ok := i.names[key]; ok {
+		return name
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, symbol)
+
+	if amod.IsKeyword(name) {
+		name += "_"
+	}
+
+	i.names[key] = name
+	return name
+}
+
+func (i *importer) addModel(node *lisp.Node) {
+	if len(node.Children) < 2 || node.Children[1].Type != lisp.NodeSymbol {
+		i.error(node, "define-model is missing a model name")
+		return
+	}
+
+	i.model.Name = i.ident(node.Children[1].Value)
+	i.model.Description = fmt.Sprintf("Imported from %s", filepath.Base(i.fileName))
+
+	var goalFocus *lisp.Node
+
+	for _, form := range node.Children[2:] {
+		switch form.Head() {
+		case "sgp":
+			i.addParams(form)
+
+		case "chunk-type":
+			i.addChunkType(form)
+
+		case "add-dm":
+			i.addChunks(form)
+
+		case "set-similarities":
+			i.addSimilarities(form)
+
+		case "add-sji":
+			i.addAssociations(form)
+
+		case "p", "p*":
+			i.addProduction(form)
+
+		case "spp":
+			i.addProductionParams(form)
+
+		case "goal-focus":
+			goalFocus = form
+
+		case "set-buffer-chunk":
+			i.addBufferChunk(form)
+
+		default:
+			i.warning(form, "ignoring unsupported form %s", formName(form))
+		}
+	}
+
+	i.addInitializers(goalFocus)
+}
+
+// addModule handles modules defined using define-module. The only ones we can import are
+// goal-style modules which are declared as extra buffers.
+func (i *importer) addModule(node *lisp.Node) {
+	isGoalStyle := false
+	for _, child := range node.Children {
+		if child.IsSymbol("goal-style-request") {
+			isGoalStyle = true
+		}
+	}
+
+	if !isGoalStyle || len(node.Children) < 3 || !node.Children[2].IsList() {
+		i.warning(node, "ignoring unsupported module definition")
+		return
+	}
+
+	eb, _ := i.model.LookupModule("extra_buffers").(*modules.ExtraBuffers)
+	if eb == nil {
+		eb = i.model.CreateExtraBuffers()
+	}
+
+	for _, buff := range node.Children[2].Children {
+		if buff.IsList() && len(buff.Children) > 0 {
+			buff = buff.Children[0]
+		}
+
+		_ = eb.SetParam(&keyvalue.KeyValue{Key: i.ident(buff.Value)})
+	}
+}
+
+// lookupBuffer looks up a buffer by its Lisp name. If it is the imaginal buffer, we create
+// the imaginal module when we need it.
+func (i *importer) lookupBuffer(name string) buffer.Interface {
+	name = strings.ToLower(name)
+
+	if name == "imaginal" && i.model.ImaginalModule() == nil {
+		i.model.CreateImaginal()
+	}
+
+	return i.model.LookupBuffer(i.ident(name))
+}
+
+// moduleForBuffer returns the module which provides the buffer.
+func (i importer) moduleForBuffer(buff buffer.Interface) modules.Interface {
+	for _, module := range i.model.Modules {
+		if module.Buffers().Has(buff.Name()) {
+			return module
+		}
+	}
+
+	return nil
+}
+
+func numberValue(node *lisp.Node) (value float64, ok bool) {
+	if node.Type != lisp.NodeNumber {
+		return
+	}
+
+	// Lisp allows double-float exponents like 1.0d0
+	text := strings.NewReplacer("d", "e", "D", "e").Replace(node.Value)
+
+	value, err := strconv.ParseFloat(text, 64)
+	return value, err == nil
+}
+
+// numberText returns the text of a number suitable for amod.
+func numberText(node *lisp.Node) string {
+	value, ok := numberValue(node)
+	if !ok {
+		return node.Value
+	}
+
+	return numbers.Float64Str(value)
+}
+
+func isNil(node *lisp.Node) bool {
+	return node.IsSymbol("nil")
+}
+
+func (i *importer) setParam(module modules.Interface, node *lisp.Node, name string, value *lisp.Node) {
+	number, ok := numberValue(value)
+	if !ok {
+		i.error(value, "expected a number for parameter %s", node.Value)
+		return
+	}
+
+	err := module.SetParam(&keyvalue.KeyValue{Key: name, Value: keyvalue.Value{Number: &number}})
+	if err != nil {
+		i.error(value, "parameter %s: %v", node.Value, err)
+	}
+}
+
+func (i *importer) setSpreadingActivation(buff buffer.Interface, node *lisp.Node, value *lisp.Node) {
+	number, ok := numberValue(value)
+	if !ok {
+		i.error(value, "expected a number for parameter %s", node.Value)
+		return
+	}
+
+	err := buff.SetParam(&keyvalue.KeyValue{Key: "spreading_activation", Value: keyvalue.Value{Number: &number}})
+	if err != nil {
+		i.error(value, "parameter %s: %v", node.Value, err)
+	}
+}
+
+// addParams converts the sgp parameters we understand into module & model parameters.
+func (i *importer) addParams(node *lisp.Node) {
+	args := node.Children[1:]
+
+	for index := 0; index < len(args); index += 2 {
+		param := args[index]
+
+		if index+1 >= len(args) {
+			i.error(param, "parameter %s is missing a value", param.Value)
+			return
+		}
+
+		value := args[index+1]
+
+		// nil turns the parameter off, which is our default
+		if isNil(value) {
+			continue
+		}
+
+		memory := i.model.Memory
+		procedural := i.model.Procedural
+
+		name := strings.ToLower(param.Value)
+
+		switch name {
+		case ":lf":
+			i.setParam(memory, param, "latency_factor", value)
+		case ":le":
+			i.setParam(memory, param, "latency_exponent", value)
+		case ":rt":
+			i.setParam(memory, param, "retrieval_threshold", value)
+		case ":declarative-num-finsts":
+			i.setParam(memory, param, "finst_size", value)
+		case ":declarative-finst-span":
+			i.setParam(memory, param, "finst_time", value)
+		case ":bll":
+			i.setParam(memory, param, "decay", value)
+		case ":mas":
+			i.setParam(memory, param, "max_spread_strength", value)
+		case ":ans":
+			i.setParam(memory, param, "instantaneous_noise", value)
+		case ":mp":
+			i.setParam(memory, param, "mismatch_penalty", value)
+
+		case ":dat":
+			i.setParam(procedural, param, "default_action_time", value)
+		case ":iu":
+			i.setParam(procedural, param, "initial_utility", value)
+		case ":egs":
+			i.setParam(procedural, param, "utility_noise", value)
+		case ":ul":
+			i.utilityLearning = true
+		case ":alpha":
+			alpha, ok := numberValue(value)
+			if !ok {
+				i.error(value, "expected a number for parameter %s", param.Value)
+				continue
+			}
+			i.utilityAlpha = &alpha
+
+		case ":imaginal-delay":
+			i.lookupBuffer("imaginal")
+			i.setParam(i.model.ImaginalModule(), param, "delay", value)
+
+		case ":ga":
+			i.setSpreadingActivation(i.model.Goal.Buffer(), param, value)
+
+		case ":trace-detail":
+			i.setTraceDetail(param, value)
+
+		case ":act":
+			trace := true
+			i.model.DefaultParams.TraceActivations = &trace
+
+		case ":seed":
+			i.setSeed(param, value)
+
+		default:
+			// :<buffer>-activation sets the spreading activation for that buffer
+			if strings.HasSuffix(name, "-activation") {
+				buff := i.lookupBuffer(strings.TrimSuffix(strings.TrimPrefix(name, ":"), "-activation"))
+				if buff != nil {
+					i.setSpreadingActivation(buff, param, value)
+					continue
+				}
+			}
+
+			if isIgnoredParam(name) {
+				continue
+			}
+
+			i.warning(param, "ignoring unsupported parameter %s", param.Value)
+		}
+	}
+}
+
+func isIgnoredParam(name string) bool {
+	for _, ignored := range ignoredParams {
+		if name == ignored {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *importer) setTraceDetail(param, value *lisp.Node) {
+	var level string
+
+	switch strings.ToLower(value.Value) {
+	case "low":
+		level = "min"
+	case "medium":
+		level = "info"
+	case "high":
+		level = "detail"
+	default:
+		i.warning(value, "ignoring unknown value for %s: %s", param.Value, value.Value)
+		return
+	}
+
+	err := i.model.SetParam(&keyvalue.KeyValue{Key: "log_level", Value: keyvalue.Value{Str: &level}})
+	if err != nil {
+		i.error(value, "parameter %s: %v", param.Value, err)
+	}
+}
+
+// setSeed sets the random seed. ACT-R seeds look like this: (123 0)
+func (i *importer) setSeed(param, value *lisp.Node) {
+	if value.IsList() && len(value.Children) > 0 {
+		value = value.Children[0]
+	}
+
+	seed, ok := numberValue(value)
+	if !ok || seed < 0 {
+		i.warning(value, "ignoring unsupported value for %s", param.Value)
+		return
+	}
+
+	err := i.model.SetParam(&keyvalue.KeyValue{Key: "random_seed", Value: keyvalue.Value{Number: &seed}})
+	if err != nil {
+		i.error(value, "parameter %s: %v", param.Value, err)
+	}
+}
+
+// finalizeUtilityLearning sets the utility learning rate once we have seen all the parameters
+// since :ul and :alpha may be set in any order.
+func (i *importer) finalizeUtilityLearning() {
+	if !i.utilityLearning {
+		return
+	}
+
+	// the ACT-R default
+	alpha := 0.2
+	if i.utilityAlpha != nil {
+		alpha = *i.utilityAlpha
+	}
+
+	_ = i.model.Procedural.SetParam(&keyvalue.KeyValue{Key: "utility_learning_rate", Value: keyvalue.Value{Number: &alpha}})
+}
+
+// addChunkType converts a chunk-type. These may look like:
+//
+//	(chunk-type count first second)
+//	(chunk-type (count-from (:include count)) start end)
+func (i *importer) addChunkType(node *lisp.Node) {
+	if len(node.Children) < 2 {
+		i.error(node, "chunk-type is missing a name")
+		return
+	}
+
+	nameNode := node.Children[1]
+
+	chunk := &actr.Chunk{}
+
+	if nameNode.IsList() {
+		if len(nameNode.Children) == 0 {
+			i.error(nameNode, "chunk-type is missing a name")
+			return
+		}
+
+		for _, option := range nameNode.Children[1:] {
+			if option.Head() != ":include" || len(option.Children) != 2 {
+				i.warning(option, "ignoring unsupported chunk-type option %s", option)
+				continue
+			}
+
+			parent := i.model.LookupChunk(i.ident(option.Children[1].Value))
+			if parent == nil {
+				i.error(option, "could not find chunk-type %q to include", option.Children[1].Value)
+				return
+			}
+
+			chunk.SlotNames = append(chunk.SlotNames, parent.SlotNames...)
+		}
+
+		nameNode = nameNode.Children[0]
+	}
+
+	chunk.TypeName = i.ident(nameNode.Value)
+
+	if i.model.LookupChunk(chunk.TypeName) != nil {
+		i.error(nameNode, "duplicate chunk-type %q", nameNode.Value)
+		return
+	}
+
+	for _, slot := range node.Children[2:] {
+		switch {
+		case slot.Type == lisp.NodeString:
+			// documentation string
+
+		case slot.IsList() && len(slot.Children) > 0:
+			i.warning(slot, "ignoring default value for slot %q", slot.Children[0].Value)
+			chunk.SlotNames = appendSlot(chunk.SlotNames, i.ident(slot.Children[0].Value))
+
+		case slot.Type == lisp.NodeSymbol:
+			chunk.SlotNames = appendSlot(chunk.SlotNames, i.ident(slot.Value))
+
+		default:
+			i.warning(slot, "ignoring unsupported slot %s", slot)
+		}
+	}
+
+	if len(chunk.SlotNames) == 0 {
+		i.error(node, "chunk-type %q has no slots", nameNode.Value)
+		return
+	}
+
+	chunk.NumSlots = len(chunk.SlotNames)
+
+	i.model.Chunks = append(i.model.Chunks, chunk)
+}
+
+func appendSlot(slots []string, slot string) []string {
+	for _, s := range slots {
+		if s == slot {
+			return slots
+		}
+	}
+
+	return append(slots, slot)
+}
+
+// inferChunk looks for a chunk type when none was specified using "isa".
+// It picks the smallest chunk-type which has all the slots.
+func (i *importer) inferChunk(isa *lisp.Node, tests []slotTest) *actr.Chunk {
+	if isa != nil && !isa.IsSymbol("chunk") {
+		return i.model.LookupChunk(i.ident(isa.Value))
+	}
+
+	var found *actr.Chunk
+
+	for _, chunk := range i.model.Chunks {
+		hasSlots := true
+		for _, test := range tests {
+			if !chunk.HasSlot(i.ident(test.slot)) {
+				hasSlots = false
+				break
+			}
+		}
+
+		if hasSlots && (found == nil || chunk.NumSlots < found.NumSlots) {
+			found = chunk
+		}
+	}
+
+	return found
+}
+
+// parseSlots parses a list of slots & values (with optional modifiers such as "-" and "<")
+// into the spec's tests, request parameters, and chunk-type.
+func (i *importer) parseSlots(nodes []*lisp.Node, spec *bufferSpec) bool {
+	for index := 0; index < len(nodes); index++ {
+		node := nodes[index]
+		test := slotTest{}
+
+		if node.Type == lisp.NodeSymbol {
+			switch node.Value {
+			case "-", "<", ">", "<=", ">=", "=":
+				test.modifier = node.Value
+				index++
+				if index >= len(nodes) {
+					i.error(node, "missing slot after %q", node.Value)
+					return false
+				}
+				node = nodes[index]
+			}
+		}
+
+		if node.Type != lisp.NodeSymbol {
+			i.error(node, "expected a slot name, found %s", node)
+			return false
+		}
+
+		index++
+		if index >= len(nodes) {
+			i.error(node, "slot %q is missing a value", node.Value)
+			return false
+		}
+
+		test.slot = node.Value
+		test.value = nodes[index]
+
+		switch {
+		case node.IsSymbol("isa") && test.modifier == "":
+			spec.isa = test.value
+
+		case strings.HasPrefix(node.Value, ":"):
+			spec.params = append(spec.params, test)
+
+		default:
+			spec.tests = append(spec.tests, test)
+		}
+	}
+
+	return true
+}
+
+// addChunks converts the chunks from add-dm. These may look like:
+//
+//	(one isa count first 0 second 1)
+//	(one first 0 second 1)
+//	(shark)
+func (i *importer) addChunks(node *lisp.Node) {
+	for _, chunkNode := range node.Children[1:] {
+		if !chunkNode.IsList() || len(chunkNode.Children) == 0 || chunkNode.Children[0].Type != lisp.NodeSymbol {
+			i.error(chunkNode, "unsupported chunk definition %s", chunkNode)
+			continue
+		}
+
+		name := chunkNode.Children[0].Value
+
+		// chunks without slots are created as needed by the frameworks
+		if len(chunkNode.Children) == 1 {
+			continue
+		}
+
+		pattern := i.createChunkPattern(chunkNode, chunkNode.Children[1:])
+		if pattern == nil {
+			continue
+		}
+
+		key := strings.ToLower(name)
+		if _, ok := i.chunks[key]; ok {
+			i.error(chunkNode, "duplicate chunk %q", name)
+			continue
+		}
+
+		i.chunks[key] = &importedChunk{
+			name:    i.ident(name),
+			pattern: pattern,
+			node:    chunkNode,
+		}
+		i.chunkOrder = append(i.chunkOrder, key)
+	}
+}
+
+// createChunkPattern creates a pattern from a chunk definition. Any slots which are not
+// specified are set to nil.
+func (i *importer) createChunkPattern(node *lisp.Node, slotNodes []*lisp.Node) *actr.Pattern {
+	spec := &bufferSpec{node: node}
+	if !i.parseSlots(slotNodes, spec) {
+		return nil
+	}
+
+	chunk := i.inferChunk(spec.isa, spec.tests)
+	if chunk == nil {
+		i.error(node, "could not determine the chunk-type of %s", node)
+		return nil
+	}
+
+	slots := make([]*actr.PatternSlot, chunk.NumSlots)
+	for index := range slots {
+		slots[index] = &actr.PatternSlot{Nil: true}
+	}
+
+	for _, test := range spec.tests {
+		index := chunk.SlotIndex(i.ident(test.slot))
+		if index == -1 {
+			i.error(test.value, "slot %q not found in chunk-type %q", test.slot, chunk.TypeName)
+			return nil
+		}
+
+		slot := i.createPatternSlot(test.value)
+		if slot == nil || slot.Var != nil || test.modifier != "" {
+			i.error(test.value, "unsupported value for slot %q in chunk definition", test.slot)
+			return nil
+		}
+
+		slots[index-1] = slot
+	}
+
+	return &actr.Pattern{Chunk: chunk, Slots: slots}
+}
+
+// createPatternSlot converts a value into a pattern slot.
+func (i *importer) createPatternSlot(value *lisp.Node) *actr.PatternSlot {
+	switch value.Type {
+	case lisp.NodeNumber:
+		num := numberText(value)
+		return &actr.PatternSlot{Num: &num}
+
+	case lisp.NodeString:
+		str := value.Value
+		return &actr.PatternSlot{Str: &str}
+
+	case lisp.NodeSymbol:
+		// gactar outputs nil as "empty"
+		if isNil(value) || value.IsSymbol("empty") {
+			return &actr.PatternSlot{Nil: true}
+		}
+
+		if strings.HasPrefix(value.Value, "=") {
+			name := "?" + i.ident(value.Value[1:])
+			return &actr.PatternSlot{Var: &actr.PatternVar{Name: &name}}
+		}
+
+		id := i.ident(value.Value)
+		return &actr.PatternSlot{ID: &id}
+	}
+
+	return nil
+}
+
+// createValue converts a value for use in set & print statements.
+// Note that variables are returned with the "?".
+func (i *importer) createValue(value *lisp.Node) *actr.Value {
+	slot := i.createPatternSlot(value)
+	if slot == nil {
+		return nil
+	}
+
+	switch {
+	case slot.Nil:
+		isNil := true
+		return &actr.Value{Nil: &isNil}
+	case slot.Num != nil:
+		return &actr.Value{Number: slot.Num}
+	case slot.Str != nil:
+		return &actr.Value{Str: slot.Str}
+	case slot.Var != nil:
+		return &actr.Value{Var: slot.Var.Name}
+	}
+
+	return &actr.Value{ID: slot.ID}
+}
+
+func (i *importer) addSimilarities(node *lisp.Node) {
+	for _, similarNode := range node.Children[1:] {
+		if !similarNode.IsList() || len(similarNode.Children) != 3 {
+			i.error(similarNode, "unsupported similarity %s", similarNode)
+			continue
+		}
+
+		value, ok := numberValue(similarNode.Children[2])
+		if !ok {
+			i.error(similarNode.Children[2], "expected a number for similarity")
+			continue
+		}
+
+		i.model.AddSimilarity(&actr.Similarity{
+			ChunkOne: i.ident(similarNode.Children[0].Value),
+			ChunkTwo: i.ident(similarNode.Children[1].Value),
+			Value:    value,
+		})
+	}
+}
+
+func (i *importer) addAssociations(node *lisp.Node) {
+	for _, sjiNode := range node.Children[1:] {
+		if !sjiNode.IsList() || len(sjiNode.Children) != 3 {
+			i.error(sjiNode, "unsupported association %s", sjiNode)
+			continue
+		}
+
+		value, ok := numberValue(sjiNode.Children[2])
+		if !ok {
+			i.error(sjiNode.Children[2], "expected a number for association")
+			continue
+		}
+
+		i.model.AddAssociation(&actr.Association{
+			Source: i.ident(sjiNode.Children[0].Value),
+			Target: i.ident(sjiNode.Children[1].Value),
+			Value:  value,
+		})
+	}
+}
+
+// addBufferChunk handles setting the initial contents of a buffer with set-buffer-chunk:
+//
+//	(set-buffer-chunk 'imaginal 'chunk-name)
+//	(set-buffer-chunk 'imaginal '(isa sentence word1 "Mary"))
+func (i *importer) addBufferChunk(node *lisp.Node) {
+	if len(node.Children) != 3 {
+		i.warning(node, "ignoring unsupported form %s", node)
+		return
+	}
+
+	bufferNode := node.Children[1].Unquote()
+	buff := i.lookupBuffer(bufferNode.Value)
+	if buff == nil || buff.Name() == "retrieval" {
+		i.error(bufferNode, "cannot initialize buffer %q", bufferNode.Value)
+		return
+	}
+
+	chunkNode := node.Children[2].Unquote()
+
+	var pattern *actr.Pattern
+
+	if chunkNode.IsList() {
+		pattern = i.createChunkPattern(chunkNode, chunkNode.Children)
+	} else {
+		chunk, ok := i.chunks[strings.ToLower(chunkNode.Value)]
+		if !ok {
+			i.error(chunkNode, "could not find chunk %q", chunkNode.Value)
+			return
+		}
+
+		pattern = chunk.pattern
+	}
+
+	if pattern == nil {
+		return
+	}
+
+	i.model.AddInitializer(&actr.Initializer{
+		Module:  i.moduleForBuffer(buff),
+		Buffer:  buff,
+		Pattern: pattern,
+	})
+}
+
+// addInitializers adds the memory initializers & the initial goal.
+func (i *importer) addInitializers(goalFocus *lisp.Node) {
+	goalChunk := ""
+
+	if goalFocus != nil {
+		if len(goalFocus.Children) != 2 || goalFocus.Children[1].Type != lisp.NodeSymbol {
+			i.error(goalFocus, "unsupported goal-focus %s", goalFocus)
+		} else {
+			name := goalFocus.Children[1].Value
+			goalChunk = strings.ToLower(name)
+
+			chunk, ok := i.chunks[goalChunk]
+			if ok {
+				i.model.AddInitializer(&actr.Initializer{
+					Module:  i.model.Goal,
+					Buffer:  i.model.Goal.Buffer(),
+					Pattern: chunk.pattern,
+				})
+			} else {
+				i.error(goalFocus, "could not find goal chunk %q", name)
+			}
+		}
+	}
+
+	for _, key := range i.chunkOrder {
+		// the goal chunk is used to initialize the goal instead
+		if key == goalChunk {
+			continue
+		}
+
+		chunk := i.chunks[key]
+		name := chunk.name
+
+		i.model.AddInitializer(&actr.Initializer{
+			Module:    i.model.Memory,
+			Buffer:    i.model.Memory.Buffers().At(0),
+			ChunkName: &name,
+			Pattern:   chunk.pattern,
+		})
+	}
+}
+
+// splitProduction splits the production into its buffer tests (LHS) and actions (RHS).
+func (i *importer) splitProduction(nodes []*lisp.Node) (lhs, rhs []*bufferSpec, ok bool) {
+	specs := &lhs
+	var current *bufferSpec
+
+	for _, node := range nodes {
+		if node.IsSymbol("==>") {
+			specs = &rhs
+			current = nil
+			continue
+		}
+
+		if node.Type == lisp.NodeSymbol {
+			if match := bufferActionRegex.FindStringSubmatch(node.Value); match != nil {
+				current = &bufferSpec{action: match[1], bufferName: match[2], node: node}
+				*specs = append(*specs, current)
+				continue
+			}
+
+			if match := commandRegex.FindStringSubmatch(node.Value); match != nil {
+				current = &bufferSpec{action: strings.ToLower(match[1]), node: node}
+				*specs = append(*specs, current)
+				continue
+			}
+		}
+
+		if current == nil {
+			i.error(node, "unexpected %s in production", node)
+			return nil, nil, false
+		}
+
+		current.args = append(current.args, node)
+	}
+
+	return lhs, rhs, true
+}
+
+// addProduction converts a production. If any part of it cannot be converted, it is not added.
+func (i *importer) addProduction(node *lisp.Node) {
+	if len(node.Children) < 2 || node.Children[1].Type != lisp.NodeSymbol {
+		i.error(node, "production is missing a name")
+		return
+	}
+
+	nameNode := node.Children[1]
+
+	production := &actr.Production{
+		Model:       i.model,
+		Name:        i.ident(nameNode.Value),
+		VarIndexMap: map[string]actr.VarIndex{},
+	}
+
+	body := node.Children[2:]
+	if len(body) > 0 && body[0].Type == lisp.NodeString {
+		description := body[0].Value
+		production.Description = &description
+		body = body[1:]
+	}
+
+	lhs, rhs, ok := i.splitProduction(body)
+	if !ok {
+		i.error(nameNode, "production %q not imported", nameNode.Value)
+		return
+	}
+
+	ok = true
+	for _, spec := range lhs {
+		if !i.addMatch(production, spec) {
+			ok = false
+		}
+	}
+
+	// don't bother with the actions if the conditions failed since we'll only get more errors
+	if ok {
+		for _, spec := range rhs {
+			if !i.addAction(production, spec) {
+				ok = false
+			}
+		}
+	}
+
+	if ok && len(production.Matches) == 0 {
+		i.error(nameNode, "production %q has no conditions", nameNode.Value)
+		ok = false
+	}
+
+	if ok && len(production.DoStatements) == 0 {
+		i.error(nameNode, "production %q has no actions", nameNode.Value)
+		ok = false
+	}
+
+	if !ok {
+		i.error(nameNode, "production %q not imported", nameNode.Value)
+		return
+	}
+
+	simplifyUnusedVars(production)
+
+	i.productions[strings.ToLower(nameNode.Value)] = production
+	i.model.Productions = append(i.model.Productions, production)
+}
+
+// addMatch converts a buffer test on the left-hand side of a production.
+func (i *importer) addMatch(production *actr.Production, spec *bufferSpec) bool {
+	if spec.args == nil && spec.action != "=" {
+		i.error(spec.node, "%s is missing its tests", spec.node.Value)
+		return false
+	}
+
+	switch spec.action {
+	case "=":
+		buff := i.lookupBuffer(spec.bufferName)
+		if buff == nil {
+			i.error(spec.node, "unsupported buffer %q", spec.bufferName)
+			return false
+		}
+
+		if !i.parseSlots(spec.args, spec) {
+			return false
+		}
+
+		pattern := i.createMatchPattern(production, buff, spec)
+		if pattern == nil {
+			return false
+		}
+
+		production.Matches = append(production.Matches, &actr.Match{
+			BufferPattern: &actr.BufferPatternMatch{
+				Buffer:  buff,
+				Pattern: pattern,
+			},
+		})
+
+	case "?":
+		return i.addQuery(production, spec)
+
+	default:
+		i.error(spec.node, "unsupported condition %s", spec.node.Value)
+		return false
+	}
+
+	return true
+}
+
+// createMatchPattern creates a pattern to match a buffer. Slots which are not tested are wildcards.
+func (i *importer) createMatchPattern(production *actr.Production, buff buffer.Interface, spec *bufferSpec) *actr.Pattern {
+	if spec.isa == nil && len(spec.tests) == 0 {
+		return &actr.Pattern{AnyChunk: true}
+	}
+
+	if len(spec.params) > 0 {
+		i.error(spec.params[0].value, "unsupported request parameter %s in condition", spec.params[0].slot)
+		return nil
+	}
+
+	chunk := i.inferChunk(spec.isa, spec.tests)
+	if chunk == nil {
+		i.error(spec.node, "could not determine the chunk-type for %s", spec.node.Value)
+		return nil
+	}
+
+	// group the tests by slot since vanilla allows several tests per slot
+	slotTests := make([][]slotTest, chunk.NumSlots)
+	for _, test := range spec.tests {
+		index := chunk.SlotIndex(i.ident(test.slot))
+		if index == -1 {
+			i.error(test.value, "slot %q not found in chunk-type %q", test.slot, chunk.TypeName)
+			return nil
+		}
+
+		slotTests[index-1] = append(slotTests[index-1], test)
+	}
+
+	pattern := &actr.Pattern{Chunk: chunk}
+
+	// first pass creates the slots so that all the vars are known before we add constraints
+	var constraints []slotTest
+	for index, tests := range slotTests {
+		slot := &actr.PatternSlot{Wildcard: true}
+
+		if len(tests) > 0 {
+			first := tests[0]
+
+			// prefer a positive test for the slot itself
+			for _, test := range tests {
+				if test.modifier == "" || test.modifier == "=" {
+					first = test
+					break
+				}
+			}
+
+			switch first.modifier {
+			case "", "=", "-":
+				slot = i.createPatternSlot(first.value)
+				if slot == nil {
+					i.error(first.value, "unsupported value for slot %q", first.slot)
+					return nil
+				}
+
+				slot.Negated = first.modifier == "-"
+
+				for _, test := range tests {
+					if test != first {
+						constraints = append(constraints, test)
+					}
+				}
+
+			case "<", "<=", ">", ">=":
+				// we only have numeric comparisons on the slot, so bind it to a new variable to constrain
+				name := fmt.Sprintf("?%s_%s", i.ident(spec.bufferName), i.ident(first.slot))
+				slot = &actr.PatternSlot{Var: &actr.PatternVar{Name: &name}}
+
+				constraints = append(constraints, tests...)
+
+			default:
+				i.error(first.value, "unsupported comparison %q on slot %q", first.modifier, first.slot)
+				return nil
+			}
+
+			if slot.Var != nil && !slot.Negated {
+				varName := *slot.Var.Name
+				if _, ok := production.VarIndexMap[varName]; !ok {
+					production.VarIndexMap[varName] = actr.VarIndex{
+						Var:      slot.Var,
+						Buffer:   buff,
+						SlotName: chunk.SlotName(index),
+					}
+				}
+			}
+		}
+
+		pattern.AddSlot(slot)
+	}
+
+	// additional tests on a slot are converted to constraints on the slot's variable
+	for _, test := range constraints {
+		index := chunk.SlotIndex(i.ident(test.slot))
+		slot := pattern.Slots[index-1]
+
+		if slot.Var == nil || slot.Negated {
+			i.error(test.value, "cannot convert multiple tests on slot %q", test.slot)
+			return nil
+		}
+
+		comparison := actr.Equal
+		switch test.modifier {
+		case "", "=":
+		case "-":
+			comparison = actr.NotEqual
+		case "<":
+			comparison = actr.LessThan
+		case "<=":
+			comparison = actr.LessThanOrEqual
+		case ">":
+			comparison = actr.GreaterThan
+		case ">=":
+			comparison = actr.GreaterThanOrEqual
+		default:
+			i.error(test.value, "unsupported comparison %q on slot %q", test.modifier, test.slot)
+			return nil
+		}
+
+		rhs := i.createValue(test.value)
+		if rhs == nil || rhs.ID != nil {
+			i.error(test.value, "cannot convert test on slot %q", test.slot)
+			return nil
+		}
+
+		if comparison.IsRelational() && rhs.Number == nil && rhs.Var == nil {
+			i.error(test.value, "cannot compare slot %q to %s using %q", test.slot, test.value, test.modifier)
+			return nil
+		}
+
+		varIndex := production.VarIndexMap[*slot.Var.Name]
+		varIndex.Var.Constraints = append(varIndex.Var.Constraints, &actr.Constraint{
+			LHS:        slot.Var.Name,
+			Comparison: comparison,
+			RHS:        rhs,
+		})
+	}
+
+	return pattern
+}
+
+// addQuery converts a buffer query such as "?retrieval> state free".
+func (i *importer) addQuery(production *actr.Production, spec *bufferSpec) bool {
+	buff := i.lookupBuffer(spec.bufferName)
+	if buff == nil {
+		i.error(spec.node, "unsupported buffer %q", spec.bufferName)
+		return false
+	}
+
+	if !i.parseSlots(spec.args, spec) {
+		return false
+	}
+
+	match := &actr.Match{}
+
+	for _, test := range spec.tests {
+		if test.modifier != "" {
+			i.error(test.value, "unsupported query %s %s %s", test.modifier, test.slot, test.value)
+			return false
+		}
+
+		state := strings.ToLower(test.value.Value)
+
+		switch strings.ToLower(test.slot) {
+		case "state":
+			if !modules.IsValidState(state) {
+				i.error(test.value, "unsupported module state %q", test.value.Value)
+				return false
+			}
+
+			match.ModuleState = &actr.ModuleStateMatch{
+				Module: i.moduleForBuffer(buff),
+				Buffer: buff,
+				State:  state,
+			}
+
+		case "buffer":
+			if !buffer.IsValidState(state) {
+				i.error(test.value, "unsupported buffer state %q", test.value.Value)
+				return false
+			}
+
+			match.BufferState = &actr.BufferStateMatch{
+				Buffer: buff,
+				State:  state,
+			}
+
+		default:
+			i.error(test.value, "unsupported query %s %s", test.slot, test.value)
+			return false
+		}
+	}
+
+	production.Matches = append(production.Matches, match)
+
+	return true
+}
+
+// addAction converts an action on the right-hand side of a production.
+func (i *importer) addAction(production *actr.Production, spec *bufferSpec) bool {
+	switch spec.action {
+	case "=":
+		return i.addModification(production, spec)
+
+	case "+":
+		return i.addRequest(production, spec)
+
+	case "-":
+		buff := i.lookupBuffer(spec.bufferName)
+		if buff == nil {
+			i.error(spec.node, "unsupported buffer %q", spec.bufferName)
+			return false
+		}
+
+		production.AddDoStatement(&actr.Statement{
+			Clear: &actr.ClearStatement{BufferNames: []string{buff.Name()}},
+		})
+
+	case "output":
+		return i.addOutput(production, spec)
+
+	case "stop":
+		production.AddDoStatement(&actr.Statement{Stop: &actr.StopStatement{}})
+
+	case "eval":
+		return i.addEval(production, spec)
+
+	default:
+		i.error(spec.node, "unsupported action %s", spec.node.Value)
+		return false
+	}
+
+	return true
+}
+
+// addModification converts a buffer modification such as "=goal> state counting".
+func (i *importer) addModification(production *actr.Production, spec *bufferSpec) bool {
+	buff := i.lookupBuffer(spec.bufferName)
+	if buff == nil {
+		i.error(spec.node, "unsupported buffer %q", spec.bufferName)
+		return false
+	}
+
+	if !i.parseSlots(spec.args, spec) {
+		return false
+	}
+
+	if len(spec.tests) == 0 {
+		i.warning(spec.node, "ignoring %s since it does not modify the buffer", spec.node.Value)
+		return true
+	}
+
+	match := production.LookupMatchByBuffer(buff.Name())
+	if match == nil || match.Pattern.AnyChunk {
+		i.error(spec.node, "cannot modify buffer %q without matching its chunk-type", buff.Name())
+		return false
+	}
+
+	chunk := match.Pattern.Chunk
+
+	for _, test := range spec.tests {
+		if test.modifier != "" {
+			i.error(test.value, "unsupported modifier %q in modification", test.modifier)
+			return false
+		}
+
+		slotName := i.ident(test.slot)
+		index := chunk.SlotIndex(slotName)
+		if index == -1 {
+			i.error(test.value, "slot %q not found in chunk-type %q", test.slot, chunk.TypeName)
+			return false
+		}
+
+		value := i.createValue(test.value)
+		if value == nil {
+			i.error(test.value, "unsupported value for slot %q", test.slot)
+			return false
+		}
+
+		// set statements store variables without the "?"
+		if value.Var != nil {
+			varName := strings.TrimPrefix(*value.Var, "?")
+			value.Var = &varName
+		}
+
+		slot := &actr.SetSlot{
+			Name:      slotName,
+			SlotIndex: index,
+			Value:     value,
+		}
+
+		set := production.LookupSetStatementByBuffer(buff.Name())
+		if set == nil {
+			set = &actr.SetStatement{Buffer: buff, Chunk: chunk}
+			production.AddDoStatement(&actr.Statement{Set: set})
+		}
+
+		production.AddSlotToSetStatement(set, slot)
+	}
+
+	return true
+}
+
+// addRequest converts a buffer request such as "+retrieval> isa count first =num".
+func (i *importer) addRequest(production *actr.Production, spec *bufferSpec) bool {
+	buff := i.lookupBuffer(spec.bufferName)
+	if buff == nil {
+		i.error(spec.node, "unsupported buffer %q", spec.bufferName)
+		return false
+	}
+
+	if len(spec.args) == 1 {
+		i.error(spec.node, "unsupported request of a chunk (%s) to %s", spec.args[0], spec.node.Value)
+		return false
+	}
+
+	if !i.parseSlots(spec.args, spec) {
+		return false
+	}
+
+	isRetrieval := buff == i.model.Memory.Buffers().At(0)
+
+	chunk := i.inferChunk(spec.isa, spec.tests)
+	if chunk == nil {
+		i.error(spec.node, "could not determine the chunk-type for %s", spec.node.Value)
+		return false
+	}
+
+	pattern := &actr.Pattern{Chunk: chunk}
+	for index := 0; index < chunk.NumSlots; index++ {
+		// unspecified slots match anything in a retrieval, but are empty in a new chunk
+		pattern.AddSlot(&actr.PatternSlot{Wildcard: isRetrieval, Nil: !isRetrieval})
+	}
+
+	for _, test := range spec.tests {
+		index := chunk.SlotIndex(i.ident(test.slot))
+		if index == -1 {
+			i.error(test.value, "slot %q not found in chunk-type %q", test.slot, chunk.TypeName)
+			return false
+		}
+
+		if test.modifier != "" && (test.modifier != "-" || !isRetrieval) {
+			i.error(test.value, "unsupported modifier %q in request", test.modifier)
+			return false
+		}
+
+		slot := i.createPatternSlot(test.value)
+		if slot == nil {
+			i.error(test.value, "unsupported value for slot %q", test.slot)
+			return false
+		}
+
+		slot.Negated = test.modifier == "-"
+		pattern.Slots[index-1] = slot
+	}
+
+	if !isRetrieval {
+		for _, param := range spec.params {
+			i.warning(param.value, "ignoring unsupported request parameter %s", param.slot)
+		}
+
+		production.AddDoStatement(&actr.Statement{
+			Set: &actr.SetStatement{Buffer: buff, Pattern: pattern},
+		})
+
+		return true
+	}
+
+	requestParameters := map[string]string{}
+	for _, param := range spec.params {
+		if !strings.EqualFold(param.slot, ":recently-retrieved") {
+			i.warning(param.value, "ignoring unsupported request parameter %s", param.slot)
+			continue
+		}
+
+		requestParameters["recently_retrieved"] = strings.ToLower(param.value.Value)
+	}
+
+	production.AddDoStatement(&actr.Statement{
+		Recall: &actr.RecallStatement{
+			Pattern:           pattern,
+			MemoryModuleName:  i.model.Memory.ModuleName(),
+			RequestParameters: requestParameters,
+		},
+	})
+
+	return true
+}
+
+// addOutput converts !output! to a print statement. It handles these forms:
+//
+//	!output! ("count: ~a" =x)
+//	!output! (=x =y)
+//	!output! =x
+func (i *importer) addOutput(production *actr.Production, spec *bufferSpec) bool {
+	if len(spec.args) != 1 {
+		i.error(spec.node, "unsupported !output!")
+		return false
+	}
+
+	args := spec.args
+	if spec.args[0].IsList() {
+		args = spec.args[0].Children
+	}
+
+	values := []*actr.Value{}
+
+	if len(args) > 0 && args[0].Type == lisp.NodeString {
+		format := args[0].Value
+		args = args[1:]
+
+		// Split the format string at the directives and put the args in their places
+		literals := formatDirectiveRegex.Split(format, -1)
+		directives := formatDirectiveRegex.FindAllString(format, -1)
+
+		if strings.Contains(strings.Join(literals, ""), "~") {
+			i.error(spec.args[0], "unsupported format directive in %q", format)
+			return false
+		}
+
+		for index, literal := range literals {
+			if literal != "" {
+				str := literal
+				values = append(values, &actr.Value{Str: &str})
+			}
+
+			if index >= len(directives) || directives[index] == "~%" {
+				continue
+			}
+
+			if len(args) == 0 {
+				i.error(spec.args[0], "not enough arguments for format %q", format)
+				return false
+			}
+
+			value := i.createPrintValue(args[0])
+			if value == nil {
+				return false
+			}
+
+			values = append(values, value)
+			args = args[1:]
+		}
+
+		if len(args) > 0 {
+			i.error(args[0], "too many arguments for format %q", format)
+			return false
+		}
+	} else {
+		for index, arg := range args {
+			if index > 0 {
+				space := " "
+				values = append(values, &actr.Value{Str: &space})
+			}
+
+			value := i.createPrintValue(arg)
+			if value == nil {
+				return false
+			}
+
+			values = append(values, value)
+		}
+	}
+
+	production.AddDoStatement(&actr.Statement{Print: &actr.PrintStatement{Values: &values}})
+
+	return true
+}
+
+// createPrintValue converts a value for printing. IDs in print statements refer to buffers
+// in amod, so symbols are printed as strings.
+func (i *importer) createPrintValue(node *lisp.Node) *actr.Value {
+	value := i.createValue(node)
+	if value == nil {
+		i.error(node, "unsupported value %s in !output!", node)
+		return nil
+	}
+
+	if value.ID != nil || value.Nil != nil {
+		str := node.Value
+		return &actr.Value{Str: &str}
+	}
+
+	return value
+}
+
+// addEval handles !eval!. The only thing we can convert is triggering a reward.
+func (i *importer) addEval(production *actr.Production, spec *bufferSpec) bool {
+	if len(spec.args) == 1 && spec.args[0].Head() == "trigger-reward" && len(spec.args[0].Children) == 2 {
+		reward, ok := numberValue(spec.args[0].Children[1])
+		if ok {
+			production.Reward = &reward
+			return true
+		}
+	}
+
+	i.error(spec.node, "unsupported !eval!")
+	return false
+}
+
+// addProductionParams handles spp which sets production parameters:
+//
+//	(spp increment :u 5 :reward 10)
+//	(spp (increment start) :u 5)
+func (i *importer) addProductionParams(node *lisp.Node) {
+	if len(node.Children) < 2 {
+		return
+	}
+
+	nameNodes := []*lisp.Node{node.Children[1]}
+	if node.Children[1].IsList() {
+		nameNodes = node.Children[1].Children
+	}
+
+	args := node.Children[2:]
+
+	for _, nameNode := range nameNodes {
+		production, ok := i.productions[strings.ToLower(nameNode.Value)]
+		if !ok {
+			i.warning(nameNode, "ignoring parameters for unknown production %q", nameNode.Value)
+			continue
+		}
+
+		for index := 0; index+1 < len(args); index += 2 {
+			param := args[index]
+
+			value, ok := numberValue(args[index+1])
+
+			switch {
+			case !ok:
+				i.warning(args[index+1], "ignoring unsupported value for %s", param.Value)
+
+			case param.IsSymbol(":u"):
+				production.Utility = &value
+
+			case param.IsSymbol(":reward"):
+				production.Reward = &value
+
+			default:
+				i.warning(param, "ignoring unsupported production parameter %s", param.Value)
+			}
+		}
+	}
+}
+
+// simplifyUnusedVars replaces variables which are only referenced once with wildcards.
+// Vanilla allows these, but amod requires "*".
+func simplifyUnusedVars(production *actr.Production) {
+	counts := map[string]int{}
+
+	countPattern := func(pattern *actr.Pattern) {
+		for _, slot := range pattern.Slots {
+			if slot.Var != nil {
+				counts[*slot.Var.Name]++
+
+				for _, constraint := range slot.Var.Constraints {
+					counts[*constraint.LHS]++
+					if constraint.RHS.Var != nil {
+						counts[*constraint.RHS.Var]++
+					}
+				}
+			}
+		}
+	}
+
+	for _, match := range production.Matches {
+		if match.BufferPattern != nil && !match.BufferPattern.Pattern.AnyChunk {
+			countPattern(match.BufferPattern.Pattern)
+		}
+	}
+
+	for _, statement := range production.DoStatements {
+		switch {
+		case statement.Set != nil && statement.Set.Slots != nil:
+			for _, slot := range *statement.Set.Slots {
+				if slot.Value.Var != nil {
+					counts["?"+*slot.Value.Var]++
+				}
+			}
+
+		case statement.Set != nil:
+			countPattern(statement.Set.Pattern)
+
+		case statement.Recall != nil:
+			countPattern(statement.Recall.Pattern)
+
+		case statement.Print != nil:
+			for _, value := range *statement.Print.Values {
+				if value.Var != nil {
+					counts[*value.Var]++
+				}
+			}
+		}
+	}
+
+	for _, match := range production.Matches {
+		if match.BufferPattern == nil || match.BufferPattern.Pattern.AnyChunk {
+			continue
+		}
+
+		for _, slot := range match.BufferPattern.Pattern.Slots {
+			if slot.Var == nil || counts[*slot.Var.Name] != 1 {
+				continue
+			}
+
+			delete(production.VarIndexMap, *slot.Var.Name)
+
+			*slot = actr.PatternSlot{Wildcard: true}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package vanilla_actr
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylelemons/godebug/diff"
+
+	"github.com/asmaloney/gactar/amod"
+)
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	// import our test models as well as the code we generate
+	match, err := filepath.Glob("testdata/import/*.lisp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated, err := filepath.Glob("testdata/*.lisp.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match = append(match, generated...)
+
+	for _, input := range match {
+		input := input
+		name := strings.Split(filepath.Base(input), ".")[0]
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			output := filepath.Join("testdata", "import", name+".amod.golden")
+
+			runImportTest(t, input, output)
+		})
+	}
+}
+
+func runImportTest(t *testing.T, input, output string) { //nolint to avoid Helper info since it doesn't apply
+	model, log, err := ImportModel(input)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, log)
+	}
+
+	code := amod.GenerateCode(model)
+
+	// the generated amod must be valid
+	_, amodLog, err := amod.GenerateModel(string(code))
+	if err != nil {
+		t.Errorf("generated amod is not valid:\n%s\n%s", amodLog, code)
+		return
+	}
+
+	expected, err := os.ReadFile(output)
+	if err != nil {
+		err = os.WriteFile(output, code, 0660)
+		if err != nil {
+			return
+		}
+
+		t.Skip("golden file did not exist, so I created it")
+		return
+	}
+
+	if !bytes.Equal(code, expected) {
+		diffs := diff.Diff(string(expected), string(code))
+		t.Errorf("code does not match %s file:\n%s", output, diffs)
+	}
+}
+
+func TestImportIssues(t *testing.T) {
+	t.Parallel()
+
+	_, log, err := ImportModel("testdata/import/unsupported.lisp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `WARN: ignoring unsupported form (defvar ...) (unsupported.lisp, line 3, col 1)
+WARN: ignoring unsupported parameter :needs-mouse (unsupported.lisp, line 7, col 28)
+ERROR: unsupported buffer "visual-location" (unsupported.lisp, line 18, col 4)
+ERROR: production "attend" not imported (unsupported.lisp, line 14, col 4)
+ERROR: cannot compare slot "value" to "zero" using ">" (unsupported.lisp, line 29, col 16)
+ERROR: production "compare" not imported (unsupported.lisp, line 25, col 4)
+WARN: ignoring unsupported production parameter :at (unsupported.lisp, line 45, col 16)
+WARN: ignoring unsupported form (run ...) (unsupported.lisp, line 50, col 1)
+`
+
+	if log.String() != expected {
+		t.Errorf("incorrect log:\n%s", diff.Diff(expected, log.String()))
+	}
+}
+
+func TestImportNoModel(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := ImportModelFromString("(clear-all)", "empty.lisp")
+	if err != ErrImportNoModel {
+		t.Errorf("expected ErrImportNoModel, got %v", err)
+	}
+
+	_, log, err := ImportModelFromString("(define-model foo", "broken.lisp")
+	if err == nil {
+		t.Errorf("expected syntax error")
+	}
+
+	if !log.HasError() {
+		t.Errorf("expected syntax error in log")
+	}
+}
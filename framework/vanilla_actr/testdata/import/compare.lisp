@@ -0,0 +1,50 @@
+;;; Numeric comparisons on slots.
+
+(clear-all)
+
+(define-model compare
+
+(sgp :esc t :v t)
+
+(chunk-type range low high value state)
+
+(add-dm
+ (first-goal ISA range low 1 high 10 value 5 state check))
+
+(P in-range
+   =goal>
+      ISA         range
+      low         =low
+      high        =high
+      value       =value
+    >= value      =low
+    <= value      =high
+      state       check
+==>
+   =goal>
+      state       in
+)
+
+(P below
+   =goal>
+      ISA         range
+      low         =low
+    < value       =low
+      state       check
+==>
+   =goal>
+      state       below
+)
+
+(P above
+   =goal>
+      ISA         range
+    > value       10
+      state       check
+==>
+   =goal>
+      state       above
+)
+
+(goal-focus first-goal)
+)
@@ -0,0 +1,50 @@
+(clear-all)
+
+(defvar *response* nil)
+
+(define-model unsupported
+
+(sgp :esc t :ul t :egs 0.2 :needs-mouse t)
+
+(chunk-type task state value)
+
+(add-dm
+ (goal isa task state start value 1))
+
+(p attend
+   =goal>
+      isa      task
+      state    start
+   ?visual-location>
+      state    free
+ ==>
+   +visual-location>
+      isa      visual-location
+)
+
+(p compare
+   =goal>
+      isa      task
+      state    start
+    > value    "zero"
+ ==>
+   =goal>
+      state    "done"
+)
+
+(p done
+   =goal>
+      isa      task
+      state    "done"
+ ==>
+   !output!    ("value is ~a" "done")
+   !eval!      (trigger-reward 2)
+   !stop!
+)
+
+(spp done :u 5 :at 0.1)
+
+(goal-focus goal)
+)
+
+(run 10)
@@ -3,7 +3,9 @@
 package vanilla_actr
 
 import (
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -27,7 +29,13 @@ import (
 //go:embed vanilla_print.lisp
 var vanillaPrint string
 
-const vanillaPrintFileName = "vanilla_print.lisp"
+//go:embed vanilla_trace.lisp
+var vanillaTrace string
+
+const (
+	vanillaPrintFileName = "vanilla_print.lisp"
+	vanillaTraceFileName = "vanilla_trace.lisp"
+)
 
 func init() {
 	// We only support 64-bit. Nobody still uses 32-bit, right?
@@ -54,6 +62,15 @@ var Info framework.Info = framework.Info{
 	// ExecutableName: have to set this in init()
 }
 
+// lispNames maps the names of the buffers, chunk types, and slots provided by our modules
+// to ACT-R's names for them. ACT-R uses hyphens which are not valid in amod names.
+var lispNames = map[string]string{
+	"visual_location": "visual-location",
+	"visual_object":   "visual-object",
+	"screen_x":        "screen-x",
+	"screen_y":        "screen-y",
+}
+
 type VanillaACTR struct {
 	framework.Framework
 	framework.WriterHelper
@@ -63,6 +80,7 @@ type VanillaACTR struct {
 	envPath   string
 
 	printStatementCount int
+	traceEvents         bool // output print & stop events for the trace
 }
 
 // New creates a new VanillaACTR instance and sets some paths.
@@ -85,6 +103,13 @@ func (VanillaACTR) Info() *framework.Info {
 	return &Info
 }
 
+func (v VanillaACTR) NewInstance(tempPath string) framework.Framework {
+	return &VanillaACTR{
+		tmpPath: tempPath,
+		envPath: v.envPath,
+	}
+}
+
 func (VanillaACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 	log = issues.New()
 	return
@@ -106,7 +131,7 @@ func (c VanillaACTR) Model() (model *actr.Model) {
 	return c.model
 }
 
-func (v *VanillaACTR) Run(options *runoptions.Options) (result *framework.RunResult, err error) {
+func (v *VanillaACTR) Run(ctx context.Context, options *runoptions.Options, sink framework.OutputSink) (result *framework.RunResult, err error) {
 	modelFile, err := v.WriteModel(v.tmpPath, options)
 	if err != nil {
 		return
@@ -118,7 +143,7 @@ func (v *VanillaACTR) Run(options *runoptions.Options) (result *framework.RunRes
 		GeneratedCode: v.GetContents(),
 	}
 
-	runFile, err := v.createRunFile(modelFile)
+	runFile, err := v.createRunFile(modelFile, options)
 	if err != nil {
 		return
 	}
@@ -129,18 +154,47 @@ func (v *VanillaACTR) Run(options *runoptions.Options) (result *framework.RunRes
 	}
 
 	// run it!
-	output, err := executil.ExecCommand(Info.ExecutableName, "--batch", "--quiet", "--load", runFile)
+	filter := newPreambleFilter(sink)
+	writer := framework.NewLineWriter(filter, options.IsTracingEvents())
+	output, err := executil.ExecCommandWithOutput(ctx, writer, Info.ExecutableName, "--batch", "--quiet", "--load", runFile)
+	writer.Flush()
+	filter.flush()
 	output = removePreamble(output)
 	if err != nil {
-		err = &executil.ErrExecuteCommand{Output: output}
+		var execErr *executil.ErrExecuteCommand
+		if errors.As(err, &execErr) {
+			execErr.Output = output
+		}
 		return
 	}
 
 	result.Output = []byte(output)
 
+	if options.IsTracingEvents() {
+		result.Trace, result.Output = framework.ParseTrace(result.Output)
+		v.fixProductionNames(result.Trace)
+	}
+
 	return
 }
 
+// fixProductionNames restores the case of production names in the trace since ACT-R
+// converts them to uppercase.
+func (v VanillaACTR) fixProductionNames(trace *framework.Trace) {
+	for i, event := range trace.Events {
+		if event.Production == "" {
+			continue
+		}
+
+		for _, production := range v.model.Productions {
+			if strings.EqualFold(production.Name, event.Production) {
+				trace.Events[i].Production = production.Name
+				break
+			}
+		}
+	}
+}
+
 // WriteModel converts the internal actr.Model to Lisp and writes it to a file.
 func (v *VanillaACTR) WriteModel(path string, options *runoptions.Options) (outputFileName string, err error) {
 	// If our model has a print statement, then write out our support file
@@ -151,6 +205,13 @@ func (v *VanillaACTR) WriteModel(path string, options *runoptions.Options) (outp
 		}
 	}
 
+	if options.IsTracingEvents() {
+		err = framework.WriteSupportFile(path, vanillaTraceFileName, vanillaTrace)
+		if err != nil {
+			return
+		}
+	}
+
 	outputFileName = fmt.Sprintf("%s.lisp", v.modelName)
 	if path != "" {
 		outputFileName = fmt.Sprintf("%s/%s", path, outputFileName)
@@ -183,6 +244,8 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 
 	goal := patterns["goal"]
 
+	v.traceEvents = options.IsTracingEvents()
+
 	err = v.InitWriterHelper()
 	if err != nil {
 		return
@@ -194,6 +257,8 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 
 	v.Writeln("")
 
+	v.writeExtensions()
+
 	v.writeExtraBufferInit()
 
 	v.Writeln("(define-model %s\n", v.modelName)
@@ -243,6 +308,19 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 		v.Writeln("\t:dat %s", numbers.Float64Str(*procedural.DefaultActionTime))
 	}
 
+	if procedural.InitialUtility != nil {
+		v.Writeln("\t:iu %s", numbers.Float64Str(*procedural.InitialUtility))
+	}
+
+	if procedural.UtilityNoise != nil {
+		v.Writeln("\t:egs %s", numbers.Float64Str(*procedural.UtilityNoise))
+	}
+
+	if procedural.IsUsingUtilityLearning() {
+		v.Writeln("\t:ul t")
+		v.Writeln("\t:alpha %s", numbers.Float64Str(*procedural.UtilityLearningRate))
+	}
+
 	switch *options.LogLevel {
 	case "min":
 		v.Writeln("\t:trace-detail low")
@@ -263,6 +341,31 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 			v.Writeln("\t:imaginal-delay %s", numbers.Float64Str(*imaginal.Delay))
 		}
 	}
+
+	blending := v.model.BlendingModule()
+	if blending != nil && blending.Temperature != nil {
+		v.Writeln("\t:tmp %s", numbers.Float64Str(*blending.Temperature))
+	}
+
+	visual := v.model.VisualModule()
+	if visual != nil && visual.AttentionLatency != nil {
+		v.Writeln("\t:visual-attention-latency %s", numbers.Float64Str(*visual.AttentionLatency))
+	}
+
+	temporal := v.model.TemporalModule()
+	if temporal != nil {
+		if temporal.TimeNoise != nil {
+			v.Writeln("\t:time-noise %s", numbers.Float64Str(*temporal.TimeNoise))
+		}
+
+		if temporal.TimeMult != nil {
+			v.Writeln("\t:time-mult %s", numbers.Float64Str(*temporal.TimeMult))
+		}
+
+		if temporal.TimeStartIncrement != nil {
+			v.Writeln("\t:time-master-start-increment %s", numbers.Float64Str(*temporal.TimeStartIncrement))
+		}
+	}
 	v.Writeln(")\n")
 
 	// random
@@ -272,7 +375,7 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 
 	// chunks
 	for _, chunk := range v.model.Chunks {
-		if chunk.IsInternal() {
+		if chunk.IsInternal() || chunk.BuiltIn {
 			continue
 		}
 
@@ -285,6 +388,8 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 
 	v.writeSimilarities()
 
+	v.writeAssociations()
+
 	v.writeProductions()
 
 	// Useful for debugging - output the contents of the imaginal buffer and the dm
@@ -295,6 +400,8 @@ func (v *VanillaACTR) GenerateCode(options *runoptions.Options) (code []byte, er
 
 	v.Writeln(")")
 
+	v.writeScreen()
+
 	code = v.GetContents()
 	return
 }
@@ -328,6 +435,14 @@ func (v VanillaACTR) writeAuthors() {
 	v.Writeln("")
 }
 
+// If we use any modules which are ACT-R extensions, load them
+func (v VanillaACTR) writeExtensions() {
+	if v.model.BlendingModule() != nil {
+		v.Writeln(`(require-extra "blending")`)
+		v.Writeln("")
+	}
+}
+
 // If we have any extra buffers, define them in code
 func (v VanillaACTR) writeExtraBufferInit() {
 	extraBuffers := v.model.LookupModule("extra_buffers")
@@ -348,6 +463,29 @@ func (v VanillaACTR) writeExtraBufferInit() {
 	}
 }
 
+// If the model uses the visual or motor modules, create a window with the screen's items and
+// install it so the model can see it and press keys.
+func (v VanillaACTR) writeScreen() {
+	if v.model.VisualModule() == nil {
+		if v.model.MotorModule() != nil {
+			v.Writeln("")
+			v.Writeln(`(install-device '("motor" "keyboard"))`)
+		}
+
+		return
+	}
+
+	v.Writeln("")
+	v.Writeln(`(let ((window (open-exp-window "%s" :visible nil)))`, v.model.Name)
+
+	for _, item := range v.model.Screen {
+		v.Writeln("\t;; amod line %d", item.AMODLineNumber)
+		v.Writeln("\t(add-text-to-exp-window window %q :x %d :y %d)", item.Text, item.X, item.Y)
+	}
+
+	v.Writeln("\t(install-device window))")
+}
+
 // If spreading activation is on, write its parameters
 func (v VanillaACTR) writeSpreadingActivation() {
 	memory := v.model.Memory
@@ -363,7 +501,7 @@ func (v VanillaACTR) writeSpreadingActivation() {
 
 		if buffer.SpreadingActivation() != 0.0 {
 			// the default ACT-R parameter for any buffer is ":<buffer>-activation"
-			paramName := fmt.Sprintf(":%s-activation", bufferName)
+			paramName := fmt.Sprintf(":%s-activation", lispName(bufferName))
 
 			// "goal" is an exception
 			if bufferName == "goal" {
@@ -408,19 +546,29 @@ func (v VanillaACTR) writeInitializers(goal *actr.Pattern) {
 
 	v.writeImplicitChunks()
 
+	// chunk names of initializers with base-level settings
+	baseLevelNames := map[*actr.Initializer]string{}
+
 	factNum := 0
 	for _, init := range v.model.Initializers {
 		moduleName := init.Module.ModuleName()
 
 		if moduleName == "memory" {
-			v.Writeln(" ;; amod line %d", init.AMODLineNumber)
+			name := ""
 			if init.ChunkName != nil {
-				v.Writeln(" (%s", *init.ChunkName)
+				name = *init.ChunkName
 			} else {
-				v.Writeln(" (%s_%d", init.Pattern.Chunk.TypeName, factNum)
+				name = fmt.Sprintf("%s_%d", init.Pattern.Chunk.TypeName, factNum)
 				factNum++
 			}
 
+			if init.BaseLevel != nil {
+				baseLevelNames[init] = name
+			}
+
+			v.Writeln(" ;; amod line %d", init.AMODLineNumber)
+			v.Writeln(" (%s", name)
+
 			v.outputPattern(init.Pattern, 1)
 			v.Writeln(" )")
 		} else if moduleName == "goal" {
@@ -441,6 +589,8 @@ func (v VanillaACTR) writeInitializers(goal *actr.Pattern) {
 
 	v.Writeln(")\n")
 
+	v.writeBaseLevels(baseLevelNames)
+
 	// now everything else
 	for _, init := range v.model.Initializers {
 		module := init.Module
@@ -463,6 +613,29 @@ func (v VanillaACTR) writeInitializers(goal *actr.Pattern) {
 	}
 }
 
+// writeBaseLevels sets the reference count and creation time of chunks in memory.
+// Without base-level learning, set-base-levels sets the base-level activation directly
+// so we only use it if base-level learning is on.
+func (v VanillaACTR) writeBaseLevels(names map[*actr.Initializer]string) {
+	if len(names) == 0 || !v.model.Memory.IsUsingBaseLevelLearning() {
+		return
+	}
+
+	v.Writeln("(set-base-levels")
+
+	for _, init := range v.model.Initializers {
+		name, ok := names[init]
+		if !ok {
+			continue
+		}
+
+		v.Writeln("    ;; amod line %d", init.AMODLineNumber)
+		v.Writeln("    (%s %d %s)", name, init.BaseLevel.References, numbers.Float64Str(init.BaseLevel.CreationTime))
+	}
+
+	v.Writeln(")\n")
+}
+
 func (v VanillaACTR) writeSimilarities() {
 	if len(v.model.Similarities) == 0 {
 		return
@@ -478,6 +651,21 @@ func (v VanillaACTR) writeSimilarities() {
 	v.Writeln(")\n")
 }
 
+func (v VanillaACTR) writeAssociations() {
+	if len(v.model.Associations) == 0 {
+		return
+	}
+
+	v.Writeln("(add-sji")
+
+	for _, association := range v.model.Associations {
+		v.Writeln("    ;; amod line %d", association.AMODLineNumber)
+		v.Writeln("    (%s %s %s)", association.Source, association.Target, numbers.Float64Str(association.Value))
+	}
+
+	v.Writeln(")\n")
+}
+
 func (v VanillaACTR) writeProductions() {
 	for _, production := range v.model.Productions {
 		v.Writeln(";; amod line %d", production.AMODLineNumber)
@@ -499,16 +687,60 @@ func (v VanillaACTR) writeProductions() {
 			}
 		}
 
+		if production.Reward != nil {
+			v.Writeln("\t!eval! (trigger-reward %s)", numbers.Float64Str(*production.Reward))
+		}
+
 		v.Writeln(")\n")
 	}
+
+	v.writeProductionUtilities()
+}
+
+// writeProductionUtilities sets the utility of any productions which specify one.
+func (v VanillaACTR) writeProductionUtilities() {
+	for _, production := range v.model.Productions {
+		if production.Utility == nil {
+			continue
+		}
+
+		v.Writeln("(spp %s :u %s)", production.Name, numbers.Float64Str(*production.Utility))
+	}
+}
+
+// lispName returns ACT-R's name for one of our modules' buffers, chunk types, or slots.
+func lispName(name string) string {
+	if lisp, ok := lispNames[name]; ok {
+		return lisp
+	}
+
+	return name
+}
+
+// chunkTypeName returns ACT-R's name for the chunk type.
+func chunkTypeName(chunk *actr.Chunk) string {
+	if chunk.BuiltIn {
+		return lispName(chunk.TypeName)
+	}
+
+	return chunk.TypeName
+}
+
+// slotName returns ACT-R's name for the chunk's slot.
+func slotName(chunk *actr.Chunk, slot string) string {
+	if chunk.BuiltIn {
+		return lispName(slot)
+	}
+
+	return slot
 }
 
 func (v VanillaACTR) outputPattern(pattern *actr.Pattern, tabs int) {
 	tabbedItems := framework.KeyValueList{}
-	tabbedItems.Add("isa", pattern.Chunk.TypeName)
+	tabbedItems.Add("isa", chunkTypeName(pattern.Chunk))
 
 	for i, slot := range pattern.Slots {
-		slotName := pattern.Chunk.SlotNames[i]
+		slotName := slotName(pattern.Chunk, pattern.Chunk.SlotNames[i])
 		addPatternSlot(&tabbedItems, slotName, slot)
 	}
 
@@ -520,7 +752,7 @@ func (v VanillaACTR) outputMatch(match *actr.Match) {
 
 	// check for case where we need to combine module & buffer checks
 	if (match.BufferState != nil) && (match.ModuleState != nil) {
-		bufferName := match.BufferState.Buffer.Name()
+		bufferName := lispName(match.BufferState.Buffer.Name())
 
 		v.Writeln("\t?%s>", bufferName)
 		tabbedItems.Add("buffer", match.BufferState.State)
@@ -532,7 +764,7 @@ func (v VanillaACTR) outputMatch(match *actr.Match) {
 
 	switch {
 	case match.BufferPattern != nil:
-		bufferName := match.BufferPattern.Buffer.Name()
+		bufferName := lispName(match.BufferPattern.Buffer.Name())
 
 		v.Writeln("\t=%s>", bufferName)
 		if !match.BufferPattern.Pattern.AnyChunk {
@@ -540,14 +772,14 @@ func (v VanillaACTR) outputMatch(match *actr.Match) {
 		}
 
 	case match.BufferState != nil:
-		bufferName := match.BufferState.Buffer.Name()
+		bufferName := lispName(match.BufferState.Buffer.Name())
 
 		v.Writeln("\t?%s>", bufferName)
 		tabbedItems.Add("buffer", match.BufferState.State)
 		v.TabWrite(2, tabbedItems)
 
 	case match.ModuleState != nil:
-		bufferName := match.ModuleState.Buffer.Name()
+		bufferName := lispName(match.ModuleState.Buffer.Name())
 
 		v.Writeln("\t?%s>", bufferName)
 		tabbedItems.Add("state", match.ModuleState.State)
@@ -555,39 +787,55 @@ func (v VanillaACTR) outputMatch(match *actr.Match) {
 	}
 }
 
-func addPatternSlot(tabbedItems *framework.KeyValueList, slotName string, slot *actr.PatternSlot) {
-	if slot.Wildcard {
-		return
-	}
-
-	value := ""
-	slotStr := ""
-
-	if slot.Negated {
-		slotStr = "- "
-	}
-
+// patternSlotValue returns the value of the slot as it is written in a pattern.
+func patternSlotValue(slot *actr.PatternSlot) string {
 	switch {
 	case slot.Nil:
-		value = "empty"
+		return "empty"
 
 	case slot.ID != nil:
-		value = *slot.ID
+		return *slot.ID
 
 	case slot.Str != nil:
-		value = fmt.Sprintf("%q", *slot.Str)
+		return fmt.Sprintf("%q", *slot.Str)
 
 	case slot.Num != nil:
-		value = *slot.Num
+		return *slot.Num
 
 	case slot.Var != nil:
 		varName := strings.TrimPrefix(*slot.Var.Name, "?")
-		value = fmt.Sprintf("=%s", varName)
+		return fmt.Sprintf("=%s", varName)
+	}
+
+	return ""
+}
+
+// chunkDefinition returns a chunk definition which may be used with merge-dm.
+// Variables are replaced with their values when the production fires.
+func chunkDefinition(pattern *actr.Pattern) string {
+	items := []string{"isa", chunkTypeName(pattern.Chunk)}
+
+	for i, slot := range pattern.Slots {
+		items = append(items, slotName(pattern.Chunk, pattern.Chunk.SlotNames[i]), patternSlotValue(slot))
+	}
+
+	return "(" + strings.Join(items, " ") + ")"
+}
+
+func addPatternSlot(tabbedItems *framework.KeyValueList, slotName string, slot *actr.PatternSlot) {
+	if slot.Wildcard {
+		return
+	}
+
+	slotStr := ""
+
+	if slot.Negated {
+		slotStr = "- "
 	}
 
 	slotStr += slotName
 
-	tabbedItems.Add(slotStr, value)
+	tabbedItems.Add(slotStr, patternSlotValue(slot))
 
 	// Check for constraints on a var and output them
 	if slot.Var != nil {
@@ -595,17 +843,20 @@ func addPatternSlot(tabbedItems *framework.KeyValueList, slotName string, slot *
 			for _, constraint := range slot.Var.Constraints {
 				slotStr := ""
 
-				if constraint.Comparison == actr.NotEqual {
+				switch {
+				case constraint.Comparison == actr.NotEqual:
 					slotStr = "- "
+
+				case constraint.Comparison.IsRelational():
+					slotStr = constraint.Comparison.String() + " "
 				}
 
 				slotStr += slotName
 
 				if constraint.RHS.Var != nil {
 					varName := strings.TrimPrefix(*constraint.RHS.Var, "?")
-					value = fmt.Sprintf("=%s", varName)
 
-					tabbedItems.Add(slotStr, value)
+					tabbedItems.Add(slotStr, fmt.Sprintf("=%s", varName))
 				} else {
 					tabbedItems.Add(slotStr, constraint.RHS.String())
 				}
@@ -618,14 +869,15 @@ func (p VanillaACTR) outputRequestParameters(params map[string]string, tabs int)
 	tabbedItems := framework.KeyValueList{}
 
 	for _, param := range maps.Keys(params) {
-		if param != "recently_retrieved" {
-			continue
-		}
+		var name string
 
-		name := param
-
-		if param == "recently_retrieved" {
+		switch param {
+		case "recently_retrieved":
 			name = ":recently-retrieved"
+		case "attended":
+			name = ":attended"
+		default:
+			continue
 		}
 
 		tabbedItems.Add(name, params[param])
@@ -639,14 +891,23 @@ func (v *VanillaACTR) outputStatement(s *actr.Statement) {
 	case s.Set != nil:
 		buffer := s.Set.Buffer
 
-		v.Writeln("\t=%s>", buffer.Name())
+		// bind any expressions first so we can use the result in the buffer modification
+		if s.Set.Slots != nil {
+			for _, slot := range *s.Set.Slots {
+				if slot.Value.Expr != nil {
+					v.Writeln("\t!bind!\t=%s\t%s", expressionVarName(buffer.Name(), slot.Name), lispExpression(slot.Value.Expr))
+				}
+			}
+		}
+
+		v.Writeln("\t=%s>", lispName(buffer.Name()))
 
 		if s.Set.Slots != nil {
 			tabbedItems := framework.KeyValueList{}
-			tabbedItems.Add("isa", s.Set.Chunk.TypeName)
+			tabbedItems.Add("isa", chunkTypeName(s.Set.Chunk))
 
 			for _, slot := range *s.Set.Slots {
-				slotName := slot.Name
+				slotName := slotName(s.Set.Chunk, slot.Name)
 
 				switch {
 				case slot.Value.Nil != nil:
@@ -663,6 +924,9 @@ func (v *VanillaACTR) outputStatement(s *actr.Statement) {
 
 				case slot.Value.Str != nil:
 					tabbedItems.Add(slotName, fmt.Sprintf(`%q`, *slot.Value.Str))
+
+				case slot.Value.Expr != nil:
+					tabbedItems.Add(slotName, "="+expressionVarName(buffer.Name(), slot.Name))
 				}
 			}
 			v.TabWrite(2, tabbedItems)
@@ -675,35 +939,146 @@ func (v *VanillaACTR) outputStatement(s *actr.Statement) {
 		v.outputPattern(s.Recall.Pattern, 2)
 		v.outputRequestParameters(s.Recall.RequestParameters, 2)
 
+	case s.Blend != nil:
+		v.Writeln("\t+blending>")
+		v.outputPattern(s.Blend.Pattern, 2)
+
+	case s.Remember != nil:
+		v.Writeln("\t!eval! (merge-dm-fct '(%s))", chunkDefinition(s.Remember.Pattern))
+
+	case s.Reinforce != nil:
+		chunk := s.Reinforce.Chunk.String()
+		if s.Reinforce.Chunk.Var != nil {
+			chunk = "=" + strings.TrimPrefix(chunk, "?")
+		}
+
+		// Merging a copy of the chunk into memory adds a reference to the original.
+		v.Writeln("\t!eval! (merge-dm-fct (list (mapcan (lambda (slot) (list slot (chunk-slot-value-fct '%[1]s slot))) (chunk-filled-slots-list-fct '%[1]s))))", chunk)
+
+	case s.FindLocation != nil:
+		v.Writeln("\t+visual-location>")
+		v.outputPattern(s.FindLocation.Pattern, 2)
+		v.outputRequestParameters(s.FindLocation.RequestParameters, 2)
+
+	case s.Attend != nil:
+		tabbedItems := framework.KeyValueList{}
+		tabbedItems.Add("cmd", "move-attention")
+		tabbedItems.Add("screen-pos", "=visual-location")
+
+		v.Writeln("\t+visual>")
+		v.TabWrite(2, tabbedItems)
+
+	case s.PressKey != nil:
+		key := s.PressKey.Key
+
+		tabbedItems := framework.KeyValueList{}
+		tabbedItems.Add("cmd", "press-key")
+
+		switch {
+		case key.Var != nil:
+			tabbedItems.Add("key", "="+strings.TrimPrefix(*key.Var, "?"))
+		case key.Str != nil:
+			tabbedItems.Add("key", fmt.Sprintf("%q", *key.Str))
+		default:
+			tabbedItems.Add("key", key.String())
+		}
+
+		v.Writeln("\t+manual>")
+		v.TabWrite(2, tabbedItems)
+
+	case s.Punch != nil:
+		tabbedItems := framework.KeyValueList{}
+		tabbedItems.Add("cmd", "punch")
+		tabbedItems.Add("hand", s.Punch.Hand)
+		tabbedItems.Add("finger", s.Punch.Finger)
+
+		v.Writeln("\t+manual>")
+		v.TabWrite(2, tabbedItems)
+
+	case s.StartTimer != nil:
+		tabbedItems := framework.KeyValueList{}
+		tabbedItems.Add("isa", "time")
+
+		v.Writeln("\t+temporal>")
+		v.TabWrite(2, tabbedItems)
+
 	case s.Print != nil:
 		if s.Print.IsBufferOutput() {
 			id := *((*s.Print.Values)[0].ID)
 			ids := strings.Split(id, ".")
 
 			if len(ids) == 1 {
-				v.Write("\t!bind!\t=value%d (vanilla-print-buffer '%s)\n", v.printStatementCount, id)
+				v.Write("\t!bind!\t=value%d (vanilla-print-buffer '%s)\n", v.printStatementCount, lispName(id))
 				v.Write("\t!output!\t(%q =value%d)\n", fmt.Sprintf("%s: ~a", id), v.printStatementCount)
 			} else {
-				v.Write("\t!bind!\t=value%d (buffer-slot-value '%s '%s)\n", v.printStatementCount, ids[0], ids[1])
+				v.Write("\t!bind!\t=value%d (buffer-slot-value '%s '%s)\n", v.printStatementCount, lispName(ids[0]), v.printSlotName(ids[0], ids[1]))
 				v.Write("\t!output!\t(%q =value%d)\n", fmt.Sprintf("%s: ~a", id), v.printStatementCount)
 			}
 
+			if v.traceEvents {
+				v.Write("\t!eval!\t(gactar-trace \"print\" :text (format nil %q =value%d))\n", fmt.Sprintf("%s: ~a", id), v.printStatementCount)
+			}
+
 			v.printStatementCount++
 		} else {
 			outputArgs := createOutputArgs(s.Print.Values)
 			v.Write("\t!output!\t(%s)\n", outputArgs)
+
+			if v.traceEvents {
+				v.Write("\t!eval!\t(gactar-trace \"print\" :text (format nil %s))\n", outputArgs)
+			}
 		}
 
 	case s.Clear != nil:
 		for _, name := range s.Clear.BufferNames {
-			v.Writeln("\t-%s>", name)
+			v.Writeln("\t-%s>", lispName(name))
 		}
 
 	case s.Stop != nil:
+		if v.traceEvents {
+			v.Writeln("\t!eval!\t(gactar-trace \"stop\")")
+		}
+
 		v.Writeln("\t!stop!")
 	}
 }
 
+// printSlotName returns ACT-R's name for a slot referenced in a print statement (e.g. "visual_location.screen_x").
+// Only the slots of the visual module's buffers have different names.
+func (v VanillaACTR) printSlotName(bufferName, slot string) string {
+	visual := v.model.VisualModule()
+	if visual != nil && visual.Buffers().Has(bufferName) {
+		return lispName(slot)
+	}
+
+	return slot
+}
+
+// expressionVarName returns the name of the variable we bind the result of an expression to.
+// amod variables cannot contain "-", so this will not conflict with them.
+func expressionVarName(bufferName, slotName string) string {
+	return fmt.Sprintf("%s-%s-value", bufferName, slotName)
+}
+
+// lispExpression converts an arithmetic expression to lisp (e.g. "(+ =count 1)").
+// Division converts to a float to avoid lisp's ratios (e.g. 1/2).
+func lispExpression(expr *actr.Expression) string {
+	if expr.Operand != nil {
+		if expr.Operand.Var != nil {
+			return "=" + *expr.Operand.Var
+		}
+
+		return expr.Operand.String()
+	}
+
+	lhs := lispExpression(expr.LHS)
+	if expr.Operator == actr.Divide {
+		lhs = fmt.Sprintf("(float %s)", lhs)
+	}
+
+	return fmt.Sprintf("(%s %s %s)", expr.Operator, lhs, lispExpression(expr.RHS))
+}
+
 // createOutputArgs creates a string suitable for use in an !output! statement
 // !output! is explained in:
 //
@@ -744,7 +1119,7 @@ func createOutputArgs(values *[]*actr.Value) string {
 }
 
 // createRunFile creates a lisp program to load ACTR and our model and then run them.
-func (v VanillaACTR) createRunFile(modelFile string) (outputFile string, err error) {
+func (v VanillaACTR) createRunFile(modelFile string, options *runoptions.Options) (outputFile string, err error) {
 	err = v.InitWriterHelper()
 	if err != nil {
 		return
@@ -759,11 +1134,20 @@ func (v VanillaACTR) createRunFile(modelFile string) (outputFile string, err err
 		v.Writeln(`(load "%s")`, path)
 	}
 
+	if options.IsTracingEvents() {
+		path = filepath.Join(v.tmpPath, vanillaTraceFileName)
+
+		v.Writeln(`(load "%s")`, path)
+	}
+
 	v.Writeln(`(load "%s")`, filepath.ToSlash(modelFile))
 
-	// TODO: We should be able to set this somewhere.
-	// 10.0 is an arbitrary length of time.
-	v.Writeln(`(run 10.0)`)
+	// 10.0 is an arbitrary length of time used if the model does not set max_time.
+	maxTime := "10.0"
+	if options.MaxTime != nil {
+		maxTime = numbers.Float64Str(*options.MaxTime)
+	}
+	v.Writeln(`(run %s)`, maxTime)
 
 	v.Writeln(`(quit)`)
 
@@ -780,9 +1164,59 @@ func (v VanillaACTR) createRunFile(modelFile string) (outputFile string, err err
 	return
 }
 
+// preambleMarker is the last line of the long preamble output whenever ACT-R is loaded.
+const preambleMarker = "######### This is a single threaded build #########"
+
+// preambleFilter is an OutputSink which removes the preamble from streamed output. Lines are held
+// until we see the end of the preamble. If we never see it, they are sent when flushed.
+type preambleFilter struct {
+	sink framework.OutputSink
+
+	held      []string
+	foundLast bool
+}
+
+func newPreambleFilter(sink framework.OutputSink) *preambleFilter {
+	return &preambleFilter{sink: sink}
+}
+
+func (f *preambleFilter) OutputLine(line string) {
+	if f.sink == nil {
+		return
+	}
+
+	if f.foundLast {
+		f.sink.OutputLine(line)
+		return
+	}
+
+	if strings.Contains(line, preambleMarker) {
+		f.foundLast = true
+		f.held = nil
+		return
+	}
+
+	f.held = append(f.held, line)
+}
+
+func (f *preambleFilter) TraceEvent(event framework.TraceEvent) {
+	if f.sink != nil {
+		f.sink.TraceEvent(event)
+	}
+}
+
+// flush sends any lines we are holding
+func (f *preambleFilter) flush() {
+	for _, line := range f.held {
+		f.sink.OutputLine(line)
+	}
+
+	f.held = nil
+}
+
 // removePreamble will remove the long preamble whenever ACT-R is loaded.
 func removePreamble(text string) string {
-	r := regexp.MustCompile(`(?s).+######### This is a single threaded build #########(.+)`)
+	r := regexp.MustCompile(`(?s).+` + preambleMarker + `(.+)`)
 	matches := r.FindAllStringSubmatch(text, -1)
 	if len(matches) == 1 {
 		text = strings.TrimSpace(matches[0][1])
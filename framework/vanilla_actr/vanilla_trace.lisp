@@ -0,0 +1,43 @@
+;; vanilla_trace adds gactar's framework-neutral event trace to ACT-R.
+
+;; It outputs lines of the form:
+
+;;   gactar-trace|<time>|<type>|<production>|<buffer>|<chunk>|<text>
+
+;; which gactar removes from the output and parses into its trace.
+
+;; Most events come from a post-event hook. Print & stop events are output
+;; by the productions themselves using "gactar-trace".
+
+(defun gactar-trace (type &key (production "") (buffer "") (chunk "") (text ""))
+  (format t "gactar-trace|~,3f|~a|~a|~a|~a|~a~%" (mp-time) type production buffer chunk text))
+
+(defun gactar-trace-name (item)
+  (string-downcase (format nil "~a" item)))
+
+;; Output the slot values of a chunk so they may be compared with other frameworks.
+(defun gactar-trace-chunk (chunk)
+  (if chunk
+      (format nil "[~{~a~^ ~}]"
+              (mapcar (lambda (slot) (gactar-trace-name (chunk-slot-value-fct chunk slot)))
+                      (chunk-filled-slots-list-fct chunk)))
+      ""))
+
+(defun gactar-trace-hook (event)
+  (let ((action (gactar-trace-name (evt-action event)))
+        (params (evt-params event)))
+    (cond
+      ((string= action "production-fired")
+       (gactar-trace "production-fired" :production (gactar-trace-name (first params))))
+      ((or (string= action "set-buffer-chunk") (string= action "mod-buffer-chunk"))
+       (gactar-trace "buffer-set" :buffer (gactar-trace-name (first params))
+                     :chunk (gactar-trace-chunk (buffer-read (first params)))))
+      ((string= action "start-retrieval")
+       (gactar-trace "retrieval-started" :buffer "retrieval"))
+      ((string= action "retrieved-chunk")
+       (gactar-trace "retrieval-succeeded" :buffer "retrieval" :chunk (gactar-trace-chunk (first params))))
+      ((string= action "retrieval-failure")
+       (gactar-trace "retrieval-failed" :buffer "retrieval")))))
+
+(add-act-r-command "gactar-trace-hook" 'gactar-trace-hook "Outputs gactar trace events. Do not call directly." nil)
+(add-post-event-hook "gactar-trace-hook")
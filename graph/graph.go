@@ -0,0 +1,242 @@
+// Package graph builds a dependency graph of the productions in a model so we can visualize its
+// control flow and spot dead ends.
+package graph
+
+import (
+	"slices"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// Node is a production in the graph.
+type Node struct {
+	Name    string `json:"name"`
+	Initial bool   `json:"initial"` // can match the model's initial buffer contents
+	Stops   bool   `json:"stops"`   // stops the model
+}
+
+// Edge from one production to another means the "from" production's set & recall statements can
+// produce a buffer state which satisfies the "to" production's match patterns. Buffers lists the
+// buffers which were changed by "from" and are matched by "to".
+type Edge struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Buffers []string `json:"buffers"`
+}
+
+// Graph is the dependency graph of the productions in a model.
+type Graph struct {
+	Model string `json:"model"`
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// bufferState is what a production knows about its buffers after it fires. A nil pattern means
+// the buffer was cleared.
+type bufferState struct {
+	patterns map[string]*actr.Pattern
+	changed  []string // buffers changed by set or recall (in statement order)
+}
+
+// New builds the graph for the model.
+func New(model *actr.Model) *Graph {
+	g := &Graph{
+		Model: model.Name,
+		Nodes: []Node{},
+		Edges: []Edge{},
+	}
+
+	states := make([]bufferState, len(model.Productions))
+
+	for i, production := range model.Productions {
+		g.Nodes = append(g.Nodes, Node{
+			Name:    production.Name,
+			Initial: matchesInitial(model, production),
+			Stops:   stops(production),
+		})
+
+		states[i] = stateAfter(model, production)
+	}
+
+	for i, from := range model.Productions {
+		for _, to := range model.Productions {
+			buffers := satisfies(states[i], to)
+			if len(buffers) == 0 {
+				continue
+			}
+
+			g.Edges = append(g.Edges, Edge{From: from.Name, To: to.Name, Buffers: buffers})
+		}
+	}
+
+	return g
+}
+
+// stateAfter returns the state of the buffers after the production fires
+func stateAfter(model *actr.Model, production *actr.Production) (state bufferState) {
+	state.patterns = map[string]*actr.Pattern{}
+
+	for _, match := range production.Matches {
+		if match.BufferPattern != nil {
+			state.patterns[match.BufferPattern.Buffer.Name()] = match.BufferPattern.Pattern
+		}
+	}
+
+	change := func(bufferName string, pattern *actr.Pattern) {
+		state.patterns[bufferName] = pattern
+
+		if !slices.Contains(state.changed, bufferName) {
+			state.changed = append(state.changed, bufferName)
+		}
+	}
+
+	for _, statement := range production.DoStatements {
+		switch {
+		case statement.Set != nil:
+			set := statement.Set
+			bufferName := set.Buffer.Name()
+
+			if set.Pattern != nil {
+				change(bufferName, set.Pattern)
+				break
+			}
+
+			current := state.patterns[bufferName]
+			if current == nil || set.Slots == nil {
+				break
+			}
+
+			pattern := &actr.Pattern{
+				AnyChunk: current.AnyChunk,
+				Chunk:    current.Chunk,
+				Slots:    slices.Clone(current.Slots),
+			}
+
+			for _, slot := range *set.Slots {
+				index := slot.SlotIndex - 1
+				if index >= 0 && index < len(pattern.Slots) {
+					pattern.Slots[index] = slot.Value.PatternSlot()
+				}
+			}
+
+			change(bufferName, pattern)
+
+		case statement.Recall != nil:
+			module := model.LookupModule(statement.Recall.MemoryModuleName)
+			if module == nil || !module.HasBuffers() {
+				break
+			}
+
+			change(module.Buffers().At(0).Name(), statement.Recall.Pattern)
+
+		case statement.Blend != nil:
+			change("blending", statement.Blend.Pattern)
+
+		case statement.FindLocation != nil:
+			// we don't know which location will be found
+			change("visual_location", anyPattern(model, "visual_location"))
+
+		case statement.Attend != nil:
+			change("visual", anyPattern(model, "visual_object"))
+
+		case statement.StartTimer != nil:
+			// the ticks keep changing
+			change("temporal", anyPattern(model, "time"))
+
+		case statement.Clear != nil:
+			for _, bufferName := range statement.Clear.BufferNames {
+				state.patterns[bufferName] = nil
+			}
+		}
+	}
+
+	return
+}
+
+// anyPattern returns a pattern of the chunk type with all wildcard slots.
+func anyPattern(model *actr.Model, chunkType string) *actr.Pattern {
+	chunk := model.LookupChunk(chunkType)
+
+	pattern := &actr.Pattern{Chunk: chunk}
+	for range chunk.SlotNames {
+		pattern.Slots = append(pattern.Slots, &actr.PatternSlot{Wildcard: true})
+	}
+
+	return pattern
+}
+
+// satisfies returns the changed buffers in "state" which "production" matches. If any of the
+// production's matches can't be satisfied by the state, it returns nil.
+func satisfies(state bufferState, production *actr.Production) (buffers []string) {
+	for _, match := range production.Matches {
+		switch {
+		case match.BufferPattern != nil:
+			bufferName := match.BufferPattern.Buffer.Name()
+
+			pattern, known := state.patterns[bufferName]
+			if !known {
+				// we don't know what is in this buffer, so assume it could match
+				continue
+			}
+
+			if pattern == nil || !pattern.Compatible(match.BufferPattern.Pattern) {
+				return nil
+			}
+
+			if slices.Contains(state.changed, bufferName) {
+				buffers = append(buffers, bufferName)
+			}
+
+		case match.BufferState != nil:
+			pattern, known := state.patterns[match.BufferState.Buffer.Name()]
+			if known && pattern != nil && match.BufferState.State == "empty" {
+				return nil
+			}
+		}
+	}
+
+	return
+}
+
+// matchesInitial checks if the production's patterns can match the initial buffer contents
+func matchesInitial(model *actr.Model, production *actr.Production) bool {
+	goalName := model.Goal.Buffers().At(0).Name()
+
+	for _, match := range production.Matches {
+		if match.BufferPattern == nil {
+			continue
+		}
+
+		bufferName := match.BufferPattern.Buffer.Name()
+		pattern := match.BufferPattern.Pattern
+
+		initial := []*actr.Pattern{}
+
+		for _, init := range model.Initializers {
+			if init.Buffer != nil && init.Buffer.Name() == bufferName {
+				initial = append(initial, init.Pattern)
+			}
+		}
+
+		if bufferName == goalName {
+			initial = append(initial, model.Examples...)
+
+			// If we don't have a goal, it is set from outside the model (e.g. the command line).
+			if len(initial) == 0 {
+				continue
+			}
+		}
+
+		if !slices.ContainsFunc(initial, pattern.Compatible) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stops(production *actr.Production) bool {
+	return slices.ContainsFunc(production.DoStatements, func(statement *actr.Statement) bool {
+		return statement.Stop != nil
+	})
+}
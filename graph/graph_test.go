@@ -0,0 +1,157 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/asmaloney/gactar/amod"
+)
+
+const countModel = `
+~~ model ~~
+name: count
+~~ config ~~
+chunks {
+	[count: first second]
+	[countFrom: start end status]
+}
+~~ init ~~
+memory {
+	[count: 0 1]
+	[count: 1 2]
+}
+goal [countFrom: 0 2 'starting']
+~~ productions ~~
+begin {
+	match { goal [countFrom: ?start * 'starting'] }
+	do {
+		recall [count: ?start *]
+		set goal.status to 'counting'
+	}
+}
+increment {
+	match {
+		goal [countFrom: ?x !?x 'counting']
+		retrieval [count: ?x ?next]
+	}
+	do {
+		recall [count: ?next *]
+		set goal.start to ?next
+	}
+}
+end {
+	match { goal [countFrom: ?x ?x 'counting'] }
+	do {
+		clear goal
+		stop
+	}
+}`
+
+func writeGraph(src, format string) {
+	model, log, err := amod.GenerateModel(src)
+	if err != nil {
+		fmt.Print(log)
+		return
+	}
+
+	err = New(model).Write(os.Stdout, format)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+}
+
+func Example_dot() {
+	writeGraph(countModel, "dot")
+
+	// Output:
+	// digraph "count" {
+	//     node [shape=box];
+	//     "begin" [style=bold];
+	//     "increment";
+	//     "end" [peripheries=2];
+	//     "begin" -> "increment" [label="goal, retrieval"];
+	//     "begin" -> "end" [label="goal"];
+	//     "increment" -> "increment" [label="goal, retrieval"];
+	//     "increment" -> "end" [label="goal"];
+	// }
+}
+
+func Example_mermaid() {
+	writeGraph(countModel, "mermaid")
+
+	// Output:
+	// flowchart TD
+	//     begin([begin])
+	//     increment[increment]
+	//     end(((end)))
+	//     begin -->|goal, retrieval| increment
+	//     begin -->|goal| end
+	//     increment -->|goal, retrieval| increment
+	//     increment -->|goal| end
+}
+
+func Example_json() {
+	writeGraph(`
+	~~ model ~~
+	name: json
+	~~ config ~~
+	chunks { [task: state] }
+	~~ init ~~
+	goal [task: 'one']
+	~~ productions ~~
+	one {
+		match { goal [task: 'one'] }
+		do { set goal.state to 'two' }
+	}
+	two {
+		match { goal [task: 'two'] }
+		do { set goal.state to 'one' }
+	}`, "json")
+
+	// Output:
+	// {
+	//   "model": "json",
+	//   "nodes": [
+	//     {
+	//       "name": "one",
+	//       "initial": true,
+	//       "stops": false
+	//     },
+	//     {
+	//       "name": "two",
+	//       "initial": false,
+	//       "stops": false
+	//     }
+	//   ],
+	//   "edges": [
+	//     {
+	//       "from": "one",
+	//       "to": "two",
+	//       "buffers": [
+	//         "goal"
+	//       ]
+	//     },
+	//     {
+	//       "from": "two",
+	//       "to": "one",
+	//       "buffers": [
+	//         "goal"
+	//       ]
+	//     }
+	//   ]
+	// }
+}
+
+func TestUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := Graph{}.Write(io.Discard, "svg")
+
+	var unknown ErrUnknownFormat
+	if !errors.As(err, &unknown) || unknown.Format != "svg" {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats lists the output formats we support.
+var Formats = []string{"dot", "mermaid", "json"}
+
+// ErrUnknownFormat is returned if we are asked to write a format we don't support.
+type ErrUnknownFormat struct {
+	Format string
+}
+
+func (e ErrUnknownFormat) Error() string {
+	return fmt.Sprintf("unknown graph format %q - valid options: %s", e.Format, strings.Join(Formats, ", "))
+}
+
+// Write outputs the graph in the given format.
+func (g Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "dot":
+		return g.WriteDOT(w)
+	case "mermaid":
+		return g.WriteMermaid(w)
+	case "json":
+		return g.WriteJSON(w)
+	}
+
+	return ErrUnknownFormat{Format: format}
+}
+
+// WriteDOT outputs the graph in Graphviz's DOT format. Initial productions are bold and
+// productions which stop the model have a double border.
+func (g Graph) WriteDOT(w io.Writer) (err error) {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "digraph %s {\n", strconv.Quote(g.Model))
+	out.WriteString("    node [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		attributes := []string{}
+
+		if node.Initial {
+			attributes = append(attributes, "style=bold")
+		}
+
+		if node.Stops {
+			attributes = append(attributes, "peripheries=2")
+		}
+
+		fmt.Fprintf(&out, "    %s", strconv.Quote(node.Name))
+		if len(attributes) > 0 {
+			fmt.Fprintf(&out, " [%s]", strings.Join(attributes, ", "))
+		}
+		out.WriteString(";\n")
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "    %s -> %s [label=%s];\n",
+			strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(strings.Join(edge.Buffers, ", ")))
+	}
+
+	out.WriteString("}\n")
+
+	_, err = io.WriteString(w, out.String())
+	return
+}
+
+// WriteMermaid outputs the graph as a Mermaid flowchart. Initial productions are rounded and
+// productions which stop the model are double circles.
+func (g Graph) WriteMermaid(w io.Writer) (err error) {
+	var out strings.Builder
+
+	out.WriteString("flowchart TD\n")
+
+	for _, node := range g.Nodes {
+		switch {
+		case node.Stops:
+			fmt.Fprintf(&out, "    %s(((%s)))\n", node.Name, node.Name)
+		case node.Initial:
+			fmt.Fprintf(&out, "    %s([%s])\n", node.Name, node.Name)
+		default:
+			fmt.Fprintf(&out, "    %s[%s]\n", node.Name, node.Name)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "    %s -->|%s| %s\n", edge.From, strings.Join(edge.Buffers, ", "), edge.To)
+	}
+
+	_, err = io.WriteString(w, out.String())
+	return
+}
+
+// WriteJSON outputs the graph as JSON.
+func (g Graph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(g)
+}
@@ -0,0 +1,123 @@
+// Package lint checks models for things which are valid but are almost certainly mistakes, such as
+// chunk types which are never used or productions which can never fire.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+// Rule is one check we run on a model. Each has an ID so it can be disabled (or enabled if it is
+// opt-in).
+type Rule struct {
+	ID          string
+	Description string
+	OptIn       bool // only run if enabled in Options because it is often noisy
+
+	check func(model *actr.Model, report reportFunc)
+}
+
+// reportFunc is used by rules to report an issue at a line in an amod file
+type reportFunc func(fileName string, line int, format string, a ...any)
+
+// Rules is the list of all our rules in the order they are run.
+var Rules = []Rule{
+	{
+		ID:          "unused-chunk-type",
+		Description: "chunk type is declared but never used",
+		check:       checkUnusedChunkTypes,
+	},
+	{
+		ID:          "unreachable-production",
+		Description: "production matches a goal which no initializer, example, or production produces",
+		check:       checkUnreachableProductions,
+	},
+	{
+		ID:          "unrecalled-chunk",
+		Description: "memory chunk is never matched by a recall",
+		OptIn:       true,
+		check:       checkUnrecalledChunks,
+	},
+	{
+		ID:          "recall-retrieval-conflict",
+		Description: "production recalls while another production can match at the same time using the retrieval buffer",
+		check:       checkRecallRetrievalConflicts,
+	},
+	{
+		ID:          "duplicate-production",
+		Description: "production has the same matches and statements as another production",
+		check:       checkDuplicateProductions,
+	},
+	{
+		ID:          "unknown-similar-chunk",
+		Description: "similar refers to a chunk which does not exist",
+		check:       checkUnknownSimilarChunks,
+	},
+	{
+		ID:          "unknown-association-chunk",
+		Description: "associations refers to a chunk which does not exist",
+		check:       checkUnknownAssociationChunks,
+	},
+}
+
+// ErrUnknownRule is returned if a rule ID we were asked to enable or disable does not exist.
+type ErrUnknownRule struct {
+	ID string
+}
+
+func (e ErrUnknownRule) Error() string {
+	return fmt.Sprintf("unknown lint rule %q - valid rules: %s", e.ID, strings.Join(RuleIDs(), ", "))
+}
+
+// Options control which rules are run.
+type Options struct {
+	Enabled  []string // IDs of opt-in rules to run
+	Disabled []string // IDs of rules to skip
+}
+
+// RuleIDs returns the IDs of all our rules.
+func RuleIDs() (ids []string) {
+	for _, rule := range Rules {
+		ids = append(ids, rule.ID)
+	}
+
+	return
+}
+
+// Lint runs the enabled rules on the model and adds a warning to the log for each issue found.
+// The rule ID is added to the end of each warning so users know which rule to disable.
+func Lint(model *actr.Model, log *issues.Log, options Options) error {
+	for _, id := range append(slices.Clone(options.Enabled), options.Disabled...) {
+		if !slices.Contains(RuleIDs(), id) {
+			return ErrUnknownRule{ID: id}
+		}
+	}
+
+	for _, rule := range Rules {
+		if slices.Contains(options.Disabled, rule.ID) {
+			continue
+		}
+
+		if rule.OptIn && !slices.Contains(options.Enabled, rule.ID) {
+			continue
+		}
+
+		id := rule.ID
+		report := func(fileName string, line int, format string, a ...any) {
+			var location *issues.Location
+			if line > 0 {
+				location = &issues.Location{SourceFile: fileName, Line: line}
+			}
+
+			log.Warning(location, "%s [%s]", fmt.Sprintf(format, a...), id)
+		}
+
+		rule.check(model, report)
+	}
+
+	return nil
+}
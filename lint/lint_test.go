@@ -0,0 +1,306 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+func lintToStdout(src string, disabled ...string) {
+	lintToStdoutWith(src, Options{Disabled: disabled})
+}
+
+func lintToStdoutWith(src string, options Options) {
+	model, log, err := amod.GenerateModel(src)
+	if err != nil {
+		fmt.Print(log)
+		return
+	}
+
+	lintLog := issues.New()
+
+	err = Lint(model, lintLog, options)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	err = lintLog.Write(os.Stdout)
+	if err != nil {
+		fmt.Print(err.Error())
+	}
+}
+
+func Example_unusedChunkType() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[count: first second]
+		[unused: thing]
+	}
+	~~ init ~~
+	goal [count: 1 2]
+	~~ productions ~~
+	start {
+		match { goal [count: 1 *] }
+		do { stop }
+	}`)
+
+	// Output:
+	// WARN: chunk type 'unused' is never used [unused-chunk-type] (line 7, col 0)
+}
+
+func Example_unreachableProduction() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[count: first second]
+		[other: thing]
+	}
+	~~ init ~~
+	goal [count: 1 'starting']
+	~~ productions ~~
+	start {
+		match { goal [count: * 'starting'] }
+		do { set goal.second to 'counting' }
+	}
+	counting {
+		match { goal [count: * 'counting'] }
+		do { stop }
+	}
+	stopped {
+		match { goal [count: * 'stopped'] }
+		do { stop }
+	}
+	other {
+		match { goal [other: *] }
+		do { stop }
+	}`)
+
+	// Output:
+	// WARN: production 'stopped' can never fire: goal slot 'second' is never set to 'stopped' [unreachable-production] (line 20, col 0)
+	// WARN: production 'other' can never fire: goal is never set to a 'other' chunk [unreachable-production] (line 24, col 0)
+}
+
+func Example_unrecalledChunk() {
+	lintToStdoutWith(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[property: object attribute value]
+		[question: object]
+	}
+	~~ init ~~
+	memory {
+		[property: shark category fish]
+		[property: shark color grey]
+	}
+	goal [question: shark]
+	~~ productions ~~
+	start {
+		match { goal [question: ?obj] }
+		do { recall [property: ?obj category *] }
+	}`, Options{Enabled: []string{"unrecalled-chunk"}})
+
+	// Output:
+	// WARN: memory chunk [property: shark color grey] is never recalled [unrecalled-chunk] (line 12, col 0)
+}
+
+func Example_recallRetrievalConflict() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[count: first second]
+		[countFrom: start end]
+	}
+	~~ init ~~
+	memory {
+		[count: 1 2]
+	}
+	goal [countFrom: 1 2]
+	~~ productions ~~
+	start {
+		match { goal [countFrom: ?start *] }
+		do { recall [count: ?start *] }
+	}
+	increment {
+		match {
+			goal [countFrom: ?start *]
+			retrieval [count: ?start ?next]
+		}
+		do { set goal.start to ?next }
+	}`)
+
+	// Output:
+	// WARN: production 'start' recalls whenever production 'increment' can match on the retrieval buffer [recall-retrieval-conflict] (line 15, col 0)
+}
+
+func Example_duplicateProduction() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[count: first]
+	}
+	~~ init ~~
+	goal [count: 1]
+	~~ productions ~~
+	start {
+		match { goal [count: ?x] }
+		do { print ?x }
+	}
+	again {
+		match { goal [count: ?x] }
+		do { print ?x }
+	}`)
+
+	// Output:
+	// WARN: production 'again' is a duplicate of production 'start' [duplicate-production] (line 15, col 0)
+}
+
+func Example_unknownSimilarChunk() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[count: first]
+	}
+	~~ init ~~
+	memory {
+		[count: one]
+		[count: two]
+	}
+	goal [count: one]
+	similar {
+		( one two -0.1 )
+		( one three -0.5 )
+	}
+	~~ productions ~~
+	start {
+		match { goal [count: *] }
+		do { recall [count: *] }
+	}`)
+
+	// Output:
+	// WARN: similar refers to chunk 'three' which does not exist [unknown-similar-chunk] (line 16, col 0)
+}
+
+func Example_unknownAssociationChunk() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	modules {
+		memory { max_spread_strength: 1.0 }
+	}
+	chunks {
+		[count: first]
+	}
+	~~ init ~~
+	memory {
+		one [count: 1]
+		two [count: 2]
+	}
+	goal [count: 1]
+	associations {
+		( one two 1.5 )
+		( three two 0.5 )
+	}
+	~~ productions ~~
+	start {
+		match { goal [count: *] }
+		do { recall [count: *] }
+	}`)
+
+	// Output:
+	// WARN: associations refers to chunk 'three' which does not exist [unknown-association-chunk] (line 19, col 0)
+}
+
+func Example_disabledRule() {
+	lintToStdout(`
+	~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[count: first]
+		[unused: thing]
+	}
+	~~ init ~~
+	goal [count: 1]
+	~~ productions ~~
+	start {
+		match { goal [count: *] }
+		do { stop }
+	}`, "unused-chunk-type")
+
+	// Output:
+}
+
+func TestUnknownRule(t *testing.T) {
+	t.Parallel()
+
+	err := Lint(nil, issues.New(), Options{Disabled: []string{"no-such-rule"}})
+
+	var unknown ErrUnknownRule
+	if !errors.As(err, &unknown) || unknown.ID != "no-such-rule" {
+		t.Errorf("expected ErrUnknownRule, got %v", err)
+	}
+}
+
+func TestUnknownEnabledRule(t *testing.T) {
+	t.Parallel()
+
+	err := Lint(nil, issues.New(), Options{Enabled: []string{"no-such-rule"}})
+
+	var unknown ErrUnknownRule
+	if !errors.As(err, &unknown) || unknown.ID != "no-such-rule" {
+		t.Errorf("expected ErrUnknownRule, got %v", err)
+	}
+}
+
+// TestExamples checks that the example models lint clean using the default rules.
+func TestExamples(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob(filepath.Join("..", "examples", "*.amod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no example files found")
+	}
+
+	for _, file := range files {
+		model, log, err := amod.GenerateModelFromFile(file)
+		if err != nil {
+			t.Errorf("%s: %s", file, log)
+			continue
+		}
+
+		lintLog := issues.New()
+
+		err = Lint(model, lintLog, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lintLog.HasIssues() {
+			t.Errorf("%s: expected no issues, got:\n%s", file, lintLog)
+		}
+	}
+}
@@ -0,0 +1,595 @@
+package lint
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/buffer"
+)
+
+// checkUnusedChunkTypes looks for chunk types which are not used in any pattern.
+func checkUnusedChunkTypes(model *actr.Model, report reportFunc) {
+	used := map[string]bool{}
+
+	usePattern := func(pattern *actr.Pattern) {
+		if pattern != nil && !pattern.AnyChunk {
+			used[pattern.Chunk.TypeName] = true
+		}
+	}
+
+	for _, init := range model.Initializers {
+		usePattern(init.Pattern)
+	}
+
+	for _, example := range model.Examples {
+		usePattern(example)
+	}
+
+	for _, production := range model.Productions {
+		for _, match := range production.Matches {
+			if match.BufferPattern != nil {
+				usePattern(match.BufferPattern.Pattern)
+			}
+		}
+
+		for _, statement := range production.DoStatements {
+			switch {
+			case statement.Set != nil:
+				usePattern(statement.Set.Pattern)
+
+				if statement.Set.Chunk != nil {
+					used[statement.Set.Chunk.TypeName] = true
+				}
+
+			case statement.Recall != nil:
+				usePattern(statement.Recall.Pattern)
+
+			case statement.Blend != nil:
+				usePattern(statement.Blend.Pattern)
+
+			case statement.Remember != nil:
+				usePattern(statement.Remember.Pattern)
+			}
+		}
+	}
+
+	for _, chunk := range model.Chunks {
+		if chunk.IsInternal() || chunk.BuiltIn || used[chunk.TypeName] {
+			continue
+		}
+
+		report(chunk.AMODFileName, chunk.AMODLineNumber, "chunk type '%s' is never used", chunk.TypeName)
+	}
+}
+
+// checkUnreachableProductions looks for productions which match a goal that is never produced by
+// a goal initializer, an example, or another production.
+func checkUnreachableProductions(model *actr.Model, report reportFunc) {
+	isGoal := func(b buffer.Interface) bool {
+		return b != nil && model.Goal.Buffers().Has(b.Name())
+	}
+
+	// patterns which may be put in the goal buffer
+	sources := []*actr.Pattern{}
+
+	for _, init := range model.Initializers {
+		if isGoal(init.Buffer) {
+			sources = append(sources, init.Pattern)
+		}
+	}
+
+	sources = append(sources, model.Examples...)
+
+	// If we don't have a goal to start with, it is set from outside the model (e.g. the command
+	// line), so we can't tell what can fire.
+	if len(sources) == 0 {
+		return
+	}
+
+	// values set on individual goal slots keyed by "chunk type:slot index"
+	slotValues := map[string][]*actr.PatternSlot{}
+
+	for _, production := range model.Productions {
+		for _, statement := range production.DoStatements {
+			set := statement.Set
+			if set == nil || !isGoal(set.Buffer) {
+				continue
+			}
+
+			if set.Pattern != nil {
+				sources = append(sources, set.Pattern)
+				continue
+			}
+
+			if set.Slots == nil || set.Chunk == nil {
+				continue
+			}
+
+			for _, slot := range *set.Slots {
+				key := set.Chunk.TypeName + ":" + strconv.Itoa(slot.SlotIndex)
+				slotValues[key] = append(slotValues[key], slot.Value.PatternSlot())
+			}
+		}
+	}
+
+	for _, production := range model.Productions {
+		for _, match := range production.Matches {
+			if match.BufferPattern == nil || !isGoal(match.BufferPattern.Buffer) {
+				continue
+			}
+
+			pattern := match.BufferPattern.Pattern
+			if pattern.AnyChunk {
+				continue
+			}
+
+			typeName := pattern.Chunk.TypeName
+
+			typeSources := []*actr.Pattern{}
+			for _, source := range sources {
+				if source.AnyChunk || source.Chunk.TypeName == typeName {
+					typeSources = append(typeSources, source)
+				}
+			}
+
+			if len(typeSources) == 0 {
+				report(production.AMODFileName, production.AMODLineNumber,
+					"production '%s' can never fire: goal is never set to a '%s' chunk", production.Name, typeName)
+				continue
+			}
+
+			for i, slot := range pattern.Slots {
+				if slot.Var != nil || slot.Wildcard || slot.Negated {
+					continue
+				}
+
+				if slotProduced(typeSources, slotValues[typeName+":"+strconv.Itoa(i+1)], i, slot) {
+					continue
+				}
+
+				report(production.AMODFileName, production.AMODLineNumber,
+					"production '%s' can never fire: goal slot '%s' is never set to %s",
+					production.Name, pattern.Chunk.SlotName(i), slot.String())
+				break
+			}
+		}
+	}
+}
+
+// slotProduced checks if any of the source patterns or set values could put "slot" in slot "index"
+func slotProduced(sources []*actr.Pattern, setValues []*actr.PatternSlot, index int, slot *actr.PatternSlot) bool {
+	for _, source := range sources {
+		if source.AnyChunk || index >= len(source.Slots) {
+			return true
+		}
+
+		if source.Slots[index].Compatible(slot) {
+			return true
+		}
+	}
+
+	for _, setValue := range setValues {
+		if setValue.Compatible(slot) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkUnrecalledChunks looks for chunks in memory which can not be matched by any recall or blend.
+func checkUnrecalledChunks(model *actr.Model, report reportFunc) {
+	recalls := []*actr.Pattern{}
+
+	for _, production := range model.Productions {
+		for _, statement := range production.DoStatements {
+			switch {
+			case statement.Recall != nil:
+				recalls = append(recalls, statement.Recall.Pattern)
+
+			case statement.Blend != nil:
+				recalls = append(recalls, statement.Blend.Pattern)
+			}
+		}
+	}
+
+	// Chunks in similarities may be retrieved by partial matching, so treat them as matching.
+	similar := map[string]bool{}
+	for _, similarity := range model.Similarities {
+		similar[similarity.ChunkOne] = true
+		similar[similarity.ChunkTwo] = true
+	}
+
+	recallable := func(recall, chunk *actr.Pattern) bool {
+		if recall.AnyChunk {
+			return true
+		}
+
+		if recall.Chunk.TypeName != chunk.Chunk.TypeName {
+			return false
+		}
+
+		for i := 0; i < min(len(recall.Slots), len(chunk.Slots)); i++ {
+			a, b := recall.Slots[i], chunk.Slots[i]
+
+			if a.Compatible(b) || (similar[slotName(a)] && similar[slotName(b)]) {
+				continue
+			}
+
+			return false
+		}
+
+		return true
+	}
+
+	for _, init := range model.Initializers {
+		if init.Module == nil || init.Module.ModuleName() != model.Memory.ModuleName() {
+			continue
+		}
+
+		if init.Pattern.AnyChunk {
+			continue
+		}
+
+		if slices.ContainsFunc(recalls, func(recall *actr.Pattern) bool { return recallable(recall, init.Pattern) }) {
+			continue
+		}
+
+		name := init.Pattern.String()
+		if init.ChunkName != nil {
+			name = *init.ChunkName + " " + name
+		}
+
+		report(init.AMODFileName, init.AMODLineNumber, "memory chunk %s is never recalled", name)
+	}
+}
+
+// slotName returns the ID or string in a slot or an empty string if it has neither
+func slotName(slot *actr.PatternSlot) string {
+	switch {
+	case slot.ID != nil:
+		return *slot.ID
+
+	case slot.Str != nil:
+		return *slot.Str
+	}
+
+	return ""
+}
+
+// checkRecallRetrievalConflicts looks for productions which recall and can match whenever another
+// production which uses the retrieval buffer can. The recall can then fire instead of the other
+// production and replace the contents of the buffer it is waiting on.
+func checkRecallRetrievalConflicts(model *actr.Model, report reportFunc) {
+	for _, p := range model.Productions {
+		for _, statement := range p.DoStatements {
+			if statement.Recall == nil {
+				continue
+			}
+
+			module := model.LookupModule(statement.Recall.MemoryModuleName)
+			if module == nil {
+				continue
+			}
+
+			for _, q := range model.Productions {
+				if q == p || !matchesBufferOf(q, module.Buffers()) || !matchesSubsume(p, q) {
+					continue
+				}
+
+				report(p.AMODFileName, p.AMODLineNumber,
+					"production '%s' recalls whenever production '%s' can match on the retrieval buffer",
+					p.Name, q.Name)
+			}
+
+			break
+		}
+	}
+}
+
+// matchesBufferOf checks if the production matches a pattern on any of the buffers
+func matchesBufferOf(production *actr.Production, buffers buffer.List) bool {
+	for _, match := range production.Matches {
+		if match.BufferPattern != nil && buffers.Has(match.BufferPattern.Buffer.Name()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesSubsume checks if production "p" matches whenever production "q" does. This is
+// conservative - variables used more than once or with constraints are treated as not matching.
+func matchesSubsume(p, q *actr.Production) bool {
+	varCount := map[string]int{}
+
+	for _, match := range p.Matches {
+		if match.BufferPattern == nil {
+			continue
+		}
+
+		for _, slot := range match.BufferPattern.Pattern.Slots {
+			if slot.Var != nil {
+				varCount[*slot.Var.Name]++
+			}
+		}
+	}
+
+	for _, a := range p.Matches {
+		if !slices.ContainsFunc(q.Matches, func(b *actr.Match) bool { return matchSubsumes(a, b, varCount) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchSubsumes checks if match "a" is true whenever match "b" is
+func matchSubsumes(a, b *actr.Match, varCount map[string]int) bool {
+	switch {
+	case a.BufferPattern != nil && b.BufferPattern != nil:
+		if a.BufferPattern.Buffer.Name() != b.BufferPattern.Buffer.Name() {
+			return false
+		}
+
+		return patternSubsumes(a.BufferPattern.Pattern, b.BufferPattern.Pattern, varCount)
+
+	case a.BufferState != nil && b.BufferState != nil:
+		return a.BufferState.Buffer.Name() == b.BufferState.Buffer.Name() && a.BufferState.State == b.BufferState.State
+
+	case a.ModuleState != nil && b.ModuleState != nil:
+		return a.ModuleState.Module.ModuleName() == b.ModuleState.Module.ModuleName() && a.ModuleState.State == b.ModuleState.State
+	}
+
+	return false
+}
+
+// patternSubsumes checks if pattern "a" matches every chunk pattern "b" does
+func patternSubsumes(a, b *actr.Pattern, varCount map[string]int) bool {
+	if a.AnyChunk {
+		return true
+	}
+
+	if b.AnyChunk || a.Chunk.TypeName != b.Chunk.TypeName {
+		return false
+	}
+
+	for i, slot := range a.Slots {
+		if slot.Wildcard {
+			continue
+		}
+
+		if slot.Var != nil {
+			if varCount[*slot.Var.Name] == 1 && len(slot.Var.Constraints) == 0 && !slot.Negated {
+				continue
+			}
+
+			return false
+		}
+
+		if i >= len(b.Slots) {
+			return false
+		}
+
+		// we only need to know if they are the same, so comparing the text is enough
+		if slot.String() != b.Slots[i].String() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkDuplicateProductions looks for productions with the same matches & statements as an
+// earlier production.
+func checkDuplicateProductions(model *actr.Model, report reportFunc) {
+	seen := map[string]string{}
+
+	for _, production := range model.Productions {
+		key := productionKey(production)
+
+		if original, ok := seen[key]; ok {
+			report(production.AMODFileName, production.AMODLineNumber,
+				"production '%s' is a duplicate of production '%s'", production.Name, original)
+			continue
+		}
+
+		seen[key] = production.Name
+	}
+}
+
+// productionKey creates a string describing what the production matches and does
+func productionKey(production *actr.Production) string {
+	var key strings.Builder
+
+	for _, match := range production.Matches {
+		switch {
+		case match.BufferPattern != nil:
+			key.WriteString("match " + match.BufferPattern.Buffer.Name() + " " + patternKey(match.BufferPattern.Pattern))
+
+		case match.BufferState != nil:
+			key.WriteString("buffer_state " + match.BufferState.Buffer.Name() + " " + match.BufferState.State)
+
+		case match.ModuleState != nil:
+			key.WriteString("module_state " + match.ModuleState.Module.ModuleName() + " " + match.ModuleState.State)
+		}
+
+		key.WriteString("\n")
+	}
+
+	for _, statement := range production.DoStatements {
+		switch {
+		case statement.Attend != nil:
+			key.WriteString("attend")
+
+		case statement.Blend != nil:
+			key.WriteString("blend " + patternKey(statement.Blend.Pattern))
+
+		case statement.Clear != nil:
+			key.WriteString("clear " + strings.Join(statement.Clear.BufferNames, " "))
+
+		case statement.FindLocation != nil:
+			key.WriteString("find_location " + patternKey(statement.FindLocation.Pattern))
+
+		case statement.PressKey != nil:
+			key.WriteString("press_key " + statement.PressKey.Key.String())
+
+		case statement.Punch != nil:
+			key.WriteString("punch " + statement.Punch.Hand + " " + statement.Punch.Finger)
+
+		case statement.Print != nil:
+			key.WriteString("print")
+			if statement.Print.Values != nil {
+				for _, value := range *statement.Print.Values {
+					key.WriteString(" " + valueKey(value))
+				}
+			}
+
+		case statement.Recall != nil:
+			key.WriteString("recall " + patternKey(statement.Recall.Pattern))
+
+		case statement.Reinforce != nil:
+			key.WriteString("reinforce " + statement.Reinforce.Chunk.String())
+
+		case statement.Remember != nil:
+			key.WriteString("remember " + patternKey(statement.Remember.Pattern))
+
+		case statement.Set != nil:
+			set := statement.Set
+			key.WriteString("set " + set.Buffer.Name())
+
+			if set.Pattern != nil {
+				key.WriteString(" " + patternKey(set.Pattern))
+			}
+
+			if set.Slots != nil {
+				for _, slot := range *set.Slots {
+					key.WriteString(" " + slot.Name + "=" + valueKey(slot.Value))
+				}
+			}
+
+		case statement.StartTimer != nil:
+			key.WriteString("start_timer")
+
+		case statement.Stop != nil:
+			key.WriteString("stop")
+		}
+
+		key.WriteString("\n")
+	}
+
+	return key.String()
+}
+
+func patternKey(pattern *actr.Pattern) string {
+	if pattern.AnyChunk {
+		return "[any]"
+	}
+
+	return pattern.String()
+}
+
+func valueKey(value *actr.Value) string {
+	if value.Var != nil {
+		return "?" + *value.Var
+	}
+
+	return value.String()
+}
+
+// knownChunks returns the names of all the chunks which exist anywhere in the model.
+func knownChunks(model *actr.Model) map[string]bool {
+	known := map[string]bool{}
+
+	for _, name := range model.ExplicitChunks {
+		known[name] = true
+	}
+
+	addPattern := func(pattern *actr.Pattern) {
+		if pattern == nil {
+			return
+		}
+
+		for _, slot := range pattern.Slots {
+			if slot.ID != nil {
+				known[*slot.ID] = true
+			}
+		}
+	}
+
+	for _, init := range model.Initializers {
+		addPattern(init.Pattern)
+	}
+
+	for _, example := range model.Examples {
+		addPattern(example)
+	}
+
+	for _, production := range model.Productions {
+		for _, match := range production.Matches {
+			if match.BufferPattern != nil {
+				addPattern(match.BufferPattern.Pattern)
+			}
+		}
+
+		for _, statement := range production.DoStatements {
+			switch {
+			case statement.Recall != nil:
+				addPattern(statement.Recall.Pattern)
+
+			case statement.Blend != nil:
+				addPattern(statement.Blend.Pattern)
+
+			case statement.Remember != nil:
+				addPattern(statement.Remember.Pattern)
+
+			case statement.Set != nil:
+				addPattern(statement.Set.Pattern)
+
+				if statement.Set.Slots != nil {
+					for _, slot := range *statement.Set.Slots {
+						if slot.Value.ID != nil {
+							known[*slot.Value.ID] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return known
+}
+
+// checkUnknownSimilarChunks looks for similarities which name chunks that don't exist anywhere.
+func checkUnknownSimilarChunks(model *actr.Model, report reportFunc) {
+	known := knownChunks(model)
+
+	for _, similarity := range model.Similarities {
+		for _, name := range []string{similarity.ChunkOne, similarity.ChunkTwo} {
+			if known[name] {
+				continue
+			}
+
+			report(similarity.AMODFileName, similarity.AMODLineNumber,
+				"similar refers to chunk '%s' which does not exist", name)
+		}
+	}
+}
+
+// checkUnknownAssociationChunks looks for associations which name chunks that don't exist anywhere.
+func checkUnknownAssociationChunks(model *actr.Model, report reportFunc) {
+	known := knownChunks(model)
+
+	for _, association := range model.Associations {
+		for _, name := range []string{association.Source, association.Target} {
+			if known[name] {
+				continue
+			}
+
+			report(association.AMODFileName, association.AMODLineNumber,
+				"associations refers to chunk '%s' which does not exist", name)
+		}
+	}
+}
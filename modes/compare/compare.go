@@ -0,0 +1,567 @@
+// Package compare runs a model on several frameworks and compares their traces to find where
+// the frameworks diverge.
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/chalk"
+	"github.com/asmaloney/gactar/util/cli"
+	"github.com/asmaloney/gactar/util/runoptions"
+	"github.com/asmaloney/gactar/util/validate"
+)
+
+var (
+	ErrNotEnoughFrameworks  = errors.New("need at least two frameworks to compare")
+	ErrInvalidModel         = errors.New("model is not valid")
+	ErrUnexpectedDivergence = errors.New("frameworks diverged")
+)
+
+// ErrInvalidCategory is returned when trying to ignore a category which does not exist.
+type ErrInvalidCategory struct {
+	Category string
+}
+
+func (e ErrInvalidCategory) Error() string {
+	return fmt.Sprintf("invalid category: %q (expected one of: %s)", e.Category, strings.Join(CategoryNames(), ", "))
+}
+
+// Category is one of the things we compare between frameworks.
+type Category string
+
+const (
+	Productions Category = "productions" // order of productions fired
+	Retrievals  Category = "retrievals"  // results of retrievals
+	Prints      Category = "prints"      // printed values
+	Buffers     Category = "buffers"     // final contents of buffers set by productions
+)
+
+// Categories lists all the categories in the order they are compared.
+var Categories = []Category{Productions, Retrievals, Prints, Buffers}
+
+// CategoryNames returns the names of all the categories.
+func CategoryNames() (names []string) {
+	for _, category := range Categories {
+		names = append(names, string(category))
+	}
+
+	return
+}
+
+// ParseCategory checks that the string is a valid category and returns it.
+func ParseCategory(name string) (category Category, err error) {
+	category = Category(strings.ToLower(strings.TrimSpace(name)))
+
+	if !slices.Contains(Categories, category) {
+		err = &ErrInvalidCategory{Category: name}
+		return "", err
+	}
+
+	return
+}
+
+// unsupportedEvents lists the trace events each framework cannot report.
+// See "Trace Events" in "doc/Framework Comparison.md".
+var unsupportedEvents = map[string][]framework.TraceEventType{
+	"ccm":    {framework.TraceRetrievalSucceeded, framework.TraceRetrievalFailed},
+	"pyactr": {framework.TraceBufferSet, framework.TracePrint, framework.TraceStop},
+}
+
+// requiredEvents lists the trace events a framework must report for us to compare a category.
+var requiredEvents = map[Category][]framework.TraceEventType{
+	Productions: {framework.TraceProductionFired},
+	Retrievals:  {framework.TraceRetrievalSucceeded, framework.TraceRetrievalFailed},
+	Prints:      {framework.TracePrint},
+	Buffers:     {framework.TraceBufferSet},
+}
+
+// unknown is used for items we cannot compare because a framework does not give us enough
+// information (e.g. ccm prints of a buffer). It matches anything.
+const unknown = "?"
+
+// supports checks if the framework can report everything we need to compare the category.
+func supports(frameworkName string, category Category) bool {
+	for _, eventType := range requiredEvents[category] {
+		if slices.Contains(unsupportedEvents[frameworkName], eventType) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Divergence describes where a framework differs from the reference framework.
+type Divergence struct {
+	Category  Category
+	Framework string
+
+	// Where the divergence occurred - the 1-based position in the sequence or the buffer name
+	Where string
+
+	Reference string // what the reference framework had ("" if nothing)
+	Actual    string // what this framework had ("" if nothing)
+
+	// Ignored is set if the user told us to ignore this category
+	Ignored bool
+}
+
+func (d Divergence) String() string {
+	reference := d.Reference
+	if reference == "" {
+		reference = "(nothing)"
+	}
+
+	actual := d.Actual
+	if actual == "" {
+		actual = "(nothing)"
+	}
+
+	return fmt.Sprintf("%s: %s differs at %s: expected %s, got %s", d.Category, d.Framework, d.Where, reference, actual)
+}
+
+// Report is the result of comparing the runs of a model on several frameworks.
+type Report struct {
+	ModelName string
+
+	Reference  string   // framework the others are compared with
+	Frameworks []string // frameworks which ran successfully (sorted)
+
+	Failed  map[string]string     // frameworks which did not run -> reason
+	Skipped map[string][]Category // categories we could not compare for a framework
+
+	Divergences []Divergence
+}
+
+// HasUnexpectedDivergence checks if there are any divergences which were not ignored or if
+// any framework failed to run.
+func (r Report) HasUnexpectedDivergence() bool {
+	if len(r.Failed) > 0 {
+		return true
+	}
+
+	for _, d := range r.Divergences {
+		if !d.Ignored {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String outputs the report in a form suitable for the command line.
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %q using %s as the reference\n", chalk.Header("Comparing"), r.ModelName, r.Reference)
+
+	failedNames := make([]string, 0, len(r.Failed))
+	for name := range r.Failed {
+		failedNames = append(failedNames, name)
+	}
+	sort.Strings(failedNames)
+
+	for _, name := range failedNames {
+		fmt.Fprintf(&b, "%s %s did not run: %s\n", chalk.Error("✗"), name, r.Failed[name])
+	}
+
+	for _, name := range r.Frameworks {
+		if name == r.Reference {
+			continue
+		}
+
+		for _, category := range r.Skipped[name] {
+			fmt.Fprintf(&b, "- %s: %s not compared (framework cannot report it)\n", name, category)
+		}
+	}
+
+	for _, category := range Categories {
+		divergences := r.divergencesFor(category)
+
+		if len(divergences) == 0 {
+			fmt.Fprintf(&b, "%s %s match\n", chalk.Success("✓"), category)
+			continue
+		}
+
+		for _, d := range divergences {
+			if d.Ignored {
+				fmt.Fprintf(&b, "%s %s (ignored)\n", chalk.Warning("~"), d)
+			} else {
+				fmt.Fprintf(&b, "%s %s\n", chalk.Error("✗"), d)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func (r Report) divergencesFor(category Category) (divergences []Divergence) {
+	for _, d := range r.Divergences {
+		if d.Category == category {
+			divergences = append(divergences, d)
+		}
+	}
+
+	return
+}
+
+// Options for running a comparison.
+type Options struct {
+	Goal   string     // initial goal (if not set in the model)
+	Ignore []Category // categories where divergence is expected
+
+	// these override any options from the model
+	runoptions.Options
+}
+
+// Run generates the model from the amod file, runs it on all the active frameworks, and compares
+// the traces.
+func Run(settings *cli.Settings, amodFile string, options Options) (report *Report, err error) {
+	if len(settings.ActiveFrameworks) < 2 {
+		return nil, ErrNotEnoughFrameworks
+	}
+
+	model, log, err := amod.GenerateModelFromFile(amodFile)
+	if err != nil {
+		fmt.Print(log)
+		return
+	}
+
+	initialGoal := strings.TrimSpace(options.Goal)
+
+	validate.Goal(model, initialGoal, log)
+	fmt.Print(log)
+
+	if log.HasError() {
+		return nil, ErrInvalidModel
+	}
+
+	traceEvents := true
+	options.TraceEvents = &traceEvents
+	options.Timeout = settings.Timeout
+
+	runOptions := model.DefaultParams.Override(&options.Options)
+
+	if initialGoal != "" {
+		runOptions.InitialBuffers = runoptions.InitialBuffers{
+			"goal": initialGoal,
+		}
+	}
+
+	_, err = cli.CreateTempDir(settings)
+	if err != nil {
+		return
+	}
+
+	runMap := framework.RunModelOnFrameworks(context.Background(), model, runOptions, settings.ActiveFrameworks)
+
+	report = Compare(model, runMap, options.Ignore)
+	return
+}
+
+// Compare compares the traces of the runs and reports any divergence from the reference
+// framework. The reference is "native" if it ran, otherwise the first framework by name.
+func Compare(model *actr.Model, runMap framework.FrameworkRunMap, ignore []Category) (report *Report) {
+	report = &Report{
+		ModelName: model.Name,
+		Failed:    map[string]string{},
+		Skipped:   map[string][]Category{},
+	}
+
+	traces := map[string]*framework.Trace{}
+
+	for name, run := range runMap {
+		switch {
+		case run.Log != nil && run.Log.HasError():
+			report.Failed[name] = strings.TrimSpace(run.Log.String())
+
+		case run.Result == nil || run.Result.Trace == nil:
+			report.Failed[name] = "no trace"
+
+		default:
+			traces[name] = run.Result.Trace
+			report.Frameworks = append(report.Frameworks, name)
+		}
+	}
+
+	sort.Strings(report.Frameworks)
+
+	if len(report.Frameworks) == 0 {
+		return
+	}
+
+	report.Reference = report.Frameworks[0]
+	if slices.Contains(report.Frameworks, "native") {
+		report.Reference = "native"
+	}
+
+	n := newNormalizer(model)
+	reference := newSummary(traces[report.Reference], n)
+
+	for _, name := range report.Frameworks {
+		if name == report.Reference {
+			continue
+		}
+
+		summary := newSummary(traces[name], n)
+
+		for _, category := range Categories {
+			if !supports(report.Reference, category) || !supports(name, category) {
+				report.Skipped[name] = append(report.Skipped[name], category)
+				continue
+			}
+
+			var divergences []Divergence
+
+			if category == Buffers {
+				divergences = compareBuffers(reference.buffers, summary.buffers)
+			} else {
+				divergences = compareSequences(reference.sequences[category], summary.sequences[category])
+			}
+
+			for _, d := range divergences {
+				d.Category = category
+				d.Framework = name
+				d.Ignored = slices.Contains(ignore, category)
+
+				report.Divergences = append(report.Divergences, d)
+			}
+		}
+	}
+
+	return
+}
+
+// summary is the normalized version of a trace which we use for comparisons.
+type summary struct {
+	sequences map[Category][]string
+	buffers   map[string]string
+}
+
+func newSummary(trace *framework.Trace, n normalizer) (s summary) {
+	s.sequences = map[Category][]string{}
+	s.buffers = map[string]string{}
+
+	for _, event := range trace.Events {
+		switch event.Type {
+		case framework.TraceProductionFired:
+			s.sequences[Productions] = append(s.sequences[Productions], strings.ToLower(event.Production))
+
+		case framework.TraceRetrievalSucceeded:
+			s.sequences[Retrievals] = append(s.sequences[Retrievals], n.chunk(event.Chunk))
+
+		case framework.TraceRetrievalFailed:
+			s.sequences[Retrievals] = append(s.sequences[Retrievals], "failed")
+
+		case framework.TracePrint:
+			text := strings.TrimSpace(event.Text)
+			if text == "" {
+				text = unknown
+			}
+
+			s.sequences[Prints] = append(s.sequences[Prints], text)
+
+		case framework.TraceBufferSet:
+			// the retrieval buffer is covered by the retrievals
+			if event.Buffer == "" || event.Buffer == "retrieval" {
+				continue
+			}
+
+			s.buffers[strings.ToLower(event.Buffer)] = n.chunk(event.Chunk)
+		}
+	}
+
+	return
+}
+
+// compareSequences reports the first place where the sequences differ. We only report the
+// first one because once they diverge, everything after is likely to differ as well.
+func compareSequences(reference, actual []string) []Divergence {
+	for i := 0; i < max(len(reference), len(actual)); i++ {
+		var ref, act string
+
+		if i < len(reference) {
+			ref = reference[i]
+		}
+
+		if i < len(actual) {
+			act = actual[i]
+		}
+
+		if !itemsMatch(ref, act) {
+			return []Divergence{{
+				Where:     fmt.Sprintf("#%d", i+1),
+				Reference: ref,
+				Actual:    act,
+			}}
+		}
+	}
+
+	return nil
+}
+
+// compareBuffers reports each buffer whose final contents differ. Buffers which only one of
+// the frameworks reported are skipped since some frameworks do not report initialization.
+func compareBuffers(reference, actual map[string]string) (divergences []Divergence) {
+	names := make([]string, 0, len(reference))
+	for name := range reference {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		act, ok := actual[name]
+		if !ok {
+			continue
+		}
+
+		if !itemsMatch(reference[name], act) {
+			divergences = append(divergences, Divergence{
+				Where:     name,
+				Reference: reference[name],
+				Actual:    act,
+			})
+		}
+	}
+
+	return
+}
+
+func itemsMatch(a, b string) bool {
+	if a == unknown || b == unknown {
+		return (a != "") && (b != "")
+	}
+
+	return a == b
+}
+
+// normalizer converts the framework-specific chunk strings from a trace into a form which we
+// can compare across frameworks.
+type normalizer struct {
+	slotNames map[string][]string // slot names of each chunk type (lower case)
+}
+
+func newNormalizer(model *actr.Model) (n normalizer) {
+	n.slotNames = map[string][]string{}
+
+	for _, chunk := range model.Chunks {
+		slots := make([]string, len(chunk.SlotNames))
+		for i, name := range chunk.SlotNames {
+			slots[i] = strings.ToLower(name)
+		}
+
+		n.slotNames[strings.ToLower(chunk.TypeName)] = slots
+	}
+
+	return
+}
+
+// chunk normalizes a chunk string by extracting the slot values in slot order. Only the
+// formatting (case, punctuation, whitespace, number format) is normalized - the order of the
+// values is significant. It handles the forms the frameworks use, such as:
+//
+//	native:  [property: shark category fish]
+//	pyactr:  property(object= shark, attribute= category, value= fish)
+//	vanilla: [shark category fish]
+//
+// When the slots are named (as in pyactr), the values are put in the order the chunk type
+// declares its slots. If the string is just a chunk name, it returns unknown.
+func (n normalizer) chunk(str string) string {
+	str = strings.TrimSpace(str)
+	if str == "" || !strings.ContainsAny(str, " []()") {
+		return unknown
+	}
+
+	str = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("[](),'\"", r) {
+			return ' '
+		}
+		return r
+	}, str)
+
+	values := []string{}
+	slots := []string{} // slot name of each value (if named)
+	typeName := ""
+	slotName := ""
+
+	for _, token := range strings.Fields(str) {
+		token = strings.ToLower(token)
+
+		// "type:" or "slot=" on its own is a type or slot name
+		if strings.HasSuffix(token, ":") || strings.HasSuffix(token, "=") {
+			name := token[:len(token)-1]
+			if _, isType := n.slotNames[name]; isType && typeName == "" {
+				typeName = name
+			} else {
+				slotName = name
+			}
+			continue
+		}
+
+		// "slot:value" or "slot=value"
+		if index := strings.IndexAny(token, ":="); index != -1 {
+			slotName = token[:index]
+			token = token[index+1:]
+		}
+
+		if _, isType := n.slotNames[token]; isType && typeName == "" && slotName == "" {
+			typeName = token
+			continue
+		}
+
+		if token == "isa" {
+			continue
+		}
+
+		if token == "none" {
+			token = "nil"
+		}
+
+		if f, err := strconv.ParseFloat(token, 64); err == nil {
+			token = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+
+		values = append(values, token)
+		slots = append(slots, slotName)
+		slotName = ""
+	}
+
+	values = n.orderValues(typeName, slots, values)
+
+	return "[" + strings.Join(values, " ") + "]"
+}
+
+// orderValues puts named slot values in the order the chunk type declares its slots.
+// Unnamed values are positional, so they are left as they are.
+func (n normalizer) orderValues(typeName string, slots, values []string) []string {
+	declared, ok := n.slotNames[typeName]
+	if !ok {
+		return values
+	}
+
+	for _, slot := range slots {
+		if !slices.Contains(declared, slot) {
+			return values
+		}
+	}
+
+	ordered := make([]string, len(declared))
+	for i := range ordered {
+		ordered[i] = "nil"
+	}
+
+	for i, slot := range slots {
+		ordered[slices.Index(declared, slot)] = values[i]
+	}
+
+	return ordered
+}
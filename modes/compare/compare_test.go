@@ -0,0 +1,179 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+)
+
+func testModel(t *testing.T) *actr.Model {
+	t.Helper()
+
+	src := `~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks {
+		[property: object attribute value]
+	}
+	~~ init ~~
+	~~ productions ~~`
+
+	model, log, err := amod.GenerateModel(src)
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	return model
+}
+
+func run(events ...framework.TraceEvent) framework.FrameworkRun {
+	return framework.FrameworkRun{
+		Result: &framework.RunResult{
+			Trace: &framework.Trace{Events: events},
+		},
+	}
+}
+
+func fired(name string) framework.TraceEvent {
+	return framework.TraceEvent{Type: framework.TraceProductionFired, Production: name}
+}
+
+func retrieved(chunk string) framework.TraceEvent {
+	return framework.TraceEvent{Type: framework.TraceRetrievalSucceeded, Buffer: "retrieval", Chunk: chunk}
+}
+
+func TestCompareMatching(t *testing.T) {
+	t.Parallel()
+
+	runMap := framework.FrameworkRunMap{
+		"native":  run(fired("start"), retrieved("[property: shark category fish]"), fired("Done")),
+		"pyactr":  run(fired("start"), retrieved("property(object= shark, attribute= category, value= fish)"), fired("Done")),
+		"vanilla": run(fired("START"), retrieved("[SHARK CATEGORY FISH]"), fired("DONE")),
+	}
+
+	report := Compare(testModel(t), runMap, nil)
+
+	if report.Reference != "native" {
+		t.Errorf("Incorrect reference: expected native, got %q", report.Reference)
+	}
+
+	if report.HasUnexpectedDivergence() {
+		t.Errorf("Unexpected divergence:\n%s", report)
+	}
+}
+
+func TestCompareProductionOrder(t *testing.T) {
+	t.Parallel()
+
+	runMap := framework.FrameworkRunMap{
+		"native":  run(fired("start"), fired("increment"), fired("stop")),
+		"vanilla": run(fired("start"), fired("stop")),
+	}
+
+	report := Compare(testModel(t), runMap, nil)
+
+	if len(report.Divergences) != 1 {
+		t.Fatalf("Incorrect number of divergences: expected 1, got %d", len(report.Divergences))
+	}
+
+	d := report.Divergences[0]
+	if d.Category != Productions || d.Where != "#2" || d.Reference != "increment" || d.Actual != "stop" {
+		t.Errorf("Incorrect divergence: %s", d)
+	}
+
+	if !report.HasUnexpectedDivergence() {
+		t.Errorf("Expected unexpected divergence")
+	}
+
+	// Now ignore it
+	report = Compare(testModel(t), runMap, []Category{Productions})
+
+	if report.HasUnexpectedDivergence() {
+		t.Errorf("Expected divergence to be ignored:\n%s", report)
+	}
+}
+
+func TestCompareSkipsUnsupported(t *testing.T) {
+	t.Parallel()
+
+	runMap := framework.FrameworkRunMap{
+		"ccm":     run(fired("start")),
+		"vanilla": run(fired("start"), retrieved("[shark category fish]")),
+	}
+
+	report := Compare(testModel(t), runMap, nil)
+
+	if report.Reference != "ccm" {
+		t.Errorf("Incorrect reference: expected ccm, got %q", report.Reference)
+	}
+
+	if report.HasUnexpectedDivergence() {
+		t.Errorf("Unexpected divergence:\n%s", report)
+	}
+
+	skipped := report.Skipped["vanilla"]
+	if len(skipped) != 1 || skipped[0] != Retrievals {
+		t.Errorf("Expected retrievals to be skipped, got %v", skipped)
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	t.Parallel()
+
+	category, err := ParseCategory(" Prints")
+	if err != nil || category != Prints {
+		t.Errorf("Incorrect category: expected prints, got %q (%v)", category, err)
+	}
+
+	_, err = ParseCategory("foo")
+	if err == nil {
+		t.Errorf("Expected error for invalid category")
+	}
+}
+
+func TestCompareSwappedSlotValues(t *testing.T) {
+	t.Parallel()
+
+	runMap := framework.FrameworkRunMap{
+		"native":  run(fired("start"), retrieved("[property: shark category fish]")),
+		"pyactr":  run(fired("start"), retrieved("property(object= shark, attribute= fish, value= category)")),
+		"vanilla": run(fired("START"), retrieved("[FISH CATEGORY SHARK]")),
+	}
+
+	report := Compare(testModel(t), runMap, nil)
+
+	if len(report.Divergences) != 2 {
+		t.Fatalf("Incorrect number of divergences: expected 2, got %d:\n%s", len(report.Divergences), report)
+	}
+
+	for _, d := range report.Divergences {
+		if d.Category != Retrievals {
+			t.Errorf("Incorrect divergence: %s", d)
+		}
+	}
+}
+
+func TestNormalizeChunk(t *testing.T) {
+	t.Parallel()
+
+	n := newNormalizer(testModel(t))
+
+	tests := []struct {
+		chunk    string
+		expected string
+	}{
+		{"[property: shark category fish]", "[shark category fish]"},
+		{"[SHARK  CATEGORY FISH]", "[shark category fish]"},
+		{"property(value= fish, object= shark, attribute= category)", "[shark category fish]"},
+		{"[property: shark 2.0 None]", "[shark 2 nil]"},
+		{"shark", unknown},
+	}
+
+	for _, tt := range tests {
+		if actual := n.chunk(tt.chunk); actual != tt.expected {
+			t.Errorf("Incorrect normalization of %q: expected %q, got %q", tt.chunk, tt.expected, actual)
+		}
+	}
+}
@@ -2,8 +2,11 @@
 package defaultmode
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/asmaloney/gactar/actr"
@@ -28,6 +31,10 @@ type CommandLineOptions struct {
 	FileList           []string
 	RunAfterGeneration bool
 
+	// TraceFile is the file to write the trace events of all the frameworks to (as a JSON object
+	// keyed by framework name). If it is empty, each framework's trace is output after its run.
+	TraceFile string
+
 	// these override any options from the model
 	runoptions.Options
 }
@@ -140,12 +147,14 @@ func (d *DefaultMode) generateCode() (err error) {
 }
 
 func (d *DefaultMode) runCode(frameworks framework.List) {
-	for _, f := range frameworks {
+	traces := map[string]*framework.Trace{}
+
+	for name, f := range frameworks {
 		model := f.Model()
 
 		options := model.DefaultParams.Override(&d.commandLineOptions.Options)
 
-		result, err := f.Run(options)
+		result, err := framework.RunModel(context.Background(), model, options, f, nil)
 		if err != nil {
 			fmt.Println(err.Error())
 			continue
@@ -153,6 +162,52 @@ func (d *DefaultMode) runCode(frameworks framework.List) {
 
 		fmt.Printf("== %s ==\n", f.Info().Name)
 		fmt.Println(string(result.Output))
+
+		for _, warning := range result.Warnings {
+			chalk.PrintWarningStr(warning)
+		}
+
+		if result.Trace != nil {
+			if d.commandLineOptions.TraceFile != "" {
+				traces[name] = result.Trace
+			} else {
+				err = writeJSON(os.Stdout, result.Trace)
+				if err != nil {
+					fmt.Println(err.Error())
+				}
+			}
+		}
+
 		fmt.Println()
 	}
+
+	if len(traces) > 0 {
+		err := writeTraceFile(d.commandLineOptions.TraceFile, traces)
+		if err != nil {
+			fmt.Println(err.Error())
+		} else {
+			fmt.Printf("traces written to %s\n", d.commandLineOptions.TraceFile)
+		}
+	}
+}
+
+// writeTraceFile writes the traces (keyed by framework name) to a JSON file.
+func writeTraceFile(fileName string, traces map[string]*framework.Trace) (err error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	return writeJSON(file, traces)
+}
+
+func writeJSON(w io.Writer, v any) (err error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return
 }
@@ -0,0 +1,678 @@
+// Package fit searches for the module parameter values which make a model's measures best
+// match observed (e.g. human) data.
+package fit
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/modes/sweep"
+
+	"github.com/asmaloney/gactar/util/chalk"
+	"github.com/asmaloney/gactar/util/cli"
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+var (
+	ErrNoFreeParams     = errors.New("no free parameters to fit")
+	ErrNoData           = errors.New("no observations in data")
+	ErrOneFramework     = errors.New("fitting requires exactly one active framework (use --framework to choose one)")
+	ErrInvalidRuns      = errors.New("number of runs must be at least 1")
+	ErrNotEnoughData    = errors.New("need at least two observations to use correlation")
+	ErrInvalidGridSteps = errors.New("grid steps must be at least 2")
+)
+
+// ErrInvalidFreeParam is returned when a free parameter specification cannot be parsed.
+type ErrInvalidFreeParam struct {
+	Spec    string
+	Message string
+}
+
+func (e ErrInvalidFreeParam) Error() string {
+	return fmt.Sprintf("invalid free parameter %q: %s", e.Spec, e.Message)
+}
+
+// ErrInvalidData is returned when the data file cannot be used.
+type ErrInvalidData struct {
+	Line    int
+	Message string
+}
+
+func (e ErrInvalidData) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("invalid data: %s", e.Message)
+	}
+
+	return fmt.Sprintf("invalid data on line %d: %s", e.Line, e.Message)
+}
+
+// ErrInvalidOption is returned when an option is not one of the valid choices.
+type ErrInvalidOption struct {
+	Option string
+	Value  string
+	Valid  []string
+}
+
+func (e ErrInvalidOption) Error() string {
+	return fmt.Sprintf("invalid %s %q - valid options: %s", e.Option, e.Value, strings.Join(e.Valid, ", "))
+}
+
+// ErrUnsupportedMeasure is returned when the framework cannot report the measure.
+type ErrUnsupportedMeasure struct {
+	Framework string
+	Measure   string
+}
+
+func (e ErrUnsupportedMeasure) Error() string {
+	return fmt.Sprintf("%s does not report the %q measure - use another framework or measure", e.Framework, e.Measure)
+}
+
+// Measures lists the measures we can fit. These are extracted from the trace of each run:
+//
+//	time:              simulation time of the last event
+//	productions_fired: number of productions fired
+//	print:             last printed value (must be a number)
+var Measures = []string{"time", "productions_fired", "print"}
+
+// unsupportedMeasures lists the measures each framework cannot report.
+// See "Trace Events" in "doc/Framework Comparison.md".
+var unsupportedMeasures = map[string][]string{
+	"pyactr": {"print"},
+}
+
+// Objectives lists the ways we can compare the simulated and observed measures.
+var Objectives = []string{"rmse", "correlation"}
+
+// Methods lists the search methods.
+var Methods = []string{"nelder-mead", "grid"}
+
+// goalColumn is the (optional) column in the data which holds the initial goal for each observation
+const goalColumn = "goal"
+
+// FreeParam is a module parameter we are fitting and its bounds.
+type FreeParam struct {
+	Name string // see sweep.Param
+
+	Min float64
+	Max float64
+}
+
+// ParseFreeParam parses a free parameter specification of the form "name[min,max]".
+func ParseFreeParam(spec string) (param *FreeParam, err error) {
+	spec = strings.TrimSpace(spec)
+
+	name, bounds, found := strings.Cut(spec, "[")
+	name = strings.TrimSpace(name)
+
+	if !found || name == "" || !strings.HasSuffix(bounds, "]") {
+		return nil, &ErrInvalidFreeParam{Spec: spec, Message: "expected name[min,max]"}
+	}
+
+	limits := strings.Split(strings.TrimSuffix(bounds, "]"), ",")
+	if len(limits) != 2 {
+		return nil, &ErrInvalidFreeParam{Spec: spec, Message: "expected name[min,max]"}
+	}
+
+	param = &FreeParam{Name: name}
+
+	param.Min, err = strconv.ParseFloat(strings.TrimSpace(limits[0]), 64)
+	if err != nil {
+		return nil, &ErrInvalidFreeParam{Spec: spec, Message: fmt.Sprintf("%q is not a number", limits[0])}
+	}
+
+	param.Max, err = strconv.ParseFloat(strings.TrimSpace(limits[1]), 64)
+	if err != nil {
+		return nil, &ErrInvalidFreeParam{Spec: spec, Message: fmt.Sprintf("%q is not a number", limits[1])}
+	}
+
+	if param.Max <= param.Min {
+		return nil, &ErrInvalidFreeParam{Spec: spec, Message: "max must be greater than min"}
+	}
+
+	return
+}
+
+// value converts a normalized value in [0, 1] to a value within the bounds.
+func (p FreeParam) value(normalized float64) float64 {
+	return p.Min + normalized*(p.Max-p.Min)
+}
+
+// Observation is one observed value of the measure and the goal it was observed with.
+type Observation struct {
+	Goal  string // empty to use the model's goal
+	Value float64
+}
+
+// ReadData reads observations of "measure" from CSV data. The first row is the header which must
+// have a column named after the measure. If there is a column named "goal", it is used as the
+// initial goal for that observation.
+func ReadData(r io.Reader, measure string) (observations []Observation, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &ErrInvalidData{Message: err.Error()}
+	}
+
+	if len(records) < 2 {
+		return nil, ErrNoData
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	measureIndex := slices.Index(header, measure)
+	if measureIndex == -1 {
+		return nil, &ErrInvalidData{Line: 1, Message: fmt.Sprintf("no column named %q", measure)}
+	}
+
+	goalIndex := slices.Index(header, goalColumn)
+
+	for i, record := range records[1:] {
+		line := i + 2
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[measureIndex]), 64)
+		if err != nil {
+			return nil, &ErrInvalidData{Line: line, Message: fmt.Sprintf("%q is not a number", record[measureIndex])}
+		}
+
+		observation := Observation{Value: value}
+
+		if goalIndex != -1 {
+			observation.Goal = strings.TrimSpace(record[goalIndex])
+		}
+
+		observations = append(observations, observation)
+	}
+
+	return
+}
+
+// Options for fitting a model.
+type Options struct {
+	Params []*FreeParam
+
+	DataFile string
+	Measure  string
+
+	Objective string // one of Objectives
+	Method    string // one of Methods
+
+	Runs int    // number of runs to average for each observation
+	Seed uint32 // seed for the first run - it is incremented for each subsequent run
+
+	MaxEvals  int // (nelder-mead) maximum number of evaluations
+	GridSteps int // (grid) number of values for each parameter
+}
+
+// Condition compares the observed and simulated measure for one observation.
+type Condition struct {
+	Goal      string
+	Observed  float64
+	Simulated float64
+}
+
+// Report is the result of fitting a model.
+type Report struct {
+	ModelName string
+	Framework string
+
+	Measure   string
+	Objective string
+	Method    string
+
+	Evaluations int
+
+	Params []*FreeParam
+	Best   []float64 // best values of the params
+
+	RMSE        float64
+	Correlation float64 // NaN if it cannot be calculated
+
+	Conditions []Condition
+}
+
+// String outputs the report in a form suitable for the command line.
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %q on %s: %s using %s (%d evaluations)\n",
+		chalk.Header("Best fit"), r.ModelName, r.Framework, r.Measure, r.Method, r.Evaluations)
+
+	w := tabwriter.NewWriter(&b, 1, 1, 3, ' ', 0)
+
+	for i, param := range r.Params {
+		fmt.Fprintf(w, "  %s\t%s\t[%s, %s]\n", param.Name, formatValue(r.Best[i]),
+			numbers.Float64Str(param.Min), numbers.Float64Str(param.Max))
+	}
+	w.Flush()
+
+	fmt.Fprintf(&b, "RMSE: %s  r: %s\n\n", formatValue(r.RMSE), formatValue(r.Correlation))
+
+	w = tabwriter.NewWriter(&b, 1, 1, 3, ' ', 0)
+
+	fmt.Fprintln(w, "goal\tobserved\tsimulated\tdifference")
+	for _, condition := range r.Conditions {
+		goal := condition.Goal
+		if goal == "" {
+			goal = "(model)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", goal, formatValue(condition.Observed),
+			formatValue(condition.Simulated), formatValue(condition.Simulated-condition.Observed))
+	}
+	w.Flush()
+
+	return b.String()
+}
+
+func formatValue(value float64) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "-"
+	}
+
+	return strconv.FormatFloat(value, 'g', 4, 64)
+}
+
+// fitter holds everything we need to evaluate a set of parameter values.
+type fitter struct {
+	model      *actr.Model
+	runOptions *runoptions.Options
+	frameworks framework.List
+
+	options      Options
+	observations []Observation
+	paramNames   []string
+}
+
+// Run generates the model from the amod file and searches for the values of the free parameters
+// which best fit the observed data.
+func Run(settings *cli.Settings, amodFile string, options Options) (report *Report, err error) {
+	err = checkOptions(&options)
+	if err != nil {
+		return
+	}
+
+	if len(settings.ActiveFrameworks) != 1 {
+		return nil, ErrOneFramework
+	}
+
+	err = checkMeasure(settings.ActiveFrameworks.Names()[0], options.Measure)
+	if err != nil {
+		return
+	}
+
+	file, err := os.Open(options.DataFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	observations, err := ReadData(file, options.Measure)
+	if err != nil {
+		return
+	}
+
+	if options.Objective == "correlation" && len(observations) < 2 {
+		return nil, ErrNotEnoughData
+	}
+
+	model, runOptions, err := sweep.LoadModel(settings, amodFile, "")
+	if err != nil {
+		return
+	}
+
+	return fit(model, runOptions, settings.ActiveFrameworks, observations, options)
+}
+
+// checkOptions validates the options and fills in defaults.
+func checkOptions(options *Options) error {
+	if len(options.Params) == 0 {
+		return ErrNoFreeParams
+	}
+
+	if options.Runs < 1 {
+		return ErrInvalidRuns
+	}
+
+	if options.Measure == "" {
+		options.Measure = Measures[0]
+	}
+
+	if options.Objective == "" {
+		options.Objective = Objectives[0]
+	}
+
+	if options.Method == "" {
+		options.Method = Methods[0]
+	}
+
+	if !slices.Contains(Measures, options.Measure) {
+		return &ErrInvalidOption{Option: "measure", Value: options.Measure, Valid: Measures}
+	}
+
+	if !slices.Contains(Objectives, options.Objective) {
+		return &ErrInvalidOption{Option: "objective", Value: options.Objective, Valid: Objectives}
+	}
+
+	if !slices.Contains(Methods, options.Method) {
+		return &ErrInvalidOption{Option: "method", Value: options.Method, Valid: Methods}
+	}
+
+	if options.MaxEvals <= 0 {
+		options.MaxEvals = 100
+	}
+
+	if options.Method == "grid" && options.GridSteps < 2 {
+		return ErrInvalidGridSteps
+	}
+
+	return nil
+}
+
+// checkMeasure checks that the framework can report the measure.
+func checkMeasure(frameworkName, measure string) error {
+	if slices.Contains(unsupportedMeasures[frameworkName], measure) {
+		return &ErrUnsupportedMeasure{Framework: frameworkName, Measure: measure}
+	}
+
+	return nil
+}
+
+// fit searches for the best values using the model which has already been loaded.
+func fit(model *actr.Model, runOptions *runoptions.Options, frameworks framework.List, observations []Observation, options Options) (report *Report, err error) {
+	f := &fitter{
+		model:        model,
+		runOptions:   runOptions,
+		frameworks:   frameworks,
+		options:      options,
+		observations: observations,
+	}
+
+	// check the params and goals before we start running
+	for _, param := range options.Params {
+		f.paramNames = append(f.paramNames, param.Name)
+
+		err = sweep.SetParam(model, param.Name, numbers.Float64Str(param.Min))
+		if err != nil {
+			return
+		}
+	}
+
+	for _, observation := range observations {
+		if observation.Goal == "" {
+			continue
+		}
+
+		_, err = amod.ParseChunk(model, observation.Goal)
+		if err != nil {
+			return nil, fmt.Errorf("goal %s: %w", observation.Goal, err)
+		}
+	}
+
+	report = &Report{
+		ModelName: model.Name,
+		Framework: frameworks.Names()[0],
+		Measure:   options.Measure,
+		Objective: options.Objective,
+		Method:    options.Method,
+		Params:    options.Params,
+	}
+
+	var best []float64
+
+	if options.Method == "grid" {
+		best, report.Evaluations = f.gridSearch()
+	} else {
+		start := make([]float64, len(options.Params))
+		for i := range start {
+			start[i] = 0.5
+		}
+
+		best, _, report.Evaluations = nelderMead(f.objective, start, options.MaxEvals)
+	}
+
+	report.Best = f.values(best)
+
+	simulated, err := f.simulate(best)
+	if err != nil {
+		return
+	}
+
+	observed := make([]float64, len(observations))
+	for i, observation := range observations {
+		observed[i] = observation.Value
+
+		report.Conditions = append(report.Conditions, Condition{
+			Goal:      observation.Goal,
+			Observed:  observation.Value,
+			Simulated: simulated[i],
+		})
+	}
+
+	report.RMSE = RMSE(observed, simulated)
+	report.Correlation = Correlation(observed, simulated)
+
+	return
+}
+
+// values converts normalized values to parameter values.
+func (f fitter) values(normalized []float64) []float64 {
+	values := make([]float64, len(normalized))
+	for i, param := range f.options.Params {
+		values[i] = param.value(normalized[i])
+	}
+
+	return values
+}
+
+// objective returns the value to minimize for the normalized parameter values.
+func (f fitter) objective(normalized []float64) float64 {
+	simulated, err := f.simulate(normalized)
+	if err != nil {
+		return math.Inf(1)
+	}
+
+	observed := make([]float64, len(f.observations))
+	for i, observation := range f.observations {
+		observed[i] = observation.Value
+	}
+
+	if f.options.Objective == "correlation" {
+		r := Correlation(observed, simulated)
+		if math.IsNaN(r) {
+			return 1 // no better than perfectly anti-correlated
+		}
+
+		return -r
+	}
+
+	return RMSE(observed, simulated)
+}
+
+// simulate runs the model with the normalized parameter values and returns the mean of the
+// measure over the runs for each observation.
+func (f fitter) simulate(normalized []float64) (simulated []float64, err error) {
+	values := []string{}
+	for _, value := range f.values(normalized) {
+		values = append(values, numbers.Float64Str(value))
+	}
+
+	// observations with the same goal will have the same result
+	means := map[string]float64{}
+
+	for _, observation := range f.observations {
+		mean, ok := means[observation.Goal]
+		if !ok {
+			mean, err = f.runGoal(observation.Goal, values)
+			if err != nil {
+				return
+			}
+
+			means[observation.Goal] = mean
+		}
+
+		simulated = append(simulated, mean)
+	}
+
+	return
+}
+
+// runGoal runs the model with the initial goal and returns the mean of the measure over the runs.
+func (f fitter) runGoal(goal string, values []string) (mean float64, err error) {
+	runOptions := *f.runOptions
+
+	if goal != "" {
+		runOptions.InitialBuffers = runoptions.InitialBuffers{
+			"goal": goal,
+		}
+	}
+
+	results, err := sweep.RunModel(f.model, &runOptions, f.frameworks, f.paramNames, values, f.options.Runs, f.options.Seed)
+	if err != nil {
+		return
+	}
+
+	sum := 0.0
+	for _, result := range results {
+		value, err := Measure(result, f.options.Measure)
+		if err != nil {
+			return 0, err
+		}
+
+		sum += value
+	}
+
+	return sum / float64(len(results)), nil
+}
+
+// Measure extracts the named measure (see Measures) from the result of a run.
+func Measure(result sweep.Result, measure string) (value float64, err error) {
+	if result.Error != "" {
+		return 0, errors.New(result.Error)
+	}
+
+	switch measure {
+	case "time":
+		return result.Time, nil
+
+	case "productions_fired":
+		return float64(result.ProductionsFired), nil
+
+	case "print":
+		if len(result.Prints) == 0 {
+			return 0, errors.New("nothing was printed")
+		}
+
+		last := result.Prints[len(result.Prints)-1]
+
+		value, err = strconv.ParseFloat(strings.Trim(last, `'"`), 64)
+		if err != nil {
+			return 0, fmt.Errorf("printed value %q is not a number", last)
+		}
+
+		return
+	}
+
+	return 0, &ErrInvalidOption{Option: "measure", Value: measure, Valid: Measures}
+}
+
+// gridSearch evaluates every combination of GridSteps values for each parameter and returns
+// the best one.
+func (f fitter) gridSearch() (best []float64, evals int) {
+	steps := f.options.GridSteps
+
+	normalized := make([]float64, steps)
+	for i := range normalized {
+		normalized[i] = float64(i) / float64(steps-1)
+	}
+
+	bestValue := math.Inf(1)
+
+	point := make([]int, len(f.options.Params))
+	for {
+		x := make([]float64, len(point))
+		for i, index := range point {
+			x[i] = normalized[index]
+		}
+
+		value := f.objective(x)
+		evals++
+
+		if best == nil || value < bestValue {
+			best = x
+			bestValue = value
+		}
+
+		// move to the next point on the grid
+		i := 0
+		for ; i < len(point); i++ {
+			point[i]++
+			if point[i] < steps {
+				break
+			}
+
+			point[i] = 0
+		}
+
+		if i == len(point) {
+			break
+		}
+	}
+
+	return
+}
+
+// RMSE returns the root-mean-square error between the observed and simulated values.
+func RMSE(observed, simulated []float64) float64 {
+	sum := 0.0
+	for i := range observed {
+		diff := simulated[i] - observed[i]
+		sum += diff * diff
+	}
+
+	return math.Sqrt(sum / float64(len(observed)))
+}
+
+// Correlation returns the Pearson correlation coefficient between the observed and simulated
+// values. It returns NaN if either has no variance.
+func Correlation(observed, simulated []float64) float64 {
+	n := float64(len(observed))
+
+	meanObserved, meanSimulated := 0.0, 0.0
+	for i := range observed {
+		meanObserved += observed[i] / n
+		meanSimulated += simulated[i] / n
+	}
+
+	covariance, varObserved, varSimulated := 0.0, 0.0, 0.0
+	for i := range observed {
+		o := observed[i] - meanObserved
+		s := simulated[i] - meanSimulated
+
+		covariance += o * s
+		varObserved += o * o
+		varSimulated += s * s
+	}
+
+	if varObserved == 0 || varSimulated == 0 {
+		return math.NaN()
+	}
+
+	return covariance / math.Sqrt(varObserved*varSimulated)
+}
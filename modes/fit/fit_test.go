@@ -0,0 +1,289 @@
+package fit
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/framework/native"
+	"github.com/asmaloney/gactar/modes/sweep"
+)
+
+func TestParseFreeParam(t *testing.T) {
+	t.Parallel()
+
+	param, err := ParseFreeParam("memory.latency_factor[0.1, 2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if param.Name != "memory.latency_factor" || param.Min != 0.1 || param.Max != 2 {
+		t.Errorf("incorrect param: %+v", param)
+	}
+
+	for _, spec := range []string{"memory.latency_factor", "[0,1]", "memory.decay[0.5]", "memory.decay[a,1]", "memory.decay[1,0.5]", "memory.decay[0,1"} {
+		_, err := ParseFreeParam(spec)
+		if err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestReadData(t *testing.T) {
+	t.Parallel()
+
+	data := `subject, goal, time
+1, "[countFrom: 2 4 starting]", 0.5
+2, "[countFrom: 2 5 starting]", 0.75
+`
+
+	observations, err := ReadData(strings.NewReader(data), "time")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(observations) != 2 {
+		t.Fatalf("expected 2 observations, got %d", len(observations))
+	}
+
+	if observations[1].Goal != "[countFrom: 2 5 starting]" || observations[1].Value != 0.75 {
+		t.Errorf("incorrect observation: %+v", observations[1])
+	}
+
+	_, err = ReadData(strings.NewReader(data), "print")
+	if err == nil || err.Error() != `invalid data on line 1: no column named "print"` {
+		t.Errorf("expected missing column error, got %v", err)
+	}
+
+	_, err = ReadData(strings.NewReader("time\nfast\n"), "time")
+	if err == nil || err.Error() != `invalid data on line 2: "fast" is not a number` {
+		t.Errorf("expected number error, got %v", err)
+	}
+
+	_, err = ReadData(strings.NewReader("time\n"), "time")
+	if err != ErrNoData {
+		t.Errorf("expected ErrNoData, got %v", err)
+	}
+}
+
+func TestRMSEAndCorrelation(t *testing.T) {
+	t.Parallel()
+
+	observed := []float64{1, 2, 3}
+
+	if rmse := RMSE(observed, []float64{1, 2, 5}); math.Abs(rmse-math.Sqrt(4.0/3.0)) > 1e-12 {
+		t.Errorf("incorrect RMSE: %v", rmse)
+	}
+
+	if r := Correlation(observed, []float64{2, 4, 6}); math.Abs(r-1) > 1e-12 {
+		t.Errorf("incorrect correlation: %v", r)
+	}
+
+	if r := Correlation(observed, []float64{3, 2, 1}); math.Abs(r+1) > 1e-12 {
+		t.Errorf("incorrect correlation: %v", r)
+	}
+
+	if r := Correlation(observed, []float64{1, 1, 1}); !math.IsNaN(r) {
+		t.Errorf("expected NaN correlation, got %v", r)
+	}
+}
+
+func TestNelderMead(t *testing.T) {
+	t.Parallel()
+
+	f := func(x []float64) float64 {
+		return (x[0]-0.3)*(x[0]-0.3) + (x[1]-0.7)*(x[1]-0.7)
+	}
+
+	best, value, evals := nelderMead(f, []float64{0.5, 0.5}, 200)
+
+	if math.Abs(best[0]-0.3) > 0.01 || math.Abs(best[1]-0.7) > 0.01 {
+		t.Errorf("incorrect minimum: %v (value %v after %d evaluations)", best, value, evals)
+	}
+
+	if evals > 200 {
+		t.Errorf("too many evaluations: %d", evals)
+	}
+
+	// minimum outside of the bounds should end up on the bound
+	f = func(x []float64) float64 {
+		return (x[0] - 2) * (x[0] - 2)
+	}
+
+	best, _, _ = nelderMead(f, []float64{0.5}, 100)
+	if math.Abs(best[0]-1) > 0.01 {
+		t.Errorf("expected minimum on the bound, got %v", best)
+	}
+}
+
+const countModel = `~~ model ~~
+name: count
+~~ config ~~
+chunks {
+	[count: first second]
+	[countFrom: start end status]
+}
+~~ init ~~
+memory {
+	[count: 2 3]
+	[count: 3 4]
+	[count: 4 5]
+}
+goal [countFrom: 2 4 'starting']
+~~ productions ~~
+start {
+	match { goal [countFrom: ?start ?end 'starting'] }
+	do {
+		recall [count: ?start *]
+		set goal to [countFrom: ?start ?end 'counting']
+	}
+}
+increment {
+	match {
+		goal [countFrom: ?x !?x 'counting']
+		retrieval [count: ?x ?next]
+	}
+	do {
+		print ?x
+		recall [count: ?next *]
+		set goal.start to ?next
+	}
+}
+done {
+	match { goal [countFrom: ?x ?x 'counting'] }
+	do {
+		print ?x
+		stop
+	}
+}`
+
+func TestFit(t *testing.T) {
+	t.Parallel()
+
+	model, log, err := amod.GenerateModel(countModel)
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	fw, err := native.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frameworks := framework.List{"native": fw}
+
+	traceEvents := true
+	runOptions := model.DefaultParams
+	runOptions.TraceEvents = &traceEvents
+
+	// generate our "observed" data using a known value
+	goals := []string{"[countFrom: 2 4 'starting']", "[countFrom: 2 5 'starting']"}
+	observations := []Observation{}
+
+	for _, goal := range goals {
+		options := runOptions
+		options.InitialBuffers = map[string]string{"goal": goal}
+
+		results, err := sweep.RunModel(model, &options, frameworks, []string{"procedural.default_action_time"}, []string{"0.08"}, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		observations = append(observations, Observation{Goal: goal, Value: results[0].Time})
+	}
+
+	params := []*FreeParam{{Name: "procedural.default_action_time", Min: 0.01, Max: 0.2}}
+
+	for _, method := range Methods {
+		options := Options{
+			Params:    params,
+			Measure:   "time",
+			Method:    method,
+			Runs:      1,
+			Seed:      1,
+			GridSteps: 20,
+		}
+
+		err = checkOptions(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := fit(model, &runOptions, frameworks, observations, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(report.Best[0]-0.08) > 0.01 {
+			t.Errorf("%s: expected best fit near 0.08, got %v\n%s", method, report.Best[0], report)
+		}
+
+		if report.RMSE > 0.05 {
+			t.Errorf("%s: RMSE too large\n%s", method, report)
+		}
+
+		if len(report.Conditions) != 2 {
+			t.Errorf("%s: expected 2 conditions, got %d", method, len(report.Conditions))
+		}
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	t.Parallel()
+
+	params := []*FreeParam{{Name: "memory.decay", Min: 0, Max: 1}}
+
+	options := Options{Params: params, Runs: 1}
+	err := checkOptions(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Measure != "time" || options.Objective != "rmse" || options.Method != "nelder-mead" || options.MaxEvals != 100 {
+		t.Errorf("incorrect defaults: %+v", options)
+	}
+
+	invalid := []Options{
+		{Runs: 1},
+		{Params: params},
+		{Params: params, Runs: 1, Measure: "foo"},
+		{Params: params, Runs: 1, Objective: "foo"},
+		{Params: params, Runs: 1, Method: "foo"},
+		{Params: params, Runs: 1, Method: "grid", GridSteps: 1},
+	}
+
+	for _, options := range invalid {
+		options := options
+
+		err := checkOptions(&options)
+		if err == nil {
+			t.Errorf("expected error for %+v", options)
+		}
+	}
+}
+
+func TestCheckMeasure(t *testing.T) {
+	t.Parallel()
+
+	err := checkMeasure("pyactr", "print")
+
+	var unsupported *ErrUnsupportedMeasure
+	if !errors.As(err, &unsupported) {
+		t.Errorf("expected ErrUnsupportedMeasure for pyactr print, got %v", err)
+	}
+
+	for _, measure := range Measures {
+		err = checkMeasure("native", measure)
+		if err != nil {
+			t.Errorf("unexpected error for native %s: %v", measure, err)
+		}
+	}
+
+	err = checkMeasure("pyactr", "time")
+	if err != nil {
+		t.Errorf("unexpected error for pyactr time: %v", err)
+	}
+}
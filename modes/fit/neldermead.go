@@ -0,0 +1,153 @@
+package fit
+
+import (
+	"math"
+	"sort"
+)
+
+// objectiveFunc is the function we are minimizing. Its parameters are normalized to [0, 1].
+type objectiveFunc func(x []float64) float64
+
+// Nelder–Mead coefficients
+const (
+	reflection  = 1.0
+	expansion   = 2.0
+	contraction = 0.5
+	shrinkage   = 0.5
+
+	initialStep = 0.25 // size of the initial simplex (in normalized units)
+
+	// stop when the simplex and the spread of its values are this small
+	sizeTolerance  = 1e-3
+	valueTolerance = 1e-6
+)
+
+type vertex struct {
+	x     []float64
+	value float64
+}
+
+// nelderMead minimizes "f" using the Nelder–Mead simplex method starting at "start".
+// All points are clamped to [0, 1]. It stops after "maxEvals" evaluations of "f" or when
+// the simplex has converged.
+// See: https://en.wikipedia.org/wiki/Nelder%E2%80%93Mead_method
+func nelderMead(f objectiveFunc, start []float64, maxEvals int) (best []float64, bestValue float64, evals int) {
+	n := len(start)
+
+	evaluate := func(x []float64) vertex {
+		evals++
+		return vertex{x: x, value: f(x)}
+	}
+
+	simplex := make([]vertex, 0, n+1)
+	simplex = append(simplex, evaluate(clamp(append([]float64{}, start...))))
+
+	for i := 0; i < n; i++ {
+		x := append([]float64{}, start...)
+
+		// step away from the closest bound so the vertex is distinct after clamping
+		if x[i]+initialStep <= 1 {
+			x[i] += initialStep
+		} else {
+			x[i] -= initialStep
+		}
+
+		simplex = append(simplex, evaluate(clamp(x)))
+	}
+
+	for evals < maxEvals {
+		sort.SliceStable(simplex, func(i, j int) bool {
+			return simplex[i].value < simplex[j].value
+		})
+
+		if converged(simplex) {
+			break
+		}
+
+		worst := simplex[n]
+		centroid := centroidOf(simplex[:n])
+
+		reflected := evaluate(clamp(along(centroid, worst.x, -reflection)))
+
+		switch {
+		case reflected.value < simplex[0].value:
+			expanded := evaluate(clamp(along(centroid, worst.x, -expansion)))
+			if expanded.value < reflected.value {
+				simplex[n] = expanded
+			} else {
+				simplex[n] = reflected
+			}
+
+		case reflected.value < simplex[n-1].value:
+			simplex[n] = reflected
+
+		default:
+			contracted := evaluate(clamp(along(centroid, worst.x, contraction)))
+			if contracted.value < worst.value {
+				simplex[n] = contracted
+				continue
+			}
+
+			// shrink towards the best vertex
+			for i := 1; i <= n; i++ {
+				simplex[i] = evaluate(along(simplex[0].x, simplex[i].x, shrinkage))
+			}
+		}
+	}
+
+	sort.SliceStable(simplex, func(i, j int) bool {
+		return simplex[i].value < simplex[j].value
+	})
+
+	return simplex[0].x, simplex[0].value, evals
+}
+
+// along returns the point "from" + t * ("to" - "from").
+func along(from, to []float64, t float64) []float64 {
+	x := make([]float64, len(from))
+	for i := range from {
+		x[i] = from[i] + t*(to[i]-from[i])
+	}
+
+	return x
+}
+
+func centroidOf(vertices []vertex) []float64 {
+	centroid := make([]float64, len(vertices[0].x))
+
+	for _, v := range vertices {
+		for i, value := range v.x {
+			centroid[i] += value / float64(len(vertices))
+		}
+	}
+
+	return centroid
+}
+
+// converged checks if the (sorted) simplex is small enough to stop.
+func converged(simplex []vertex) bool {
+	best := simplex[0]
+	worst := simplex[len(simplex)-1]
+
+	if math.IsInf(worst.value, 1) || math.Abs(worst.value-best.value) > valueTolerance {
+		return false
+	}
+
+	for _, v := range simplex[1:] {
+		for i := range v.x {
+			if math.Abs(v.x[i]-best.x[i]) > sizeTolerance {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func clamp(x []float64) []float64 {
+	for i := range x {
+		x[i] = math.Max(0, math.Min(1, x[i]))
+	}
+
+	return x
+}
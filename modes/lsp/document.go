@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+// document is an amod file which is open in the editor
+type document struct {
+	uri      string
+	fileName string // empty if the URI is not a file
+	version  int
+	text     string
+	lines    []string
+
+	symbols []amod.Symbol
+
+	// model is the last model we generated without errors. We keep it around so we can still
+	// provide hover etc. while the user is in the middle of editing.
+	model *actr.Model
+
+	// otherURIs are the URIs of included files we published diagnostics for
+	otherURIs []string
+}
+
+// update sets the text of the document and updates the symbols and model
+func (d *document) update(version int, text string) (log *issues.Log) {
+	d.version = version
+	d.text = text
+	d.lines = splitLines(text)
+	d.symbols = amod.Symbols(text)
+
+	model, log := amod.CheckSource(d.fileName, text)
+	if model != nil {
+		d.model = model
+	}
+
+	return
+}
+
+func splitLines(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// symbolAt returns the symbol at the position or nil if there isn't one
+func (d *document) symbolAt(pos position) *amod.Symbol {
+	line, column := d.fromPosition(pos)
+
+	for i := range d.symbols {
+		symbol := &d.symbols[i]
+
+		if symbol.Line == line && column >= symbol.ColumnStart && column <= symbol.ColumnEnd {
+			return symbol
+		}
+	}
+
+	return nil
+}
+
+// lookupChunk returns the chunk type from the model or nil if we don't have it
+func (d *document) lookupChunk(name string) *actr.Chunk {
+	if d.model == nil {
+		return nil
+	}
+
+	return d.model.LookupChunk(name)
+}
+
+// lookupProduction returns the production from the model or nil if we don't have it
+func (d *document) lookupProduction(name string) *actr.Production {
+	if d.model == nil {
+		return nil
+	}
+
+	for _, production := range d.model.Productions {
+		if production.Name == name {
+			return production
+		}
+	}
+
+	return nil
+}
+
+// fromPosition converts an LSP position to an amod line (1-based) & column (0-based, bytes)
+func (d *document) fromPosition(pos position) (line, column int) {
+	line = pos.Line + 1
+
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return line, pos.Character
+	}
+
+	return line, byteOffset(d.lines[pos.Line], pos.Character)
+}
+
+// toPosition converts an amod line (1-based) & column (0-based, bytes) to an LSP position
+func (d *document) toPosition(line, column int) position {
+	return linesToPosition(d.lines, line, column)
+}
+
+// toRange converts a location in the document to an LSP range
+func (d *document) toRange(loc issues.Location) textRange {
+	return locationToRange(d.lines, loc)
+}
+
+func locationToRange(lines []string, loc issues.Location) textRange {
+	return textRange{
+		Start: linesToPosition(lines, loc.Line, loc.ColumnStart),
+		End:   linesToPosition(lines, loc.Line, loc.ColumnEnd),
+	}
+}
+
+func linesToPosition(lines []string, line, column int) position {
+	if line < 1 {
+		return position{}
+	}
+
+	if line > len(lines) {
+		return position{Line: line - 1, Character: column}
+	}
+
+	return position{Line: line - 1, Character: utf16Length(lines[line-1], column)}
+}
+
+// utf16Length returns the number of UTF-16 code units in the first "offset" bytes of "str"
+func utf16Length(str string, offset int) (length int) {
+	offset = min(max(offset, 0), len(str))
+
+	for _, r := range str[:offset] {
+		length += runeLength(r)
+	}
+
+	return
+}
+
+// byteOffset returns the byte offset in "str" of "units" UTF-16 code units
+func byteOffset(str string, units int) (offset int) {
+	for units > 0 && offset < len(str) {
+		r, size := utf8.DecodeRuneInString(str[offset:])
+		units -= runeLength(r)
+		offset += size
+	}
+
+	return
+}
+
+// uriToPath returns the file path of a "file" URI or an empty string for other URIs
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+
+	path := u.Path
+
+	// Windows paths look like "/C:/foo"
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path)
+}
+
+// pathToURI returns the "file" URI of a file path
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+// runeLength returns the number of UTF-16 code units needed to encode "r"
+func runeLength(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
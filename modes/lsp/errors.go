@@ -0,0 +1,10 @@
+package lsp
+
+import (
+	"errors"
+)
+
+var (
+	ErrExitWithoutShutdown  = errors.New("exit notification received before shutdown request")
+	ErrMissingContentLength = errors.New("message header is missing a valid Content-Length")
+)
@@ -0,0 +1,477 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+)
+
+// documentPosition decodes the params of a request for a position in a document. The document is
+// nil if it is not open.
+func (s *Server) documentPosition(params json.RawMessage) (doc *document, pos position, err error) {
+	var p textDocumentPositionParams
+	err = unmarshalParams(params, &p)
+	if err != nil {
+		return
+	}
+
+	return s.documents[p.TextDocument.URI], p.Position, nil
+}
+
+// definition finds the declaration of chunk types, buffers, and modules, the place a variable is
+// bound in a production, and included files.
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	doc, pos, err := s.documentPosition(params)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	symbol := doc.symbolAt(pos)
+	if symbol == nil {
+		return nil, nil
+	}
+
+	switch symbol.Kind {
+	case amod.SymbolChunkType:
+		return s.findDeclaration(doc, amod.SymbolChunkType, symbol.Name), nil
+
+	case amod.SymbolBuffer, amod.SymbolModule:
+		// buffers which are not configured may be configured in a module of the same name
+		loc := s.findDeclaration(doc, amod.SymbolBuffer, symbol.Name)
+		if loc == nil {
+			loc = s.findDeclaration(doc, amod.SymbolModule, symbol.Name)
+		}
+		return loc, nil
+
+	case amod.SymbolVar:
+		binding := doc.varBinding(*symbol)
+		if binding == nil {
+			return nil, nil
+		}
+
+		return &location{URI: doc.uri, Range: doc.toRange(binding.Location)}, nil
+
+	case amod.SymbolInclude:
+		path := includePath(doc.fileName, symbol.Name)
+		if path == "" {
+			return nil, nil
+		}
+
+		return &location{URI: pathToURI(path)}, nil
+	}
+
+	return nil, nil
+}
+
+// findDeclaration looks for the declaration of a symbol in the document and the files it includes
+func (s *Server) findDeclaration(doc *document, kind amod.SymbolKind, name string) *location {
+	return s.findDeclarationInSymbols(doc.uri, doc.fileName, doc.lines, doc.symbols, kind, name, []string{doc.fileName})
+}
+
+func (s *Server) findDeclarationInSymbols(uri, fileName string, lines []string, symbols []amod.Symbol, kind amod.SymbolKind, name string, visited []string) *location {
+	for _, symbol := range symbols {
+		if symbol.Kind == kind && symbol.IsDeclaration && symbol.Name == name {
+			return &location{URI: uri, Range: locationToRange(lines, symbol.Location)}
+		}
+	}
+
+	for _, symbol := range symbols {
+		if symbol.Kind != amod.SymbolInclude {
+			continue
+		}
+
+		path := includePath(fileName, symbol.Name)
+		if path == "" || slices.Contains(visited, path) {
+			continue
+		}
+
+		visited = append(visited, path)
+
+		text, ok := s.fileText(path)
+		if !ok {
+			continue
+		}
+
+		loc := s.findDeclarationInSymbols(pathToURI(path), path, splitLines(text), amod.Symbols(text), kind, name, visited)
+		if loc != nil {
+			return loc
+		}
+	}
+
+	return nil
+}
+
+// includePath returns the path of a file included by "fileName" or an empty string if we can't
+// resolve it
+func includePath(fileName, include string) string {
+	if filepath.IsAbs(include) {
+		return include
+	}
+
+	if fileName == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(fileName), include)
+}
+
+// varBinding returns the occurrence of a variable in a production's match which binds it. We use
+// the production's VarIndexMap to find the buffer & slot if we have a model, otherwise we use the
+// first occurrence in a match.
+func (d *document) varBinding(symbol amod.Symbol) *amod.Symbol {
+	if symbol.Production == "" {
+		return nil
+	}
+
+	var varIndex *actr.VarIndex
+	if production := d.lookupProduction(symbol.Production); production != nil {
+		if index, ok := production.VarIndexMap[symbol.Name]; ok {
+			varIndex = &index
+		}
+	}
+
+	for i := range d.symbols {
+		other := &d.symbols[i]
+
+		if other.Kind != amod.SymbolVar || other.Production != symbol.Production ||
+			other.Name != symbol.Name || other.Buffer == "" {
+			continue
+		}
+
+		if varIndex == nil {
+			return other
+		}
+
+		chunk := d.lookupChunk(other.ChunkType)
+		if chunk == nil {
+			continue
+		}
+
+		if other.Buffer == varIndex.Buffer.Name() && chunk.SlotName(other.SlotIndex) == varIndex.SlotName {
+			return other
+		}
+	}
+
+	return nil
+}
+
+// hover shows the layout of chunk types, what variables are bound to, and information about buffers
+// & modules.
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	doc, pos, err := s.documentPosition(params)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	symbol := doc.symbolAt(pos)
+	if symbol == nil {
+		return nil, nil
+	}
+
+	var text string
+
+	switch symbol.Kind {
+	case amod.SymbolChunkType:
+		if chunk := doc.lookupChunk(symbol.Name); chunk != nil {
+			text = codeBlock(chunkLayout(chunk.TypeName, chunk.SlotNames))
+		}
+
+	case amod.SymbolSlot, amod.SymbolVar:
+		text = doc.slotHover(*symbol)
+
+	case amod.SymbolBuffer:
+		text = doc.bufferHover(symbol.Name)
+
+	case amod.SymbolModule:
+		text = doc.moduleHover(symbol.Name)
+		if text == "" {
+			text = doc.bufferHover(symbol.Name)
+		}
+	}
+
+	if text == "" {
+		return nil, nil
+	}
+
+	r := doc.toRange(symbol.Location)
+
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: text},
+		Range:    &r,
+	}, nil
+}
+
+// slotHover shows which slot a variable or slot name is in and which buffer slot a variable is bound to
+func (d *document) slotHover(symbol amod.Symbol) string {
+	lines := []string{}
+
+	if symbol.SlotIndex >= 0 {
+		if chunk := d.lookupChunk(symbol.ChunkType); chunk != nil && symbol.SlotIndex < chunk.NumSlots {
+			lines = append(lines, fmt.Sprintf("slot `%s` of `%s`", chunk.SlotName(symbol.SlotIndex), chunkLayout(chunk.TypeName, chunk.SlotNames)))
+		}
+	}
+
+	if symbol.Kind == amod.SymbolVar {
+		if production := d.lookupProduction(symbol.Production); production != nil {
+			if varIndex, ok := production.VarIndexMap[symbol.Name]; ok && varIndex.Buffer != nil {
+				lines = append(lines, fmt.Sprintf("bound to `%s.%s`", varIndex.Buffer.Name(), varIndex.SlotName))
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return codeBlock(symbol.Name) + "\n" + strings.Join(lines, "\n\n")
+}
+
+func (d *document) bufferHover(name string) string {
+	if d.model == nil {
+		return ""
+	}
+
+	for _, module := range d.model.Modules {
+		if module.Buffers().Has(name) {
+			return fmt.Sprintf("buffer `%s` of the `%s` module", name, module.ModuleName())
+		}
+	}
+
+	return ""
+}
+
+func (d *document) moduleHover(name string) string {
+	if d.model == nil {
+		return ""
+	}
+
+	module := d.model.LookupModule(name)
+	if module == nil {
+		return ""
+	}
+
+	text := fmt.Sprintf("module `%s`", module.ModuleName())
+	if description := module.ModuleDescription(); description != "" {
+		text += ": " + description
+	}
+
+	return text
+}
+
+// chunkLayout formats a chunk type like its declaration
+func chunkLayout(name string, slots []string) string {
+	return fmt.Sprintf("[%s: %s]", name, strings.Join(slots, " "))
+}
+
+func codeBlock(code string) string {
+	return "```amod\n" + code + "\n```"
+}
+
+// completion completes the chunk type or the next slot in a pattern.
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	doc, pos, err := s.documentPosition(params)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	items := []completionItem{}
+
+	line, column := doc.fromPosition(pos)
+
+	patternPos, ok := amod.PatternAt(doc.text, line, column)
+	if !ok {
+		return items, nil
+	}
+
+	// replace what has been typed so far
+	edit := textRange{Start: doc.toPosition(line, doc.wordStart(line, column)), End: pos}
+
+	add := func(label string, kind int, detail string) {
+		items = append(items, completionItem{
+			Label:    label,
+			Kind:     kind,
+			Detail:   detail,
+			SortText: fmt.Sprintf("%03d", len(items)),
+			TextEdit: &textEdit{Range: edit, NewText: label},
+		})
+	}
+
+	if patternPos.SlotIndex < 0 {
+		for _, name := range doc.chunkTypes() {
+			add(name, completionKindClass, chunkLayout(name, doc.chunkSlots(name)))
+		}
+
+		return items, nil
+	}
+
+	slots := doc.chunkSlots(patternPos.ChunkType)
+	if patternPos.SlotIndex >= len(slots) {
+		return items, nil
+	}
+
+	slotName := slots[patternPos.SlotIndex]
+	add("?"+slotName, completionKindVariable, fmt.Sprintf("slot %d (%s) of %s", patternPos.SlotIndex+1, slotName, chunkLayout(patternPos.ChunkType, slots)))
+
+	// variables already used in this production
+	production := doc.productionAt(line, column)
+	if production != "" {
+		seen := []string{"?" + slotName}
+
+		for _, symbol := range doc.symbols {
+			if symbol.Kind != amod.SymbolVar || symbol.Production != production || slices.Contains(seen, symbol.Name) {
+				continue
+			}
+
+			seen = append(seen, symbol.Name)
+			add(symbol.Name, completionKindVariable, "variable in "+production)
+		}
+	}
+
+	add("*", completionKindKeyword, "wildcard - matches any value")
+	add("nil", completionKindValue, "no value")
+
+	return items, nil
+}
+
+// wordStart returns the column where the word before "column" starts
+func (d *document) wordStart(line, column int) int {
+	if line < 1 || line > len(d.lines) {
+		return column
+	}
+
+	text := d.lines[line-1]
+	column = min(column, len(text))
+
+	start := column
+	for start > 0 && isWordChar(text[start-1]) {
+		start--
+	}
+
+	return start
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '-' || c == '?' || c == '*' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// productionAt returns the name of the production containing the position based on the symbols
+// before it
+func (d *document) productionAt(line, column int) (name string) {
+	for _, symbol := range d.symbols {
+		if symbol.Line > line || (symbol.Line == line && symbol.ColumnStart > column) {
+			break
+		}
+
+		name = symbol.Production
+	}
+
+	return
+}
+
+// chunkTypes returns the names of the chunk types declared in the model (or the document if we
+// don't have a model)
+func (d *document) chunkTypes() (names []string) {
+	if d.model != nil {
+		for _, chunk := range d.model.Chunks {
+			if !chunk.IsInternal() {
+				names = append(names, chunk.TypeName)
+			}
+		}
+	}
+
+	for _, symbol := range d.symbols {
+		if symbol.Kind == amod.SymbolChunkType && symbol.IsDeclaration && !slices.Contains(names, symbol.Name) {
+			names = append(names, symbol.Name)
+		}
+	}
+
+	slices.Sort(names)
+	return
+}
+
+// chunkSlots returns the slot names of a chunk type from the model (or the document if we don't have
+// a model)
+func (d *document) chunkSlots(name string) (slots []string) {
+	if chunk := d.lookupChunk(name); chunk != nil {
+		return chunk.SlotNames
+	}
+
+	for _, symbol := range d.symbols {
+		if symbol.Kind == amod.SymbolSlot && symbol.ChunkType == name {
+			slots = append(slots, symbol.Name)
+		}
+	}
+
+	return
+}
+
+// documentHighlight highlights the other uses of a symbol. For variables this is limited to the
+// production they are in and the place they are bound is marked as a "write".
+func (s *Server) documentHighlight(params json.RawMessage) (any, error) {
+	doc, pos, err := s.documentPosition(params)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	highlights := []documentHighlight{}
+
+	symbol := doc.symbolAt(pos)
+	if symbol == nil {
+		return highlights, nil
+	}
+
+	if symbol.Kind == amod.SymbolVar {
+		if symbol.Production == "" {
+			return highlights, nil
+		}
+
+		// only use read/write if the variable is in the production's VarIndexMap
+		var binding *amod.Symbol
+		if production := doc.lookupProduction(symbol.Production); production != nil {
+			if _, ok := production.VarIndexMap[symbol.Name]; ok {
+				binding = doc.varBinding(*symbol)
+			}
+		}
+
+		for _, other := range doc.symbols {
+			if other.Kind != amod.SymbolVar || other.Production != symbol.Production || other.Name != symbol.Name {
+				continue
+			}
+
+			kind := highlightText
+			if binding != nil {
+				kind = highlightRead
+				if other.Location == binding.Location {
+					kind = highlightWrite
+				}
+			}
+
+			highlights = append(highlights, documentHighlight{Range: doc.toRange(other.Location), Kind: kind})
+		}
+
+		return highlights, nil
+	}
+
+	for _, other := range doc.symbols {
+		if sameKind(other.Kind, symbol.Kind) && other.Name == symbol.Name {
+			highlights = append(highlights, documentHighlight{Range: doc.toRange(other.Location), Kind: highlightText})
+		}
+	}
+
+	return highlights, nil
+}
+
+// sameKind treats buffers and modules as the same since they often share names (e.g. goal)
+func sameKind(a, b amod.SymbolKind) bool {
+	isBufferOrModule := func(kind amod.SymbolKind) bool {
+		return kind == amod.SymbolBuffer || kind == amod.SymbolModule
+	}
+
+	return a == b || (isBufferOrModule(a) && isBufferOrModule(b))
+}
@@ -0,0 +1,360 @@
+// Package lsp provides a Language Server Protocol server for amod files so editors can show
+// diagnostics, go to definitions, show hover info, complete patterns, and highlight variables.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/version"
+)
+
+// Server talks to an editor using the Language Server Protocol over a pair of streams (usually
+// stdin & stdout). Requests are handled one at a time in the order they are received.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	initialized bool
+	shutdown    bool
+	documents   map[string]*document
+
+	requestHandlers      map[string]func(json.RawMessage) (any, error)
+	notificationHandlers map[string]func(json.RawMessage) error
+}
+
+type errInvalidParams struct {
+	err error
+}
+
+func (e errInvalidParams) Error() string {
+	return fmt.Sprintf("invalid params: %s", e.err.Error())
+}
+
+// New creates a server which reads from "in" and writes to "out".
+func New(in io.Reader, out io.Writer) *Server {
+	s := &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]*document{},
+	}
+
+	s.requestHandlers = map[string]func(json.RawMessage) (any, error){
+		"initialize":                     s.initialize,
+		"shutdown":                       s.shutdownRequest,
+		"textDocument/definition":        s.definition,
+		"textDocument/hover":             s.hover,
+		"textDocument/completion":        s.completion,
+		"textDocument/documentHighlight": s.documentHighlight,
+	}
+
+	s.notificationHandlers = map[string]func(json.RawMessage) error{
+		"textDocument/didOpen":   s.didOpen,
+		"textDocument/didChange": s.didChange,
+		"textDocument/didClose":  s.didClose,
+	}
+
+	return s
+}
+
+// Run handles messages until the client sends "exit" or closes the input.
+func (s *Server) Run() error {
+	for {
+		content, err := readMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		var req request
+		err = json.Unmarshal(content, &req)
+		if err != nil {
+			err = s.replyError(nil, codeParseError, err.Error())
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+
+			return nil
+		}
+
+		err = s.handle(req)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a request or notification. The error returned is only for problems writing
+// to the client - errors handling the request are sent to the client.
+func (s *Server) handle(req request) (err error) {
+	if req.isNotification() {
+		handler, ok := s.notificationHandlers[req.Method]
+		if !ok || !s.initialized || s.shutdown {
+			// we are allowed to ignore notifications we don't handle
+			return nil
+		}
+
+		err = handler(req.Params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gactar lsp: %s: %s\n", req.Method, err.Error())
+		}
+		return nil
+	}
+
+	id := *req.ID
+
+	switch {
+	case !s.initialized && req.Method != "initialize":
+		return s.replyError(id, codeServerNotInitialized, "server not initialized")
+
+	case s.shutdown:
+		return s.replyError(id, codeInvalidRequest, "server is shutting down")
+	}
+
+	handler, ok := s.requestHandlers[req.Method]
+	if !ok {
+		return s.replyError(id, codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
+	}
+
+	result, err := handler(req.Params)
+	if err != nil {
+		var invalidParams errInvalidParams
+		if errors.As(err, &invalidParams) {
+			return s.replyError(id, codeInvalidParams, err.Error())
+		}
+
+		return s.replyError(id, codeInvalidRequest, err.Error())
+	}
+
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyError(id json.RawMessage, code int, message string) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return writeMessage(s.out, errorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   responseError{Code: code, Message: message},
+	})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// unmarshalParams decodes the params of a request into "v"
+func unmarshalParams(params json.RawMessage, v any) error {
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return errInvalidParams{err}
+	}
+
+	return nil
+}
+
+func (s *Server) initialize(json.RawMessage) (any, error) {
+	s.initialized = true
+
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:   textDocumentSyncFull,
+			DefinitionProvider: true,
+			HoverProvider:      true,
+			CompletionProvider: completionOptions{
+				TriggerCharacters: []string{"[", ":", "?"},
+			},
+			DocumentHighlightProvider: true,
+		},
+		ServerInfo: serverInfo{
+			Name:    "gactar",
+			Version: version.BuildVersion,
+		},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (err error) {
+	var p didOpenTextDocumentParams
+	err = unmarshalParams(params, &p)
+	if err != nil {
+		return
+	}
+
+	doc := &document{
+		uri:      p.TextDocument.URI,
+		fileName: uriToPath(p.TextDocument.URI),
+	}
+	s.documents[doc.uri] = doc
+
+	log := doc.update(p.TextDocument.Version, p.TextDocument.Text)
+
+	return s.publishDiagnostics(doc, log)
+}
+
+func (s *Server) didChange(params json.RawMessage) (err error) {
+	var p didChangeTextDocumentParams
+	err = unmarshalParams(params, &p)
+	if err != nil {
+		return
+	}
+
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok || len(p.ContentChanges) == 0 {
+		return
+	}
+
+	// we use full sync, so the last change has the whole document
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+
+	log := doc.update(p.TextDocument.Version, text)
+
+	return s.publishDiagnostics(doc, log)
+}
+
+func (s *Server) didClose(params json.RawMessage) (err error) {
+	var p didCloseTextDocumentParams
+	err = unmarshalParams(params, &p)
+	if err != nil {
+		return
+	}
+
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return
+	}
+
+	delete(s.documents, doc.uri)
+
+	// clear the diagnostics we published
+	for _, uri := range append(doc.otherURIs, doc.uri) {
+		err = s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: []diagnostic{}})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// publishDiagnostics sends the issues in the log to the client. Issues in included files are sent
+// for those files.
+func (s *Server) publishDiagnostics(doc *document, log *issues.Log) (err error) {
+	// clear anything we published for included files last time
+	uris := append([]string{doc.uri}, doc.otherURIs...)
+	byURI := map[string][]diagnostic{}
+	lines := map[string][]string{doc.uri: doc.lines}
+
+	doc.otherURIs = []string{}
+
+	for _, issue := range log.AllIssues() {
+		uri := doc.uri
+
+		loc := issues.Location{}
+		if issue.Location != nil {
+			loc = *issue.Location
+		}
+
+		if loc.SourceFile != "" && !sameFile(loc.SourceFile, doc.fileName) {
+			uri = pathToURI(loc.SourceFile)
+
+			if !slices.Contains(doc.otherURIs, uri) {
+				doc.otherURIs = append(doc.otherURIs, uri)
+				lines[uri] = s.fileLines(loc.SourceFile)
+			}
+
+			if !slices.Contains(uris, uri) {
+				uris = append(uris, uri)
+			}
+		}
+
+		byURI[uri] = append(byURI[uri], diagnostic{
+			Range:    locationToRange(lines[uri], loc),
+			Severity: severity(issue),
+			Source:   "gactar",
+			Message:  issue.Text,
+		})
+	}
+
+	for _, uri := range uris {
+		params := publishDiagnosticsParams{URI: uri, Diagnostics: byURI[uri]}
+		if params.Diagnostics == nil {
+			params.Diagnostics = []diagnostic{}
+		}
+
+		if uri == doc.uri {
+			params.Version = &doc.version
+		}
+
+		err = s.notify("textDocument/publishDiagnostics", params)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// fileLines returns the lines of a file - from the editor if it is open, otherwise from disk
+func (s *Server) fileLines(path string) []string {
+	text, _ := s.fileText(path)
+	return splitLines(text)
+}
+
+// fileText returns the text of a file - from the editor if it is open, otherwise from disk
+func (s *Server) fileText(path string) (text string, ok bool) {
+	for _, doc := range s.documents {
+		if sameFile(doc.fileName, path) {
+			return doc.text, true
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	return string(data), true
+}
+
+func severity(issue issues.Issue) int {
+	switch issue.Level {
+	case "error":
+		return severityError
+	case "warning":
+		return severityWarning
+	}
+
+	return severityInformation
+}
+
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+
+	return filepath.Clean(a) == filepath.Clean(b)
+}
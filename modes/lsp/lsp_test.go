@@ -0,0 +1,434 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///tmp/test.amod"
+
+const testModel = `~~ model ~~
+name: test
+~~ config ~~
+chunks {
+    [count: first second]
+    [countFrom: start end status]
+}
+~~ init ~~
+~~ productions ~~
+increment {
+    match {
+        goal [countFrom: ?x !?x counting]
+        retrieval [count: ?x ?next]
+    }
+    do {
+        recall [count: ?next *]
+        set goal.start to ?next
+    }
+}
+`
+
+// testClient queues up messages for the server and decodes its replies
+type testClient struct {
+	input  bytes.Buffer
+	nextID int
+}
+
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	c := &testClient{}
+	c.request(t, "initialize", map[string]any{})
+	c.notify(t, "initialized", map[string]any{})
+
+	return c
+}
+
+func (c *testClient) request(t *testing.T, method string, params any) (id int) {
+	t.Helper()
+
+	c.nextID++
+
+	err := writeMessage(&c.input, map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c.nextID
+}
+
+func (c *testClient) notify(t *testing.T, method string, params any) {
+	t.Helper()
+
+	err := writeMessage(&c.input, map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testClient) open(t *testing.T, uri, text string) {
+	t.Helper()
+
+	c.notify(t, "textDocument/didOpen", didOpenTextDocumentParams{
+		TextDocument: textDocumentItem{URI: uri, LanguageID: "amod", Version: 1, Text: text},
+	})
+}
+
+func (c *testClient) requestAt(t *testing.T, method, uri string, line, character int) int {
+	t.Helper()
+
+	return c.request(t, method, textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: line, Character: character},
+	})
+}
+
+// run runs the server on the queued messages and returns the messages it sent
+func (c *testClient) run(t *testing.T) []testMessage {
+	t.Helper()
+
+	var output bytes.Buffer
+
+	err := New(&c.input, &output).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return readMessages(t, &output)
+}
+
+// readMessages decodes all the messages the server sent
+func readMessages(t *testing.T, output *bytes.Buffer) (messages []testMessage) {
+	t.Helper()
+
+	r := bufio.NewReader(output)
+	for {
+		content, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var msg testMessage
+		err = json.Unmarshal(content, &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		messages = append(messages, msg)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func decodeResponse(t *testing.T, messages []testMessage, id int, result any) {
+	t.Helper()
+
+	for _, msg := range messages {
+		if msg.ID == nil || *msg.ID != id {
+			continue
+		}
+
+		if msg.Error != nil {
+			t.Fatalf("request %d failed: %s", id, msg.Error.Message)
+		}
+
+		err := json.Unmarshal(msg.Result, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	t.Fatalf("no response to request %d", id)
+}
+
+func diagnosticsFor(messages []testMessage, uri string) (all [][]diagnostic) {
+	for _, msg := range messages {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var params publishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil && params.URI == uri {
+			all = append(all, params.Diagnostics)
+		}
+	}
+
+	return
+}
+
+func TestDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t)
+	c.open(t, testURI, strings.Replace(testModel, "?x ?next]", "?x ?next ?extra]", 1))
+	c.notify(t, "textDocument/didChange", didChangeTextDocumentParams{
+		TextDocument:   versionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []textDocumentContentChangeEvent{{Text: testModel}},
+	})
+
+	published := diagnosticsFor(c.run(t), testURI)
+	if len(published) != 2 {
+		t.Fatalf("expected diagnostics to be published twice, got %d", len(published))
+	}
+
+	if len(published[0]) == 0 {
+		t.Fatal("expected diagnostics for invalid pattern")
+	}
+
+	first := published[0][0]
+	if first.Severity != severityError || first.Range.Start.Line != 12 {
+		t.Errorf("unexpected diagnostic: %+v", first)
+	}
+
+	if len(published[1]) != 0 {
+		t.Errorf("expected no diagnostics after fix, got %+v", published[1])
+	}
+}
+
+func TestDiagnosticsInclude(t *testing.T) {
+	t.Parallel()
+
+	path, err := filepath.Abs(filepath.Join("testdata", "error.amod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t)
+	c.open(t, pathToURI(path), readTestFile(t, path))
+
+	messages := c.run(t)
+
+	published := diagnosticsFor(messages, pathToURI(filepath.Join(filepath.Dir(path), "error_chunks.amod")))
+	if len(published) != 1 || len(published[0]) != 1 {
+		t.Fatalf("expected one diagnostic for the included file, got %+v", published)
+	}
+
+	diag := published[0][0]
+	if diag.Range.Start.Line != 3 || diag.Range.Start.Character != 11 {
+		t.Errorf("unexpected diagnostic location: %+v", diag.Range)
+	}
+}
+
+func TestDiagnosticsUTF16(t *testing.T) {
+	t.Parallel()
+
+	// the emoji is 4 bytes in UTF-8 but 2 code units in UTF-16
+	c := newTestClient(t)
+	c.open(t, testURI, strings.Replace(testModel, "recall [count: ?next *]", "print '😀', ?nope", 1))
+
+	published := diagnosticsFor(c.run(t), testURI)
+	if len(published) != 1 || len(published[0]) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", published)
+	}
+
+	diag := published[0][0]
+	if diag.Range.Start.Character != 20 || diag.Range.End.Character != 25 {
+		t.Errorf("expected diagnostic range in UTF-16 code units, got %+v", diag.Range)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t)
+	c.open(t, testURI, testModel)
+	chunkType := c.requestAt(t, "textDocument/definition", testURI, 12, 20)
+	variable := c.requestAt(t, "textDocument/definition", testURI, 16, 27)
+
+	messages := c.run(t)
+
+	var loc location
+	decodeResponse(t, messages, chunkType, &loc)
+	expected := textRange{Start: position{4, 5}, End: position{4, 10}}
+	if loc.URI != testURI || loc.Range != expected {
+		t.Errorf("expected chunk type declaration at %+v, got %+v", expected, loc)
+	}
+
+	decodeResponse(t, messages, variable, &loc)
+	expected = textRange{Start: position{12, 29}, End: position{12, 34}}
+	if loc.Range != expected {
+		t.Errorf("expected variable binding at %+v, got %+v", expected, loc.Range)
+	}
+}
+
+func TestDefinitionInclude(t *testing.T) {
+	t.Parallel()
+
+	path, err := filepath.Abs(filepath.Join("testdata", "main.amod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t)
+	c.open(t, pathToURI(path), readTestFile(t, path))
+	id := c.requestAt(t, "textDocument/definition", pathToURI(path), 15, 16)
+
+	var loc location
+	decodeResponse(t, c.run(t), id, &loc)
+
+	expectedURI := pathToURI(filepath.Join(filepath.Dir(path), "chunks.amod"))
+	if loc.URI != expectedURI || loc.Range.Start.Line != 4 {
+		t.Errorf("expected declaration in %s line 4, got %+v", expectedURI, loc)
+	}
+}
+
+func TestHover(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t)
+	c.open(t, testURI, testModel)
+	chunkType := c.requestAt(t, "textDocument/hover", testURI, 12, 20)
+	variable := c.requestAt(t, "textDocument/hover", testURI, 12, 27)
+
+	messages := c.run(t)
+
+	var h hover
+	decodeResponse(t, messages, chunkType, &h)
+	if !strings.Contains(h.Contents.Value, "[count: first second]") {
+		t.Errorf("expected chunk layout, got %q", h.Contents.Value)
+	}
+
+	decodeResponse(t, messages, variable, &h)
+	if !strings.Contains(h.Contents.Value, "slot `first`") || !strings.Contains(h.Contents.Value, "bound to `goal.start`") {
+		t.Errorf("expected slot and binding, got %q", h.Contents.Value)
+	}
+}
+
+func TestHoverUTF16(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t)
+	c.open(t, testURI, strings.Replace(testModel, "recall [count: ?next *]", "print '😀', ?next", 1))
+	variable := c.requestAt(t, "textDocument/hover", testURI, 15, 21)
+
+	messages := c.run(t)
+
+	var h hover
+	decodeResponse(t, messages, variable, &h)
+	if !strings.Contains(h.Contents.Value, "bound to `retrieval.second`") {
+		t.Errorf("expected binding of ?next, got %q", h.Contents.Value)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t)
+	c.open(t, testURI, testModel)
+	slot := c.requestAt(t, "textDocument/completion", testURI, 15, 29)
+	chunkType := c.requestAt(t, "textDocument/completion", testURI, 15, 16)
+	outside := c.requestAt(t, "textDocument/completion", testURI, 16, 9)
+
+	messages := c.run(t)
+
+	var items []completionItem
+	decodeResponse(t, messages, slot, &items)
+	if len(items) == 0 || items[0].Label != "?second" {
+		t.Fatalf("expected ?second to be completed first, got %+v", items)
+	}
+
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+
+	if strings.Join(labels, " ") != "?second ?x ?next * nil" {
+		t.Errorf("unexpected completions: %v", labels)
+	}
+
+	decodeResponse(t, messages, chunkType, &items)
+	if len(items) != 2 || items[0].Label != "count" || items[1].Label != "countFrom" {
+		t.Errorf("expected chunk types, got %+v", items)
+	}
+
+	decodeResponse(t, messages, outside, &items)
+	if len(items) != 0 {
+		t.Errorf("expected no completions outside a pattern, got %+v", items)
+	}
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t)
+	c.open(t, testURI, testModel)
+	id := c.requestAt(t, "textDocument/documentHighlight", testURI, 12, 27)
+
+	var highlights []documentHighlight
+	decodeResponse(t, c.run(t), id, &highlights)
+
+	expected := []documentHighlight{
+		{Range: textRange{Start: position{11, 25}, End: position{11, 27}}, Kind: highlightWrite},
+		{Range: textRange{Start: position{11, 29}, End: position{11, 31}}, Kind: highlightRead},
+		{Range: textRange{Start: position{12, 26}, End: position{12, 28}}, Kind: highlightRead},
+	}
+
+	if len(highlights) != len(expected) {
+		t.Fatalf("expected %d highlights, got %+v", len(expected), highlights)
+	}
+
+	for i := range expected {
+		if highlights[i] != expected[i] {
+			t.Errorf("expected highlight %+v, got %+v", expected[i], highlights[i])
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Parallel()
+
+	c := &testClient{}
+	early := c.request(t, "textDocument/hover", map[string]any{})
+	c.request(t, "initialize", map[string]any{})
+	unknown := c.request(t, "workspace/unknown", map[string]any{})
+	c.notify(t, "exit", nil)
+
+	var output bytes.Buffer
+	err := New(&c.input, &output).Run()
+	if !errors.Is(err, ErrExitWithoutShutdown) {
+		t.Errorf("expected ErrExitWithoutShutdown, got %v", err)
+	}
+
+	codes := map[int]int{}
+	for _, msg := range readMessages(t, &output) {
+		if msg.ID != nil && msg.Error != nil {
+			codes[*msg.ID] = msg.Error.Code
+		}
+	}
+
+	if codes[early] != codeServerNotInitialized {
+		t.Errorf("expected request before initialize to fail with %d, got %d", codeServerNotInitialized, codes[early])
+	}
+
+	if codes[unknown] != codeMethodNotFound {
+		t.Errorf("expected unknown method to fail with %d, got %d", codeMethodNotFound, codes[unknown])
+	}
+}
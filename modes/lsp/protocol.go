@@ -0,0 +1,228 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// This file contains the parts of JSON-RPC and the Language Server Protocol we use.
+// See: https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/
+
+// JSON-RPC error codes
+const (
+	codeParseError           = -32700
+	codeInvalidRequest       = -32600
+	codeMethodNotFound       = -32601
+	codeInvalidParams        = -32602
+	codeServerNotInitialized = -32002
+)
+
+// request is a request or notification from the client. Notifications do not have an ID.
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+func (r request) isNotification() bool {
+	return r.ID == nil
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   responseError   `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// readMessage reads one message - a header with the content length followed by the JSON content.
+func readMessage(r *bufio.Reader) (content []byte, err error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil {
+		return nil, ErrMissingContentLength
+	}
+
+	content = make([]byte, length)
+	_, err = io.ReadFull(r, content)
+	return
+}
+
+// writeMessage writes the JSON of "msg" with a header
+func writeMessage(w io.Writer, msg any) (err error) {
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return
+}
+
+// LSP types
+
+type position struct {
+	Line      int `json:"line"`      // 0-based
+	Character int `json:"character"` // 0-based, in UTF-16 code units
+}
+
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string    `json:"uri"`
+	Range textRange `json:"range"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type textDocumentContentChangeEvent struct {
+	Text string `json:"text"` // we use full sync, so this is the whole document
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type didOpenTextDocumentParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeTextDocumentParams struct {
+	TextDocument   versionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []textDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type didCloseTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type serverInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+const textDocumentSyncFull = 1
+
+type serverCapabilities struct {
+	TextDocumentSync          int               `json:"textDocumentSync"`
+	DefinitionProvider        bool              `json:"definitionProvider"`
+	HoverProvider             bool              `json:"hoverProvider"`
+	CompletionProvider        completionOptions `json:"completionProvider"`
+	DocumentHighlightProvider bool              `json:"documentHighlightProvider"`
+}
+
+type completionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// diagnostic severities
+const (
+	severityError       = 1
+	severityWarning     = 2
+	severityInformation = 3
+)
+
+type diagnostic struct {
+	Range    textRange `json:"range"`
+	Severity int       `json:"severity"`
+	Source   string    `json:"source"`
+	Message  string    `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *textRange    `json:"range,omitempty"`
+}
+
+// completion item kinds
+const (
+	completionKindVariable = 6
+	completionKindClass    = 7
+	completionKindValue    = 12
+	completionKindKeyword  = 14
+)
+
+type completionItem struct {
+	Label    string    `json:"label"`
+	Kind     int       `json:"kind"`
+	Detail   string    `json:"detail,omitempty"`
+	SortText string    `json:"sortText,omitempty"`
+	TextEdit *textEdit `json:"textEdit,omitempty"`
+}
+
+type textEdit struct {
+	Range   textRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+// document highlight kinds
+const (
+	highlightText  = 1
+	highlightRead  = 2
+	highlightWrite = 3
+)
+
+type documentHighlight struct {
+	Range textRange `json:"range"`
+	Kind  int       `json:"kind"`
+}
@@ -2,9 +2,11 @@
 package shell
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"slices"
 	"sort"
 	"strconv"
@@ -94,6 +96,8 @@ func Initialize(settings *cli.Settings) (s *Shell, err error) {
 		activeFrameworks: map[string]bool{},
 	}
 
+	s.runOptions.Timeout = settings.Timeout
+
 	s.preamble()
 
 	for name := range settings.ActiveFrameworks {
@@ -294,6 +298,10 @@ func (s *Shell) cmdRun(initialGoal string) (err error) {
 	validate.Goal(s.currentModel, initialGoal, log)
 	fmt.Print(log)
 
+	// Let ctrl-c cancel the run instead of quitting the shell
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	for name, f := range s.settings.ActiveFrameworks {
 		if !s.activeFrameworks[name] {
 			continue
@@ -301,17 +309,12 @@ func (s *Shell) cmdRun(initialGoal string) (err error) {
 
 		fmt.Printf("== %s ==\n", f.Info().Name)
 
-		err = f.SetModel(s.currentModel)
-		if err != nil {
-			return err
-		}
-
 		options := s.currentModel.DefaultParams.Override(&s.runOptions)
 		options.InitialBuffers = runoptions.InitialBuffers{
 			"goal": strings.TrimSpace(initialGoal),
 		}
 
-		result, err := f.Run(options)
+		result, err := framework.RunModel(ctx, s.currentModel, options, f, nil)
 		if err != nil {
 			return err
 		}
@@ -321,6 +324,10 @@ func (s *Shell) cmdRun(initialGoal string) (err error) {
 		if result.Output[len(result.Output)-1] != '\n' {
 			fmt.Println()
 		}
+
+		for _, warning := range result.Warnings {
+			chalk.PrintWarningStr(warning)
+		}
 	}
 
 	return
@@ -0,0 +1,460 @@
+// Package sweep runs a model many times while varying module parameters and collects
+// measures from each run.
+package sweep
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/cli"
+	"github.com/asmaloney/gactar/util/keyvalue"
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/runoptions"
+	"github.com/asmaloney/gactar/util/validate"
+)
+
+var (
+	ErrNoParams     = errors.New("no parameters to sweep")
+	ErrInvalidModel = errors.New("model is not valid")
+	ErrInvalidRuns  = errors.New("number of runs must be at least 1")
+)
+
+// ErrInvalidParam is returned when a parameter specification cannot be parsed or applied.
+type ErrInvalidParam struct {
+	Spec    string
+	Message string
+}
+
+func (e ErrInvalidParam) Error() string {
+	return fmt.Sprintf("invalid parameter %q: %s", e.Spec, e.Message)
+}
+
+// Param is a module parameter and the values to use for it.
+type Param struct {
+	// Name of the parameter in the form "module.param" or "module.buffer.param"
+	// e.g. "memory.decay" or "memory.retrieval.spreading_activation"
+	Name string
+
+	Values []string
+}
+
+// ParseParam parses a parameter specification of the form "name=values" where values is either:
+//
+//	a range:  start:end:step (e.g. "0.3:0.7:0.1" - end is included)
+//	a list:   value,value,... (e.g. "0.05,0.1")
+func ParseParam(spec string) (param *Param, err error) {
+	name, values, found := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	values = strings.TrimSpace(values)
+
+	if !found || name == "" || values == "" {
+		return nil, &ErrInvalidParam{Spec: spec, Message: "expected name=values"}
+	}
+
+	if len(strings.Split(name, ".")) < 2 {
+		return nil, &ErrInvalidParam{Spec: spec, Message: "expected name of the form module.param"}
+	}
+
+	param = &Param{Name: name}
+
+	if strings.Contains(values, ":") {
+		param.Values, err = parseRange(values)
+		if err != nil {
+			return nil, &ErrInvalidParam{Spec: spec, Message: err.Error()}
+		}
+
+		return
+	}
+
+	for _, value := range strings.Split(values, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, &ErrInvalidParam{Spec: spec, Message: "empty value in list"}
+		}
+
+		param.Values = append(param.Values, value)
+	}
+
+	return
+}
+
+// parseRange expands "start:end:step" into a list of values.
+func parseRange(str string) (values []string, err error) {
+	parts := strings.Split(str, ":")
+	if len(parts) != 3 {
+		return nil, errors.New("expected range of the form start:end:step")
+	}
+
+	limits := make([]float64, 3)
+	for i, part := range parts {
+		limits[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+	}
+
+	start, end, step := limits[0], limits[1], limits[2]
+
+	if step <= 0 {
+		return nil, errors.New("step must be greater than 0")
+	}
+
+	if end < start {
+		return nil, errors.New("end of range must not be less than the start")
+	}
+
+	// allow for floating point error so the end is included
+	count := int(math.Floor((end-start)/step+1e-9)) + 1
+
+	for i := 0; i < count; i++ {
+		value := start + float64(i)*step
+
+		// remove floating point noise (e.g. 0.30000000000000004)
+		value = math.Round(value*1e9) / 1e9
+
+		values = append(values, numbers.Float64Str(value))
+	}
+
+	return
+}
+
+// SetParam sets the parameter "name" (see Param) on the model's module or buffer.
+func SetParam(model *actr.Model, name, value string) (err error) {
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return &ErrInvalidParam{Spec: name, Message: "expected name of the form module.param or module.buffer.param"}
+	}
+
+	module := model.LookupModule(parts[0])
+	if module == nil {
+		return &ErrInvalidParam{Spec: name, Message: fmt.Sprintf("module %q not found in model", parts[0])}
+	}
+
+	kv := &keyvalue.KeyValue{
+		Key:   parts[len(parts)-1],
+		Value: parseValue(value),
+	}
+
+	if len(parts) == 3 {
+		buffer := module.Buffers().Lookup(parts[1])
+		if buffer == nil {
+			return &ErrInvalidParam{Spec: name, Message: fmt.Sprintf("buffer %q not found in module %q", parts[1], parts[0])}
+		}
+
+		err = buffer.SetParam(kv)
+	} else {
+		err = module.SetParam(kv)
+	}
+
+	if err != nil {
+		return &ErrInvalidParam{Spec: name + "=" + value, Message: err.Error()}
+	}
+
+	return
+}
+
+func parseValue(value string) keyvalue.Value {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return keyvalue.Value{Number: &number}
+	}
+
+	return keyvalue.Value{ID: &value}
+}
+
+// Combinations returns the Cartesian product of the values of the params. Each combination
+// has one value per param in the same order as "params".
+func Combinations(params []*Param) (combinations [][]string) {
+	combinations = [][]string{{}}
+
+	for _, param := range params {
+		next := make([][]string, 0, len(combinations)*len(param.Values))
+
+		for _, combination := range combinations {
+			for _, value := range param.Values {
+				next = append(next, append(append([]string{}, combination...), value))
+			}
+		}
+
+		combinations = next
+	}
+
+	return
+}
+
+// Result is the result of one run of the model on one framework.
+type Result struct {
+	Values []string // values of the params in the same order as Results.ParamNames
+
+	Run       int    // 1-based run number for these values
+	Seed      uint32 // random seed used
+	Framework string
+
+	// Measures extracted from the trace
+	Time             float64  // simulation time of the last event
+	ProductionsFired int      // number of productions fired
+	Prints           []string // printed values (pyactr does not report these)
+
+	Error string // set if the framework failed to run
+}
+
+// Results holds all the runs from a sweep.
+type Results struct {
+	ModelName  string
+	ParamNames []string
+	Runs       []Result
+}
+
+// Options for running a sweep.
+type Options struct {
+	Params []*Param
+
+	Runs int    // number of runs for each combination of values
+	Seed uint32 // seed for the first run - it is incremented for each subsequent run
+
+	Goal string // initial goal (if not set in the model)
+}
+
+// Run generates the model from the amod file and runs it on all the active frameworks for
+// every combination of parameter values.
+func Run(settings *cli.Settings, amodFile string, options Options) (results *Results, err error) {
+	if len(options.Params) == 0 {
+		return nil, ErrNoParams
+	}
+
+	if options.Runs < 1 {
+		return nil, ErrInvalidRuns
+	}
+
+	model, runOptions, err := LoadModel(settings, amodFile, options.Goal)
+	if err != nil {
+		return
+	}
+
+	// check all the values before we start running
+	for _, param := range options.Params {
+		for _, value := range param.Values {
+			err = SetParam(model, param.Name, value)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	results = &Results{
+		ModelName: model.Name,
+	}
+
+	for _, param := range options.Params {
+		results.ParamNames = append(results.ParamNames, param.Name)
+	}
+
+	for _, values := range Combinations(options.Params) {
+		runs, err := RunModel(model, runOptions, settings.ActiveFrameworks, results.ParamNames, values, options.Runs, options.Seed)
+		if err != nil {
+			return nil, err
+		}
+
+		results.Runs = append(results.Runs, runs...)
+	}
+
+	return
+}
+
+// LoadModel generates the model from the amod file, validates the initial goal, and creates the
+// run options we need to collect measures.
+func LoadModel(settings *cli.Settings, amodFile, goal string) (model *actr.Model, runOptions *runoptions.Options, err error) {
+	model, log, err := amod.GenerateModelFromFile(amodFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w\n%s", err, log)
+	}
+
+	initialGoal := strings.TrimSpace(goal)
+
+	validate.Goal(model, initialGoal, log)
+	if log.HasError() {
+		return nil, nil, fmt.Errorf("%w\n%s", ErrInvalidModel, log)
+	}
+
+	traceEvents := true
+	runOptions = model.DefaultParams.Override(&runoptions.Options{
+		TraceEvents: &traceEvents,
+		Timeout:     settings.Timeout,
+	})
+
+	if initialGoal != "" {
+		runOptions.InitialBuffers = runoptions.InitialBuffers{
+			"goal": initialGoal,
+		}
+	}
+
+	_, err = cli.CreateTempDir(settings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return
+}
+
+// RunModel sets the params on the model to the values and runs it "runs" times on each of the
+// frameworks. The seed is incremented for each run so every set of values uses the same seeds.
+func RunModel(model *actr.Model, runOptions *runoptions.Options, frameworks framework.List, paramNames, values []string, runs int, seed uint32) (results []Result, err error) {
+	for i, name := range paramNames {
+		err = SetParam(model, name, values[i])
+		if err != nil {
+			return
+		}
+	}
+
+	for run := 1; run <= runs; run++ {
+		options := *runOptions
+
+		runSeed := seed + uint32(run-1)
+		options.RandomSeed = &runSeed
+
+		runMap := framework.RunModelOnFrameworks(context.Background(), model, &options, frameworks)
+
+		names := make([]string, 0, len(runMap))
+		for name := range runMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			result := newResult(runMap[name])
+
+			result.Values = values
+			result.Run = run
+			result.Seed = runSeed
+			result.Framework = name
+
+			results = append(results, result)
+		}
+	}
+
+	return
+}
+
+// newResult extracts the measures from a framework run.
+func newResult(frameworkRun framework.FrameworkRun) (run Result) {
+	if frameworkRun.Log != nil && frameworkRun.Log.HasError() {
+		run.Error = strings.TrimSpace(frameworkRun.Log.String())
+		return
+	}
+
+	if frameworkRun.Result == nil || frameworkRun.Result.Trace == nil {
+		run.Error = "no trace"
+		return
+	}
+
+	for _, event := range frameworkRun.Result.Trace.Events {
+		run.Time = max(run.Time, event.Time)
+
+		switch event.Type {
+		case framework.TraceProductionFired:
+			run.ProductionsFired++
+
+		case framework.TracePrint:
+			run.Prints = append(run.Prints, strings.TrimSpace(event.Text))
+		}
+	}
+
+	return
+}
+
+// WriteCSV writes the results in CSV format with a header. Printed values are separated by "; ".
+func (r Results) WriteCSV(w io.Writer) (err error) {
+	writer := csv.NewWriter(w)
+
+	header := append(append([]string{}, r.ParamNames...), "run", "seed", "framework", "time", "productions_fired", "prints", "error")
+
+	err = writer.Write(header)
+	if err != nil {
+		return
+	}
+
+	for _, run := range r.Runs {
+		record := append(append([]string{}, run.Values...),
+			strconv.Itoa(run.Run),
+			strconv.FormatUint(uint64(run.Seed), 10),
+			run.Framework,
+			numbers.Float64Str(run.Time),
+			strconv.Itoa(run.ProductionsFired),
+			strings.Join(run.Prints, "; "),
+			run.Error,
+		)
+
+		err = writer.Write(record)
+		if err != nil {
+			return
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// jsonRun is the JSON form of a Result which uses the parameter names.
+type jsonRun struct {
+	Params map[string]string `json:"params"`
+
+	Run       int    `json:"run"`
+	Seed      uint32 `json:"seed"`
+	Framework string `json:"framework"`
+
+	Time             float64  `json:"time"`
+	ProductionsFired int      `json:"productionsFired"`
+	Prints           []string `json:"prints"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// WriteJSON writes the results in JSON format.
+func (r Results) WriteJSON(w io.Writer) error {
+	output := struct {
+		Model string    `json:"model"`
+		Runs  []jsonRun `json:"runs"`
+	}{
+		Model: r.ModelName,
+		Runs:  make([]jsonRun, 0, len(r.Runs)),
+	}
+
+	for _, run := range r.Runs {
+		params := make(map[string]string, len(r.ParamNames))
+		for i, name := range r.ParamNames {
+			params[name] = run.Values[i]
+		}
+
+		prints := run.Prints
+		if prints == nil {
+			prints = []string{}
+		}
+
+		output.Runs = append(output.Runs, jsonRun{
+			Params:           params,
+			Run:              run.Run,
+			Seed:             run.Seed,
+			Framework:        run.Framework,
+			Time:             run.Time,
+			ProductionsFired: run.ProductionsFired,
+			Prints:           prints,
+			Error:            run.Error,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(output)
+}
@@ -0,0 +1,247 @@
+package sweep
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/framework/native"
+)
+
+func TestParseParam(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec   string
+		name   string
+		values []string
+	}{
+		{"memory.decay=0.3:0.7:0.1", "memory.decay", []string{"0.3", "0.4", "0.5", "0.6", "0.7"}},
+		{"procedural.default_action_time=0.05,0.1", "procedural.default_action_time", []string{"0.05", "0.1"}},
+		{" memory.retrieval.spreading_activation = 1 ", "memory.retrieval.spreading_activation", []string{"1"}},
+		{"memory.latency_factor=0:1:0.5", "memory.latency_factor", []string{"0", "0.5", "1"}},
+	}
+
+	for _, test := range tests {
+		param, err := ParseParam(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.spec, err)
+			continue
+		}
+
+		if param.Name != test.name {
+			t.Errorf("%q: expected name %q, got %q", test.spec, test.name, param.Name)
+		}
+
+		if !slices.Equal(param.Values, test.values) {
+			t.Errorf("%q: expected values %v, got %v", test.spec, test.values, param.Values)
+		}
+	}
+}
+
+func TestParseParamErrors(t *testing.T) {
+	t.Parallel()
+
+	specs := []string{
+		"memory.decay",
+		"decay=0.5",
+		"memory.decay=",
+		"memory.decay=0.5,,0.6",
+		"memory.decay=0.3:0.7",
+		"memory.decay=0.3:0.7:0",
+		"memory.decay=0.7:0.3:0.1",
+		"memory.decay=a:b:c",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseParam(spec)
+		if err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	t.Parallel()
+
+	params := []*Param{
+		{Name: "a", Values: []string{"1", "2"}},
+		{Name: "b", Values: []string{"x", "y", "z"}},
+	}
+
+	combinations := Combinations(params)
+
+	expected := [][]string{
+		{"1", "x"}, {"1", "y"}, {"1", "z"},
+		{"2", "x"}, {"2", "y"}, {"2", "z"},
+	}
+
+	if !slices.EqualFunc(combinations, expected, slices.Equal[[]string]) {
+		t.Errorf("expected %v, got %v", expected, combinations)
+	}
+}
+
+const countModel = `~~ model ~~
+name: count
+~~ config ~~
+chunks {
+	[count: first second]
+	[countFrom: start end status]
+}
+~~ init ~~
+memory {
+	[count: 2 3]
+	[count: 3 4]
+}
+goal [countFrom: 2 4 'starting']
+~~ productions ~~
+start {
+	match { goal [countFrom: ?start ?end 'starting'] }
+	do {
+		recall [count: ?start *]
+		set goal to [countFrom: ?start ?end 'counting']
+	}
+}
+increment {
+	match {
+		goal [countFrom: ?x !?x 'counting']
+		retrieval [count: ?x ?next]
+	}
+	do {
+		print ?x
+		recall [count: ?next *]
+		set goal.start to ?next
+	}
+}
+done {
+	match { goal [countFrom: ?x ?x 'counting'] }
+	do {
+		print ?x
+		stop
+	}
+}`
+
+func TestSetParam(t *testing.T) {
+	t.Parallel()
+
+	model, log, err := amod.GenerateModel(countModel)
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	err = SetParam(model, "memory.latency_factor", "0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if model.Memory.LatencyFactor == nil || *model.Memory.LatencyFactor != 0.5 {
+		t.Errorf("latency_factor was not set")
+	}
+
+	err = SetParam(model, "memory.retrieval.spreading_activation", "0.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if model.Memory.Buffers().Lookup("retrieval").SpreadingActivation() != 0.25 {
+		t.Errorf("spreading_activation was not set")
+	}
+
+	for _, name := range []string{"foo.bar", "memory.foo", "memory.foo.spreading_activation", "memory"} {
+		err = SetParam(model, name, "1")
+		if err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
+
+func TestRunModel(t *testing.T) {
+	t.Parallel()
+
+	model, log, err := amod.GenerateModel(countModel)
+	if err != nil {
+		t.Fatal(log)
+	}
+
+	fw, err := native.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frameworks := framework.List{"native": fw}
+
+	traceEvents := true
+	options := model.DefaultParams
+	options.TraceEvents = &traceEvents
+
+	params := []*Param{{Name: "procedural.default_action_time", Values: []string{"0.05", "0.1"}}}
+
+	results := &Results{ModelName: model.Name, ParamNames: []string{params[0].Name}}
+
+	for _, values := range Combinations(params) {
+		runs, err := RunModel(model, &options, frameworks, results.ParamNames, values, 2, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results.Runs = append(results.Runs, runs...)
+	}
+
+	if len(results.Runs) != 4 {
+		t.Fatalf("expected 4 runs, got %d", len(results.Runs))
+	}
+
+	for _, run := range results.Runs {
+		if run.Error != "" {
+			t.Fatalf("unexpected error: %s", run.Error)
+		}
+
+		if !slices.Equal(run.Prints, []string{"2", "3", "4"}) {
+			t.Errorf("incorrect prints: %v", run.Prints)
+		}
+
+		if run.ProductionsFired != 4 {
+			t.Errorf("expected 4 productions fired, got %d", run.ProductionsFired)
+		}
+	}
+
+	if results.Runs[0].Seed != 10 || results.Runs[1].Seed != 11 || results.Runs[2].Seed != 10 {
+		t.Errorf("incorrect seeds")
+	}
+
+	if results.Runs[2].Time <= results.Runs[0].Time {
+		t.Errorf("expected a longer default_action_time to take longer: %v vs. %v", results.Runs[0].Time, results.Runs[2].Time)
+	}
+
+	var csv bytes.Buffer
+	err = results.WriteCSV(&csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines of CSV, got:\n%s", csv.String())
+	}
+
+	if lines[0] != "procedural.default_action_time,run,seed,framework,time,productions_fired,prints,error" {
+		t.Errorf("incorrect CSV header: %s", lines[0])
+	}
+
+	if !strings.HasPrefix(lines[1], "0.05,1,10,native,") || !strings.HasSuffix(lines[1], ",4,2; 3; 4,") {
+		t.Errorf("incorrect CSV line: %s", lines[1])
+	}
+
+	var json bytes.Buffer
+	err = results.WriteJSON(&json)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(json.String(), `"procedural.default_action_time": "0.1"`) {
+		t.Errorf("incorrect JSON:\n%s", json.String())
+	}
+}
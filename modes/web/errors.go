@@ -3,25 +3,78 @@ package web
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 var (
-	ErrEmptyRequestBody = errors.New("empty request body")
-	ErrNoModel          = errors.New("no model loaded")
+	ErrEmptyRequestBody      = errors.New("empty request body")
+	ErrInvalidMaxJobs        = errors.New("max-jobs must be greater than zero")
+	ErrInvalidMaxQueued      = errors.New("max-queued must not be negative")
+	ErrInvalidTimeout        = errors.New("timeout must be greater than zero")
+	ErrStreamingNotSupported = errors.New("streaming is not supported by this connection")
 )
 
+type ErrInvalidRunID struct {
+	ID string
+}
+
+func (e ErrInvalidRunID) Error() string {
+	return fmt.Sprintf("invalid run id: %q", e.ID)
+}
+
+type ErrInvalidRequest struct {
+	Problems []string
+}
+
+func (e ErrInvalidRequest) Error() string {
+	return fmt.Sprintf("invalid request: %s", strings.Join(e.Problems, "; "))
+}
+
+type ErrInvalidJobID struct {
+	ID string
+}
+
+func (e ErrInvalidJobID) Error() string {
+	return fmt.Sprintf("invalid job id: %q", e.ID)
+}
+
 type ErrInvalidModelID struct {
-	ID int
+	ID string
 }
 
 func (e ErrInvalidModelID) Error() string {
-	return fmt.Sprintf("invalid model id: %d", e.ID)
+	return fmt.Sprintf("invalid model id: %q", e.ID)
 }
 
 type ErrInvalidSessionID struct {
-	ID int
+	ID string
 }
 
 func (e ErrInvalidSessionID) Error() string {
-	return fmt.Sprintf("invalid session id: %d", e.ID)
+	return fmt.Sprintf("invalid session id: %q", e.ID)
+}
+
+type ErrRunIDInUse struct {
+	ID string
+}
+
+func (e ErrRunIDInUse) Error() string {
+	return fmt.Sprintf("run id is already in use: %q", e.ID)
+}
+
+type ErrMethodNotAllowed struct {
+	Method string
+}
+
+func (e ErrMethodNotAllowed) Error() string {
+	return fmt.Sprintf("method not allowed: %s", e.Method)
+}
+
+type ErrQueueFull struct {
+	RetryAfter time.Duration
+}
+
+func (e ErrQueueFull) Error() string {
+	return fmt.Sprintf("the server is busy (the job queue is full) - try again in %d seconds", int(e.RetryAfter.Seconds()))
 }
@@ -4,16 +4,30 @@ import "net/http"
 
 func initExamples(w *Web) {
 	exampleHandler := assetHandler(w.examples, "/api/examples/", "")
-	http.HandleFunc("/api/examples/", exampleHandler.ServeHTTP)
-	http.HandleFunc("/api/examples/list", w.listExamples)
+
+	w.handle(endpoint{
+		path:                "/api/examples/{name}",
+		methods:             []string{http.MethodGet},
+		summary:             "Get the amod source of an example",
+		responseType:        "text/plain",
+		responseDescription: "The amod source",
+		handler:             exampleHandler.ServeHTTP,
+	})
+	w.handle(endpoint{
+		path:     "/api/examples/list",
+		methods:  []string{http.MethodGet},
+		summary:  "List the examples included in the build",
+		response: examplesListResponse{},
+		handler:  w.listExamples,
+	})
+}
+
+type examplesListResponse struct {
+	List []string `json:"exampleList"`
 }
 
 // listExamples simply returns a list of the examples included in the build.
 func (w *Web) listExamples(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		List []string `json:"exampleList"`
-	}
-
 	entries, err := w.examples.ReadDir(".")
 	if err != nil {
 		encodeErrorResponse(rw, err)
@@ -26,7 +40,7 @@ func (w *Web) listExamples(rw http.ResponseWriter, req *http.Request) {
 		list = append(list, entry.Name())
 	}
 
-	encodeResponse(rw, response{
+	encodeResponse(rw, examplesListResponse{
 		List: list,
 	})
 }
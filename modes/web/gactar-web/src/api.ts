@@ -6,6 +6,9 @@ function init(port: number) {
   gactarHTTP = axios.create({
     headers: { 'Content-Type': 'application/json' },
     baseURL: `http://localhost:${port}`,
+    // Errors are returned with an HTTP error status and a list of issues in the body,
+    // so don't throw - the callers check the issues.
+    validateStatus: () => true,
   })
 }
 
@@ -66,8 +69,14 @@ export interface RunOptions {
   // Output detailed info about activations
   traceActivations: boolean
 
+  // Include a framework-neutral trace of events in the results
+  traceEvents?: boolean
+
   // Seed to use for generating pseudo-random numbers
   randomSeed?: number
+
+  // Cancel the run if it takes longer than this many seconds
+  timeout?: number
 }
 
 export interface RunParams {
@@ -77,6 +86,9 @@ export interface RunParams {
   // The starting goal.
   goal: string
 
+  // (optional) ID used to cancel the run using /run/cancel.
+  runID?: string
+
   // options!
   options: RunOptions
 }
@@ -101,6 +113,22 @@ export interface Issue {
 
 export type IssueList = Issue[]
 
+// One event which occurred while running a model.
+// Type is one of 'production-fired', 'buffer-set', 'retrieval-started',
+// 'retrieval-succeeded', 'retrieval-failed', 'print', or 'stop'.
+export interface TraceEvent {
+  time: number
+  type: string
+  production?: string
+  buffer?: string
+  chunk?: string
+  text?: string
+}
+
+export interface Trace {
+  events: TraceEvent[]
+}
+
 export interface FrameworkResult {
   // Name of the model (from the amod text).
   modelName: string
@@ -108,7 +136,8 @@ export interface FrameworkResult {
   // Any issues specific to a framework.
   issues?: IssueList
 
-  // Intermediate code file (full path).
+  // Intermediate code file (full path). For runs, it is removed when the run
+  // finishes, so use "code" for its contents.
   filePath?: string
 
   // Code which was run.
@@ -116,6 +145,9 @@ export interface FrameworkResult {
 
   // Output of run (stdout + stderr).
   output?: string
+
+  // Trace of events (if the traceEvents option was set).
+  trace?: Trace
 }
 
 export type FrameworkResultMap = { [key: string]: FrameworkResult }
@@ -130,6 +162,31 @@ async function run(params: RunParams): Promise<RunResult> {
   return response.data
 }
 
+// jobs
+export interface Job {
+  // The ID of the job to use with jobGet.
+  jobID: string
+
+  // One of 'queued', 'running', or 'done'.
+  status: string
+
+  // When the job was submitted (RFC 3339).
+  submitted: string
+
+  // The result of the run (once the status is 'done').
+  result?: RunResult
+}
+
+async function jobSubmit(params: RunParams): Promise<Job> {
+  const response = await gactarHTTP.post<Job>('/api/jobs', params)
+  return response.data
+}
+
+async function jobGet(jobID: string): Promise<Job> {
+  const response = await gactarHTTP.get<Job>('/api/jobs/' + jobID)
+  return response.data
+}
+
 // examples
 // List of example names which are built into the webserver.
 export type ExampleList = string[]
@@ -152,15 +209,15 @@ async function getExample(name: string): Promise<string> {
 
 // sessions
 export interface Session {
-  sessionID: number
+  sessionID: string
 }
 
 export interface SessionRunParams {
   // The id of the session.
-  sessionID: number
+  sessionID: string
 
   // The ID of the model to run.
-  modelID: number
+  modelID: string
 
   // The initial contents of the buffers.
   buffers: string
@@ -174,10 +231,10 @@ export interface SessionRunParams {
 
 export interface SessionRunResult extends FrameworkResult {
   // The id of the session.
-  sessionID: number
+  sessionID: string
 
   // The ID of the model which was run.
-  modelID: number
+  modelID: string
 }
 
 export type SessionResultMap = { [key: string]: SessionRunResult }
@@ -196,6 +253,92 @@ async function sessionEnd(session: Session): Promise<void> {
   return
 }
 
+export interface SessionModelSummary {
+  // The ID of the model.
+  modelID: string
+
+  // The name of the model (comes from the amod code).
+  modelName: string
+}
+
+export interface SessionSummary {
+  // The id of the session.
+  sessionID: string
+
+  // When the session was created (RFC 3339).
+  created: string
+
+  // The models loaded in the session.
+  models: SessionModelSummary[]
+
+  // The number of runs in the session's history.
+  runCount: number
+}
+
+interface SessionListResponse {
+  sessions: SessionSummary[]
+}
+
+export interface SessionModel {
+  // The ID of the model.
+  modelID: string
+
+  // The name of the model (comes from the amod code).
+  modelName: string
+
+  // The amod code which was loaded.
+  amod: string
+
+  // When the model was loaded (RFC 3339).
+  loaded: string
+}
+
+export interface SessionRun {
+  // The ID of the model which was run.
+  modelID: string
+
+  // When the run finished (RFC 3339).
+  time: string
+
+  // The initial contents of the buffers.
+  buffers?: { [key: string]: string }
+
+  // The options used for the run.
+  options: RunOptions
+
+  // The results of the run.
+  results: SessionResultMap
+}
+
+export interface SessionHistory {
+  // The id of the session.
+  sessionID: string
+
+  // When the session was created (RFC 3339).
+  created: string
+
+  // The models loaded in the session.
+  models: SessionModel[]
+
+  // The runs, oldest first.
+  runs: SessionRun[]
+}
+
+async function sessionList(): Promise<SessionSummary[]> {
+  const response = await gactarHTTP.get<SessionListResponse>(
+    '/api/session/list'
+  )
+  return response.data.sessions
+}
+
+async function sessionHistory(session: Session): Promise<SessionHistory> {
+  const response = await gactarHTTP.put<SessionHistory>(
+    '/api/session/history',
+    session
+  )
+  return response.data
+}
+
 async function sessionRun(
   params: SessionRunParams
 ): Promise<SessionRunResults> {
@@ -212,18 +355,18 @@ export interface ModelParams {
   amod: string
 
   // The id of the session to load this model in.
-  sessionID: number
+  sessionID: string
 }
 
 export interface ModelLoadResult {
   // The ID of this model to use in other calls to the API.
-  modelID: number
+  modelID: string
 
   // The name of the model (comes from the amod code).
   modelName: string
 
   // The id of the session.
-  sessionID: number
+  sessionID: string
 }
 
 async function modelLoad(params: ModelParams): Promise<ModelLoadResult> {
@@ -234,15 +377,66 @@ async function modelLoad(params: ModelParams): Promise<ModelLoadResult> {
   return response.data
 }
 
+export interface ModelValidateParams {
+  // The amod code to validate.
+  amod: string
+
+  // (optional) List of frameworks to validate for (if empty, all of them).
+  frameworks?: string[]
+}
+
+export interface ModelValidateResult {
+  // Issues with the amod code.
+  issues: IssueList
+
+  // Issues found by each framework (only if the amod code has no errors).
+  results: { [key: string]: IssueList }
+}
+
+async function modelValidate(
+  params: ModelValidateParams
+): Promise<ModelValidateResult> {
+  const response = await gactarHTTP.post<ModelValidateResult>(
+    '/api/model/validate',
+    params
+  )
+  return response.data
+}
+
+export interface ModelGenerateParams {
+  // The amod code to generate from.
+  amod: string
+
+  // The starting goal.
+  goal?: string
+
+  // options!
+  options?: RunOptions
+}
+
+async function modelGenerate(params: ModelGenerateParams): Promise<RunResult> {
+  const response = await gactarHTTP.post<RunResult>(
+    '/api/model/generate',
+    params
+  )
+  return response.data
+}
+
 export default {
   getExample,
   getExampleList,
   getFrameworks,
   getVersion,
   init,
+  jobGet,
+  jobSubmit,
+  modelGenerate,
   modelLoad,
+  modelValidate,
   run,
   sessionBegin,
   sessionEnd,
+  sessionHistory,
+  sessionList,
   sessionRun,
 }
@@ -33,24 +33,36 @@ CodeMirror.defineMode('amod', function () {
     },
 
     init: {
+      and: true,
+      associations: true,
       nil: true,
+      screen: true,
       similar: true,
+      with: true,
     },
 
     productions: {
       and: true,
       any: true,
+      attend: true,
+      blend: true,
       buffer_state: true,
       clear: true,
       description: true,
       do: true,
+      find_location: true,
       match: true,
       module_state: true,
       nil: true,
       '!nil': true,
+      press_key: true,
       print: true,
+      punch: true,
       recall: true,
+      reinforce: true,
+      remember: true,
       set: true,
+      start_timer: true,
       stop: true,
       to: true,
       when: true,
@@ -59,12 +71,18 @@ CodeMirror.defineMode('amod', function () {
   }
 
   const builtInGlobals = {
+    blending: true,
     extra_buffers: true,
     goal: true,
     imaginal: true,
+    manual: true,
     memory: true,
+    motor: true,
     procedural: true,
     retrieval: true,
+    temporal: true,
+    visual: true,
+    visual_location: true,
   }
 
   function tokenString(stream: StringStream, state: State): string {
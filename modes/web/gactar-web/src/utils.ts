@@ -9,6 +9,7 @@ function commentString(language: string, text: string): string {
       break
 
     case 'python':
+    case 'text':
       comment = '#'
       break
 
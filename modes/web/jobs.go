@@ -0,0 +1,349 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+const (
+	// queueRetryAfter is the hint we give clients when the queue is full
+	queueRetryAfter = 10 * time.Second
+
+	// jobRetention is how long we keep the results of a submitted job after it finishes
+	jobRetention = 30 * time.Minute
+)
+
+// QueueOptions limits the number of framework processes the server runs at once.
+type QueueOptions struct {
+	MaxJobs   int // maximum number of framework processes to run at once
+	MaxQueued int // maximum number of runs waiting to start (more are rejected)
+}
+
+// jobQueue limits the number of framework processes running at once. Each run reserves one
+// process per framework before it starts. Runs start in the order they were queued.
+type jobQueue struct {
+	mutex sync.Mutex
+
+	maxProcesses int
+	running      int // number of processes reserved by runs in progress
+
+	maxQueued int
+	waiting   []*ticket // runs waiting to start (oldest first)
+
+	jobs map[string]*job // submitted jobs by ID (kept for jobRetention after they finish)
+}
+
+// ticket is a run's place in the queue.
+type ticket struct {
+	queue     *jobQueue
+	processes int
+	ready     chan struct{} // closed when the processes have been reserved
+}
+
+type jobStatus string
+
+const (
+	jobQueued  jobStatus = "queued"
+	jobRunning jobStatus = "running"
+	jobDone    jobStatus = "done"
+)
+
+// job is a run submitted using /api/jobs. Its status and results are polled using /api/jobs/{id}.
+type job struct {
+	mutex sync.Mutex
+
+	id        string
+	status    jobStatus
+	submitted time.Time
+	result    *runResult // set when the status is "done"
+}
+
+// jobResponse is the response to /api/jobs and /api/jobs/{id}
+type jobResponse struct {
+	ID        string     `json:"jobID"`
+	Status    jobStatus  `json:"status" enum:"queued,running,done"`
+	Submitted time.Time  `json:"submitted"`
+	Result    *runResult `json:"result,omitempty"`
+}
+
+func newJobQueue(options QueueOptions) *jobQueue {
+	return &jobQueue{
+		maxProcesses: options.MaxJobs,
+		maxQueued:    options.MaxQueued,
+		waiting:      []*ticket{},
+		jobs:         map[string]*job{},
+	}
+}
+
+// enqueue adds a run which needs "processes" framework processes to the queue. It returns
+// ErrQueueFull if too many runs are already waiting.
+func (q *jobQueue) enqueue(processes int) (t *ticket, err error) {
+	// a run may use all the processes, but no more
+	processes = max(1, min(processes, q.maxProcesses))
+
+	t = &ticket{
+		queue:     q,
+		processes: processes,
+		ready:     make(chan struct{}),
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	// start it now if nothing is waiting and there are enough processes available
+	if len(q.waiting) == 0 && q.running+processes <= q.maxProcesses {
+		q.running += processes
+		close(t.ready)
+		return
+	}
+
+	if len(q.waiting) >= q.maxQueued {
+		return nil, &ErrQueueFull{RetryAfter: queueRetryAfter}
+	}
+
+	q.waiting = append(q.waiting, t)
+
+	return
+}
+
+// dispatch reserves processes for the waiting runs in order until we run out.
+// The caller must hold the mutex.
+func (q *jobQueue) dispatch() {
+	for len(q.waiting) > 0 {
+		next := q.waiting[0]
+		if q.running+next.processes > q.maxProcesses {
+			return
+		}
+
+		q.running += next.processes
+		q.waiting = q.waiting[1:]
+
+		close(next.ready)
+	}
+}
+
+// wait waits until the ticket's processes have been reserved. If "ctx" is done first, the ticket
+// is removed from the queue and the context's error is returned.
+func (t *ticket) wait(ctx context.Context) error {
+	select {
+	case <-t.ready:
+		return nil
+
+	case <-ctx.Done():
+		q := t.queue
+
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+
+		select {
+		case <-t.ready:
+			// we were given the processes while cancelling, so give them back
+			q.running -= t.processes
+			q.dispatch()
+
+		default:
+			for i, waiting := range q.waiting {
+				if waiting == t {
+					q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+					break
+				}
+			}
+		}
+
+		return ctx.Err()
+	}
+}
+
+// release gives back the ticket's processes once the run is finished.
+func (t *ticket) release() {
+	q := t.queue
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.running -= t.processes
+	q.dispatch()
+}
+
+func (q *jobQueue) addJob(j *job) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.jobs[j.id] = j
+}
+
+func (q *jobQueue) lookupJob(id string) *job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.jobs[id]
+}
+
+func (q *jobQueue) removeJob(id string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.jobs, id)
+}
+
+func (j *job) setStatus(status jobStatus) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.status = status
+}
+
+func (j *job) finish(result runResult) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.status = jobDone
+	j.result = &result
+}
+
+func (j *job) response() jobResponse {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return jobResponse{
+		ID:        j.id,
+		Status:    j.status,
+		Submitted: j.submitted,
+		Result:    j.result,
+	}
+}
+
+func initJobs(w *Web) {
+	w.handle(endpoint{
+		path:     "/api/jobs",
+		methods:  []string{http.MethodPost, http.MethodPut},
+		summary:  "Queue a run of an amod file",
+		request:  runRequest{},
+		response: jobResponse{},
+		handler:  w.submitJobHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/jobs/{jobID}",
+		methods:  []string{http.MethodGet},
+		summary:  "Get the status of a job (and its results once it is done)",
+		response: jobResponse{},
+		handler:  w.getJobHandler,
+	})
+}
+
+// enqueueRun adds a run on these frameworks to the queue.
+func (w *Web) enqueueRun(frameworkNames []string) (*ticket, error) {
+	processes := 0
+	for _, name := range frameworkNames {
+		if _, ok := w.settings.ActiveFrameworks[name]; ok {
+			processes++
+		}
+	}
+
+	return w.jobs.enqueue(processes)
+}
+
+// waitToRun queues a run on these frameworks and waits for its turn. Call "release" when the
+// run is finished.
+func (w *Web) waitToRun(ctx context.Context, frameworkNames []string) (release func(), err error) {
+	t, err := w.enqueueRun(frameworkNames)
+	if err != nil {
+		return
+	}
+
+	err = t.wait(ctx)
+	if err != nil {
+		return nil, framework.ErrRunCancelled
+	}
+
+	return t.release, nil
+}
+
+// submitJobHandler queues a run and returns immediately with the job's ID. The job's status and
+// results are polled using getJobHandler. It takes the same request as runModelHandler.
+func (w *Web) submitJobHandler(rw http.ResponseWriter, req *http.Request) {
+	var data runRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	model, options, log, err := w.prepareRun(data)
+	if err != nil {
+		if log.HasError() {
+			encodeIssueResponse(rw, log)
+		} else {
+			encodeErrorResponse(rw, err)
+		}
+		return
+	}
+
+	// The job outlives the request, so it can only be cancelled using its runID.
+	ctx, finish, err := w.runs.start(context.Background(), data.RunID)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	t, err := w.enqueueRun(options.Frameworks)
+	if err != nil {
+		finish()
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	j := &job{
+		id:        newID(),
+		status:    jobQueued,
+		submitted: time.Now().UTC(),
+	}
+
+	w.jobs.addJob(j)
+
+	go func() {
+		defer finish()
+
+		result := runResult{Issues: log.AllIssues()}
+
+		err := t.wait(ctx)
+		if err != nil {
+			result.Issues = append(result.Issues, issuesFromError(framework.ErrRunCancelled)...)
+		} else {
+			j.setStatus(jobRunning)
+
+			result.Results, err = w.runModel(ctx, model, options)
+			t.release()
+
+			if err != nil {
+				result.Issues = append(result.Issues, issuesFromError(err)...)
+			}
+		}
+
+		j.finish(result)
+
+		time.AfterFunc(jobRetention, func() {
+			w.jobs.removeJob(j.id)
+		})
+	}()
+
+	encodeResponse(rw, j.response())
+}
+
+// getJobHandler returns the status of a job (and its results once it is done).
+func (w *Web) getJobHandler(rw http.ResponseWriter, req *http.Request) {
+	id := strings.TrimPrefix(req.URL.Path, "/api/jobs/")
+
+	j := w.jobs.lookupJob(id)
+	if j == nil {
+		encodeErrorResponse(rw, &ErrInvalidJobID{ID: id})
+		return
+	}
+
+	encodeResponse(rw, j.response())
+}
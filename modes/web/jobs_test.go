@@ -0,0 +1,190 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isReady checks if the ticket's processes have been reserved without waiting
+func isReady(t *ticket) bool {
+	select {
+	case <-t.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	queue := newJobQueue(QueueOptions{MaxJobs: 2, MaxQueued: 1})
+
+	first, err := queue.enqueue(4) // limited to 2
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isReady(first) {
+		t.Fatal("expected first run to start")
+	}
+
+	second, err := queue.enqueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isReady(second) {
+		t.Fatal("expected second run to wait")
+	}
+
+	_, err = queue.enqueue(1)
+
+	var queueFull *ErrQueueFull
+	if !errors.As(err, &queueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	first.release()
+
+	if !isReady(second) {
+		t.Fatal("expected second run to start when the first finished")
+	}
+
+	// a cancelled run leaves the queue
+	third, err := queue.enqueue(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if third.wait(ctx) == nil {
+		t.Fatal("expected wait to be cancelled")
+	}
+
+	if len(queue.waiting) != 0 {
+		t.Errorf("expected cancelled run to be removed from the queue")
+	}
+
+	second.release()
+
+	if queue.running != 0 {
+		t.Errorf("expected no processes to be running, got %d", queue.running)
+	}
+}
+
+func TestRunModelHandlerQueueFull(t *testing.T) {
+	busy := &Web{
+		settings: webTest.settings,
+		runs:     newRunList(),
+		jobs:     newJobQueue(QueueOptions{MaxJobs: 1, MaxQueued: 0}),
+	}
+
+	running, err := busy.jobs.enqueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer running.release()
+
+	body := `{"amod": "~~ model ~~\nname: Test\n~~ config ~~\n~~ init ~~\n~~ productions ~~", "options": {"frameworks": ["native"]}}`
+
+	request, err := http.NewRequest("POST", "/run", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(busy.runModelHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	if status := responseRecorder.Code; status != http.StatusTooManyRequests {
+		t.Errorf("handler returned incorrect status code: expected '%v' got '%v'",
+			http.StatusTooManyRequests, status)
+	}
+
+	if retry := responseRecorder.Header().Get("Retry-After"); retry != "10" {
+		t.Errorf("expected Retry-After of 10 seconds, got '%s'", retry)
+	}
+}
+
+func TestJobHandlers(t *testing.T) {
+	body, err := json.Marshal(map[string]any{
+		"amod":    concurrentModel(7),
+		"options": map[string]any{"frameworks": []string{"native"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := http.NewRequest("POST", "/api/jobs", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(webTest.submitJobHandler).ServeHTTP(responseRecorder, request)
+
+	var submitted jobResponse
+	err = json.Unmarshal(responseRecorder.Body.Bytes(), &submitted)
+	if err != nil {
+		t.Fatalf("unexpected response: %s", responseRecorder.Body.String())
+	}
+
+	if submitted.ID == "" {
+		t.Fatalf("expected a job ID: %s", responseRecorder.Body.String())
+	}
+
+	// poll until it's done
+	var polled jobResponse
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		request, err = http.NewRequest("GET", "/api/jobs/"+submitted.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		responseRecorder = httptest.NewRecorder()
+		http.HandlerFunc(webTest.getJobHandler).ServeHTTP(responseRecorder, request)
+
+		err = json.Unmarshal(responseRecorder.Body.Bytes(), &polled)
+		if err != nil {
+			t.Fatalf("unexpected response: %s", responseRecorder.Body.String())
+		}
+
+		if polled.Status == jobDone {
+			break
+		}
+	}
+
+	if polled.Status != jobDone || polled.Result == nil {
+		t.Fatalf("job did not finish: %+v", polled)
+	}
+
+	err = checkConcurrentResult(7, polled.Result.Results["native"])
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetJobHandlerInvalidID(t *testing.T) {
+	request, err := http.NewRequest("GET", "/api/jobs/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(webTest.getJobHandler).ServeHTTP(responseRecorder, request)
+
+	expected := `{"issues":[{"level":"error","text":"invalid job id: \"foo\"","location":null}]}`
+	responseStr := strings.TrimSpace(responseRecorder.Body.String())
+	if responseStr != expected {
+		t.Errorf("handler returned unexpected body: expected '%v' got '%v'", expected, responseStr)
+	}
+}
@@ -2,45 +2,71 @@ package web
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/asmaloney/gactar/actr"
 	"github.com/asmaloney/gactar/amod"
 	"github.com/asmaloney/gactar/framework"
 
+	"github.com/asmaloney/gactar/util/issues"
 	"github.com/asmaloney/gactar/util/runoptions"
 )
 
-var currentModelID = 1
-
 type Model struct {
-	id        int
+	id        string
 	actrModel *actr.Model
 }
 
 // runOptionsJSON is the JSON version of runoptions.Options
 type runOptionsJSON struct {
 	Frameworks       runoptions.FrameworkNameList `json:"frameworks,omitempty"` // list of frameworks to run on (if empty, "all")
-	LogLevel         *string                      `json:"logLevel,omitempty"`
+	LogLevel         *string                      `json:"logLevel,omitempty" enum:"min,info,detail"`
 	TraceActivations *bool                        `json:"traceActivations,omitempty"`
+	TraceEvents      *bool                        `json:"traceEvents,omitempty"`
 	RandomSeed       *uint32                      `json:"randomSeed,omitempty"`
+	Timeout          *float64                     `json:"timeout,omitempty"` // cancel the run after this many seconds
 }
 
 func initModels(w *Web) {
-	http.HandleFunc("/api/model/load", w.loadModelHandler)
+	w.handle(endpoint{
+		path:     "/api/model/load",
+		methods:  []string{http.MethodPut, http.MethodPost},
+		summary:  "Load an amod file into a session",
+		request:  modelLoadRequest{},
+		response: modelLoadResponse{},
+		handler:  w.loadModelHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/model/validate",
+		methods:  []string{http.MethodPost, http.MethodPut},
+		summary:  "Validate an amod file for each framework without running it",
+		request:  modelValidateRequest{},
+		response: modelValidateResponse{},
+		handler:  w.validateModelHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/model/generate",
+		methods:  []string{http.MethodPost, http.MethodPut},
+		summary:  "Generate the code for each framework from an amod file without running it",
+		request:  modelGenerateRequest{},
+		response: runResult{},
+		handler:  w.generateModelHandler,
+	})
 }
 
-func (w *Web) loadModelHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		SessionID int    `json:"sessionID"`
-		AMODFile  string `json:"amod"`
-	}
-	type response struct {
-		ModelID   int    `json:"modelID"`
-		ModelName string `json:"modelName"`
-		SessionID int    `json:"sessionID"`
-	}
+type modelLoadRequest struct {
+	SessionID string `json:"sessionID"`
+	AMODFile  string `json:"amod"`
+}
+
+type modelLoadResponse struct {
+	ModelID   string `json:"modelID"`
+	ModelName string `json:"modelName"`
+	SessionID string `json:"sessionID"`
+}
 
-	var data request
+func (w *Web) loadModelHandler(rw http.ResponseWriter, req *http.Request) {
+	var data modelLoadRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
@@ -53,17 +79,143 @@ func (w *Web) loadModelHandler(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	encodeResponse(rw, response{
+	encodeResponse(rw, modelLoadResponse{
 		ModelID:   model.id,
 		ModelName: model.actrModel.Name,
 		SessionID: data.SessionID,
 	})
 }
 
-func (w *Web) loadModel(sessionID int, amodFile string) (model *Model, err error) {
-	session := w.lookupSession(sessionID)
-	if session == nil {
-		err = &ErrInvalidSessionID{ID: sessionID}
+type modelValidateRequest struct {
+	AMODFile   string                       `json:"amod"`                 // text of an amod file
+	Frameworks runoptions.FrameworkNameList `json:"frameworks,omitempty"` // list of frameworks to validate for (if empty, "all")
+}
+
+// modelValidateResponse contains the issues with the amod file and the issues found by each framework
+type modelValidateResponse struct {
+	Issues  issues.IssueList            `json:"issues"`
+	Results map[string]issues.IssueList `json:"results"`
+}
+
+// modelGenerateRequest is the body of a request to generate code from an amod file
+type modelGenerateRequest struct {
+	AMODFile string `json:"amod"`           // text of an amod file
+	Goal     string `json:"goal,omitempty"` // initial goal
+
+	Options *runOptionsJSON `json:"options,omitempty"`
+}
+
+// validateModelHandler checks the amod file and validates the model for each framework. Nothing
+// is run, so this is cheap enough to call as the amod file is edited. Issues with the amod file
+// are not an error for this endpoint - they are the result.
+func (w *Web) validateModelHandler(rw http.ResponseWriter, req *http.Request) {
+	var data modelValidateRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	activeFrameworkNames := w.settings.ActiveFrameworks.Names()
+
+	data.Frameworks.NormalizeFrameworkList(activeFrameworkNames)
+
+	err = data.Frameworks.VerifyFrameworkList(activeFrameworkNames)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	response := modelValidateResponse{
+		Issues:  issues.IssueList{},
+		Results: map[string]issues.IssueList{},
+	}
+
+	model, log, err := amod.GenerateModel(data.AMODFile)
+	response.Issues = append(response.Issues, log.AllIssues()...)
+
+	if err == nil {
+		for name, f := range w.newInstances(data.Frameworks, w.settings.TempPath) {
+			response.Results[name] = append(issues.IssueList{}, f.ValidateModel(model).AllIssues()...)
+		}
+	}
+
+	encodeResponse(rw, response)
+}
+
+// generateModelHandler generates the code for each framework like runModelHandler, but does
+// not run it.
+func (w *Web) generateModelHandler(rw http.ResponseWriter, req *http.Request) {
+	var data modelGenerateRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	model, options, log, err := w.prepareRun(runRequest{
+		AMODFile: data.AMODFile,
+		Goal:     data.Goal,
+		Options:  data.Options,
+	})
+	if err != nil {
+		if log.HasError() {
+			encodeIssueResponse(rw, log)
+		} else {
+			encodeErrorResponse(rw, err)
+		}
+		return
+	}
+
+	results := frameworkRunResultMap{}
+
+	for name, f := range w.newInstances(options.Frameworks, w.settings.TempPath) {
+		results[name] = generateCode(model, options, f)
+	}
+
+	encodeResponse(rw, runResult{
+		Issues:  log.AllIssues(),
+		Results: results,
+	})
+}
+
+// generateCode validates the model for the framework and generates its code.
+func generateCode(model *actr.Model, options *runoptions.Options, f framework.Framework) (result frameworkRunResult) {
+	result = frameworkRunResult{
+		ModelName: model.Name,
+	}
+
+	log := f.ValidateModel(model)
+	if !log.HasError() {
+		code, err := generateValidatedCode(model, options, f)
+		if err != nil {
+			log.Error(nil, err.Error())
+		} else {
+			codeStr := string(code)
+			result.Code = &codeStr
+		}
+	}
+
+	if log.HasIssues() {
+		all := log.AllIssues()
+		result.Issues = &all
+	}
+
+	return
+}
+
+func generateValidatedCode(model *actr.Model, options *runoptions.Options, f framework.Framework) (code []byte, err error) {
+	err = f.SetModel(model)
+	if err != nil {
+		return
+	}
+
+	return f.GenerateCode(options)
+}
+
+func (w *Web) loadModel(sessionID string, amodFile string) (model *Model, err error) {
+	session, err := w.lookupSession(sessionID)
+	if err != nil {
 		return
 	}
 
@@ -73,10 +225,19 @@ func (w *Web) loadModel(sessionID int, amodFile string) (model *Model, err error
 	}
 
 	model = &Model{
-		id:        currentModelID,
+		id:        newID(),
 		actrModel: actrModel,
 	}
-	currentModelID++
+
+	err = w.store.addModel(sessionID, storedModel{
+		ID:     model.id,
+		Name:   actrModel.Name,
+		AMOD:   amodFile,
+		Loaded: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	session.addModel(model)
 
@@ -84,9 +245,10 @@ func (w *Web) loadModel(sessionID int, amodFile string) (model *Model, err error
 }
 
 // actrOptionsFromJSON converts runOptionsJSON into actr.Options. It defaults to the model's defaults.
-func (w Web) actrOptionsFromJSON(defaults *runoptions.Options, options *runOptionsJSON) (*runoptions.Options, error) {
+func (w *Web) actrOptionsFromJSON(defaults *runoptions.Options, options *runOptionsJSON) (*runoptions.Options, error) {
+	// options are optional, so use the defaults
 	if options == nil {
-		return nil, nil
+		options = &runOptionsJSON{}
 	}
 
 	activeFrameworkNames := w.settings.ActiveFrameworks.Names()
@@ -115,10 +277,28 @@ func (w Web) actrOptionsFromJSON(defaults *runoptions.Options, options *runOptio
 		opts.TraceActivations = options.TraceActivations
 	}
 
+	if options.TraceEvents != nil {
+		opts.TraceEvents = options.TraceEvents
+	}
+
 	if options.RandomSeed != nil {
 		opts.RandomSeed = options.RandomSeed
 	}
 
+	// use the server's timeout (if any) - a request may only lower it
+	opts.Timeout = w.settings.Timeout
+
+	if options.Timeout != nil {
+		if *options.Timeout <= 0 {
+			err = ErrInvalidTimeout
+		} else {
+			timeout := time.Duration(*options.Timeout * float64(time.Second))
+			if opts.Timeout == nil || timeout < *opts.Timeout {
+				opts.Timeout = &timeout
+			}
+		}
+	}
+
 	return &opts, err
 }
 
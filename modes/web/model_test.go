@@ -2,21 +2,26 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/asmaloney/gactar/actr/param"
+	"github.com/asmaloney/gactar/util/cli"
+	"github.com/asmaloney/gactar/util/runoptions"
 )
 
 func TestAddModel(t *testing.T) {
-	session := webTest.newSession()
-
-	if session == nil {
-		t.Fatalf("Could not create session")
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err.Error())
 	}
 
-	err := webTest.endSession(session.id)
+	err = webTest.endSession(session.id)
 
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
@@ -28,7 +33,10 @@ func TestAddModel(t *testing.T) {
 }
 
 func TestLoadModelHandler(t *testing.T) {
-	session := webTest.newSession()
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	src := `~~ model ~~
 	name: Test
@@ -41,7 +49,7 @@ func TestLoadModelHandler(t *testing.T) {
 	)
 	src = replacer.Replace(src)
 
-	data := []byte(fmt.Sprintf(`{"sessionID":%d, "amod":"%s"}`, session.id, src))
+	data := []byte(fmt.Sprintf(`{"sessionID":%q, "amod":"%s"}`, session.id, src))
 
 	request, err := http.NewRequest("PUT", "/model/load", bytes.NewBuffer(data))
 	if err != nil {
@@ -58,7 +66,7 @@ func TestLoadModelHandler(t *testing.T) {
 			http.StatusOK, status)
 	}
 
-	expected := `{"modelID":1,"modelName":"Test","sessionID":`
+	expected := `{"modelID":"`
 	responseStr := strings.TrimSpace(responseRecorder.Body.String())
 	if !strings.HasPrefix(responseStr, expected) {
 		t.Errorf("handler returned unexpected body: expected '%v' got '%v'",
@@ -71,3 +79,157 @@ func TestLoadModelHandler(t *testing.T) {
 
 	webTest.clearSessions()
 }
+
+func TestValidateModelHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		amod       string
+		hasIssues  bool
+		frameworks int
+	}{
+		{name: "valid", amod: concurrentModel(1), hasIssues: false, frameworks: 1},
+		{name: "invalid", amod: "~~ model ~~", hasIssues: true, frameworks: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(modelValidateRequest{AMODFile: tt.amod, Frameworks: []string{"native"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request, err := http.NewRequest("POST", "/model/validate", bytes.NewBuffer(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			responseRecorder := httptest.NewRecorder()
+			handler := http.HandlerFunc(webTest.validateModelHandler)
+
+			handler.ServeHTTP(responseRecorder, request)
+
+			// issues with the amod are the result, not an error
+			if status := responseRecorder.Code; status != http.StatusOK {
+				t.Errorf("handler returned incorrect status code: expected '%v' got '%v'",
+					http.StatusOK, status)
+			}
+
+			var response modelValidateResponse
+
+			err = json.NewDecoder(responseRecorder.Body).Decode(&response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hasIssues := len(response.Issues) > 0; hasIssues != tt.hasIssues {
+				t.Errorf("unexpected issues: %v", response.Issues)
+			}
+
+			if len(response.Results) != tt.frameworks {
+				t.Errorf("expected results for %d frameworks, got %v", tt.frameworks, response.Results)
+			}
+		})
+	}
+}
+
+func TestGenerateModelHandler(t *testing.T) {
+	data, err := json.Marshal(modelGenerateRequest{
+		AMODFile: concurrentModel(7),
+		Options:  &runOptionsJSON{Frameworks: []string{"native"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := http.NewRequest("POST", "/model/generate", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.generateModelHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	if status := responseRecorder.Code; status != http.StatusOK {
+		t.Fatalf("handler returned incorrect status code: expected '%v' got '%v'",
+			http.StatusOK, status)
+	}
+
+	var response runResult
+
+	err = json.NewDecoder(responseRecorder.Body).Decode(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, ok := response.Results["native"]
+	if !ok {
+		t.Fatalf("missing native result: %v", response)
+	}
+
+	if result.Code == nil || !strings.Contains(*result.Code, "Test7") {
+		t.Errorf("unexpected code: %v", result.Code)
+	}
+
+	if result.Output != nil {
+		t.Errorf("model should not have been run")
+	}
+}
+
+func TestGenerateModelHandlerInvalid(t *testing.T) {
+	request, err := http.NewRequest("POST", "/model/generate", bytes.NewBufferString(`{"amod": "~~ model ~~"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.generateModelHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	if status := responseRecorder.Code; status != http.StatusUnprocessableEntity {
+		t.Errorf("handler returned incorrect status code: expected '%v' got '%v'",
+			http.StatusUnprocessableEntity, status)
+	}
+}
+
+func TestRunOptionsTimeout(t *testing.T) {
+	serverTimeout := 10 * time.Second
+	w := &Web{settings: &cli.Settings{Timeout: &serverTimeout}}
+
+	tests := []struct {
+		name     string
+		request  *float64
+		expected time.Duration
+	}{
+		{"no request timeout", nil, serverTimeout},
+		{"shorter request timeout", param.Ptr[float64](2), 2 * time.Second},
+		{"longer request timeout", param.Ptr[float64](60), serverTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := w.actrOptionsFromJSON(&runoptions.Options{}, &runOptionsJSON{Timeout: tt.request})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if options.Timeout == nil || *options.Timeout != tt.expected {
+				t.Errorf("expected timeout %v got %v", tt.expected, options.Timeout)
+			}
+		})
+	}
+
+	// Without a server timeout, the request's is used
+	w = &Web{settings: &cli.Settings{}}
+
+	options, err := w.actrOptionsFromJSON(&runoptions.Options{}, &runOptionsJSON{Timeout: param.Ptr[float64](60)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Timeout == nil || *options.Timeout != time.Minute {
+		t.Errorf("expected timeout %v got %v", time.Minute, options.Timeout)
+	}
+}
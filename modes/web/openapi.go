@@ -0,0 +1,509 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/asmaloney/gactar/util/version"
+)
+
+// endpoint describes one of our API endpoints. It is used to register the handler and to generate
+// the OpenAPI document served at /api/openapi.json.
+type endpoint struct {
+	path    string   // path (may end with a parameter in braces e.g. "/api/jobs/{id}")
+	methods []string // allowed methods (the first one is documented)
+	summary string
+
+	request  any // zero value of the type of the request body (nil if there is no body)
+	response any // zero value of the type of the response (nil if it is not JSON)
+
+	responseType        string // content type of the response if it is not JSON
+	responseDescription string // description of the response if it is not JSON
+
+	handler http.HandlerFunc
+}
+
+// schema is an OpenAPI schema object. We only use the parts we need to describe our types.
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+// schemaGenerator creates schemas from Go types. Named struct types are added to "components"
+// and referenced using "$ref".
+type schemaGenerator struct {
+	components map[string]*schema
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: map[string]*schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// schemaFor returns the schema for the type of "v".
+func (g *schemaGenerator) schemaFor(v any) *schema {
+	return g.schemaForType(reflect.TypeOf(v))
+}
+
+func (g *schemaGenerator) schemaForType(t reflect.Type) *schema {
+	switch t {
+	case timeType:
+		return &schema{Type: "string", Format: "date-time"}
+
+	case rawMessageType:
+		return &schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schemaForType(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+
+	case reflect.String:
+		return &schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		return &schema{Type: "array", Items: g.schemaForType(t.Elem())}
+
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: g.schemaForType(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		name, exists := g.names[t]
+		if !exists {
+			name = g.componentName(t)
+			g.names[t] = name
+
+			// add it before we generate it in case it refers to itself
+			g.components[name] = &schema{}
+			*g.components[name] = *g.structSchema(t)
+		}
+
+		return &schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// interfaces etc. may be anything
+	return &schema{}
+}
+
+// componentName returns a unique name for a named type e.g. "runOptionsJSON" -> "RunOptions".
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := strings.TrimSuffix(t.Name(), "JSON")
+
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	name = string(runes)
+
+	if _, exists := g.components[name]; exists {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	return name
+}
+
+// structSchema creates an object schema from the struct's exported fields using their JSON names.
+// Fields are required unless they are pointers or are marked "omitempty". Fields may list their
+// valid values using an "enum" tag (e.g. `enum:"min,info,detail"`).
+func (g *schemaGenerator) structSchema(t reflect.Type) *schema {
+	s := &schema{
+		Type:       "object",
+		Properties: map[string]*schema{},
+	}
+
+	g.addFields(s, t)
+
+	sort.Strings(s.Required)
+
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a JSON name have their fields promoted
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(s, embedded)
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaForType(field.Type)
+
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property.Enum = strings.Split(enum, ",")
+		}
+
+		s.Properties[name] = property
+
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// resolve follows a schema's reference (if any).
+func (g *schemaGenerator) resolve(s *schema) *schema {
+	if s.Ref == "" {
+		return s
+	}
+
+	return g.components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+}
+
+// validate checks a decoded JSON value against the schema and returns a list of problems.
+func (g *schemaGenerator) validate(value any, s *schema, path string) (problems []string) {
+	s = g.resolve(s)
+
+	label := path
+	if label == "" {
+		label = "request"
+	}
+
+	if value == nil {
+		if s.Type != "" && !s.Nullable {
+			problems = append(problems, fmt.Sprintf("%s must not be null", label))
+		}
+		return
+	}
+
+	switch s.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a boolean", label))
+		}
+
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			problems = append(problems, fmt.Sprintf("%s must be an integer", label))
+		}
+
+	case "number":
+		if _, ok := value.(float64); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a number", label))
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a string", label))
+			break
+		}
+
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			problems = append(problems, fmt.Sprintf("%s must be one of: %s", label, strings.Join(s.Enum, ", ")))
+		}
+
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s must be an array", label))
+			break
+		}
+
+		for i, item := range list {
+			problems = append(problems, g.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s must be an object", label))
+			break
+		}
+
+		for _, name := range s.Required {
+			if _, exists := object[name]; !exists {
+				problems = append(problems, fmt.Sprintf("%s is required", joinPath(path, name)))
+			}
+		}
+
+		// sort the keys so the problems are always in the same order
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			property := s.Properties[key]
+			if property == nil {
+				property = s.AdditionalProperties
+			} else if object[key] == nil && !slices.Contains(s.Required, key) {
+				// optional properties may be null
+				continue
+			}
+
+			// other properties are allowed and ignored
+			if property == nil {
+				continue
+			}
+
+			problems = append(problems, g.validate(object[key], property, joinPath(path, key))...)
+		}
+	}
+
+	return
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// handle registers the endpoint's handler. Requests using other methods are rejected and
+// request bodies are validated against the request type's schema before calling the handler.
+func (w *Web) handle(e endpoint) {
+	w.endpoints = append(w.endpoints, e)
+
+	pattern, _, _ := strings.Cut(e.path, "{")
+
+	var requestSchema *schema
+	if e.request != nil {
+		requestSchema = w.schemas.schemaFor(e.request)
+	}
+
+	http.HandleFunc(pattern, func(rw http.ResponseWriter, req *http.Request) {
+		if !slices.Contains(e.methods, req.Method) {
+			rw.Header().Set("Allow", strings.Join(e.methods, ", "))
+			encodeErrorResponse(rw, &ErrMethodNotAllowed{Method: req.Method})
+			return
+		}
+
+		if requestSchema != nil {
+			err := w.validateBody(req, requestSchema)
+			if err != nil {
+				encodeErrorResponse(rw, err)
+				return
+			}
+		}
+
+		e.handler(rw, req)
+	})
+}
+
+// validateBody reads the request's body, validates it, and replaces it so the handler can decode it.
+func (w *Web) validateBody(req *http.Request, s *schema) (err error) {
+	if req.Body == nil {
+		return ErrEmptyRequestBody
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ErrEmptyRequestBody
+	}
+
+	var value any
+
+	err = json.Unmarshal(body, &value)
+	if err != nil {
+		return &ErrInvalidRequest{Problems: []string{err.Error()}}
+	}
+
+	problems := w.schemas.validate(value, s, "")
+	if len(problems) > 0 {
+		return &ErrInvalidRequest{Problems: problems}
+	}
+
+	return
+}
+
+// openAPIHandler returns an OpenAPI 3 document describing the endpoints.
+func (w *Web) openAPIHandler(rw http.ResponseWriter, req *http.Request) {
+	encodeResponse(rw, w.openAPI)
+}
+
+// openAPIDocument creates the OpenAPI document from the registered endpoints. It must be called
+// after all the endpoints have been registered.
+func (w *Web) openAPIDocument() map[string]any {
+	errorContent := map[string]any{
+		"application/json": map[string]any{
+			"schema": w.schemas.schemaFor(runResult{}),
+		},
+	}
+
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     errorContent,
+		}
+	}
+
+	paths := map[string]any{}
+
+	for _, e := range w.endpoints {
+		operation := map[string]any{
+			"operationId": operationID(e.path),
+			"summary":     e.summary,
+		}
+
+		responses := map[string]any{
+			"405": errorResponse("The method is not allowed"),
+			"500": errorResponse("Internal error"),
+		}
+
+		if e.response != nil {
+			responses["200"] = map[string]any{
+				"description": "OK",
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": w.schemas.schemaFor(e.response),
+					},
+				},
+			}
+		} else {
+			responses["200"] = map[string]any{
+				"description": e.responseDescription,
+				"content": map[string]any{
+					e.responseType: map[string]any{
+						"schema": &schema{Type: "string"},
+					},
+				},
+			}
+		}
+
+		if e.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": w.schemas.schemaFor(e.request),
+					},
+				},
+			}
+
+			responses["400"] = errorResponse("The request is invalid")
+		}
+
+		if strings.Contains(e.path, "{") {
+			name := e.path[strings.Index(e.path, "{")+1 : strings.Index(e.path, "}")]
+
+			operation["parameters"] = []any{
+				map[string]any{
+					"name":     name,
+					"in":       "path",
+					"required": true,
+					"schema":   &schema{Type: "string"},
+				},
+			}
+
+			responses["404"] = errorResponse("Not found")
+		}
+
+		operation["responses"] = responses
+
+		paths[e.path] = map[string]any{
+			strings.ToLower(e.methods[0]): operation,
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "gactar",
+			"description": "Web API for running amod models. Errors are returned as a list of issues along with an HTTP status code (400 invalid request, 404 unknown ID, 409 conflict, 422 the model has errors, 429 the job queue is full).",
+			"version":     version.BuildVersion,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": w.schemas.components,
+		},
+	}
+}
+
+// operationID creates an operation ID from the path e.g. "/api/session/runModel" -> "sessionRunModel".
+func operationID(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/"), "/")
+
+	id := ""
+	for i, part := range parts {
+		part = strings.Trim(part, "{}")
+		if part == "" {
+			continue
+		}
+
+		if i > 0 {
+			part = strings.ToUpper(part[:1]) + part[1:]
+		}
+
+		id += part
+	}
+
+	return id
+}
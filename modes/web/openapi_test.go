@@ -0,0 +1,190 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", response.StatusCode, http.StatusOK)
+	}
+
+	var document struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Errorf("unexpected openapi version: %q", document.OpenAPI)
+	}
+
+	expectedPaths := map[string]string{
+		"/api/version":         "get",
+		"/api/run":             "post",
+		"/api/jobs/{jobID}":    "get",
+		"/api/session/begin":   "get",
+		"/api/session/history": "put",
+		"/api/model/load":      "put",
+	}
+
+	for path, method := range expectedPaths {
+		if _, ok := document.Paths[path][method]; !ok {
+			t.Errorf("missing %s %s", method, path)
+		}
+	}
+
+	request, ok := document.Components.Schemas["RunRequest"]
+	if !ok {
+		t.Fatal("missing RunRequest schema")
+	}
+
+	if len(request.Required) != 1 || request.Required[0] != "amod" {
+		t.Errorf("unexpected required properties of RunRequest: %v", request.Required)
+	}
+
+	options, ok := document.Components.Schemas["RunOptions"]
+	if !ok {
+		t.Fatal("missing RunOptions schema")
+	}
+
+	if logLevel := options.Properties["logLevel"]; logLevel == nil || len(logLevel.Enum) != 3 {
+		t.Errorf("expected logLevel to list its values: %v", logLevel)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		problems []string
+	}{
+		{
+			name:     "missing field",
+			body:     `{"goal": "foo"}`,
+			status:   http.StatusBadRequest,
+			problems: []string{"amod is required"},
+		},
+		{
+			name:     "wrong types",
+			body:     `{"amod": 42, "options": {"frameworks": "vanilla", "logLevel": "everything"}}`,
+			status:   http.StatusBadRequest,
+			problems: []string{"amod must be a string", "options.frameworks must be an array", "options.logLevel must be one of: min, info, detail"},
+		},
+		{
+			name:     "not an object",
+			body:     `[1, 2]`,
+			status:   http.StatusBadRequest,
+			problems: []string{"request must be an object"},
+		},
+		{
+			name:     "empty",
+			body:     ``,
+			status:   http.StatusBadRequest,
+			problems: []string{"empty request body"},
+		},
+		{
+			name:   "model has errors",
+			body:   `{"amod": "~~ model ~~"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := http.Post(server.URL+"/api/run", "application/json", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", response.StatusCode, tt.status)
+			}
+
+			var result runResult
+
+			err = json.NewDecoder(response.Body).Decode(&result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Issues) == 0 {
+				t.Fatal("expected issues in the response")
+			}
+
+			if tt.problems == nil {
+				return
+			}
+
+			if len(result.Issues) != len(tt.problems) {
+				t.Fatalf("expected %d issues, got %d: %v", len(tt.problems), len(result.Issues), result.Issues)
+			}
+
+			for i, problem := range tt.problems {
+				if result.Issues[i].Text != problem {
+					t.Errorf("issue %d: got %q want %q", i, result.Issues[i].Text, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("handler returned wrong status code: got %v want %v", response.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	if allow := response.Header.Get("Allow"); allow != "POST, PUT" {
+		t.Errorf("unexpected Allow header: %q", allow)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	rw := httptest.NewRecorder()
+
+	encodeErrorResponse(rw, &ErrInvalidSessionID{ID: "foo"})
+
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("wrong status code: got %v want %v", rw.Code, http.StatusNotFound)
+	}
+
+	if contentType := rw.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("unexpected content type: %q", contentType)
+	}
+}
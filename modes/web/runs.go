@@ -0,0 +1,102 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// runList keeps track of the runs in progress which were given a run ID by the client so they
+// may be cancelled using /api/run/cancel.
+type runList struct {
+	mutex sync.Mutex
+	runs  map[string]*runEntry
+}
+
+// runEntry is an entry in the runList. We compare pointers when a run finishes so we only remove our
+// own entry - a cancelled run's ID may already have been reused by a new run.
+type runEntry struct {
+	cancel context.CancelFunc
+}
+
+func newRunList() *runList {
+	return &runList{
+		runs: map[string]*runEntry{},
+	}
+}
+
+// start creates the context for a run. The run is cancelled if the request's context is done or
+// if it is cancelled using its ID. If "runID" is empty, the run cannot be cancelled by ID.
+// Call "finish" when the run is done.
+func (l *runList) start(ctx context.Context, runID string) (runCtx context.Context, finish func(), err error) {
+	runCtx, cancel := context.WithCancel(ctx)
+
+	if runID == "" {
+		return runCtx, cancel, nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := l.runs[runID]; exists {
+		cancel()
+		return nil, nil, &ErrRunIDInUse{ID: runID}
+	}
+
+	r := &runEntry{cancel: cancel}
+	l.runs[runID] = r
+
+	finish = func() {
+		l.mutex.Lock()
+		if l.runs[runID] == r {
+			delete(l.runs, runID)
+		}
+		l.mutex.Unlock()
+
+		cancel()
+	}
+
+	return runCtx, finish, nil
+}
+
+// cancel cancels the run with this ID. It returns false if there is no such run.
+func (l *runList) cancel(runID string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	r, exists := l.runs[runID]
+	if !exists {
+		return false
+	}
+
+	r.cancel()
+	delete(l.runs, runID)
+
+	return true
+}
+
+type cancelRunRequest struct {
+	RunID string `json:"runID"`
+}
+
+type cancelRunResponse struct {
+	RunID string `json:"runID"`
+}
+
+func (w *Web) cancelRunHandler(rw http.ResponseWriter, req *http.Request) {
+	var data cancelRunRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	if !w.runs.cancel(data.RunID) {
+		encodeErrorResponse(rw, &ErrInvalidRunID{ID: data.RunID})
+		return
+	}
+
+	encodeResponse(rw, cancelRunResponse{
+		RunID: data.RunID,
+	})
+}
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunList(t *testing.T) {
+	list := newRunList()
+
+	ctx, finish, err := list.start(context.Background(), "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer finish()
+
+	_, _, err = list.start(context.Background(), "run-1")
+
+	var inUse *ErrRunIDInUse
+	if !errors.As(err, &inUse) {
+		t.Errorf("expected ErrRunIDInUse, got %v", err)
+	}
+
+	if !list.cancel("run-1") {
+		t.Fatal("could not cancel run")
+	}
+
+	if ctx.Err() == nil {
+		t.Error("expected run's context to be cancelled")
+	}
+
+	if list.cancel("run-1") {
+		t.Error("expected cancelled run to be removed from the list")
+	}
+}
+
+func TestRunListReuseCancelledID(t *testing.T) {
+	list := newRunList()
+
+	_, finishFirst, err := list.start(context.Background(), "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !list.cancel("run-1") {
+		t.Fatal("could not cancel first run")
+	}
+
+	// The ID may be reused while the cancelled run is still finishing
+	ctx, finishSecond, err := list.start(context.Background(), "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer finishSecond()
+
+	// Finishing the first run must not remove the second one
+	finishFirst()
+
+	if !list.cancel("run-1") {
+		t.Fatal("could not cancel second run")
+	}
+
+	if ctx.Err() == nil {
+		t.Error("expected second run's context to be cancelled")
+	}
+}
+
+func TestCancelRunHandler(t *testing.T) {
+	ctx, finish, err := webTest.runs.start(context.Background(), "run-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer finish()
+
+	request, err := http.NewRequest("POST", "/run/cancel", bytes.NewBufferString(`{"runID": "run-2"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.cancelRunHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	expected := `{"runID":"run-2"}`
+	responseStr := strings.TrimSpace(responseRecorder.Body.String())
+	if responseStr != expected {
+		t.Errorf("handler returned unexpected body: expected '%v' got '%v'", expected, responseStr)
+	}
+
+	if ctx.Err() == nil {
+		t.Error("expected run's context to be cancelled")
+	}
+}
+
+func TestCancelRunHandlerInvalidID(t *testing.T) {
+	request, err := http.NewRequest("POST", "/run/cancel", bytes.NewBufferString(`{"runID": "foo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.cancelRunHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	expected := `{"issues":[{"level":"error","text":"invalid run id: \"foo\"","location":null}]}`
+	responseStr := strings.TrimSpace(responseRecorder.Body.String())
+	if responseStr != expected {
+		t.Errorf("handler returned unexpected body: expected '%v' got '%v'", expected, responseStr)
+	}
+}
@@ -1,142 +1,325 @@
 package web
 
 import (
-	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/asmaloney/gactar/framework"
 
-	"github.com/asmaloney/gactar/util/cli"
 	"github.com/asmaloney/gactar/util/runoptions"
 )
 
+// Session holds the models loaded by a client. Sessions (along with the amod source of their
+// models and the results of their runs) are persisted using the sessionStore, so a session which
+// is not in memory - e.g. after a restart - is reloaded from the store when it is referenced.
 type Session struct {
-	id     int
+	id string
+
+	mutex  sync.Mutex // protects models
 	models []*Model
 }
 
 type SessionList []*Session
 
 func initSessions(w *Web) {
-	http.HandleFunc("/api/session/begin", w.beginSessionHandler)
-	http.HandleFunc("/api/session/runModel", w.runModelSessionHandler)
-	http.HandleFunc("/api/session/end", w.endSessionHandler)
+	w.handle(endpoint{
+		path:     "/api/session/begin",
+		methods:  []string{http.MethodGet, http.MethodPost, http.MethodPut},
+		summary:  "Begin a new session",
+		response: sessionBeginResponse{},
+		handler:  w.beginSessionHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/session/runModel",
+		methods:  []string{http.MethodPost, http.MethodPut},
+		summary:  "Run a model which was loaded into a session",
+		request:  sessionRunRequest{},
+		response: sessionRunResponse{},
+		handler:  w.runModelSessionHandler,
+	})
+	w.handle(endpoint{
+		path:                "/api/session/runModel/stream",
+		methods:             []string{http.MethodPost, http.MethodPut},
+		summary:             "Run a model which was loaded into a session and stream the output as server-sent events",
+		request:             sessionRunRequest{},
+		responseType:        "text/event-stream",
+		responseDescription: "Server-sent events (issues, output, result, error, done)",
+		handler:             w.runModelSessionStreamHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/session/end",
+		methods:  []string{http.MethodPut, http.MethodPost},
+		summary:  "End a session",
+		request:  sessionRequest{},
+		response: sessionEndResponse{},
+		handler:  w.endSessionHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/session/list",
+		methods:  []string{http.MethodGet},
+		summary:  "List the stored sessions",
+		response: sessionListResponse{},
+		handler:  w.listSessionsHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/session/history",
+		methods:  []string{http.MethodPut, http.MethodPost},
+		summary:  "Get a stored session's models and the results of its runs",
+		request:  sessionRequest{},
+		response: storedSession{},
+		handler:  w.sessionHistoryHandler,
+	})
+}
+
+// sessionRequest is the body of requests which only need a session ID
+type sessionRequest struct {
+	SessionID string `json:"sessionID"`
+}
+
+type sessionBeginResponse struct {
+	SessionID string `json:"sessionID"`
+}
+
+type sessionEndResponse struct {
+}
+
+type sessionListResponse struct {
+	Sessions []sessionSummary `json:"sessions"`
+}
+
+type sessionRunResponse struct {
+	Results frameworkRunResultMap `json:"results"`
 }
 
 func (w *Web) beginSessionHandler(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		SessionID int `json:"session_id"`
+	session, err := w.newSession()
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
 	}
 
-	session := w.newSession()
-
-	encodeResponse(rw, response{
+	encodeResponse(rw, sessionBeginResponse{
 		SessionID: session.id,
 	})
 }
 
+// sessionRunRequest is the body of a request to run a model which was loaded into a session
+type sessionRunRequest struct {
+	SessionID   string                    `json:"sessionID"`
+	ModelID     string                    `json:"modelID"`
+	Buffers     runoptions.InitialBuffers `json:"buffers,omitempty"`     // set the initial buffers
+	IncludeCode bool                      `json:"includeCode,omitempty"` // include generated code in the result
+	Options     runOptionsJSON            `json:"options,omitempty"`
+	RunID       string                    `json:"runID,omitempty"` // (optional) ID used to cancel the run
+}
+
 func (w *Web) runModelSessionHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		SessionID   int                       `json:"sessionID"`
-		ModelID     int                       `json:"modelID"`
-		Buffers     runoptions.InitialBuffers `json:"buffers"`     // set the initial buffers
-		IncludeCode bool                      `json:"includeCode"` // include generated code in the result
-		Options     runOptionsJSON            `json:"options"`
-	}
-	type response struct {
-		Results json.RawMessage `json:"results"`
+	var data sessionRunRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
 	}
 
-	var data request
-	err := decodeBody(req, &data)
+	model, options, err := w.prepareSessionRun(data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	session := w.lookupSession(data.SessionID)
-	if session == nil {
-		err = &ErrInvalidSessionID{ID: data.SessionID}
+	ctx, finish, err := w.runs.start(req.Context(), data.RunID)
+	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
+	defer finish()
 
-	model := session.lookupModel(data.ModelID)
-	if model == nil {
-		err = &ErrInvalidModelID{ID: data.ModelID}
+	release, err := w.waitToRun(ctx, options.Frameworks)
+	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
+	defer release()
 
-	options, err := w.actrOptionsFromJSON(&model.actrModel.DefaultParams, &data.Options)
+	resultMap, err := w.runModel(ctx, model.actrModel, options)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	options.InitialBuffers = data.Buffers
+	for key := range resultMap {
+		result := resultMap[key]
+		data.adjustResult(&result)
+		resultMap[key] = result
+	}
 
-	// ensure temp dir exists
-	// https://github.com/asmaloney/gactar/issues/103
-	_, err = cli.CreateTempDir(w.settings)
+	err = w.storeRun(data, resultMap)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	resultMap := w.runModel(model.actrModel, options)
+	encodeResponse(rw, sessionRunResponse{
+		Results: resultMap,
+	})
+}
 
-	for key := range resultMap {
-		result := resultMap[key]
+// runModelSessionStreamHandler runs the model like runModelSessionHandler, but streams the
+// output as it is produced using server-sent events. See runStream for the events.
+func (w *Web) runModelSessionStreamHandler(rw http.ResponseWriter, req *http.Request) {
+	stream, err := newRunStream(rw)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
 
-		// Remove the code if we just want the results
-		if !data.IncludeCode {
-			result.Code = nil
-		}
+	var data sessionRunRequest
+	err = decodeBody(req, &data)
+	if err != nil {
+		stream.sendError(err)
+		return
+	}
 
-		result.SessionID = &data.SessionID
-		result.ModelID = &data.ModelID
+	model, options, err := w.prepareSessionRun(data)
+	if err != nil {
+		stream.sendError(err)
+		return
+	}
 
-		resultMap[key] = result
+	ctx, finish, err := w.runs.start(req.Context(), data.RunID)
+	if err != nil {
+		stream.sendError(err)
+		return
 	}
+	defer finish()
 
-	results, err := json.Marshal(resultMap)
+	// Nothing has been sent yet, so if the queue is full we can still respond with an HTTP error.
+	t, err := w.enqueueRun(options.Frameworks)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	encodeResponse(rw, response{
-		Results: json.RawMessage(string(results)),
+	err = t.wait(ctx)
+	if err != nil {
+		stream.sendError(framework.ErrRunCancelled)
+		return
+	}
+	defer t.release()
+
+	stream.adjustResult = data.adjustResult
+	stream.run(ctx, w, model.actrModel, options)
+
+	// The stream is done, so there's no way to report a problem storing the results to the client.
+	_ = w.storeRun(data, stream.results)
+}
+
+// prepareSessionRun looks up the model and creates the run options from the request.
+func (w *Web) prepareSessionRun(data sessionRunRequest) (model *Model, options *runoptions.Options, err error) {
+	session, err := w.lookupSession(data.SessionID)
+	if err != nil {
+		return
+	}
+
+	model = session.lookupModel(data.ModelID)
+	if model == nil {
+		err = &ErrInvalidModelID{ID: data.ModelID}
+		return
+	}
+
+	options, err = w.actrOptionsFromJSON(&model.actrModel.DefaultParams, &data.Options)
+	if err != nil {
+		return
+	}
+
+	options.InitialBuffers = data.Buffers
+
+	return
+}
+
+// adjustResult adds the session info to the result and removes the code if it wasn't requested
+func (data sessionRunRequest) adjustResult(result *frameworkRunResult) {
+	// Remove the code if we just want the results
+	if !data.IncludeCode {
+		result.Code = nil
+	}
+
+	result.SessionID = &data.SessionID
+	result.ModelID = &data.ModelID
+}
+
+// storeRun adds the results of a run to the session's history.
+func (w *Web) storeRun(data sessionRunRequest, results frameworkRunResultMap) error {
+	return w.store.addRun(data.SessionID, storedRun{
+		ModelID: data.ModelID,
+		Time:    time.Now().UTC(),
+		Buffers: data.Buffers,
+		Options: data.Options,
+		Results: results,
 	})
 }
 
 func (w *Web) endSessionHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		SessionID int `json:"sessionID"`
+	var data sessionRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
 	}
-	type response struct {
+
+	err = w.endSession(data.SessionID)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
 	}
 
-	var data request
+	encodeResponse(rw, sessionEndResponse{})
+}
+
+// listSessionsHandler lists all the stored sessions so a client may reopen one.
+func (w *Web) listSessionsHandler(rw http.ResponseWriter, req *http.Request) {
+	sessions, err := w.store.list()
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	encodeResponse(rw, sessionListResponse{
+		Sessions: sessions,
+	})
+}
+
+// sessionHistoryHandler returns a stored session's models (including their amod source) and the
+// results of all its runs.
+func (w *Web) sessionHistoryHandler(rw http.ResponseWriter, req *http.Request) {
+	var data sessionRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	err = w.endSession(data.SessionID)
+	session, err := w.store.load(data.SessionID)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	encodeResponse(rw, response{})
+	encodeResponse(rw, session)
 }
 
 func (s *Session) addModel(model *Model) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.models = append(s.models, model)
 }
 
-func (s *Session) lookupModel(modelID int) (model *Model) {
+func (s *Session) lookupModel(modelID string) (model *Model) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	for _, model := range s.models {
 		if model.id == modelID {
 			return model
@@ -147,21 +330,35 @@ func (s *Session) lookupModel(modelID int) (model *Model) {
 }
 
 func (s *Session) end() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.models = []*Model{}
 }
 
-func (w *Web) newSession() *Session {
-	session := &Session{
-		id: w.currentSessionID,
+func (w *Web) newSession() (session *Session, err error) {
+	session = &Session{
+		id: newID(),
 	}
-	w.currentSessionID++
 
+	err = w.store.create(session.id)
+	if err != nil {
+		return nil, err
+	}
+
+	w.sessionMutex.Lock()
 	w.sessionList = append(w.sessionList, session)
+	w.sessionMutex.Unlock()
 
-	return session
+	return
 }
 
-func (w *Web) endSession(id int) error {
+// endSession removes the session from memory. It remains in the store so its history is
+// available and it may be reopened.
+func (w *Web) endSession(id string) error {
+	w.sessionMutex.Lock()
+	defer w.sessionMutex.Unlock()
+
 	for index, session := range w.sessionList {
 		if session.id == id {
 			session.end()
@@ -170,14 +367,51 @@ func (w *Web) endSession(id int) error {
 		}
 	}
 
-	return &ErrInvalidSessionID{ID: id}
+	_, err := w.store.load(id)
+	return err
 }
 
-func (w Web) hasSessions() bool {
+func (w *Web) hasSessions() bool {
+	w.sessionMutex.Lock()
+	defer w.sessionMutex.Unlock()
+
 	return len(w.sessionList) > 0
 }
 
-func (w Web) lookupSession(id int) *Session {
+// lookupSession returns the session with this ID. If it is not in memory, it is reloaded from
+// the store.
+func (w *Web) lookupSession(id string) (*Session, error) {
+	session := w.findSession(id)
+	if session != nil {
+		return session, nil
+	}
+
+	// Regenerating the models may take a while, so do it without holding the lock.
+	session, err := w.loadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	w.sessionMutex.Lock()
+	defer w.sessionMutex.Unlock()
+
+	// Another request may have loaded it while we were
+	for _, existing := range w.sessionList {
+		if existing.id == id {
+			return existing, nil
+		}
+	}
+
+	w.sessionList = append(w.sessionList, session)
+
+	return session, nil
+}
+
+// findSession returns the session with this ID if it is in memory or nil if it is not.
+func (w *Web) findSession(id string) *Session {
+	w.sessionMutex.Lock()
+	defer w.sessionMutex.Unlock()
+
 	for _, session := range w.sessionList {
 		if session.id == id {
 			return session
@@ -187,7 +421,37 @@ func (w Web) lookupSession(id int) *Session {
 	return nil
 }
 
+// loadSession creates a session from the store and regenerates its models.
+func (w *Web) loadSession(id string) (*Session, error) {
+	stored, err := w.store.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		id: stored.ID,
+	}
+
+	for _, storedModel := range stored.Models {
+		actrModel, err := generateModel(storedModel.AMOD)
+		if err != nil {
+			// The model may no longer be valid (e.g. if amod has changed), so skip it.
+			continue
+		}
+
+		session.addModel(&Model{
+			id:        storedModel.ID,
+			actrModel: actrModel,
+		})
+	}
+
+	return session, nil
+}
+
 func (w *Web) clearSessions() {
+	w.sessionMutex.Lock()
+	defer w.sessionMutex.Unlock()
+
 	for _, session := range w.sessionList {
 		session.end()
 	}
@@ -10,23 +10,21 @@ import (
 )
 
 func TestNewSession(t *testing.T) {
-	session := webTest.newSession()
-
-	if session == nil {
-		t.Errorf("Could not create session")
+	session, err := webTest.newSession()
+	if err != nil || session == nil {
+		t.Errorf("Could not create session: %v", err)
 	}
 
 	webTest.clearSessions()
 }
 
 func TestEndSession(t *testing.T) {
-	session := webTest.newSession()
-
-	if session == nil {
-		t.Fatalf("Could not create session")
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err.Error())
 	}
 
-	err := webTest.endSession(session.id)
+	err = webTest.endSession(session.id)
 
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
@@ -53,7 +51,7 @@ func TestBeginSessionHandler(t *testing.T) {
 			http.StatusOK, status)
 	}
 
-	expected := `{"session_id":`
+	expected := `{"sessionID":`
 	responseStr := strings.TrimSpace(responseRecorder.Body.String())
 	if !strings.HasPrefix(responseStr, expected) {
 		t.Errorf("handler returned unexpected body: expected to start with '%v' got '%v'",
@@ -64,9 +62,12 @@ func TestBeginSessionHandler(t *testing.T) {
 }
 
 func TestEndSessionHandler(t *testing.T) {
-	session := webTest.newSession()
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	data := []byte(fmt.Sprintf(`{"sessionID":%d}`, session.id))
+	data := []byte(fmt.Sprintf(`{"sessionID":%q}`, session.id))
 
 	request, err := http.NewRequest("PUT", "/session/end", bytes.NewBuffer(data))
 	if err != nil {
@@ -98,7 +99,7 @@ func TestEndSessionHandler(t *testing.T) {
 // Commented out for now since the CI does not install any frameworks.
 
 // func TestRunModelSessionHandler(t *testing.T) {
-// 	session := webTest.newSession()
+// 	session, _ := webTest.newSession()
 
 // 	src := `==model==
 // 	name: Test
@@ -156,7 +157,7 @@ func TestEndSessionHandler(t *testing.T) {
 // 		return
 // 	}
 
-// 	data := []byte(fmt.Sprintf(`{"sessionID":%d, "modelID":%d, "buffers":{ "goal":"[countFrom: 2 5 starting]" }}`, session.id, model.id))
+// 	data := []byte(fmt.Sprintf(`{"sessionID":%q, "modelID":%q, "buffers":{ "goal":"[countFrom: 2 5 starting]" }}`, session.id, model.id))
 
 // 	request, err := http.NewRequest("PUT", "/session/run", bytes.NewBuffer(data))
 // 	if err != nil {
@@ -186,3 +187,47 @@ func TestEndSessionHandler(t *testing.T) {
 // 		t.Errorf("Did not remove session from list")
 // 	}
 // }
+
+func TestSessionHistoryHandler(t *testing.T) {
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(fmt.Sprintf(`{"sessionID":%q}`, session.id))
+
+	request, err := http.NewRequest("PUT", "/session/history", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.sessionHistoryHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	expected := fmt.Sprintf(`{"sessionID":%q,"created":`, session.id)
+	responseStr := strings.TrimSpace(responseRecorder.Body.String())
+	if !strings.HasPrefix(responseStr, expected) {
+		t.Errorf("handler returned unexpected body: expected to start with '%v' got '%v'",
+			expected, responseStr)
+	}
+
+	// the session should be in the list
+	request, err = http.NewRequest("GET", "/session/list", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder = httptest.NewRecorder()
+	handler = http.HandlerFunc(webTest.listSessionsHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	responseStr = strings.TrimSpace(responseRecorder.Body.String())
+	if !strings.Contains(responseStr, fmt.Sprintf(`{"sessionID":%q,`, session.id)) {
+		t.Errorf("session list does not contain session %q: %v", session.id, responseStr)
+	}
+
+	webTest.clearSessions()
+}
@@ -0,0 +1,245 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asmaloney/gactar/util/filesystem"
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+// idLength is the number of random bytes in session & model IDs (they are hex-encoded)
+const idLength = 16
+
+// sessionStore persists sessions, the amod source of the models loaded in them, and the results of
+// their runs so they survive restarting the server. Each session is stored as a JSON file in "path".
+type sessionStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// storedSession is the on-disk form of a session. It is also the response to /api/session/history.
+type storedSession struct {
+	ID      string        `json:"sessionID"`
+	Created time.Time     `json:"created"`
+	Models  []storedModel `json:"models"`
+	Runs    []storedRun   `json:"runs"`
+}
+
+type storedModel struct {
+	ID     string    `json:"modelID"`
+	Name   string    `json:"modelName"`
+	AMOD   string    `json:"amod"`
+	Loaded time.Time `json:"loaded"`
+}
+
+type storedRun struct {
+	ModelID string                    `json:"modelID"`
+	Time    time.Time                 `json:"time"`
+	Buffers runoptions.InitialBuffers `json:"buffers,omitempty"`
+	Options runOptionsJSON            `json:"options"`
+	Results frameworkRunResultMap     `json:"results"`
+}
+
+// sessionSummary is used to list the stored sessions
+type sessionSummary struct {
+	ID       string         `json:"sessionID"`
+	Created  time.Time      `json:"created"`
+	Models   []modelSummary `json:"models"`
+	RunCount int            `json:"runCount"`
+}
+
+type modelSummary struct {
+	ID   string `json:"modelID"`
+	Name string `json:"modelName"`
+}
+
+// newSessionStore creates the directory to store the sessions in (if necessary).
+func newSessionStore(path string) (store *sessionStore, err error) {
+	err = filesystem.CreateDir(path)
+	if err != nil {
+		return
+	}
+
+	store = &sessionStore{path: path}
+	return
+}
+
+// newID returns a new random ID.
+func newID() string {
+	b := make([]byte, idLength)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// validID checks that the ID looks like one we created. IDs come from clients and are used in
+// file names, so we need to be strict.
+func validID(id string) bool {
+	if len(id) != idLength*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func (s *sessionStore) fileName(id string) string {
+	return filepath.Join(s.path, id+".json")
+}
+
+// create stores a new, empty session.
+func (s *sessionStore) create(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.write(&storedSession{
+		ID:      id,
+		Created: time.Now().UTC(),
+		Models:  []storedModel{},
+		Runs:    []storedRun{},
+	})
+}
+
+// load reads the stored session with this ID.
+func (s *sessionStore) load(id string) (session *storedSession, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.read(id)
+}
+
+// addModel adds a model to the stored session.
+func (s *sessionStore) addModel(sessionID string, model storedModel) error {
+	return s.update(sessionID, func(session *storedSession) {
+		session.Models = append(session.Models, model)
+	})
+}
+
+// addRun adds the results of a run to the stored session.
+func (s *sessionStore) addRun(sessionID string, run storedRun) error {
+	return s.update(sessionID, func(session *storedSession) {
+		session.Runs = append(session.Runs, run)
+	})
+}
+
+// list returns a summary of each stored session, oldest first.
+func (s *sessionStore) list() (summaries []sessionSummary, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return
+	}
+
+	summaries = []sessionSummary{}
+
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if !found || !validID(id) {
+			continue
+		}
+
+		session, err := s.read(id)
+		if err != nil {
+			// skip any files we can't read
+			continue
+		}
+
+		summary := sessionSummary{
+			ID:       session.ID,
+			Created:  session.Created,
+			Models:   []modelSummary{},
+			RunCount: len(session.Runs),
+		}
+
+		for _, model := range session.Models {
+			summary.Models = append(summary.Models, modelSummary{ID: model.ID, Name: model.Name})
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Created.Before(summaries[j].Created)
+	})
+
+	return
+}
+
+func (s *sessionStore) update(id string, modify func(session *storedSession)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, err := s.read(id)
+	if err != nil {
+		return err
+	}
+
+	modify(session)
+
+	return s.write(session)
+}
+
+// read reads a session. The caller must hold the mutex.
+func (s *sessionStore) read(id string) (session *storedSession, err error) {
+	if !validID(id) {
+		return nil, &ErrInvalidSessionID{ID: id}
+	}
+
+	data, err := os.ReadFile(s.fileName(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = &ErrInvalidSessionID{ID: id}
+		}
+		return
+	}
+
+	session = &storedSession{}
+
+	err = json.Unmarshal(data, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// write writes a session to a temporary file and then renames it so that a partially-written
+// file never replaces a good one. The caller must hold the mutex.
+func (s *sessionStore) write(session *storedSession) (err error) {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return
+	}
+
+	file, err := os.CreateTemp(s.path, session.ID+".*.tmp")
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return
+	}
+
+	return os.Rename(file.Name(), s.fileName(session.ID))
+}
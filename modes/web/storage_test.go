@@ -0,0 +1,151 @@
+package web
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestValidID(t *testing.T) {
+	if !validID(newID()) {
+		t.Error("expected new ID to be valid")
+	}
+
+	for _, id := range []string{"", "1", "../../etc/passwd", "0123456789abcdef0123456789abcdeg"} {
+		if validID(id) {
+			t.Errorf("expected %q to be invalid", id)
+		}
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	store, err := newSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := newID()
+
+	err = store.create(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.addModel(id, storedModel{ID: "model", Name: "Test", AMOD: "amod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.addRun(id, storedRun{ModelID: "model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := store.load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(session.Models) != 1 || session.Models[0].AMOD != "amod" {
+		t.Errorf("model not stored: %+v", session.Models)
+	}
+
+	if len(session.Runs) != 1 || session.Runs[0].ModelID != "model" {
+		t.Errorf("run not stored: %+v", session.Runs)
+	}
+
+	list, err := store.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || list[0].ID != id || list[0].RunCount != 1 || list[0].Models[0].Name != "Test" {
+		t.Errorf("unexpected session list: %+v", list)
+	}
+
+	_, err = store.load(newID())
+
+	var invalid *ErrInvalidSessionID
+	if !errors.As(err, &invalid) {
+		t.Errorf("expected ErrInvalidSessionID, got %v", err)
+	}
+}
+
+func TestSessionReloadedFromStore(t *testing.T) {
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, err := webTest.loadModel(session.id, "~~ model ~~\nname: Test\n~~ config ~~\n~~ init ~~\n~~ productions ~~")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate restarting the server using the same store
+	restarted := &Web{
+		settings:    webTest.settings,
+		sessionList: SessionList{},
+		store:       webTest.store,
+		runs:        newRunList(),
+	}
+
+	reloaded, err := restarted.lookupSession(session.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded.lookupModel(model.id) == nil {
+		t.Error("model was not reloaded")
+	}
+
+	webTest.clearSessions()
+}
+
+func TestSessionReloadedConcurrently(t *testing.T) {
+	session, err := webTest.newSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = webTest.loadModel(session.id, "~~ model ~~\nname: Test\n~~ config ~~\n~~ init ~~\n~~ productions ~~")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := &Web{
+		settings:    webTest.settings,
+		sessionList: SessionList{},
+		store:       webTest.store,
+		runs:        newRunList(),
+	}
+
+	const lookups = 8
+
+	var wg sync.WaitGroup
+	sessions := make([]*Session, lookups)
+
+	for i := 0; i < lookups; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			sessions[i], _ = restarted.lookupSession(session.id)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, reloaded := range sessions {
+		if reloaded == nil || reloaded != sessions[0] {
+			t.Fatal("expected every lookup to return the same session")
+		}
+	}
+
+	if len(restarted.sessionList) != 1 {
+		t.Errorf("expected session to be added once, got %d", len(restarted.sessionList))
+	}
+
+	webTest.clearSessions()
+}
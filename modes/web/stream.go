@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/runoptions"
+)
+
+// runStream sends the output of a run to the client as server-sent events as it is produced.
+// It sends these events:
+//
+//	issues: issues with the model or request {"issues": [...]}
+//	output: one line of a framework's output {"framework": "...", "line": "..."}
+//	trace:  one trace event from a framework {"framework": "...", "event": {...}}
+//	result: a framework has finished {"framework": "...", "result": {...}}
+//	done:   all frameworks have finished {}
+type runStream struct {
+	rw      http.ResponseWriter
+	flusher http.Flusher
+	mutex   sync.Mutex
+	started bool // set when the first event is sent
+
+	model *actr.Model
+
+	// adjustResult is called to modify each result before it is sent (may be nil)
+	adjustResult func(result *frameworkRunResult)
+
+	results frameworkRunResultMap // results which have been sent
+}
+
+// frameworkSink sends one framework's output to the stream
+type frameworkSink struct {
+	stream *runStream
+	name   string
+}
+
+type outputEvent struct {
+	Framework string `json:"framework"`
+	Line      string `json:"line"`
+}
+
+type traceEvent struct {
+	Framework string               `json:"framework"`
+	Event     framework.TraceEvent `json:"event"`
+}
+
+type resultEvent struct {
+	Framework string             `json:"framework"`
+	Result    frameworkRunResult `json:"result"`
+}
+
+type issuesEvent struct {
+	Issues issues.IssueList `json:"issues"`
+}
+
+// newRunStream creates an event stream on the response. The stream's headers are written when the
+// first event is sent, so until then the handler may still respond with an HTTP error.
+func newRunStream(rw http.ResponseWriter) (stream *runStream, err error) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		return nil, ErrStreamingNotSupported
+	}
+
+	return &runStream{
+		rw:      rw,
+		flusher: flusher,
+		results: frameworkRunResultMap{},
+	}, nil
+}
+
+// send writes one event to the client. If the client has gone away, there's nothing we can do
+// about it, so write errors are ignored.
+func (s *runStream) send(event string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(issuesEvent{Issues: issuesFromError(err)})
+		event = "issues"
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.started {
+		s.rw.Header().Set("Content-Type", "text/event-stream")
+		s.rw.Header().Set("Cache-Control", "no-cache")
+		s.rw.Header().Set("Connection", "keep-alive")
+		s.rw.WriteHeader(http.StatusOK)
+
+		s.started = true
+	}
+
+	_, _ = fmt.Fprintf(s.rw, "event: %s\ndata: %s\n\n", event, encoded)
+	s.flusher.Flush()
+}
+
+// sendIssues sends the issues (if there are any)
+func (s *runStream) sendIssues(list issues.IssueList) {
+	if len(list) == 0 {
+		return
+	}
+
+	s.send("issues", issuesEvent{Issues: list})
+}
+
+// sendError sends an error as an issue and ends the stream
+func (s *runStream) sendError(err error) {
+	s.sendIssues(issuesFromError(err))
+	s.done()
+}
+
+func (s *runStream) done() {
+	s.send("done", struct{}{})
+}
+
+// run runs the model on the frameworks and streams the results.
+func (s *runStream) run(ctx context.Context, w *Web, model *actr.Model, options *runoptions.Options) {
+	s.model = model
+
+	frameworks, cleanup, err := w.frameworksFor(options)
+	if err != nil {
+		s.sendError(err)
+		return
+	}
+	defer cleanup()
+
+	framework.StreamModelOnFrameworks(ctx, model, options, frameworks, s)
+
+	s.done()
+}
+
+// Sink implements framework.RunStream.
+func (s *runStream) Sink(frameworkName string) framework.OutputSink {
+	return frameworkSink{stream: s, name: frameworkName}
+}
+
+// Finished implements framework.RunStream.
+func (s *runStream) Finished(frameworkName string, run framework.FrameworkRun) {
+	result := newFrameworkRunResult(s.model, run)
+
+	if s.adjustResult != nil {
+		s.adjustResult(&result)
+	}
+
+	s.mutex.Lock()
+	s.results[frameworkName] = result
+	s.mutex.Unlock()
+
+	s.send("result", resultEvent{Framework: frameworkName, Result: result})
+}
+
+func (f frameworkSink) OutputLine(line string) {
+	f.stream.send("output", outputEvent{Framework: f.name, Line: line})
+}
+
+func (f frameworkSink) TraceEvent(event framework.TraceEvent) {
+	f.stream.send("trace", traceEvent{Framework: f.name, Event: event})
+}
+
+func issuesFromError(err error) issues.IssueList {
+	return issues.IssueList{
+		{
+			Level: "error",
+			Text:  err.Error(),
+		},
+	}
+}
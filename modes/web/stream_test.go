@@ -0,0 +1,119 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testEvent struct {
+	name string
+	data string
+}
+
+// parseEvents splits a server-sent event stream into its events
+func parseEvents(t *testing.T, body string) (events []testEvent) {
+	t.Helper()
+
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		name, data, found := strings.Cut(block, "\n")
+		if !found || !strings.HasPrefix(name, "event: ") || !strings.HasPrefix(data, "data: ") {
+			t.Fatalf("unexpected event: %q", block)
+		}
+
+		events = append(events, testEvent{
+			name: strings.TrimPrefix(name, "event: "),
+			data: strings.TrimPrefix(data, "data: "),
+		})
+	}
+
+	return
+}
+
+func TestRunModelStreamHandler(t *testing.T) {
+	src := `~~ model ~~
+	name: Test
+	~~ config ~~
+	chunks { [count: value] }
+	~~ init ~~
+	goal [count: 1]
+	~~ productions ~~
+	start {
+		match { goal [count: ?value] }
+		do {
+			print ?value
+			stop
+		}
+	}`
+
+	body, err := json.Marshal(map[string]any{
+		"amod":    src,
+		"options": map[string]any{"frameworks": []string{"native"}, "traceEvents": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := http.NewRequest("POST", "/run/stream", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.runModelStreamHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	if contentType := responseRecorder.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected event stream, got '%s'", contentType)
+	}
+
+	counts := map[string]int{}
+	var result resultEvent
+
+	events := parseEvents(t, responseRecorder.Body.String())
+	for _, event := range events {
+		counts[event.name]++
+
+		if event.name == "result" {
+			err = json.Unmarshal([]byte(event.data), &result)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if counts["output"] == 0 || counts["trace"] == 0 {
+		t.Errorf("expected output and trace events, got %v", counts)
+	}
+
+	if counts["result"] != 1 || result.Framework != "native" || result.Result.Output == nil {
+		t.Errorf("expected one result from native, got %+v", result)
+	}
+
+	if events[len(events)-1].name != "done" {
+		t.Errorf("expected stream to end with 'done', got '%s'", events[len(events)-1].name)
+	}
+}
+
+func TestRunModelStreamHandlerErrors(t *testing.T) {
+	body := []byte(`{"amod": "~~ model ~~\nname: Test\n~~ config ~~\n~~ init ~~\n~~ productions ~~\nfoo {"}`)
+
+	request, err := http.NewRequest("POST", "/run/stream", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.runModelStreamHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	events := parseEvents(t, responseRecorder.Body.String())
+	if len(events) != 2 || events[0].name != "issues" || events[1].name != "done" {
+		t.Errorf("expected issues then done, got %+v", events)
+	}
+}
@@ -2,13 +2,16 @@
 package web
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,6 +26,7 @@ import (
 	"github.com/asmaloney/gactar/framework"
 
 	"github.com/asmaloney/gactar/util/cli"
+	"github.com/asmaloney/gactar/util/filesystem"
 	"github.com/asmaloney/gactar/util/issues"
 	"github.com/asmaloney/gactar/util/runoptions"
 	"github.com/asmaloney/gactar/util/validate"
@@ -44,8 +48,16 @@ type Web struct {
 	examples *embed.FS
 	port     int
 
-	sessionList      SessionList
-	currentSessionID int
+	sessionMutex sync.Mutex // protects sessionList
+	sessionList  SessionList
+	store        *sessionStore // persists sessions, models, and run results
+
+	runs *runList  // runs in progress which may be cancelled
+	jobs *jobQueue // limits the number of framework processes running at once
+
+	endpoints []endpoint       // registered API endpoints
+	schemas   *schemaGenerator // schemas of the request & response types
+	openAPI   map[string]any   // OpenAPI document describing the endpoints
 }
 
 type frameworkRunResult struct {
@@ -56,8 +68,10 @@ type frameworkRunResult struct {
 	Code     *string `json:"code,omitempty"`     // actual code which was run
 	Output   *string `json:"output,omitempty"`   // output of run (stdout + stderr)
 
-	SessionID *int `json:"sessionID,omitempty"`
-	ModelID   *int `json:"modelID,omitempty"`
+	Trace *framework.Trace `json:"trace,omitempty"` // trace events (if "traceEvents" option was set)
+
+	SessionID *string `json:"sessionID,omitempty"`
+	ModelID   *string `json:"modelID,omitempty"`
 }
 
 type frameworkRunResultMap map[string]frameworkRunResult
@@ -67,18 +81,82 @@ type runResult struct {
 	Results frameworkRunResultMap `json:"results,omitempty"`
 }
 
-func Initialize(settings *cli.Settings, port int, examples *embed.FS) (w *Web, err error) {
+func Initialize(settings *cli.Settings, port int, queue QueueOptions, examples *embed.FS) (w *Web, err error) {
+	if queue.MaxJobs < 1 {
+		return nil, ErrInvalidMaxJobs
+	}
+
+	if queue.MaxQueued < 0 {
+		return nil, ErrInvalidMaxQueued
+	}
+
+	tempPath, err := cli.CreateTempDir(settings)
+	if err != nil {
+		return
+	}
+
+	store, err := newSessionStore(filepath.Join(tempPath, "sessions"))
+	if err != nil {
+		return
+	}
+
 	w = &Web{
-		settings:         settings,
-		examples:         examples,
-		port:             port,
-		sessionList:      SessionList{},
-		currentSessionID: 1,
+		settings:    settings,
+		examples:    examples,
+		port:        port,
+		sessionList: SessionList{},
+		store:       store,
+		runs:        newRunList(),
+		jobs:        newJobQueue(queue),
+		schemas:     newSchemaGenerator(),
 	}
 
-	http.HandleFunc("/api/version", w.getVersionHandler)
-	http.HandleFunc("/api/frameworks", w.getFrameworksHandler)
-	http.HandleFunc("/api/run", w.runModelHandler)
+	w.handle(endpoint{
+		path:     "/api/version",
+		methods:  []string{http.MethodGet},
+		summary:  "Get the version of gactar",
+		response: versionResponse{},
+		handler:  w.getVersionHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/frameworks",
+		methods:  []string{http.MethodGet},
+		summary:  "Get information about the active frameworks",
+		response: frameworksResponse{},
+		handler:  w.getFrameworksHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/run",
+		methods:  []string{http.MethodPost, http.MethodPut},
+		summary:  "Run an amod file",
+		request:  runRequest{},
+		response: runResult{},
+		handler:  w.runModelHandler,
+	})
+	w.handle(endpoint{
+		path:                "/api/run/stream",
+		methods:             []string{http.MethodPost, http.MethodPut},
+		summary:             "Run an amod file and stream the output as server-sent events",
+		request:             runRequest{},
+		responseType:        "text/event-stream",
+		responseDescription: "Server-sent events (issues, output, result, error, done)",
+		handler:             w.runModelStreamHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/run/cancel",
+		methods:  []string{http.MethodPost, http.MethodPut},
+		summary:  "Cancel a run using its run ID",
+		request:  cancelRunRequest{},
+		response: cancelRunResponse{},
+		handler:  w.cancelRunHandler,
+	})
+	w.handle(endpoint{
+		path:     "/api/openapi.json",
+		methods:  []string{http.MethodGet},
+		summary:  "Get this OpenAPI document",
+		response: map[string]any{},
+		handler:  w.openAPIHandler,
+	})
 	http.HandleFunc("/api/", http.NotFound)
 
 	if examples != nil {
@@ -87,6 +165,10 @@ func Initialize(settings *cli.Settings, port int, examples *embed.FS) (w *Web, e
 
 	initSessions(w)
 	initModels(w)
+	initJobs(w)
+
+	// all the endpoints have been registered, so we can describe them
+	w.openAPI = w.openAPIDocument()
 
 	mainHandler := compressedAssetHandler(&mainAssets, "build")
 	http.HandleFunc("/", mainHandler.ServeHTTP)
@@ -94,7 +176,7 @@ func Initialize(settings *cli.Settings, port int, examples *embed.FS) (w *Web, e
 	return
 }
 
-func (w Web) Start() (err error) {
+func (w *Web) Start() (err error) {
 	fmt.Printf("Serving gactar on ")
 	fmt.Println(gchalk.WithBlue().Underline(fmt.Sprintf("http://localhost:%d", w.port)))
 
@@ -111,21 +193,21 @@ func (w Web) Start() (err error) {
 	return
 }
 
-func (Web) getVersionHandler(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		Version string `json:"version"`
-	}
+type versionResponse struct {
+	Version string `json:"version"`
+}
 
-	encodeResponse(rw, response{
+func (*Web) getVersionHandler(rw http.ResponseWriter, req *http.Request) {
+	encodeResponse(rw, versionResponse{
 		Version: version.BuildVersion,
 	})
 }
 
-func (w Web) getFrameworksHandler(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		Frameworks framework.InfoList `json:"frameworks"`
-	}
+type frameworksResponse struct {
+	Frameworks framework.InfoList `json:"frameworks"`
+}
 
+func (w *Web) getFrameworksHandler(rw http.ResponseWriter, req *http.Request) {
 	frameworks := framework.InfoList{}
 
 	for _, framework := range w.settings.ActiveFrameworks {
@@ -137,56 +219,58 @@ func (w Web) getFrameworksHandler(rw http.ResponseWriter, req *http.Request) {
 		return frameworks[i].Name < frameworks[j].Name
 	})
 
-	encodeResponse(rw, response{
+	encodeResponse(rw, frameworksResponse{
 		Frameworks: frameworks,
 	})
 }
 
-func (w Web) runModelHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		AMODFile string `json:"amod"` // text of an amod file
-		Goal     string `json:"goal"` // initial goal
+// runRequest is the body of a request to run an amod file
+type runRequest struct {
+	AMODFile string `json:"amod"`            // text of an amod file
+	Goal     string `json:"goal,omitempty"`  // initial goal
+	RunID    string `json:"runID,omitempty"` // (optional) ID used to cancel the run
 
-		Options *runOptionsJSON `json:"options,omitempty"`
-	}
+	Options *runOptionsJSON `json:"options,omitempty"`
+}
 
-	var data request
+func (w *Web) runModelHandler(rw http.ResponseWriter, req *http.Request) {
+	var data runRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	model, log, err := amod.GenerateModel(data.AMODFile)
+	model, options, log, err := w.prepareRun(data)
 	if err != nil {
-		encodeIssueResponse(rw, log)
+		if log.HasError() {
+			encodeIssueResponse(rw, log)
+		} else {
+			encodeErrorResponse(rw, err)
+		}
 		return
 	}
 
-	options, err := w.actrOptionsFromJSON(&model.DefaultParams, data.Options)
+	ctx, finish, err := w.runs.start(req.Context(), data.RunID)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
+	defer finish()
 
-	initialGoal := strings.TrimSpace(data.Goal)
-
-	options.InitialBuffers = runoptions.InitialBuffers{
-		"goal": initialGoal,
+	release, err := w.waitToRun(ctx, options.Frameworks)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
 	}
+	defer release()
 
-	validate.Goal(model, initialGoal, log)
-
-	// ensure temp dir exists
-	// https://github.com/asmaloney/gactar/issues/103
-	_, err = cli.CreateTempDir(w.settings)
+	resultMap, err := w.runModel(ctx, model, options)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	resultMap := w.runModel(model, options)
-
 	rr := runResult{
 		Issues:  log.AllIssues(),
 		Results: resultMap,
@@ -201,85 +285,170 @@ func (w Web) runModelHandler(rw http.ResponseWriter, req *http.Request) {
 	encodeResponse(rw, json.RawMessage(string(results)))
 }
 
-func (w Web) runModel(model *actr.Model, options *runoptions.Options) (resultMap frameworkRunResultMap) {
-	resultMap = make(frameworkRunResultMap, len(options.Frameworks))
+// runModelStreamHandler runs the model like runModelHandler, but streams the output as it is
+// produced using server-sent events. See runStream for the events.
+func (w *Web) runModelStreamHandler(rw http.ResponseWriter, req *http.Request) {
+	stream, err := newRunStream(rw)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
 
-	var wg sync.WaitGroup
-	var mutex = &sync.Mutex{}
+	var data runRequest
+	err = decodeBody(req, &data)
+	if err != nil {
+		stream.sendError(err)
+		return
+	}
 
-	for _, name := range options.Frameworks {
-		f := w.settings.ActiveFrameworks[name]
+	model, options, log, err := w.prepareRun(data)
+	if err != nil {
+		if log.HasError() {
+			stream.sendIssues(log.AllIssues())
+			stream.done()
+		} else {
+			stream.sendError(err)
+		}
+		return
+	}
 
-		wg.Add(1)
+	ctx, finish, err := w.runs.start(req.Context(), data.RunID)
+	if err != nil {
+		stream.sendError(err)
+		return
+	}
+	defer finish()
 
-		go func(wg *sync.WaitGroup, name string, f framework.Framework) {
-			defer wg.Done()
+	// Nothing has been sent yet, so if the queue is full we can still respond with an HTTP error.
+	t, err := w.enqueueRun(options.Frameworks)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
 
-			result := &framework.RunResult{}
+	err = t.wait(ctx)
+	if err != nil {
+		stream.sendError(framework.ErrRunCancelled)
+		return
+	}
+	defer t.release()
 
-			log := f.ValidateModel(model)
-			if !log.HasError() {
-				r, err := runModelOnFramework(model, options, f)
-				if err != nil {
-					log.Error(nil, err.Error())
-				}
-				if r != nil {
-					result = r
-				}
-			}
+	stream.sendIssues(log.AllIssues())
+	stream.run(ctx, w, model, options)
+}
 
-			frameworkResult := frameworkRunResult{
-				ModelName: model.Name,
-			}
+// prepareRun generates the model and run options from the request. The log contains any issues
+// with the model - if it has errors, err is set as well.
+func (w *Web) prepareRun(data runRequest) (model *actr.Model, options *runoptions.Options, log *issues.Log, err error) {
+	model, log, err = amod.GenerateModel(data.AMODFile)
+	if err != nil {
+		return
+	}
 
-			mutex.Lock()
+	options, err = w.actrOptionsFromJSON(&model.DefaultParams, data.Options)
+	if err != nil {
+		return
+	}
 
-			if log.HasIssues() {
-				all := log.AllIssues()
-				frameworkResult.Issues = &all
-			}
+	initialGoal := strings.TrimSpace(data.Goal)
 
-			if result.FileName != "" {
-				frameworkResult.FilePath = &result.FileName
-			}
+	options.InitialBuffers = runoptions.InitialBuffers{
+		"goal": initialGoal,
+	}
 
-			if len(result.GeneratedCode) > 0 {
-				codeStr := string(result.GeneratedCode)
-				frameworkResult.Code = &codeStr
+	validate.Goal(model, initialGoal, log)
 
-			}
-			if len(result.Output) > 0 {
-				outputStr := string(result.Output)
-				frameworkResult.Output = &outputStr
+	return
+}
 
-			}
+// frameworksFor returns new instances of the active frameworks which were requested in the
+// options. Each run gets its own instances and its own directory for their files so concurrent
+// runs don't interfere with each other. The caller must call "cleanup" when the run is finished
+// to remove the directory.
+func (w *Web) frameworksFor(options *runoptions.Options) (frameworks framework.List, cleanup func(), err error) {
+	// This also re-creates the temp dir if it was removed while we were running.
+	// https://github.com/asmaloney/gactar/issues/103
+	runPath := filepath.Join(w.settings.TempPath, "runs", newID())
 
-			resultMap[name] = frameworkResult
+	err = filesystem.CreateDir(runPath)
+	if err != nil {
+		return
+	}
 
-			mutex.Unlock()
-		}(&wg, name, f)
+	cleanup = func() {
+		_ = os.RemoveAll(runPath)
 	}
-	wg.Wait()
+
+	frameworks = w.newInstances(options.Frameworks, runPath)
 
 	return
 }
 
-func runModelOnFramework(model *actr.Model, options *runoptions.Options, f framework.Framework) (result *framework.RunResult, err error) {
-	if model == nil {
-		err = ErrNoModel
-		return
+// newInstances returns new instances of the named active frameworks which write their files to "path".
+func (w *Web) newInstances(names []string, path string) (frameworks framework.List) {
+	frameworks = framework.List{}
+
+	for _, name := range names {
+		f, ok := w.settings.ActiveFrameworks[name]
+		if ok {
+			frameworks[name] = f.NewInstance(path)
+		}
 	}
 
-	err = f.SetModel(model)
+	return
+}
+
+func (w *Web) runModel(ctx context.Context, model *actr.Model, options *runoptions.Options) (resultMap frameworkRunResultMap, err error) {
+	frameworks, cleanup, err := w.frameworksFor(options)
 	if err != nil {
 		return
 	}
+	defer cleanup()
 
-	result, err = f.Run(options)
-	if err != nil {
+	runMap := framework.RunModelOnFrameworks(ctx, model, options, frameworks)
+
+	resultMap = make(frameworkRunResultMap, len(runMap))
+
+	for name, run := range runMap {
+		resultMap[name] = newFrameworkRunResult(model, run)
+	}
+
+	return
+}
+
+// newFrameworkRunResult converts the result of running a model on a framework for our response
+func newFrameworkRunResult(model *actr.Model, run framework.FrameworkRun) (frameworkResult frameworkRunResult) {
+	frameworkResult = frameworkRunResult{
+		ModelName: model.Name,
+	}
+
+	if run.Log.HasIssues() {
+		all := run.Log.AllIssues()
+		frameworkResult.Issues = &all
+	}
+
+	result := run.Result
+	if result == nil {
 		return
 	}
 
+	if result.FileName != "" {
+		frameworkResult.FilePath = &result.FileName
+	}
+
+	if len(result.GeneratedCode) > 0 {
+		codeStr := string(result.GeneratedCode)
+		frameworkResult.Code = &codeStr
+
+	}
+	if len(result.Output) > 0 {
+		outputStr := string(result.Output)
+		frameworkResult.Output = &outputStr
+
+	}
+
+	frameworkResult.Trace = result.Trace
+
 	return
 }
 
@@ -306,31 +475,103 @@ func encodeResponse(rw http.ResponseWriter, v interface{}) {
 	}
 }
 
+// encodeErrorResponse responds with the error as a list of issues and an HTTP status code
+// appropriate for the error (see errorStatus).
 func encodeErrorResponse(rw http.ResponseWriter, err error) {
-	errResponse := runResult{
-		Issues: issues.IssueList{
-			{
-				Level: "error",
-				Text:  err.Error(),
-			},
-		},
+	// If the queue is full, tell the client when to try again
+	var queueFull *ErrQueueFull
+	if errors.As(err, &queueFull) {
+		rw.Header().Set("Retry-After", strconv.Itoa(int(queueFull.RetryAfter.Seconds())))
 	}
 
-	encodeErr := json.NewEncoder(rw).Encode(errResponse)
-	if encodeErr != nil {
-		http.Error(rw, encodeErr.Error(), http.StatusInternalServerError)
+	errResponse := runResult{Issues: issuesFromError(err)}
+
+	// list each problem with an invalid request separately
+	var invalidRequest *ErrInvalidRequest
+	if errors.As(err, &invalidRequest) {
+		errResponse.Issues = issues.IssueList{}
+
+		for _, problem := range invalidRequest.Problems {
+			errResponse.Issues = append(errResponse.Issues, issues.Issue{
+				Level: "error",
+				Text:  problem,
+			})
+		}
 	}
+
+	encodeStatusResponse(rw, errorStatus(err), errResponse)
 }
 
+// encodeIssueResponse responds with the issues found in a model which could not be generated.
 func encodeIssueResponse(rw http.ResponseWriter, log *issues.Log) {
 	errResponse := runResult{Issues: log.AllIssues()}
 
-	encodeErr := json.NewEncoder(rw).Encode(errResponse)
+	encodeStatusResponse(rw, http.StatusUnprocessableEntity, errResponse)
+}
+
+func encodeStatusResponse(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(status)
+
+	encodeErr := json.NewEncoder(rw).Encode(v)
 	if encodeErr != nil {
 		http.Error(rw, encodeErr.Error(), http.StatusInternalServerError)
 	}
 }
 
+// errorStatus returns the HTTP status code for an error.
+func errorStatus(err error) int {
+	var (
+		queueFull         *ErrQueueFull
+		methodNotAllowed  *ErrMethodNotAllowed
+		invalidRequest    *ErrInvalidRequest
+		invalidSession    *ErrInvalidSessionID
+		invalidModel      *ErrInvalidModelID
+		invalidJob        *ErrInvalidJobID
+		invalidRun        *ErrInvalidRunID
+		runIDInUse        *ErrRunIDInUse
+		generationFailed  *framework.ErrModelGenerationFailed
+		invalidLogLevel   runoptions.ErrInvalidLogLevel
+		invalidFramework  runoptions.ErrInvalidFrameworkName
+		inactiveFramework runoptions.ErrFrameworkNotActive
+		syntaxError       *json.SyntaxError
+		typeError         *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &queueFull):
+		return http.StatusTooManyRequests
+
+	case errors.As(err, &methodNotAllowed):
+		return http.StatusMethodNotAllowed
+
+	case errors.As(err, &invalidSession),
+		errors.As(err, &invalidModel),
+		errors.As(err, &invalidJob),
+		errors.As(err, &invalidRun):
+		return http.StatusNotFound
+
+	case errors.As(err, &runIDInUse),
+		errors.Is(err, framework.ErrRunCancelled):
+		return http.StatusConflict
+
+	case errors.As(err, &generationFailed):
+		return http.StatusUnprocessableEntity
+
+	case errors.Is(err, ErrEmptyRequestBody),
+		errors.Is(err, ErrInvalidTimeout),
+		errors.As(err, &invalidRequest),
+		errors.As(err, &invalidLogLevel),
+		errors.As(err, &invalidFramework),
+		errors.As(err, &inactiveFramework),
+		errors.As(err, &syntaxError),
+		errors.As(err, &typeError):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
 // assetHandler returns an http.Handler that will serve files from
 // the given embed.FS.  When locating a file, it will optionally strip
 // and append a prefix to the filesystem lookup.
@@ -1,7 +1,16 @@
 package web
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/asmaloney/gactar/util/cli"
@@ -11,15 +20,212 @@ import (
 var webTest *Web = nil
 
 func TestMain(m *testing.M) {
-	settings := &cli.Settings{}
+	tempPath, err := os.MkdirTemp("", "gactar-web-test")
+	if err != nil {
+		panic(err)
+	}
+
+	settings := &cli.Settings{TempPath: tempPath}
 
 	frameworks := frameworkutil.CreateFrameworks(settings, nil)
 
 	settings.ActiveFrameworks = frameworks
 
-	webTest, _ = Initialize(settings, 8181, nil)
+	webTest, _ = Initialize(settings, 8181, QueueOptions{MaxJobs: 4, MaxQueued: 16}, nil)
 
 	exitVal := m.Run()
 
+	os.RemoveAll(tempPath)
+
 	os.Exit(exitVal)
 }
+
+// concurrentModel returns a model named "Test<n>" which prints "n".
+func concurrentModel(n int) string {
+	return fmt.Sprintf(`~~ model ~~
+	name: Test%[1]d
+	~~ config ~~
+	chunks { [count: value] }
+	~~ init ~~
+	goal [count: %[1]d]
+	~~ productions ~~
+	start {
+		match { goal [count: ?value] }
+		do {
+			print ?value
+			stop
+		}
+	}`, n)
+}
+
+// postJSON sends "body" to the server and decodes the response into "result".
+func postJSON(client *http.Client, url string, body any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// checkConcurrentResult checks that the result is from the model which was run.
+func checkConcurrentResult(n int, result frameworkRunResult) error {
+	if result.ModelName != fmt.Sprintf("Test%d", n) {
+		return fmt.Errorf("run %d: got result for model %q", n, result.ModelName)
+	}
+
+	if result.Output == nil || !strings.Contains(*result.Output, fmt.Sprintf("%d\n", n)) {
+		return fmt.Errorf("run %d: unexpected output %v", n, result.Output)
+	}
+
+	if result.Code == nil || !strings.Contains(*result.Code, fmt.Sprintf("Test%d", n)) {
+		return fmt.Errorf("run %d: got code for another model", n)
+	}
+
+	return nil
+}
+
+func TestRunRemovesFiles(t *testing.T) {
+	settings := *webTest.settings
+	settings.TempPath = t.TempDir()
+
+	w := &Web{
+		settings: &settings,
+		runs:     newRunList(),
+	}
+
+	model, err := generateModel(concurrentModel(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := model.DefaultParams
+	options.Frameworks = []string{"native"}
+
+	results, err := w.runModel(context.Background(), model, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkConcurrentResult(1, results["native"])
+	if err != nil {
+		t.Error(err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(settings.TempPath, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("expected run directory to be removed, found %d entries", len(entries))
+	}
+}
+
+// Run with "go test -race" to check for data races.
+func TestConcurrentRuns(t *testing.T) {
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+
+	const numRuns = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, numRuns)
+
+	for i := 0; i < numRuns; i++ {
+		wg.Add(1)
+
+		go func(n int) {
+			defer wg.Done()
+
+			request := map[string]any{
+				"amod":    concurrentModel(n),
+				"options": map[string]any{"frameworks": []string{"native"}},
+			}
+
+			var response runResult
+			err := postJSON(server.Client(), server.URL+"/api/run", request, &response)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			errs <- checkConcurrentResult(n, response.Results["native"])
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// Run with "go test -race" to check for data races.
+func TestConcurrentSessionRuns(t *testing.T) {
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+
+	const numRuns = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, numRuns)
+
+	for i := 0; i < numRuns; i++ {
+		wg.Add(1)
+
+		go func(n int) {
+			defer wg.Done()
+
+			session, err := webTest.newSession()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			model, err := webTest.loadModel(session.id, concurrentModel(n))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			request := map[string]any{
+				"sessionID":   session.id,
+				"modelID":     model.id,
+				"includeCode": true,
+				"options":     map[string]any{"frameworks": []string{"native"}},
+			}
+
+			var response struct {
+				Results frameworkRunResultMap `json:"results"`
+			}
+			err = postJSON(server.Client(), server.URL+"/api/session/runModel", request, &response)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			errs <- checkConcurrentResult(n, response.Results["native"])
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	webTest.clearSessions()
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/asmaloney/gactar/framework"
 
@@ -23,6 +24,8 @@ type Settings struct {
 
 	ActiveFrameworks framework.List // active frameworks (set from the command line)
 
+	Timeout *time.Duration // maximum time to let each run take (nil if not set)
+
 	Version string // the version string for output to command line
 }
 
@@ -2,7 +2,10 @@
 package executil
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os/exec"
 )
 
@@ -18,15 +21,33 @@ func (e ErrExecuteCommand) Error() string {
 	return fmt.Sprintf("execution failed:\n%s", e.Output)
 }
 
+// ExecCommand executes the command and returns its output (stdout + stderr).
 func ExecCommand(name string, arg ...string) (output string, err error) {
-	cmd := exec.Command(name, arg...)
+	return ExecCommandWithOutput(context.Background(), nil, name, arg...)
+}
+
+// ExecCommandWithOutput executes the command and returns its output (stdout + stderr). If "w" is
+// not nil, the output is also written to it as it is produced. If "ctx" is cancelled or times out
+// before the command finishes, the command is killed and the context's error is returned.
+func ExecCommandWithOutput(ctx context.Context, w io.Writer, name string, arg ...string) (output string, err error) {
+	cmd := exec.CommandContext(ctx, name, arg...)
 
 	if debugging {
 		fmt.Printf("Executing: %s\n", cmd.String())
 	}
 
-	outputBytes, err := cmd.CombinedOutput()
-	output = string(outputBytes)
+	var outputBuffer bytes.Buffer
+
+	var combined io.Writer = &outputBuffer
+	if w != nil {
+		combined = io.MultiWriter(&outputBuffer, w)
+	}
+
+	cmd.Stdout = combined
+	cmd.Stderr = combined
+
+	err = cmd.Run()
+	output = outputBuffer.String()
 
 	if debugging {
 		if err != nil {
@@ -36,6 +57,11 @@ func ExecCommand(name string, arg ...string) (output string, err error) {
 		}
 	}
 
+	if ctx.Err() != nil {
+		err = ctx.Err()
+		return
+	}
+
 	if err != nil {
 		err = &ErrExecuteCommand{Output: output}
 		return
@@ -52,6 +52,12 @@ func DirExists(path string) bool {
 	return !os.IsNotExist(err) && stat.IsDir()
 }
 
+// FileExists returns true if the given path exists and is not a directory.
+func FileExists(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && !stat.IsDir()
+}
+
 // CreateDir creates a directory if it does not exist.
 func CreateDir(path string) (err error) {
 	err = os.MkdirAll(path, 0750)
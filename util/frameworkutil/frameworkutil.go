@@ -5,6 +5,7 @@ package frameworkutil
 import (
 	"github.com/asmaloney/gactar/framework"
 	"github.com/asmaloney/gactar/framework/ccm_pyactr"
+	"github.com/asmaloney/gactar/framework/native"
 	"github.com/asmaloney/gactar/framework/pyactr"
 	"github.com/asmaloney/gactar/framework/vanilla_actr"
 
@@ -31,6 +32,9 @@ func CreateFrameworks(settings *cli.Settings, names []string) (list framework.Li
 		case "ccm":
 			fw, err = ccm_pyactr.New(settings.TempPath)
 
+		case "native":
+			fw, err = native.New(settings.TempPath)
+
 		case "pyactr":
 			fw, err = pyactr.New(settings.TempPath)
 
@@ -1,4 +1,5 @@
-// Package lisp implements functions for working with the Clozure Common Lisp executables.
+// Package lisp implements functions for working with the Clozure Common Lisp executables
+// and for reading Lisp source code.
 package lisp
 
 import (
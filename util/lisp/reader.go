@@ -0,0 +1,324 @@
+package lisp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NodeType is the type of a node read from Lisp source.
+type NodeType int
+
+const (
+	NodeList NodeType = iota
+	NodeSymbol
+	NodeString
+	NodeNumber
+)
+
+// Node is an s-expression read from Lisp source.
+type Node struct {
+	Type NodeType
+
+	Value    string  // symbol name (as written), string contents, or number text
+	Children []*Node // list contents
+
+	Line   int
+	Column int
+}
+
+// ErrSyntax is returned when the Lisp source cannot be read.
+type ErrSyntax struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf("%s (line %d, col %d)", e.Message, e.Line, e.Column)
+}
+
+var numberRegex = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eEdD][+-]?\d+)?$`)
+
+// IsList checks if the node is a list.
+func (n Node) IsList() bool {
+	return n.Type == NodeList
+}
+
+// IsSymbol checks if the node is a symbol. Lisp symbols are case-insensitive.
+func (n Node) IsSymbol(name string) bool {
+	return n.Type == NodeSymbol && strings.EqualFold(n.Value, name)
+}
+
+// Head returns the (lowercased) symbol at the start of a list or "" if there isn't one.
+func (n Node) Head() string {
+	if n.Type != NodeList || len(n.Children) == 0 || n.Children[0].Type != NodeSymbol {
+		return ""
+	}
+
+	return strings.ToLower(n.Children[0].Value)
+}
+
+// Unquote returns the quoted node if this is (quote x), otherwise it returns the node itself.
+func (n *Node) Unquote() *Node {
+	if n.Head() == "quote" && len(n.Children) == 2 {
+		return n.Children[1]
+	}
+
+	return n
+}
+
+func (n Node) String() string {
+	switch n.Type {
+	case NodeList:
+		items := make([]string, len(n.Children))
+		for i, child := range n.Children {
+			items[i] = child.String()
+		}
+		return "(" + strings.Join(items, " ") + ")"
+
+	case NodeString:
+		return fmt.Sprintf("%q", n.Value)
+	}
+
+	return n.Value
+}
+
+// reader tracks our position in the source while reading.
+type reader struct {
+	src    []rune
+	pos    int
+	line   int
+	column int
+}
+
+// Read reads all the top-level s-expressions from Lisp source.
+// Comments are skipped and quoted items ('x) are returned as (quote x).
+func Read(src string) (nodes []*Node, err error) {
+	r := &reader{
+		src:    []rune(src),
+		line:   1,
+		column: 1,
+	}
+
+	for {
+		err = r.skipSpaceAndComments()
+		if err != nil {
+			return
+		}
+
+		if r.atEnd() {
+			return
+		}
+
+		var node *Node
+		node, err = r.readNode()
+		if err != nil {
+			return
+		}
+
+		nodes = append(nodes, node)
+	}
+}
+
+func (r reader) atEnd() bool {
+	return r.pos >= len(r.src)
+}
+
+func (r reader) peek() rune {
+	if r.atEnd() {
+		return 0
+	}
+
+	return r.src[r.pos]
+}
+
+func (r *reader) next() rune {
+	c := r.src[r.pos]
+	r.pos++
+
+	if c == '\n' {
+		r.line++
+		r.column = 1
+	} else {
+		r.column++
+	}
+
+	return c
+}
+
+func (r reader) errorf(format string, a ...any) error {
+	return ErrSyntax{Line: r.line, Column: r.column, Message: fmt.Sprintf(format, a...)}
+}
+
+func (r *reader) skipSpaceAndComments() error {
+	for !r.atEnd() {
+		c := r.peek()
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			r.next()
+
+		case c == ';':
+			for !r.atEnd() && r.peek() != '\n' {
+				r.next()
+			}
+
+		case c == '#' && r.pos+1 < len(r.src) && r.src[r.pos+1] == '|':
+			line, column := r.line, r.column
+			r.next()
+			r.next()
+
+			depth := 1
+			for depth > 0 {
+				if r.atEnd() {
+					return ErrSyntax{Line: line, Column: column, Message: "unterminated block comment"}
+				}
+
+				c := r.next()
+				switch {
+				case c == '|' && r.peek() == '#':
+					r.next()
+					depth--
+				case c == '#' && r.peek() == '|':
+					r.next()
+					depth++
+				}
+			}
+
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (r *reader) readNode() (node *Node, err error) {
+	line, column := r.line, r.column
+
+	switch c := r.peek(); {
+	case c == '(':
+		r.next()
+		node = &Node{Type: NodeList, Line: line, Column: column}
+
+		for {
+			err = r.skipSpaceAndComments()
+			if err != nil {
+				return
+			}
+
+			if r.atEnd() {
+				return nil, ErrSyntax{Line: line, Column: column, Message: "missing closing parenthesis"}
+			}
+
+			if r.peek() == ')' {
+				r.next()
+				return
+			}
+
+			var child *Node
+			child, err = r.readNode()
+			if err != nil {
+				return
+			}
+
+			node.Children = append(node.Children, child)
+		}
+
+	case c == ')':
+		return nil, r.errorf("unexpected closing parenthesis")
+
+	case c == '\'' || c == '`':
+		r.next()
+
+		var quoted *Node
+		quoted, err = r.readQuoted()
+		if err != nil {
+			return
+		}
+
+		quote := &Node{Type: NodeSymbol, Value: "quote", Line: line, Column: column}
+		node = &Node{Type: NodeList, Children: []*Node{quote, quoted}, Line: line, Column: column}
+
+	case c == '#' && r.pos+1 < len(r.src) && r.src[r.pos+1] == '\'':
+		// function quote - #'foo
+		r.next()
+		r.next()
+
+		var quoted *Node
+		quoted, err = r.readQuoted()
+		if err != nil {
+			return
+		}
+
+		quote := &Node{Type: NodeSymbol, Value: "function", Line: line, Column: column}
+		node = &Node{Type: NodeList, Children: []*Node{quote, quoted}, Line: line, Column: column}
+
+	case c == '"':
+		r.next()
+		value := strings.Builder{}
+
+		for {
+			if r.atEnd() {
+				return nil, ErrSyntax{Line: line, Column: column, Message: "unterminated string"}
+			}
+
+			c := r.next()
+			if c == '"' {
+				break
+			}
+
+			if c == '\\' && !r.atEnd() {
+				c = r.next()
+			}
+
+			value.WriteRune(c)
+		}
+
+		node = &Node{Type: NodeString, Value: value.String(), Line: line, Column: column}
+
+	default:
+		value := strings.Builder{}
+
+		for !r.atEnd() && !isDelimiter(r.peek()) {
+			value.WriteRune(r.next())
+		}
+
+		text := value.String()
+		if text == "" {
+			return nil, r.errorf("unexpected character %q", c)
+		}
+
+		nodeType := NodeSymbol
+		if numberRegex.MatchString(text) {
+			nodeType = NodeNumber
+		}
+
+		node = &Node{Type: nodeType, Value: text, Line: line, Column: column}
+	}
+
+	return
+}
+
+func (r *reader) readQuoted() (*Node, error) {
+	err := r.skipSpaceAndComments()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.atEnd() {
+		return nil, r.errorf("nothing to quote")
+	}
+
+	return r.readNode()
+}
+
+func isDelimiter(c rune) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '(', ')', '"', ';', '\'':
+		return true
+	}
+
+	return false
+}
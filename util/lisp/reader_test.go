@@ -0,0 +1,71 @@
+package lisp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	src := `; a comment
+(define-model test
+  #| block
+     comment |#
+  (sgp :lf 0.5)
+  (p start =goal> state "go" ==> !stop!))
+(goal-focus 'first)`
+
+	nodes, err := Read(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("Incorrect number of nodes: expected 2, got %d", len(nodes))
+	}
+
+	model := nodes[0]
+	if model.Head() != "define-model" || len(model.Children) != 4 {
+		t.Errorf("Incorrect model: %s", model)
+	}
+
+	if model.Line != 2 || model.Column != 1 {
+		t.Errorf("Incorrect position: expected (2, 1), got (%d, %d)", model.Line, model.Column)
+	}
+
+	sgp := model.Children[2]
+	if sgp.Line != 5 || sgp.Children[2].Type != NodeNumber {
+		t.Errorf("Incorrect sgp: %s (line %d)", sgp, sgp.Line)
+	}
+
+	production := model.Children[3]
+	if production.Children[4].Type != NodeString || production.Children[4].Value != "go" {
+		t.Errorf("Incorrect string: %s", production.Children[4])
+	}
+
+	focus := nodes[1].Children[1]
+	if focus.Head() != "quote" || !focus.Unquote().IsSymbol("FIRST") {
+		t.Errorf("Incorrect quote: %s", focus)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Read("(p foo\n  (bar)")
+
+	var syntaxErr ErrSyntax
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected syntax error, got %v", err)
+	}
+
+	if syntaxErr.Line != 1 || syntaxErr.Column != 1 {
+		t.Errorf("Incorrect position: expected (1, 1), got (%d, %d)", syntaxErr.Line, syntaxErr.Column)
+	}
+
+	_, err = Read(`(output "hello)`)
+	if err == nil {
+		t.Errorf("Expected error for unterminated string")
+	}
+}
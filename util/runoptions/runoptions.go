@@ -3,6 +3,7 @@ package runoptions
 
 import (
 	"slices"
+	"time"
 
 	"github.com/asmaloney/gactar/util/container"
 )
@@ -12,7 +13,7 @@ type ACTRLogLevel string
 var (
 	// ValidFrameworks lists the valid options for choosing frameworks on the command line and in the
 	// interactive case. Make sure "all" is the first entry as we use [1:] to get the rest.
-	ValidFrameworks = []string{"all", "ccm", "pyactr", "vanilla"}
+	ValidFrameworks = []string{"all", "ccm", "native", "pyactr", "vanilla"}
 
 	ACTRLoggingLevels = []string{
 		"min",
@@ -50,21 +51,34 @@ type Options struct {
 	// If true, output detailed info about activations
 	TraceActivations *bool
 
+	// If true, collect a framework-neutral trace of events (see framework.Trace)
+	TraceEvents *bool
+
 	// The seed to use for generating pseudo-random numbers (allows for reproducible runs)
 	// For all frameworks, if it is not set it uses current system time.
 	// Use a uint32 because pyactr uses numpy and that's what its random number seed uses.
 	RandomSeed *uint32
+
+	// The maximum simulated time (seconds) to run the model
+	// If it is not set, each framework uses its own default.
+	MaxTime *float64
+
+	// The maximum (real) time to let a run take before it is cancelled
+	// If it is not set, runs are not timed out.
+	Timeout *time.Duration
 }
 
 // New returns a default-initialized Options struct.
 func New() Options {
 	logLevel := ACTRLogLevel("info")
 	activations := false
+	events := false
 
 	return Options{
 		Frameworks:       FrameworkNameList{"all"},
 		LogLevel:         &logLevel,
 		TraceActivations: &activations,
+		TraceEvents:      &events,
 		RandomSeed:       nil,
 	}
 }
@@ -81,13 +95,30 @@ func (o Options) Override(cliOptions *Options) *Options {
 		options.TraceActivations = cliOptions.TraceActivations
 	}
 
+	if cliOptions.TraceEvents != nil {
+		options.TraceEvents = cliOptions.TraceEvents
+	}
+
 	if cliOptions.RandomSeed != nil {
 		options.RandomSeed = cliOptions.RandomSeed
 	}
 
+	if cliOptions.MaxTime != nil {
+		options.MaxTime = cliOptions.MaxTime
+	}
+
+	if cliOptions.Timeout != nil {
+		options.Timeout = cliOptions.Timeout
+	}
+
 	return &options
 }
 
+// IsTracingEvents returns whether we should collect a trace of events while running.
+func (o Options) IsTracingEvents() bool {
+	return o.TraceEvents != nil && *o.TraceEvents
+}
+
 // IsValidFramework returns if the framework name is in our list of valid ones or not.
 func IsValidFramework(frameworkName string) bool {
 	return slices.Contains(ValidFrameworks, frameworkName)
@@ -98,10 +129,10 @@ func ValidNamedFrameworks() []string {
 	return ValidFrameworks[1:]
 }
 
-// NormalizeFrameworkList will look for "all" and replace it with all available
-// framework names.
+// NormalizeFrameworkList will look for "all" (or an empty list) and replace it with all
+// available framework names.
 func (f *FrameworkNameList) NormalizeFrameworkList(activeFrameworks FrameworkNameList) {
-	if f == nil || slices.Contains(*f, "all") {
+	if len(*f) == 0 || slices.Contains(*f, "all") {
 		*f = activeFrameworks
 	}
 